@@ -2,11 +2,14 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -15,6 +18,9 @@ import (
 	"github.com/walnut-almonds/talkrealm/pkg/config"
 	"github.com/walnut-almonds/talkrealm/pkg/database"
 	"github.com/walnut-almonds/talkrealm/pkg/logger"
+	"github.com/walnut-almonds/talkrealm/pkg/tracing"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 func main() {
@@ -25,33 +31,73 @@ func main() {
 	}
 
 	// 初始化日誌
-	if err := logger.Init(cfg.Log.Level); err != nil {
+	appLogger, err := logger.New(&cfg.Log)
+	if err != nil {
 		log.Fatalf("Failed to initialize logger: %v", err)
 	}
-	defer logger.Sync()
+	defer func() { _ = appLogger.Sync() }()
 
-	logger.Info("config", "config", cfg)
+	auditLogger, err := logger.NewAudit(cfg.AuditLog.Output, cfg.Log.RedactFields)
+	if err != nil {
+		appLogger.Fatal("Failed to initialize audit logger", zap.Error(err))
+	}
+	defer func() { _ = auditLogger.Sync() }()
 
-	logger.Info("Starting TalkRealm", "version", buildinfo.Version)
+	// 機密設定（密碼、JWT secret 等）以 ****** 取代
+	appLogger.Info("config", zap.Any("config", cfg.Masked()))
+
+	appLogger.Info("Starting TalkRealm", zap.String("version", buildinfo.Version))
 
 	// 初始化資料庫
-	if err := database.Init(&cfg.Database); err != nil {
-		logger.Fatal("Failed to initialize database", "error", err)
+	db, err := database.Open(&cfg.Database, appLogger)
+	if err != nil {
+		appLogger.Fatal("Failed to initialize database", zap.Error(err))
+	}
+
+	defer func() { _ = database.Close(db) }()
+
+	// 初始化追蹤，exporter 為 none 時不產生任何 span
+	tracerProvider, err := tracing.New(context.Background(), &cfg.Tracing)
+	if err != nil {
+		appLogger.Fatal("Failed to initialize tracing", zap.Error(err))
+	}
+
+	if err := database.Trace(db, tracerProvider); err != nil {
+		appLogger.Fatal("Failed to enable database tracing", zap.Error(err))
 	}
 
-	defer func() { _ = database.Close() }()
+	// migrate 子命令只執行資料庫遷移，不啟動伺服器
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(db, appLogger, os.Args[2:]); err != nil {
+			appLogger.Fatal("Migration failed", zap.Error(err))
+		}
 
-	// 執行資料庫遷移（可選，建議在生產環境使用專門的遷移腳本）
-	if err := database.AutoMigrate(); err != nil {
-		logger.Fatal("Failed to migrate database", "error", err)
+		return
+	}
+
+	// 單節點部署可在啟動時自動套用遷移，省去另外執行 migrate 子命令
+	if cfg.Database.AutoMigrate {
+		if err := runMigrate(db, appLogger, []string{"up"}); err != nil {
+			appLogger.Fatal("Migration failed", zap.Error(err))
+		}
 	}
 
-	// 創建伺服器
-	srv, err := server.New(cfg)
+	// 創建伺服器，其餘相依元件依設定建立
+	srv, err := server.New(server.Options{
+		Config:         cfg,
+		DB:             db,
+		Logger:         appLogger,
+		TracerProvider: tracerProvider,
+		AuditLogger:    auditLogger,
+	})
 	if err != nil {
-		logger.Fatal("Failed to create server", "error", err)
+		appLogger.Fatal("Failed to create server", zap.Error(err))
 	}
 
+	// 所有請求的 context 都衍生自 baseCtx，關閉逾時時取消以中止仍在進行的查詢
+	baseCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+
 	// 啟動 HTTP 伺服器
 	httpServer := &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.Server.Port),
@@ -59,30 +105,199 @@ func main() {
 		ReadTimeout:  cfg.Server.ReadTimeout,
 		WriteTimeout: cfg.Server.WriteTimeout,
 		IdleTimeout:  cfg.Server.IdleTimeout,
+		BaseContext: func(net.Listener) context.Context {
+			return baseCtx
+		},
 	}
 
 	// 在 goroutine 中啟動伺服器
 	go func() {
-		logger.Info("Starting TalkRealm server", "port", cfg.Server.Port)
+		appLogger.Info("Starting TalkRealm server", zap.Int("port", cfg.Server.Port))
 
 		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logger.Fatal("Failed to start server", "error", err)
+			appLogger.Fatal("Failed to start server", zap.Error(err))
 		}
 	}()
 
+	// 指標設定獨立連接埠時另外啟動伺服器，不經過對外的 API 連接埠
+	var metricsServer *http.Server
+	if cfg.Metrics.Enabled && cfg.Metrics.Port > 0 {
+		mux := http.NewServeMux()
+		mux.Handle(cfg.Metrics.Path, srv.MetricsHandler())
+
+		metricsServer = &http.Server{
+			Addr:              fmt.Sprintf(":%d", cfg.Metrics.Port),
+			Handler:           mux,
+			ReadHeaderTimeout: cfg.Server.ReadTimeout,
+		}
+
+		go func() {
+			appLogger.Info("Starting metrics server", zap.Int("port", cfg.Metrics.Port))
+
+			if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				appLogger.Fatal("Failed to start metrics server", zap.Error(err))
+			}
+		}()
+	}
+
 	// 優雅關閉
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	logger.Info("Shutting down server...")
+	appLogger.Info("Shutting down server...")
+
+	// 先讓 /readyz 失敗，等負載平衡器將本實例移出後才停止接受連線
+	srv.BeginShutdown()
+
+	if cfg.Server.DrainDelay > 0 {
+		appLogger.Info(
+			"Waiting for load balancers to drain",
+			zap.Duration("delay", cfg.Server.DrainDelay),
+		)
+		time.Sleep(cfg.Server.DrainDelay)
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer cancel()
 
+	// WebSocket 連線與一般請求在同一個逾時內並行排空
+	wsDone := make(chan error, 1)
+	go func() {
+		wsDone <- srv.ShutdownWebSockets(ctx)
+	}()
+
 	if err := httpServer.Shutdown(ctx); err != nil {
-		logger.Fatal("Server forced to shutdown", "error", err)
+		appLogger.Warn(
+			"Graceful shutdown timed out, cancelling in-flight requests",
+			zap.Error(err),
+		)
+
+		cancelRequests()
+
+		if err := httpServer.Close(); err != nil {
+			appLogger.Error("Failed to close server", zap.Error(err))
+		}
+	}
+
+	if err := <-wsDone; err != nil {
+		appLogger.Warn("WebSocket drain did not finish in time", zap.Error(err))
+	}
+
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(ctx); err != nil {
+			appLogger.Error("Failed to shut down metrics server", zap.Error(err))
+		}
+	}
+
+	// 背景工作與事件轉送在資料庫關閉前停止
+	cancelRequests()
+	srv.Close()
+
+	// 送出尚未匯出的 span
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFlush()
+
+	if err := tracerProvider.Shutdown(flushCtx); err != nil {
+		appLogger.Error("Failed to flush traces", zap.Error(err))
+	}
+
+	appLogger.Info("Server exited")
+}
+
+// runMigrate 執行 migrate 子命令：up、down [步數]、status、to <版本>、wait [逾時]
+func runMigrate(db *gorm.DB, logger *zap.Logger, args []string) error {
+	migrator, err := database.NewMigrator(db, logger)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	switch args[0] {
+	case "up":
+		err = migrator.Up(ctx)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil {
+				return errors.New(migrateUsage)
+			}
+		}
+
+		err = migrator.Down(ctx, steps)
+	case "to":
+		if len(args) < 2 {
+			return errors.New(migrateUsage)
+		}
+
+		version, parseErr := strconv.ParseUint(args[1], 10, 32)
+		if parseErr != nil {
+			return errors.New(migrateUsage)
+		}
+
+		err = migrator.To(ctx, uint(version))
+	case "status":
+		return printMigrationStatus(ctx, migrator)
+	case "wait":
+		timeout := defaultMigrateWaitTimeout
+		if len(args) > 1 {
+			if timeout, err = time.ParseDuration(args[1]); err != nil {
+				return errors.New(migrateUsage)
+			}
+		}
+
+		waitCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		// 只等待遷移完成，不變更資料庫
+		return migrator.Wait(waitCtx, migrateWaitInterval)
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Database migration completed", zap.Strings("args", args))
+
+	return nil
+}
+
+const migrateUsage = "usage: migrate up | down [steps] | to <version> | status | wait [timeout]"
+
+const (
+	// defaultMigrateWaitTimeout migrate wait 未指定逾時時的等待上限
+	defaultMigrateWaitTimeout = 5 * time.Minute
+	// migrateWaitInterval migrate wait 檢查版本紀錄的間隔
+	migrateWaitInterval = 2 * time.Second
+)
+
+// printMigrationStatus 輸出每個遷移版本的套用狀態
+func printMigrationStatus(ctx context.Context, migrator *database.Migrator) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, status := range statuses {
+		state := "pending"
+		if status.AppliedAt != nil {
+			state = "applied " + status.AppliedAt.Format(time.RFC3339)
+		}
+
+		if status.Unknown {
+			state += " (unknown to this binary)"
+		}
+
+		fmt.Printf("%04d  %-32s %s\n", status.Version, status.Name, state)
 	}
 
-	logger.Info("Server exited")
+	return nil
 }
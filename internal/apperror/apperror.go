@@ -0,0 +1,188 @@
+package apperror
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// 通用錯誤代碼，領域錯誤則在各自的套件以 New 系列函式定義專屬代碼
+const (
+	CodeInvalidRequest   = "invalid_request"
+	CodeValidationFailed = "validation_failed"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeNotFound         = "not_found"
+	CodeRateLimited      = "rate_limited"
+	CodeInternal         = "internal_error"
+)
+
+// Error 應用程式錯誤，REST 與 WebSocket 都以 Code 讓客戶端判斷錯誤種類
+//
+// Message 會直接回傳給客戶端，Detail 與原始錯誤只會寫入日誌
+type Error struct {
+	Code       string         // 穩定的機器可讀錯誤代碼
+	Status     int            // 對應的 HTTP 狀態碼
+	Message    string         // 可顯示給使用者的訊息
+	Detail     string         // 僅供內部除錯的細節
+	Fields     []FieldError   // 欄位驗證錯誤
+	Details    map[string]any // 附帶給客戶端的額外資訊
+	RetryAfter time.Duration  // 大於零時回應 Retry-After 標頭
+	cause      error
+}
+
+// FieldError 單一欄位的驗證錯誤
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Response 錯誤回應的 JSON 格式
+type Response struct {
+	Code    string         `json:"code"              example:"guild_not_found"`
+	Error   string         `json:"error"             example:"guild not found"`
+	Fields  []FieldError   `json:"fields,omitempty"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// New 建立錯誤
+func New(code string, status int, message string) *Error {
+	return &Error{Code: code, Status: status, Message: message}
+}
+
+// InvalidArgument 建立 400 錯誤
+func InvalidArgument(code, message string) *Error {
+	return New(code, http.StatusBadRequest, message)
+}
+
+// Unauthorized 建立 401 錯誤
+func Unauthorized(code, message string) *Error {
+	return New(code, http.StatusUnauthorized, message)
+}
+
+// Forbidden 建立 403 錯誤
+func Forbidden(code, message string) *Error {
+	return New(code, http.StatusForbidden, message)
+}
+
+// NotFound 建立 404 錯誤
+func NotFound(code, message string) *Error {
+	return New(code, http.StatusNotFound, message)
+}
+
+// Conflict 建立 409 錯誤
+func Conflict(code, message string) *Error {
+	return New(code, http.StatusConflict, message)
+}
+
+// TooManyRequests 建立 429 錯誤
+func TooManyRequests(code, message string) *Error {
+	return New(code, http.StatusTooManyRequests, message)
+}
+
+// BadGateway 建立 502 錯誤，用於外部服務無法使用
+func BadGateway(code, message string) *Error {
+	return New(code, http.StatusBadGateway, message)
+}
+
+// ServiceUnavailable 建立 503 錯誤，用於伺服器暫時無法處理請求
+func ServiceUnavailable(code, message string) *Error {
+	return New(code, http.StatusServiceUnavailable, message)
+}
+
+// BadRequest 建立代碼為 invalid_request 的 400 錯誤
+func BadRequest(message string) *Error {
+	return InvalidArgument(CodeInvalidRequest, message)
+}
+
+// Internal 將非預期的錯誤包裝為 500，原始錯誤不會回傳給客戶端
+func Internal(err error) *Error {
+	e := New(CodeInternal, http.StatusInternalServerError, "internal server error")
+	if err != nil {
+		e.Detail = err.Error()
+		e.cause = err
+	}
+
+	return e
+}
+
+// From 取出錯誤鏈中的應用程式錯誤，找不到時視為內部錯誤
+//
+// 以 fmt.Errorf 包裝過的錯誤會保留原本的代碼與訊息，包裝後的完整內容記錄在 Detail
+func From(err error) *Error {
+	var appErr *Error
+	if !errors.As(err, &appErr) {
+		return Internal(err)
+	}
+
+	if appErr == err {
+		return appErr
+	}
+
+	wrapped := *appErr
+	wrapped.Detail = err.Error()
+	wrapped.cause = err
+
+	return &wrapped
+}
+
+func (e *Error) Error() string {
+	if e.Detail != "" && e.Detail != e.Message {
+		return e.Message + ": " + e.Detail
+	}
+
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Is 代碼相同即視為同一種錯誤，讓 With 系列方法產生的副本仍能以 errors.Is 比對
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// WithMessage 回傳使用另一段使用者訊息的副本
+func (e *Error) WithMessage(message string) *Error {
+	c := *e
+	c.Message = message
+
+	return &c
+}
+
+// WithDetail 回傳附帶內部細節的副本
+func (e *Error) WithDetail(detail string) *Error {
+	c := *e
+	c.Detail = detail
+
+	return &c
+}
+
+// WithDetails 回傳附帶額外資訊的副本
+func (e *Error) WithDetails(details map[string]any) *Error {
+	c := *e
+	c.Details = details
+
+	return &c
+}
+
+// WithRetryAfter 回傳附帶重試等待時間的副本
+func (e *Error) WithRetryAfter(d time.Duration) *Error {
+	c := *e
+	c.RetryAfter = d
+
+	return &c
+}
+
+// Response 轉換為回傳給客戶端的格式
+func (e *Error) Response() Response {
+	return Response{
+		Code:    e.Code,
+		Error:   e.Message,
+		Fields:  e.Fields,
+		Details: e.Details,
+	}
+}
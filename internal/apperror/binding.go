@@ -0,0 +1,110 @@
+package apperror
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// FromBinding 將 ShouldBindJSON 等函式回傳的錯誤轉換為 400 錯誤
+//
+// 驗證失敗時逐一列出欄位；JSON 格式錯誤只回傳通用訊息，不洩漏解析器的錯誤內容
+func FromBinding(err error) *Error {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		e := InvalidArgument(CodeValidationFailed, "request validation failed")
+		e.Detail = err.Error()
+		e.cause = err
+
+		for _, fe := range validationErrs {
+			e.Fields = append(e.Fields, FieldError{
+				Field:   fieldPath(fe),
+				Rule:    fe.Tag(),
+				Message: fieldMessage(fe),
+			})
+		}
+
+		return e
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		e := InvalidArgument(CodeValidationFailed, "request validation failed")
+		e.Detail = err.Error()
+		e.cause = err
+		e.Fields = []FieldError{{
+			Field:   typeErr.Field,
+			Rule:    "type",
+			Message: "must be of type " + typeErr.Type.String(),
+		}}
+
+		return e
+	}
+
+	message := "invalid request body"
+	if errors.Is(err, io.EOF) {
+		message = "request body is required"
+	}
+
+	e := BadRequest(message)
+	e.Detail = err.Error()
+	e.cause = err
+
+	return e
+}
+
+// fieldPath 回傳去掉最外層結構名稱的欄位路徑，例如 CreateGuildRequest.name 回傳 name
+func fieldPath(fe validator.FieldError) string {
+	namespace := fe.Namespace()
+	if _, path, ok := strings.Cut(namespace, "."); ok {
+		return path
+	}
+
+	return fe.Field()
+}
+
+// fieldMessage 依驗證規則產生欄位錯誤訊息
+func fieldMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "url", "http_url":
+		return "must be a valid URL"
+	case "min":
+		return "must be at least " + fe.Param() + lengthUnit(fe)
+	case "max":
+		return "must be at most " + fe.Param() + lengthUnit(fe)
+	case "len":
+		return "must be exactly " + fe.Param() + lengthUnit(fe)
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "gte":
+		return "must be greater than or equal to " + fe.Param()
+	case "lt":
+		return "must be less than " + fe.Param()
+	case "lte":
+		return "must be less than or equal to " + fe.Param()
+	case "oneof":
+		return "must be one of: " + strings.Join(strings.Fields(fe.Param()), ", ")
+	default:
+		return "failed the " + fe.Tag() + " rule"
+	}
+}
+
+// lengthUnit 字串與集合的長度限制附上單位
+func lengthUnit(fe validator.FieldError) string {
+	switch fe.Kind() {
+	case reflect.String:
+		return " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return " items"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/walnut-almonds/talkrealm/internal/service"
+)
+
+// AccountHandler 帳號刪除與個人資料匯出處理器
+type AccountHandler struct {
+	accountService    service.AccountService
+	dataExportService service.DataExportService
+}
+
+// NewAccountHandler 建立帳號處理器
+func NewAccountHandler(
+	accountService service.AccountService,
+	dataExportService service.DataExportService,
+) *AccountHandler {
+	return &AccountHandler{
+		accountService:    accountService,
+		dataExportService: dataExportService,
+	}
+}
+
+// DeleteAccount 申請刪除帳號
+//
+//	@Summary		申請刪除帳號
+//	@Description	驗證密碼後排定刪除帳號，寬限期結束後個人資料會被匿名化；以 SSO 建立的帳號不需密碼，但必須在最近重新登入
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			request	body		service.DeleteAccountRequest	false	"密碼確認"
+//	@Success		202		{object}	model.User
+//	@Failure		401		{object}	ErrorResponse
+//	@Failure		403		{object}	ErrorResponse	"SSO 帳號需要重新登入"
+//	@Router			/api/v1/users/me [delete]
+func (h *AccountHandler) DeleteAccount(c *gin.Context) {
+	// 以 SSO 建立的帳號不需要密碼，可以不帶請求內容
+	var req service.DeleteAccountRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			writeBindError(c, err)
+			return
+		}
+	}
+
+	req.AuthTime = c.GetTime("auth_time")
+
+	user, err := h.accountService.RequestDeletion(c.Request.Context(), c.GetUint("user_id"), &req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":               "account deletion scheduled",
+		"deletion_scheduled_at": user.DeletionScheduledAt,
+	})
+}
+
+// CancelDeletion 取消帳號刪除
+//
+//	@Summary	取消帳號刪除
+//	@Tags		users
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Success	200	{object}	model.User
+//	@Failure	409	{object}	ErrorResponse
+//	@Router		/api/v1/users/me/cancel-deletion [post]
+func (h *AccountHandler) CancelDeletion(c *gin.Context) {
+	user, err := h.accountService.CancelDeletion(c.Request.Context(), c.GetUint("user_id"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// RequestExport 申請匯出個人資料
+//
+//	@Summary		申請匯出個人資料
+//	@Description	建立非同步的資料匯出工作，完成後可下載 zip 檔
+//	@Tags			users
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Success		202	{object}	model.DataExport
+//	@Failure		409	{object}	ErrorResponse
+//	@Router			/api/v1/users/me/exports [post]
+func (h *AccountHandler) RequestExport(c *gin.Context) {
+	export, err := h.dataExportService.RequestExport(c.Request.Context(), c.GetUint("user_id"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, export)
+}
+
+// ListExports 列出個人資料匯出工作
+//
+//	@Summary	列出個人資料匯出工作
+//	@Tags		users
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Success	200	{array}	model.DataExport
+//	@Router		/api/v1/users/me/exports [get]
+func (h *AccountHandler) ListExports(c *gin.Context) {
+	exports, err := h.dataExportService.ListExports(c.Request.Context(), c.GetUint("user_id"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, exports)
+}
+
+// DownloadExport 下載個人資料匯出檔
+//
+//	@Summary	下載個人資料匯出檔
+//	@Tags		users
+//	@Produce	application/zip
+//	@Security	BearerAuth
+//	@Param		exportId	path	int	true	"匯出工作 ID"
+//	@Success	200
+//	@Failure	404	{object}	ErrorResponse
+//	@Failure	409	{object}	ErrorResponse
+//	@Router		/api/v1/users/me/exports/{exportId}/download [get]
+func (h *AccountHandler) DownloadExport(c *gin.Context) {
+	exportID, err := strconv.ParseUint(c.Param("exportId"), 10, 32)
+	if err != nil {
+		writeError(c, errInvalidExportID)
+		return
+	}
+
+	export, rc, err := h.dataExportService.OpenExport(
+		c.Request.Context(),
+		c.GetUint("user_id"),
+		uint(exportID),
+	)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	defer rc.Close()
+
+	filename := fmt.Sprintf("talkrealm-export-%d.zip", export.ID)
+	c.DataFromReader(http.StatusOK, export.Size, "application/zip", rc, map[string]string{
+		"Content-Disposition": `attachment; filename="` + filename + `"`,
+	})
+}
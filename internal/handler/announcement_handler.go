@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/walnut-almonds/talkrealm/internal/service"
+)
+
+type AnnouncementHandler struct {
+	announcementService service.AnnouncementService
+}
+
+func NewAnnouncementHandler(announcementService service.AnnouncementService) *AnnouncementHandler {
+	return &AnnouncementHandler{
+		announcementService: announcementService,
+	}
+}
+
+// FollowChannelRequest 追蹤公告頻道請求
+type FollowChannelRequest struct {
+	SourceChannelID uint `json:"source_channel_id" binding:"required"`
+}
+
+// FollowChannel 追蹤公告頻道
+//
+//	@Summary		追蹤公告頻道
+//	@Description	讓文字頻道追蹤其他社群的公告頻道，公告發佈時會自動轉發（需要管理頻道權限）
+//	@Tags			Announcement
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int						true	"目標文字頻道 ID"
+//	@Param			request	body		FollowChannelRequest	true	"追蹤請求"
+//	@Success		201		{object}	model.ChannelFollow
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		403		{object}	ErrorResponse
+//	@Failure		404		{object}	ErrorResponse
+//	@Failure		409		{object}	ErrorResponse
+//	@Router			/api/v1/channels/{id}/follows [post]
+func (h *AnnouncementHandler) FollowChannel(c *gin.Context) {
+	channelID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		writeError(c, errInvalidChannelID)
+		return
+	}
+
+	var req FollowChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeBindError(c, err)
+		return
+	}
+
+	follow, err := h.announcementService.FollowChannel(
+		auditContext(c),
+		uint(channelID),
+		req.SourceChannelID,
+		c.GetUint("user_id"),
+	)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, follow)
+}
+
+// UnfollowChannel 取消追蹤公告頻道
+//
+//	@Summary	取消追蹤公告頻道
+//	@Tags		Announcement
+//	@Produce	json
+//	@Param		id			path		int	true	"目標文字頻道 ID"
+//	@Param		sourceId	path		int	true	"公告頻道 ID"
+//	@Success	200			{object}	SuccessResponse
+//	@Failure	403			{object}	ErrorResponse
+//	@Failure	404			{object}	ErrorResponse
+//	@Router		/api/v1/channels/{id}/follows/{sourceId} [delete]
+func (h *AnnouncementHandler) UnfollowChannel(c *gin.Context) {
+	channelID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		writeError(c, errInvalidChannelID)
+		return
+	}
+
+	sourceID, err := strconv.ParseUint(c.Param("sourceId"), 10, 32)
+	if err != nil {
+		writeError(c, errInvalidSourceChannelID)
+		return
+	}
+
+	err = h.announcementService.UnfollowChannel(
+		auditContext(c),
+		uint(channelID),
+		uint(sourceID),
+		c.GetUint("user_id"),
+	)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "channel unfollowed successfully"})
+}
+
+// ListFollowing 列出頻道追蹤的公告頻道
+//
+//	@Summary	列出頻道追蹤的公告頻道
+//	@Tags		Announcement
+//	@Produce	json
+//	@Param		id	path		int	true	"目標文字頻道 ID"
+//	@Success	200	{array}		model.ChannelFollow
+//	@Failure	403	{object}	ErrorResponse
+//	@Failure	404	{object}	ErrorResponse
+//	@Router		/api/v1/channels/{id}/follows [get]
+func (h *AnnouncementHandler) ListFollowing(c *gin.Context) {
+	channelID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		writeError(c, errInvalidChannelID)
+		return
+	}
+
+	follows, err := h.announcementService.ListFollowing(
+		c.Request.Context(),
+		uint(channelID),
+		c.GetUint("user_id"),
+	)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, follows)
+}
+
+// PublishMessage 發佈公告訊息
+//
+//	@Summary		發佈公告訊息
+//	@Description	將公告頻道的訊息轉發到所有追蹤頻道；每則訊息只會轉發一次，重複發佈不會再次轉發
+//	@Tags			Announcement
+//	@Produce		json
+//	@Param			id	path		int	true	"訊息 ID"
+//	@Success		200	{object}	model.Message
+//	@Failure		400	{object}	ErrorResponse
+//	@Failure		403	{object}	ErrorResponse
+//	@Failure		404	{object}	ErrorResponse
+//	@Router			/api/v1/messages/{id}/publish [post]
+func (h *AnnouncementHandler) PublishMessage(c *gin.Context) {
+	messageID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		writeError(c, errInvalidMessageID)
+		return
+	}
+
+	message, err := h.announcementService.PublishMessage(
+		c.Request.Context(),
+		uint(messageID),
+		c.GetUint("user_id"),
+	)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, message)
+}
@@ -0,0 +1,223 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/walnut-almonds/talkrealm/internal/service"
+)
+
+// APITokenHandler API token 與機器人帳號處理器
+type APITokenHandler struct {
+	apiTokenService service.APITokenService
+}
+
+// NewAPITokenHandler 建立 API token 處理器
+func NewAPITokenHandler(apiTokenService service.APITokenService) *APITokenHandler {
+	return &APITokenHandler{
+		apiTokenService: apiTokenService,
+	}
+}
+
+// CreateToken 建立個人 API token
+//
+//	@Summary		建立個人 API token
+//	@Description	建立長期有效且限定權限範圍的 API token，token 只會顯示一次
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			request	body		service.CreateAPITokenRequest	true	"建立 token 請求"
+//	@Success		201		{object}	service.CreateAPITokenResponse
+//	@Failure		400		{object}	ErrorResponse
+//	@Router			/api/v1/users/me/tokens [post]
+func (h *APITokenHandler) CreateToken(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	h.createToken(c, userID, userID)
+}
+
+// ListTokens 列出個人 API token
+//
+//	@Summary	列出個人 API token
+//	@Tags		users
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Success	200	{array}	service.APITokenResponse
+//	@Router		/api/v1/users/me/tokens [get]
+func (h *APITokenHandler) ListTokens(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	h.listTokens(c, userID, userID)
+}
+
+// RevokeToken 撤銷個人 API token
+//
+//	@Summary	撤銷個人 API token
+//	@Tags		users
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		tokenId	path		int	true	"Token ID"
+//	@Success	200		{object}	SuccessResponse
+//	@Failure	404		{object}	ErrorResponse
+//	@Router		/api/v1/users/me/tokens/{tokenId} [delete]
+func (h *APITokenHandler) RevokeToken(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	h.revokeToken(c, userID, userID)
+}
+
+// CreateBot 建立機器人帳號
+//
+//	@Summary	建立機器人帳號
+//	@Tags		users
+//	@Accept		json
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		request	body		service.CreateBotRequest	true	"建立機器人請求"
+//	@Success	201		{object}	model.User
+//	@Failure	400		{object}	ErrorResponse
+//	@Failure	409		{object}	ErrorResponse
+//	@Router		/api/v1/users/me/bots [post]
+func (h *APITokenHandler) CreateBot(c *gin.Context) {
+	var req service.CreateBotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeBindError(c, err)
+		return
+	}
+
+	bot, err := h.apiTokenService.CreateBot(c.Request.Context(), c.GetUint("user_id"), &req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, bot)
+}
+
+// ListBots 列出使用者擁有的機器人帳號
+//
+//	@Summary	列出機器人帳號
+//	@Tags		users
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Success	200	{array}	model.User
+//	@Router		/api/v1/users/me/bots [get]
+func (h *APITokenHandler) ListBots(c *gin.Context) {
+	bots, err := h.apiTokenService.ListBots(c.Request.Context(), c.GetUint("user_id"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, bots)
+}
+
+// CreateBotToken 為機器人建立 API token
+//
+//	@Summary	為機器人建立 API token
+//	@Tags		users
+//	@Accept		json
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		botId	path		int								true	"機器人使用者 ID"
+//	@Param		request	body		service.CreateAPITokenRequest	true	"建立 token 請求"
+//	@Success	201		{object}	service.CreateAPITokenResponse
+//	@Failure	403		{object}	ErrorResponse
+//	@Failure	404		{object}	ErrorResponse
+//	@Router		/api/v1/users/me/bots/{botId}/tokens [post]
+func (h *APITokenHandler) CreateBotToken(c *gin.Context) {
+	botID, ok := parseBotID(c)
+	if !ok {
+		return
+	}
+
+	h.createToken(c, c.GetUint("user_id"), botID)
+}
+
+// ListBotTokens 列出機器人的 API token
+//
+//	@Summary	列出機器人的 API token
+//	@Tags		users
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		botId	path	int	true	"機器人使用者 ID"
+//	@Success	200		{array}	service.APITokenResponse
+//	@Router		/api/v1/users/me/bots/{botId}/tokens [get]
+func (h *APITokenHandler) ListBotTokens(c *gin.Context) {
+	botID, ok := parseBotID(c)
+	if !ok {
+		return
+	}
+
+	h.listTokens(c, c.GetUint("user_id"), botID)
+}
+
+// RevokeBotToken 撤銷機器人的 API token
+//
+//	@Summary	撤銷機器人的 API token
+//	@Tags		users
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		botId	path		int	true	"機器人使用者 ID"
+//	@Param		tokenId	path		int	true	"Token ID"
+//	@Success	200		{object}	SuccessResponse
+//	@Router		/api/v1/users/me/bots/{botId}/tokens/{tokenId} [delete]
+func (h *APITokenHandler) RevokeBotToken(c *gin.Context) {
+	botID, ok := parseBotID(c)
+	if !ok {
+		return
+	}
+
+	h.revokeToken(c, c.GetUint("user_id"), botID)
+}
+
+func (h *APITokenHandler) createToken(c *gin.Context, actorID, userID uint) {
+	var req service.CreateAPITokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeBindError(c, err)
+		return
+	}
+
+	resp, err := h.apiTokenService.CreateToken(c.Request.Context(), actorID, userID, &req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, resp)
+}
+
+func (h *APITokenHandler) listTokens(c *gin.Context, actorID, userID uint) {
+	tokens, err := h.apiTokenService.ListTokens(c.Request.Context(), actorID, userID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+func (h *APITokenHandler) revokeToken(c *gin.Context, actorID, userID uint) {
+	tokenID, err := strconv.ParseUint(c.Param("tokenId"), 10, 32)
+	if err != nil {
+		writeError(c, errInvalidTokenID)
+		return
+	}
+
+	if err := h.apiTokenService.RevokeToken(c.Request.Context(), actorID, userID, uint(tokenID)); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "api token revoked successfully"})
+}
+
+// parseBotID 解析路徑中的機器人 ID
+func parseBotID(c *gin.Context) (uint, bool) {
+	botID, err := strconv.ParseUint(c.Param("botId"), 10, 32)
+	if err != nil {
+		writeError(c, errInvalidBotID)
+		return 0, false
+	}
+
+	return uint(botID), true
+}
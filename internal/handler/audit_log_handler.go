@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/walnut-almonds/talkrealm/internal/service"
+)
+
+// auditLogReasonHeader 特權操作可附帶的稽核紀錄原因標頭
+const auditLogReasonHeader = "X-Audit-Log-Reason"
+
+// auditContext 建立帶有稽核紀錄原因的 context
+func auditContext(c *gin.Context) context.Context {
+	return service.WithAuditReason(c.Request.Context(), c.GetHeader(auditLogReasonHeader))
+}
+
+type AuditLogHandler struct {
+	auditLogService service.AuditLogService
+}
+
+func NewAuditLogHandler(auditLogService service.AuditLogService) *AuditLogHandler {
+	return &AuditLogHandler{
+		auditLogService: auditLogService,
+	}
+}
+
+// ListAuditLogs 列出社群稽核紀錄
+//
+//	@Summary		列出社群稽核紀錄
+//	@Description	依時間由新到舊列出社群的特權操作紀錄，可依動作、操作者與時間範圍篩選
+//	@Tags			AuditLog
+//	@Produce		json
+//	@Param			id			path		int		true	"社群 ID"
+//	@Param			action		query		string	false	"動作類型，例如 member.ban"
+//	@Param			actor_id	query		int		false	"操作者 ID"
+//	@Param			after		query		string	false	"起始時間（RFC3339）"
+//	@Param			before		query		string	false	"結束時間（RFC3339）"
+//	@Param			before_id	query		int		false	"分頁游標，只回傳 ID 小於此值的紀錄"
+//	@Param			limit		query		int		false	"筆數上限（1-100，預設 50）"
+//	@Success		200			{array}		service.AuditLogEntryResponse
+//	@Failure		400			{object}	ErrorResponse
+//	@Failure		403			{object}	ErrorResponse
+//	@Failure		404			{object}	ErrorResponse
+//	@Router			/api/v1/guilds/{id}/audit-logs [get]
+func (h *AuditLogHandler) ListAuditLogs(c *gin.Context) {
+	guildID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		writeError(c, errInvalidGuildID)
+		return
+	}
+
+	var req service.ListAuditLogsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		writeBindError(c, err)
+		return
+	}
+
+	entries, err := h.auditLogService.ListEntries(
+		c.Request.Context(),
+		uint(guildID),
+		c.GetUint("user_id"),
+		&req,
+	)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
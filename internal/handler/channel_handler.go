@@ -1,7 +1,6 @@
 package handler
 
 import (
-	"errors"
 	"net/http"
 	"strconv"
 
@@ -22,7 +21,7 @@ func NewChannelHandler(channelService service.ChannelService) *ChannelHandler {
 // CreateChannel 建立頻道
 //
 //	@Summary		建立頻道
-//	@Description	在社群中建立新的文字或語音頻道（僅擁有者或管理員）
+//	@Description	在社群中建立新的文字、語音或分類頻道（僅擁有者或管理員），可指定所屬分類
 //	@Tags			Channel
 //	@Accept			json
 //	@Produce		json
@@ -37,19 +36,19 @@ func (h *ChannelHandler) CreateChannel(c *gin.Context) {
 	// 從 URL 參數獲取 guild_id
 	guildIDStr := c.Param("id")
 	if guildIDStr == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "guild_id is required"})
+		writeError(c, errInvalidGuildID)
 		return
 	}
 
 	guildID, err := strconv.ParseUint(guildIDStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid guild_id"})
+		writeError(c, errInvalidGuildID)
 		return
 	}
 
 	var req service.CreateChannelRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeBindError(c, err)
 		return
 	}
 
@@ -58,34 +57,9 @@ func (h *ChannelHandler) CreateChannel(c *gin.Context) {
 
 	userID := c.GetUint("user_id")
 
-	channel, err := h.channelService.CreateChannel(userID, &req)
+	channel, err := h.channelService.CreateChannel(auditContext(c), userID, &req)
 	if err != nil {
-		if errors.Is(err, service.ErrGuildNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "guild not found"})
-			return
-		}
-
-		if errors.Is(err, service.ErrNotGuildMemberCh) {
-			c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this guild"})
-			return
-		}
-
-		if errors.Is(err, service.ErrInvalidChannelType) {
-			c.JSON(
-				http.StatusBadRequest,
-				gin.H{"error": "invalid channel type, must be 'text' or 'voice'"},
-			)
-
-			return
-		}
-
-		if err.Error() == "only owner or admin can create channels" {
-			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
-			return
-		}
-
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-
+		writeError(c, err)
 		return
 	}
 
@@ -108,26 +82,15 @@ func (h *ChannelHandler) CreateChannel(c *gin.Context) {
 func (h *ChannelHandler) GetChannel(c *gin.Context) {
 	channelID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid channel ID"})
+		writeError(c, errInvalidChannelID)
 		return
 	}
 
 	userID := c.GetUint("user_id")
 
-	channel, err := h.channelService.GetChannel(uint(channelID), userID)
+	channel, err := h.channelService.GetChannel(c.Request.Context(), uint(channelID), userID)
 	if err != nil {
-		if errors.Is(err, service.ErrChannelNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "channel not found"})
-			return
-		}
-
-		if errors.Is(err, service.ErrNotGuildMemberCh) {
-			c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this guild"})
-			return
-		}
-
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-
+		writeError(c, err)
 		return
 	}
 
@@ -150,32 +113,21 @@ func (h *ChannelHandler) GetChannel(c *gin.Context) {
 func (h *ChannelHandler) ListGuildChannels(c *gin.Context) {
 	guildIDStr := c.Param("id")
 	if guildIDStr == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "guild_id is required"})
+		writeError(c, errInvalidGuildID)
 		return
 	}
 
 	guildID, err := strconv.ParseUint(guildIDStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid guild_id"})
+		writeError(c, errInvalidGuildID)
 		return
 	}
 
 	userID := c.GetUint("user_id")
 
-	channels, err := h.channelService.ListGuildChannels(uint(guildID), userID)
+	channels, err := h.channelService.ListGuildChannels(c.Request.Context(), uint(guildID), userID)
 	if err != nil {
-		if errors.Is(err, service.ErrGuildNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "guild not found"})
-			return
-		}
-
-		if errors.Is(err, service.ErrNotGuildMemberCh) {
-			c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this guild"})
-			return
-		}
-
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-
+		writeError(c, err)
 		return
 	}
 
@@ -200,41 +152,21 @@ func (h *ChannelHandler) ListGuildChannels(c *gin.Context) {
 func (h *ChannelHandler) UpdateChannel(c *gin.Context) {
 	channelID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid channel ID"})
+		writeError(c, errInvalidChannelID)
 		return
 	}
 
 	var req service.UpdateChannelRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeBindError(c, err)
 		return
 	}
 
 	userID := c.GetUint("user_id")
 
-	channel, err := h.channelService.UpdateChannel(uint(channelID), userID, &req)
+	channel, err := h.channelService.UpdateChannel(auditContext(c), uint(channelID), userID, &req)
 	if err != nil {
-		if errors.Is(err, service.ErrChannelNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "channel not found"})
-			return
-		}
-
-		if errors.Is(err, service.ErrInvalidChannelType) {
-			c.JSON(
-				http.StatusBadRequest,
-				gin.H{"error": "invalid channel type, must be 'text' or 'voice'"},
-			)
-
-			return
-		}
-
-		if err.Error() == "only owner or admin can update channels" {
-			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
-			return
-		}
-
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-
+		writeError(c, err)
 		return
 	}
 
@@ -258,26 +190,15 @@ func (h *ChannelHandler) UpdateChannel(c *gin.Context) {
 func (h *ChannelHandler) DeleteChannel(c *gin.Context) {
 	channelID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid channel ID"})
+		writeError(c, errInvalidChannelID)
 		return
 	}
 
 	userID := c.GetUint("user_id")
 
-	err = h.channelService.DeleteChannel(uint(channelID), userID)
+	err = h.channelService.DeleteChannel(auditContext(c), uint(channelID), userID)
 	if err != nil {
-		if errors.Is(err, service.ErrChannelNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "channel not found"})
-			return
-		}
-
-		if err.Error() == "only owner or admin can delete channels" {
-			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
-			return
-		}
-
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-
+		writeError(c, err)
 		return
 	}
 
@@ -302,32 +223,26 @@ func (h *ChannelHandler) DeleteChannel(c *gin.Context) {
 func (h *ChannelHandler) UpdateChannelPosition(c *gin.Context) {
 	channelID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid channel ID"})
+		writeError(c, errInvalidChannelID)
 		return
 	}
 
 	var req PositionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeBindError(c, err)
 		return
 	}
 
 	userID := c.GetUint("user_id")
 
-	err = h.channelService.UpdateChannelPosition(uint(channelID), userID, req.Position)
+	err = h.channelService.UpdateChannelPosition(
+		auditContext(c),
+		uint(channelID),
+		userID,
+		req.Position,
+	)
 	if err != nil {
-		if errors.Is(err, service.ErrChannelNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "channel not found"})
-			return
-		}
-
-		if err.Error() == "only owner or admin can update channel position" {
-			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
-			return
-		}
-
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-
+		writeError(c, err)
 		return
 	}
 
@@ -337,3 +252,45 @@ func (h *ChannelHandler) UpdateChannelPosition(c *gin.Context) {
 type PositionRequest struct {
 	Position int `json:"position" binding:"required,min=0"`
 }
+
+// ReorderChannels 批次調整頻道排序
+//
+//	@Summary		批次調整頻道排序
+//	@Description	在同一個交易中調整多個頻道的位置與所屬分類，並重新編號每個分類內的頻道
+//	@Tags			Channel
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int								true	"社群 ID"
+//	@Param			request	body		[]service.ChannelPositionUpdate	true	"排序設定"
+//	@Success		200		{array}		model.Channel
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		401		{object}	ErrorResponse
+//	@Failure		403		{object}	ErrorResponse
+//	@Failure		404		{object}	ErrorResponse
+//	@Router			/api/v1/guilds/{id}/channels [patch]
+func (h *ChannelHandler) ReorderChannels(c *gin.Context) {
+	guildID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		writeError(c, errInvalidGuildID)
+		return
+	}
+
+	var req []service.ChannelPositionUpdate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeBindError(c, err)
+		return
+	}
+
+	channels, err := h.channelService.ReorderChannels(
+		auditContext(c),
+		uint(guildID),
+		c.GetUint("user_id"),
+		req,
+	)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, channels)
+}
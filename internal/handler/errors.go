@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/walnut-almonds/talkrealm/internal/apperror"
+)
+
+// 路徑與查詢參數格式錯誤
+var (
+	errInvalidGuildID         = apperror.BadRequest("invalid guild ID")
+	errInvalidChannelID       = apperror.BadRequest("invalid channel ID")
+	errInvalidSourceChannelID = apperror.BadRequest("invalid source channel ID")
+	errInvalidMessageID       = apperror.BadRequest("invalid message ID")
+	errInvalidUserID          = apperror.BadRequest("invalid user ID")
+	errInvalidWebhookID       = apperror.BadRequest("invalid webhook ID")
+	errInvalidEventWebhookID  = apperror.BadRequest("invalid event webhook ID")
+	errInvalidTokenID         = apperror.BadRequest("invalid token ID")
+	errInvalidBotID           = apperror.BadRequest("invalid bot ID")
+	errInvalidExportID        = apperror.BadRequest("invalid export ID")
+	errUnauthenticated        = apperror.Unauthorized(apperror.CodeUnauthorized, "unauthorized")
+)
+
+func init() {
+	// 驗證錯誤的欄位名稱使用 JSON 名稱，與客戶端送出的欄位一致
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(jsonFieldName)
+	}
+}
+
+// jsonFieldName 取得結構欄位的 JSON 名稱，沒有 json 標籤時使用 form 標籤
+func jsonFieldName(field reflect.StructField) string {
+	for _, key := range []string{"json", "form"} {
+		name, _, _ := strings.Cut(field.Tag.Get(key), ",")
+		if name == "-" {
+			return ""
+		}
+
+		if name != "" {
+			return name
+		}
+	}
+
+	return field.Name
+}
+
+// writeError 記錄錯誤並中止請求，由 middleware.ErrorHandler 依錯誤代碼輸出 ErrorResponse
+func writeError(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+}
+
+// writeBindError 請求內容解析或驗證失敗，回應中會列出驗證失敗的欄位
+func writeBindError(c *gin.Context, err error) {
+	_ = c.Error(err).SetType(gin.ErrorTypeBind)
+	c.Abort()
+}
@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/walnut-almonds/talkrealm/internal/service"
+)
+
+type EventWebhookHandler struct {
+	eventWebhookService service.EventWebhookService
+}
+
+func NewEventWebhookHandler(eventWebhookService service.EventWebhookService) *EventWebhookHandler {
+	return &EventWebhookHandler{
+		eventWebhookService: eventWebhookService,
+	}
+}
+
+// CreateEventWebhook 建立傳出事件 webhook
+//
+//	@Summary		建立傳出事件 webhook
+//	@Description	註冊 HTTPS 端點接收社群事件（需要管理 webhook 權限），回應中的簽章秘密只會顯示一次
+//	@Tags			Event Webhook
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int									true	"社群 ID"
+//	@Param			request	body		service.CreateEventWebhookRequest	true	"建立傳出事件 webhook 請求"
+//	@Success		201		{object}	service.EventWebhookSecretResponse
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		403		{object}	ErrorResponse
+//	@Router			/api/v1/guilds/{id}/event-webhooks [post]
+func (h *EventWebhookHandler) CreateEventWebhook(c *gin.Context) {
+	guildID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		writeError(c, errInvalidGuildID)
+		return
+	}
+
+	var req service.CreateEventWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeBindError(c, err)
+		return
+	}
+
+	webhook, err := h.eventWebhookService.CreateEventWebhook(
+		auditContext(c),
+		uint(guildID),
+		c.GetUint("user_id"),
+		&req,
+	)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, webhook)
+}
+
+// ListEventWebhooks 列出社群的傳出事件 webhook
+//
+//	@Summary	列出社群的傳出事件 webhook
+//	@Tags		Event Webhook
+//	@Produce	json
+//	@Param		id	path		int	true	"社群 ID"
+//	@Success	200	{array}		service.EventWebhookResponse
+//	@Failure	403	{object}	ErrorResponse
+//	@Router		/api/v1/guilds/{id}/event-webhooks [get]
+func (h *EventWebhookHandler) ListEventWebhooks(c *gin.Context) {
+	guildID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		writeError(c, errInvalidGuildID)
+		return
+	}
+
+	webhooks, err := h.eventWebhookService.ListEventWebhooks(
+		c.Request.Context(),
+		uint(guildID),
+		c.GetUint("user_id"),
+	)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, webhooks)
+}
+
+// UpdateEventWebhook 更新傳出事件 webhook
+//
+//	@Summary		更新傳出事件 webhook
+//	@Description	可更新網址、訂閱事件、啟用狀態或更換簽章秘密；重新啟用時連續失敗次數會歸零
+//	@Tags			Event Webhook
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int									true	"社群 ID"
+//	@Param			webhookId	path		int									true	"傳出事件 webhook ID"
+//	@Param			request		body		service.UpdateEventWebhookRequest	true	"更新傳出事件 webhook 請求"
+//	@Success		200			{object}	service.EventWebhookSecretResponse
+//	@Failure		400			{object}	ErrorResponse
+//	@Failure		403			{object}	ErrorResponse
+//	@Failure		404			{object}	ErrorResponse
+//	@Router			/api/v1/guilds/{id}/event-webhooks/{webhookId} [patch]
+func (h *EventWebhookHandler) UpdateEventWebhook(c *gin.Context) {
+	guildID, webhookID, ok := parseEventWebhookParams(c)
+	if !ok {
+		return
+	}
+
+	var req service.UpdateEventWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeBindError(c, err)
+		return
+	}
+
+	webhook, err := h.eventWebhookService.UpdateEventWebhook(
+		auditContext(c),
+		guildID,
+		webhookID,
+		c.GetUint("user_id"),
+		&req,
+	)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// DeleteEventWebhook 刪除傳出事件 webhook
+//
+//	@Summary	刪除傳出事件 webhook
+//	@Tags		Event Webhook
+//	@Produce	json
+//	@Param		id			path		int	true	"社群 ID"
+//	@Param		webhookId	path		int	true	"傳出事件 webhook ID"
+//	@Success	200			{object}	SuccessResponse
+//	@Failure	403			{object}	ErrorResponse
+//	@Failure	404			{object}	ErrorResponse
+//	@Router		/api/v1/guilds/{id}/event-webhooks/{webhookId} [delete]
+func (h *EventWebhookHandler) DeleteEventWebhook(c *gin.Context) {
+	guildID, webhookID, ok := parseEventWebhookParams(c)
+	if !ok {
+		return
+	}
+
+	err := h.eventWebhookService.DeleteEventWebhook(
+		auditContext(c),
+		guildID,
+		webhookID,
+		c.GetUint("user_id"),
+	)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "event webhook deleted successfully"})
+}
+
+// ListDeliveries 列出傳出事件 webhook 的投遞紀錄
+//
+//	@Summary	列出傳出事件 webhook 的投遞紀錄
+//	@Tags		Event Webhook
+//	@Produce	json
+//	@Param		id			path		int	true	"社群 ID"
+//	@Param		webhookId	path		int	true	"傳出事件 webhook ID"
+//	@Param		limit		query		int	false	"筆數（最多 100）"	default(50)
+//	@Success	200			{array}		model.EventDelivery
+//	@Failure	403			{object}	ErrorResponse
+//	@Failure	404			{object}	ErrorResponse
+//	@Router		/api/v1/guilds/{id}/event-webhooks/{webhookId}/deliveries [get]
+func (h *EventWebhookHandler) ListDeliveries(c *gin.Context) {
+	guildID, webhookID, ok := parseEventWebhookParams(c)
+	if !ok {
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+
+	deliveries, err := h.eventWebhookService.ListDeliveries(
+		c.Request.Context(),
+		guildID,
+		webhookID,
+		c.GetUint("user_id"),
+		limit,
+	)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// parseEventWebhookParams 解析路徑中的社群 ID 與傳出事件 webhook ID
+func parseEventWebhookParams(c *gin.Context) (uint, uint, bool) {
+	guildID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		writeError(c, errInvalidGuildID)
+		return 0, 0, false
+	}
+
+	webhookID, err := strconv.ParseUint(c.Param("webhookId"), 10, 32)
+	if err != nil {
+		writeError(c, errInvalidEventWebhookID)
+		return 0, 0, false
+	}
+
+	return uint(guildID), uint(webhookID), true
+}
@@ -1,28 +1,35 @@
 package handler
 
 import (
-	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/walnut-almonds/talkrealm/internal/apperror"
+	"github.com/walnut-almonds/talkrealm/internal/middleware"
+	"github.com/walnut-almonds/talkrealm/internal/model"
 	"github.com/walnut-almonds/talkrealm/internal/service"
 	"github.com/walnut-almonds/talkrealm/pkg/logger"
+	"go.uber.org/zap"
 )
 
 type GuildHandler struct {
 	guildService       service.GuildService
 	guildMemberService service.GuildMemberService
+	logger             *zap.Logger
 }
 
 func NewGuildHandler(
 	guildService service.GuildService,
 	guildMemberService service.GuildMemberService,
+	logger *zap.Logger,
 ) *GuildHandler {
 	return &GuildHandler{
 		guildService:       guildService,
 		guildMemberService: guildMemberService,
+		logger:             logger,
 	}
 }
 
@@ -37,33 +44,36 @@ func NewGuildHandler(
 //	@Success		201		{object}	model.Guild
 //	@Failure		400		{object}	ErrorResponse
 //	@Failure		401		{object}	ErrorResponse
+//	@Failure		403		{object}	ErrorResponse	"限制社群的 API token 無法建立社群"
 //	@Router			/api/v1/guilds [post]
 func (h *GuildHandler) CreateGuild(c *gin.Context) {
 	var req service.CreateGuildRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeBindError(c, err)
 		return
 	}
 
 	// Get userID from context with detailed logging
 	userIDValue, exists := c.Get("user_id")
-	logger.Info("CreateGuild context check",
-		"user_id_exists", exists,
-		"user_id_value", userIDValue,
-		"user_id_type", fmt.Sprintf("%T", userIDValue))
+	logger.FromContext(c.Request.Context(), h.logger).Info("CreateGuild context check",
+		zap.Bool("user_id_exists", exists),
+		zap.Any("user_id_value", userIDValue),
+		zap.String("user_id_type", fmt.Sprintf("%T", userIDValue)))
 
 	userID := c.GetUint("user_id")
-	logger.Info("CreateGuild userID retrieved", "userID", userID)
+	logger.FromContext(c.Request.Context(), h.logger).
+		Info("CreateGuild userID retrieved", zap.Uint("userID", userID))
 
 	if userID == 0 {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "user ID not found in context"})
+		writeError(c, errUnauthenticated)
 		return
 	}
 
-	guild, err := h.guildService.CreateGuild(userID, &req)
+	guild, err := h.guildService.CreateGuild(c.Request.Context(), userID, &req)
 	if err != nil {
-		logger.Error("CreateGuild failed", "error", err, "userID", userID)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		logger.FromContext(c.Request.Context(), h.logger).
+			Error("CreateGuild failed", zap.Error(err), zap.Uint("userID", userID))
+		writeError(c, err)
 		return
 	}
 
@@ -85,19 +95,13 @@ func (h *GuildHandler) CreateGuild(c *gin.Context) {
 func (h *GuildHandler) GetGuild(c *gin.Context) {
 	guildID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid guild ID"})
+		writeError(c, errInvalidGuildID)
 		return
 	}
 
-	guild, err := h.guildService.GetGuild(uint(guildID))
+	guild, err := h.guildService.GetGuild(c.Request.Context(), uint(guildID))
 	if err != nil {
-		if errors.Is(err, service.ErrGuildNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "guild not found"})
-			return
-		}
-
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-
+		writeError(c, err)
 		return
 	}
 
@@ -117,12 +121,19 @@ func (h *GuildHandler) GetGuild(c *gin.Context) {
 func (h *GuildHandler) ListUserGuilds(c *gin.Context) {
 	userID := c.GetUint("user_id")
 
-	guilds, err := h.guildService.ListUserGuilds(userID)
+	guilds, err := h.guildService.ListUserGuilds(c.Request.Context(), userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeError(c, err)
 		return
 	}
 
+	// 限制社群的 API token 只能看到允許的社群
+	if principal, ok := middleware.TokenPrincipal(c); ok && len(principal.GuildIDs) > 0 {
+		guilds = slices.DeleteFunc(guilds, func(guild *model.Guild) bool {
+			return !principal.AllowsGuild(guild.ID)
+		})
+	}
+
 	c.JSON(http.StatusOK, guilds)
 }
 
@@ -144,32 +155,21 @@ func (h *GuildHandler) ListUserGuilds(c *gin.Context) {
 func (h *GuildHandler) UpdateGuild(c *gin.Context) {
 	guildID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid guild ID"})
+		writeError(c, errInvalidGuildID)
 		return
 	}
 
 	var req service.UpdateGuildRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeBindError(c, err)
 		return
 	}
 
 	userID := c.GetUint("user_id")
 
-	guild, err := h.guildService.UpdateGuild(uint(guildID), userID, &req)
+	guild, err := h.guildService.UpdateGuild(auditContext(c), uint(guildID), userID, &req)
 	if err != nil {
-		if errors.Is(err, service.ErrNotGuildOwner) {
-			c.JSON(http.StatusForbidden, gin.H{"error": "only owner can update guild"})
-			return
-		}
-
-		if errors.Is(err, service.ErrGuildNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "guild not found"})
-			return
-		}
-
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-
+		writeError(c, err)
 		return
 	}
 
@@ -193,30 +193,64 @@ func (h *GuildHandler) UpdateGuild(c *gin.Context) {
 func (h *GuildHandler) DeleteGuild(c *gin.Context) {
 	guildID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid guild ID"})
+		writeError(c, errInvalidGuildID)
 		return
 	}
 
 	userID := c.GetUint("user_id")
 
-	err = h.guildService.DeleteGuild(uint(guildID), userID)
+	err = h.guildService.DeleteGuild(auditContext(c), uint(guildID), userID)
 	if err != nil {
-		if errors.Is(err, service.ErrNotGuildOwner) {
-			c.JSON(http.StatusForbidden, gin.H{"error": "only owner can delete guild"})
-			return
-		}
+		writeError(c, err)
+		return
+	}
 
-		if errors.Is(err, service.ErrGuildNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "guild not found"})
-			return
-		}
+	c.JSON(http.StatusOK, gin.H{"message": "guild deleted successfully"})
+}
 
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+// TransferOwnership 轉移社群擁有權
+//
+//	@Summary		轉移社群擁有權
+//	@Description	擁有者確認密碼後將社群轉移給其他成員，原擁有者會降為管理員；以 SSO 建立的帳號不需密碼，但必須在最近重新登入
+//	@Tags			Guild
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int									true	"社群 ID"
+//	@Param			request	body		service.TransferOwnershipRequest	true	"轉移擁有權請求"
+//	@Success		200		{object}	model.Guild
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		401		{object}	ErrorResponse
+//	@Failure		403		{object}	ErrorResponse
+//	@Failure		404		{object}	ErrorResponse
+//	@Failure		409		{object}	ErrorResponse
+//	@Router			/api/v1/guilds/{id}/transfer [post]
+func (h *GuildHandler) TransferOwnership(c *gin.Context) {
+	guildID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		writeError(c, errInvalidGuildID)
+		return
+	}
 
+	var req service.TransferOwnershipRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeBindError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "guild deleted successfully"})
+	req.AuthTime = c.GetTime("auth_time")
+
+	guild, err := h.guildService.TransferOwnership(
+		auditContext(c),
+		uint(guildID),
+		c.GetUint("user_id"),
+		&req,
+	)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, guild)
 }
 
 // JoinGuild 加入社群
@@ -235,26 +269,15 @@ func (h *GuildHandler) DeleteGuild(c *gin.Context) {
 func (h *GuildHandler) JoinGuild(c *gin.Context) {
 	guildID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid guild ID"})
+		writeError(c, errInvalidGuildID)
 		return
 	}
 
 	userID := c.GetUint("user_id")
 
-	err = h.guildMemberService.JoinGuild(uint(guildID), userID)
+	err = h.guildMemberService.JoinGuild(c.Request.Context(), uint(guildID), userID)
 	if err != nil {
-		if errors.Is(err, service.ErrGuildNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "guild not found"})
-			return
-		}
-
-		if errors.Is(err, service.ErrAlreadyInGuild) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "already in guild"})
-			return
-		}
-
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-
+		writeError(c, err)
 		return
 	}
 
@@ -277,29 +300,15 @@ func (h *GuildHandler) JoinGuild(c *gin.Context) {
 func (h *GuildHandler) LeaveGuild(c *gin.Context) {
 	guildID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid guild ID"})
+		writeError(c, errInvalidGuildID)
 		return
 	}
 
 	userID := c.GetUint("user_id")
 
-	err = h.guildMemberService.LeaveGuild(uint(guildID), userID)
+	err = h.guildMemberService.LeaveGuild(c.Request.Context(), uint(guildID), userID)
 	if err != nil {
-		if errors.Is(err, service.ErrCannotLeaveAsOwner) {
-			c.JSON(
-				http.StatusForbidden,
-				gin.H{"error": "owner cannot leave, transfer ownership first"},
-			)
-
-			return
-		}
-
-		if errors.Is(err, service.ErrNotGuildMember) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "not a member of this guild"})
-			return
-		}
-
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeError(c, err)
 
 		return
 	}
@@ -324,32 +333,26 @@ func (h *GuildHandler) LeaveGuild(c *gin.Context) {
 func (h *GuildHandler) KickMember(c *gin.Context) {
 	guildID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid guild ID"})
+		writeError(c, errInvalidGuildID)
 		return
 	}
 
 	targetUserID, err := strconv.ParseUint(c.Param("userId"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		writeError(c, errInvalidUserID)
 		return
 	}
 
 	operatorUserID := c.GetUint("user_id")
 
-	err = h.guildMemberService.KickMember(uint(guildID), uint(targetUserID), operatorUserID)
+	err = h.guildMemberService.KickMember(
+		auditContext(c),
+		uint(guildID),
+		uint(targetUserID),
+		operatorUserID,
+	)
 	if err != nil {
-		if errors.Is(err, service.ErrNotGuildOwner) {
-			c.JSON(http.StatusForbidden, gin.H{"error": "only owner can kick members"})
-			return
-		}
-
-		if errors.Is(err, service.ErrNotGuildMember) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "user is not a member of this guild"})
-			return
-		}
-
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-
+		writeError(c, err)
 		return
 	}
 
@@ -372,19 +375,13 @@ func (h *GuildHandler) KickMember(c *gin.Context) {
 func (h *GuildHandler) ListGuildMembers(c *gin.Context) {
 	guildID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid guild ID"})
+		writeError(c, errInvalidGuildID)
 		return
 	}
 
-	members, err := h.guildMemberService.ListGuildMembers(uint(guildID))
+	members, err := h.guildMemberService.ListGuildMembers(c.Request.Context(), uint(guildID))
 	if err != nil {
-		if errors.Is(err, service.ErrGuildNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "guild not found"})
-			return
-		}
-
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-
+		writeError(c, err)
 		return
 	}
 
@@ -409,56 +406,252 @@ func (h *GuildHandler) ListGuildMembers(c *gin.Context) {
 func (h *GuildHandler) UpdateMemberRole(c *gin.Context) {
 	guildID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid guild ID"})
+		writeError(c, errInvalidGuildID)
 		return
 	}
 
 	targetUserID, err := strconv.ParseUint(c.Param("userId"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		writeError(c, errInvalidUserID)
 		return
 	}
 
 	var req UpdateMemberRoleRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeBindError(c, err)
 		return
 	}
 
 	operatorUserID := c.GetUint("user_id")
 
 	err = h.guildMemberService.UpdateMemberRole(
+		auditContext(c),
 		uint(guildID),
 		uint(targetUserID),
 		operatorUserID,
 		req.Role,
 	)
 	if err != nil {
-		if errors.Is(err, service.ErrNotGuildOwner) {
-			c.JSON(http.StatusForbidden, gin.H{"error": "only owner can update member roles"})
-			return
-		}
+		writeError(c, err)
+		return
+	}
 
-		if errors.Is(err, service.ErrNotGuildMember) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "user is not a member of this guild"})
+	c.JSON(http.StatusOK, gin.H{"message": "member role updated successfully"})
+}
+
+// BanMember 封鎖成員
+//
+//	@Summary		封鎖成員
+//	@Description	封鎖使用者並移除其成員資格，可設定期限並刪除最近 N 天的訊息
+//	@Tags			GuildMember
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int							true	"社群 ID"
+//	@Param			userId	path		int							true	"使用者 ID"
+//	@Param			request	body		service.BanMemberRequest	false	"封鎖請求"
+//	@Success		200		{object}	model.GuildBan
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		403		{object}	ErrorResponse
+//	@Router			/api/v1/guilds/{id}/bans/{userId} [put]
+func (h *GuildHandler) BanMember(c *gin.Context) {
+	guildID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		writeError(c, errInvalidGuildID)
+		return
+	}
+
+	targetUserID, err := strconv.ParseUint(c.Param("userId"), 10, 32)
+	if err != nil {
+		writeError(c, errInvalidUserID)
+		return
+	}
+
+	var req service.BanMemberRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			writeBindError(c, err)
 			return
 		}
+	}
+
+	ban, purged, err := h.guildMemberService.BanMember(
+		auditContext(c),
+		uint(guildID),
+		uint(targetUserID),
+		c.GetUint("user_id"),
+		&req,
+	)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"ban":              ban,
+		"deleted_messages": purged,
+	})
+}
 
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+// UnbanMember 解除封鎖
+//
+//	@Summary	解除封鎖
+//	@Tags		GuildMember
+//	@Produce	json
+//	@Param		id		path		int	true	"社群 ID"
+//	@Param		userId	path		int	true	"使用者 ID"
+//	@Success	200		{object}	SuccessResponse
+//	@Failure	403		{object}	ErrorResponse
+//	@Failure	404		{object}	ErrorResponse
+//	@Router		/api/v1/guilds/{id}/bans/{userId} [delete]
+func (h *GuildHandler) UnbanMember(c *gin.Context) {
+	guildID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		writeError(c, errInvalidGuildID)
+		return
+	}
 
+	targetUserID, err := strconv.ParseUint(c.Param("userId"), 10, 32)
+	if err != nil {
+		writeError(c, errInvalidUserID)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "member role updated successfully"})
+	err = h.guildMemberService.UnbanMember(
+		auditContext(c),
+		uint(guildID),
+		uint(targetUserID),
+		c.GetUint("user_id"),
+	)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "member unbanned successfully"})
+}
+
+// ListBans 列出社群封鎖紀錄
+//
+//	@Summary	列出社群封鎖紀錄
+//	@Tags		GuildMember
+//	@Produce	json
+//	@Param		id	path		int	true	"社群 ID"
+//	@Success	200	{array}		model.GuildBan
+//	@Failure	403	{object}	ErrorResponse
+//	@Router		/api/v1/guilds/{id}/bans [get]
+func (h *GuildHandler) ListBans(c *gin.Context) {
+	guildID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		writeError(c, errInvalidGuildID)
+		return
+	}
+
+	bans, err := h.guildMemberService.ListBans(
+		c.Request.Context(),
+		uint(guildID),
+		c.GetUint("user_id"),
+	)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, bans)
+}
+
+// TimeoutMember 禁言成員
+//
+//	@Summary		禁言成員
+//	@Description	在指定期間內禁止成員發送訊息（最長 28 天）
+//	@Tags			GuildMember
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int								true	"社群 ID"
+//	@Param			userId	path		int								true	"使用者 ID"
+//	@Param			request	body		service.TimeoutMemberRequest	true	"禁言請求"
+//	@Success		200		{object}	model.GuildMember
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		403		{object}	ErrorResponse
+//	@Failure		404		{object}	ErrorResponse
+//	@Router			/api/v1/guilds/{id}/members/{userId}/timeout [put]
+func (h *GuildHandler) TimeoutMember(c *gin.Context) {
+	guildID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		writeError(c, errInvalidGuildID)
+		return
+	}
+
+	targetUserID, err := strconv.ParseUint(c.Param("userId"), 10, 32)
+	if err != nil {
+		writeError(c, errInvalidUserID)
+		return
+	}
+
+	var req service.TimeoutMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeBindError(c, err)
+		return
+	}
+
+	member, err := h.guildMemberService.TimeoutMember(
+		auditContext(c),
+		uint(guildID),
+		uint(targetUserID),
+		c.GetUint("user_id"),
+		&req,
+	)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, member)
+}
+
+// RemoveTimeout 解除成員禁言
+//
+//	@Summary	解除成員禁言
+//	@Tags		GuildMember
+//	@Produce	json
+//	@Param		id		path		int	true	"社群 ID"
+//	@Param		userId	path		int	true	"使用者 ID"
+//	@Success	200		{object}	model.GuildMember
+//	@Failure	403		{object}	ErrorResponse
+//	@Failure	404		{object}	ErrorResponse
+//	@Router		/api/v1/guilds/{id}/members/{userId}/timeout [delete]
+func (h *GuildHandler) RemoveTimeout(c *gin.Context) {
+	guildID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		writeError(c, errInvalidGuildID)
+		return
+	}
+
+	targetUserID, err := strconv.ParseUint(c.Param("userId"), 10, 32)
+	if err != nil {
+		writeError(c, errInvalidUserID)
+		return
+	}
+
+	member, err := h.guildMemberService.RemoveTimeout(
+		auditContext(c),
+		uint(guildID),
+		uint(targetUserID),
+		c.GetUint("user_id"),
+	)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, member)
 }
 
 type UpdateMemberRoleRequest struct {
 	Role string `json:"role" binding:"required,oneof=admin moderator member"`
 }
 
-type ErrorResponse struct {
-	Error string `json:"error"`
-}
+// ErrorResponse 錯誤回應，code 為穩定的錯誤代碼，fields 列出驗證失敗的欄位
+type ErrorResponse = apperror.Response
 
 type SuccessResponse struct {
 	Message string `json:"message"`
@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/walnut-almonds/talkrealm/buildinfo"
+	"github.com/walnut-almonds/talkrealm/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// 健康檢查回應的狀態
+const (
+	healthStatusOK           = "ok"
+	healthStatusUnavailable  = "unavailable"
+	healthStatusShuttingDown = "shutting_down"
+	healthStatusTimeout      = "timeout"
+)
+
+// ReadinessCheck 就緒檢查的單一相依元件
+type ReadinessCheck struct {
+	Name  string                          // 回應中的元件名稱，例如 database
+	Check func(ctx context.Context) error // 元件無法使用時回傳錯誤
+}
+
+// HealthResponse 存活檢查的回應
+type HealthResponse struct {
+	Status  string `json:"status"`
+	Service string `json:"service"`
+	Version string `json:"version"`
+}
+
+// ComponentStatus 單一相依元件的檢查結果
+type ComponentStatus struct {
+	Status    string `json:"status"` // ok、unavailable 或 timeout
+	LatencyMS int64  `json:"latency_ms"`
+}
+
+// ReadinessResponse 就緒檢查的回應
+type ReadinessResponse struct {
+	Status  string                     `json:"status"` // ok、unavailable 或 shutting_down
+	Service string                     `json:"service"`
+	Version string                     `json:"version"`
+	Checks  map[string]ComponentStatus `json:"checks"`
+}
+
+type HealthHandler struct {
+	checks       []ReadinessCheck
+	timeout      time.Duration
+	shuttingDown atomic.Bool
+	logger       *zap.Logger
+}
+
+// NewHealthHandler 建立健康檢查處理器，timeout 為每個元件檢查的時間上限
+func NewHealthHandler(
+	checks []ReadinessCheck,
+	timeout time.Duration,
+	logger *zap.Logger,
+) *HealthHandler {
+	return &HealthHandler{
+		checks:  checks,
+		timeout: timeout,
+		logger:  logger,
+	}
+}
+
+// SetShuttingDown 讓就緒檢查開始失敗，負載平衡器會在連線排空前停止導入新流量
+func (h *HealthHandler) SetShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+// Live 存活檢查
+//
+//	@Summary		存活檢查
+//	@Description	行程能處理 HTTP 請求即回傳 ok，不檢查相依元件；失敗時應重新啟動容器
+//	@Tags			Health
+//	@Produce		json
+//	@Success		200	{object}	HealthResponse
+//	@Router			/livez [get]
+func (h *HealthHandler) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, HealthResponse{
+		Status:  healthStatusOK,
+		Service: "talkrealm",
+		Version: buildinfo.Version,
+	})
+}
+
+// Ready 就緒檢查
+//
+//	@Summary		就緒檢查
+//	@Description	並行檢查資料庫、限流計數儲存、WebSocket backplane 與檔案儲存，任一元件失敗或伺服器正在關閉時回傳 503
+//	@Tags			Health
+//	@Produce		json
+//	@Success		200	{object}	ReadinessResponse
+//	@Failure		503	{object}	ReadinessResponse
+//	@Router			/readyz [get]
+func (h *HealthHandler) Ready(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromContext(ctx, h.logger)
+
+	resp := ReadinessResponse{
+		Status:  healthStatusOK,
+		Service: "talkrealm",
+		Version: buildinfo.Version,
+		Checks:  make(map[string]ComponentStatus, len(h.checks)),
+	}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+
+	for _, check := range h.checks {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, h.timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := check.Check(checkCtx)
+			status := ComponentStatus{
+				Status:    healthStatusOK,
+				LatencyMS: time.Since(start).Milliseconds(),
+			}
+
+			// 錯誤訊息可能包含內部位址，只寫入日誌不回傳
+			if err != nil {
+				status.Status = healthStatusUnavailable
+				if errors.Is(err, context.DeadlineExceeded) {
+					status.Status = healthStatusTimeout
+				}
+
+				log.Warn(
+					"Readiness check failed",
+					zap.String("component", check.Name),
+					zap.Error(err),
+				)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			resp.Checks[check.Name] = status
+			if err != nil {
+				resp.Status = healthStatusUnavailable
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	// 關閉中仍回報各元件的狀態，方便確認是否為正常的排空
+	if h.shuttingDown.Load() {
+		resp.Status = healthStatusShuttingDown
+	}
+
+	code := http.StatusOK
+	if resp.Status != healthStatusOK {
+		code = http.StatusServiceUnavailable
+	}
+
+	c.JSON(code, resp)
+}
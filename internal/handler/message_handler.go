@@ -1,7 +1,6 @@
 package handler
 
 import (
-	"errors"
 	"net/http"
 	"strconv"
 
@@ -31,50 +30,38 @@ func NewMessageHandler(messageService service.MessageService) *MessageHandler {
 //	@Param			id		path		int								true	"頻道 ID"
 //	@Param			request	body		service.CreateMessageRequest	true	"建立訊息請求"
 //	@Success		201		{object}	model.Message
-//	@Failure		400		{object}	map[string]string
-//	@Failure		403		{object}	map[string]string
-//	@Failure		500		{object}	map[string]string
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		403		{object}	ErrorResponse
+//	@Failure		429		{object}	map[string]any
+//	@Failure		500		{object}	ErrorResponse
 //	@Router			/api/v1/channels/{id}/messages [post]
 func (h *MessageHandler) CreateMessage(c *gin.Context) {
 	// 從 context 取得使用者 ID
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		writeError(c, errUnauthenticated)
 		return
 	}
 
 	// 從 URL 路徑參數取得頻道 ID
 	channelID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid channel id"})
+		writeError(c, errInvalidChannelID)
 		return
 	}
 
 	var req service.CreateMessageRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeBindError(c, err)
 		return
 	}
 
 	// 設定從 URL 取得的 channelID
 	req.ChannelID = uint(channelID)
 
-	message, err := h.messageService.CreateMessage(userID.(uint), &req)
+	message, err := h.messageService.CreateMessage(c.Request.Context(), userID.(uint), &req)
 	if err != nil {
-		switch {
-		case errors.Is(err, service.ErrNotChannelMemberMsg):
-			c.JSON(
-				http.StatusForbidden,
-				gin.H{"error": "you are not a member of this channel's guild"},
-			)
-		case errors.Is(err, service.ErrEmptyMessageContent):
-			c.JSON(http.StatusBadRequest, gin.H{"error": "message content cannot be empty"})
-		case errors.Is(err, service.ErrInvalidMessageType):
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid message type"})
-		default:
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		}
-
+		writeError(c, err)
 		return
 	}
 
@@ -89,38 +76,27 @@ func (h *MessageHandler) CreateMessage(c *gin.Context) {
 //	@Produce		json
 //	@Param			id	path		int	true	"訊息 ID"
 //	@Success		200	{object}	model.Message
-//	@Failure		403	{object}	map[string]string
-//	@Failure		404	{object}	map[string]string
+//	@Failure		403	{object}	ErrorResponse
+//	@Failure		404	{object}	ErrorResponse
 //	@Router			/api/v1/messages/{id} [get]
 func (h *MessageHandler) GetMessage(c *gin.Context) {
 	// 從 context 取得使用者 ID
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		writeError(c, errUnauthenticated)
 		return
 	}
 
 	// 取得訊息 ID
 	messageID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid message id"})
+		writeError(c, errInvalidMessageID)
 		return
 	}
 
-	message, err := h.messageService.GetMessage(uint(messageID), userID.(uint))
+	message, err := h.messageService.GetMessage(c.Request.Context(), uint(messageID), userID.(uint))
 	if err != nil {
-		switch {
-		case errors.Is(err, service.ErrMessageNotFound):
-			c.JSON(http.StatusNotFound, gin.H{"error": "message not found"})
-		case errors.Is(err, service.ErrNotChannelMemberMsg):
-			c.JSON(
-				http.StatusForbidden,
-				gin.H{"error": "you are not a member of this channel's guild"},
-			)
-		default:
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		}
-
+		writeError(c, err)
 		return
 	}
 
@@ -137,21 +113,21 @@ func (h *MessageHandler) GetMessage(c *gin.Context) {
 //	@Param			page		query		int	false	"頁碼"	default(1)
 //	@Param			page_size	query		int	false	"每頁數量"	default(50)
 //	@Success		200			{object}	service.MessageListResponse
-//	@Failure		400			{object}	map[string]string
-//	@Failure		403			{object}	map[string]string
+//	@Failure		400			{object}	ErrorResponse
+//	@Failure		403			{object}	ErrorResponse
 //	@Router			/api/v1/channels/{id}/messages [get]
 func (h *MessageHandler) ListChannelMessages(c *gin.Context) {
 	// 從 context 取得使用者 ID
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		writeError(c, errUnauthenticated)
 		return
 	}
 
 	// 取得頻道 ID
 	channelID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid channel ID"})
+		writeError(c, errInvalidChannelID)
 		return
 	}
 
@@ -173,22 +149,14 @@ func (h *MessageHandler) ListChannelMessages(c *gin.Context) {
 	}
 
 	response, err := h.messageService.ListChannelMessages(
+		c.Request.Context(),
 		uint(channelID),
 		userID.(uint),
 		page,
 		pageSize,
 	)
 	if err != nil {
-		switch {
-		case errors.Is(err, service.ErrNotChannelMemberMsg):
-			c.JSON(
-				http.StatusForbidden,
-				gin.H{"error": "you are not a member of this channel's guild"},
-			)
-		default:
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		}
-
+		writeError(c, err)
 		return
 	}
 
@@ -205,44 +173,39 @@ func (h *MessageHandler) ListChannelMessages(c *gin.Context) {
 //	@Param			id		path		int								true	"訊息 ID"
 //	@Param			request	body		service.UpdateMessageRequest	true	"更新訊息請求"
 //	@Success		200		{object}	model.Message
-//	@Failure		400		{object}	map[string]string
-//	@Failure		403		{object}	map[string]string
-//	@Failure		404		{object}	map[string]string
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		403		{object}	ErrorResponse
+//	@Failure		404		{object}	ErrorResponse
 //	@Router			/api/v1/messages/{id} [put]
 func (h *MessageHandler) UpdateMessage(c *gin.Context) {
 	// 從 context 取得使用者 ID
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		writeError(c, errUnauthenticated)
 		return
 	}
 
 	// 取得訊息 ID
 	messageID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid message id"})
+		writeError(c, errInvalidMessageID)
 		return
 	}
 
 	var req service.UpdateMessageRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeBindError(c, err)
 		return
 	}
 
-	message, err := h.messageService.UpdateMessage(uint(messageID), userID.(uint), &req)
+	message, err := h.messageService.UpdateMessage(
+		c.Request.Context(),
+		uint(messageID),
+		userID.(uint),
+		&req,
+	)
 	if err != nil {
-		switch {
-		case errors.Is(err, service.ErrMessageNotFound):
-			c.JSON(http.StatusNotFound, gin.H{"error": "message not found"})
-		case errors.Is(err, service.ErrNotMessageOwner):
-			c.JSON(http.StatusForbidden, gin.H{"error": "you are not the owner of this message"})
-		case errors.Is(err, service.ErrEmptyMessageContent):
-			c.JSON(http.StatusBadRequest, gin.H{"error": "message content cannot be empty"})
-		default:
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		}
-
+		writeError(c, err)
 		return
 	}
 
@@ -256,43 +219,27 @@ func (h *MessageHandler) UpdateMessage(c *gin.Context) {
 //	@Tags			messages
 //	@Param			id	path		int	true	"訊息 ID"
 //	@Success		200	{object}	map[string]string
-//	@Failure		403	{object}	map[string]string
-//	@Failure		404	{object}	map[string]string
+//	@Failure		403	{object}	ErrorResponse
+//	@Failure		404	{object}	ErrorResponse
 //	@Router			/api/v1/messages/{id} [delete]
 func (h *MessageHandler) DeleteMessage(c *gin.Context) {
 	// 從 context 取得使用者 ID
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		writeError(c, errUnauthenticated)
 		return
 	}
 
 	// 取得訊息 ID
 	messageID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid message id"})
+		writeError(c, errInvalidMessageID)
 		return
 	}
 
-	err = h.messageService.DeleteMessage(uint(messageID), userID.(uint))
+	err = h.messageService.DeleteMessage(auditContext(c), uint(messageID), userID.(uint))
 	if err != nil {
-		switch {
-		case errors.Is(err, service.ErrMessageNotFound):
-			c.JSON(http.StatusNotFound, gin.H{"error": "message not found"})
-		case errors.Is(err, service.ErrNotMessageOwner):
-			c.JSON(
-				http.StatusForbidden,
-				gin.H{"error": "you are not authorized to delete this message"},
-			)
-		case errors.Is(err, service.ErrNotChannelMemberMsg):
-			c.JSON(
-				http.StatusForbidden,
-				gin.H{"error": "you are not a member of this channel's guild"},
-			)
-		default:
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		}
-
+		writeError(c, err)
 		return
 	}
 
@@ -0,0 +1,197 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/walnut-almonds/talkrealm/internal/apperror"
+	"github.com/walnut-almonds/talkrealm/internal/service"
+	"github.com/walnut-almonds/talkrealm/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	oidcStateCookie     = "talkrealm_oidc_state"
+	oidcStateCookiePath = "/api/v1/auth/oidc"
+)
+
+var (
+	errIdentityProviderUnavailable = apperror.BadGateway(
+		"identity_provider_unavailable",
+		"failed to contact identity provider",
+	)
+	errIdentityProviderRejected = apperror.Unauthorized(
+		"identity_provider_error",
+		"identity provider returned error",
+	)
+	errMissingAuthorizationCode = apperror.BadRequest("missing authorization code")
+)
+
+// OIDCHandler OIDC 單一登入處理器
+type OIDCHandler struct {
+	oidcService service.OIDCService
+	logger      *zap.Logger
+}
+
+// NewOIDCHandler 建立 OIDC 單一登入處理器
+func NewOIDCHandler(oidcService service.OIDCService, logger *zap.Logger) *OIDCHandler {
+	return &OIDCHandler{
+		oidcService: oidcService,
+		logger:      logger,
+	}
+}
+
+// ListProviders 列出可用的身分提供者
+//
+//	@Summary	列出可用的身分提供者
+//	@Tags		auth
+//	@Produce	json
+//	@Success	200	{array}	service.OIDCProviderInfo
+//	@Router		/api/v1/auth/oidc/providers [get]
+func (h *OIDCHandler) ListProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, h.oidcService.ListProviders(c.Request.Context()))
+}
+
+// Login 導向身分提供者進行登入
+//
+//	@Summary		OIDC 登入
+//	@Description	產生 state、nonce 與 PKCE verifier 後導向身分提供者
+//	@Tags			auth
+//	@Param			provider	path	string	true	"身分提供者名稱"
+//	@Success		302
+//	@Failure		404	{object}	ErrorResponse
+//	@Router			/api/v1/auth/oidc/{provider}/login [get]
+func (h *OIDCHandler) Login(c *gin.Context) {
+	start, err := h.oidcService.BeginLogin(c.Request.Context(), c.Param("provider"))
+	if err != nil {
+		if !errors.Is(err, service.ErrOIDCProviderNotFound) {
+			err = fmt.Errorf("%w: %w", errIdentityProviderUnavailable, err)
+		}
+
+		writeError(c, err)
+
+		return
+	}
+
+	h.setStateCookie(c, start)
+
+	c.Redirect(http.StatusFound, start.AuthURL)
+}
+
+// OIDCLinkResponse 開始連結身分的回應
+type OIDCLinkResponse struct {
+	AuthURL string `json:"auth_url"`
+}
+
+// LinkIdentity 將身分提供者的帳號連結到目前登入的使用者
+//
+//	@Summary		連結身分提供者
+//	@Description	設定登入狀態 cookie 並回傳授權網址；完成授權後回呼會把該身分連結到目前的帳號。
+//	@Description	已有本地密碼的帳號必須以此方式連結，SSO 登入不會依 email 自動連結到這類帳號
+//	@Tags			auth
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			provider	path		string	true	"身分提供者名稱"
+//	@Success		200			{object}	OIDCLinkResponse
+//	@Failure		404			{object}	ErrorResponse
+//	@Router			/api/v1/users/me/identities/{provider} [post]
+func (h *OIDCHandler) LinkIdentity(c *gin.Context) {
+	start, err := h.oidcService.BeginLink(
+		c.Request.Context(),
+		c.GetUint("user_id"),
+		c.Param("provider"),
+	)
+	if err != nil {
+		if !errors.Is(err, service.ErrOIDCProviderNotFound) {
+			err = fmt.Errorf("%w: %w", errIdentityProviderUnavailable, err)
+		}
+
+		writeError(c, err)
+
+		return
+	}
+
+	h.setStateCookie(c, start)
+
+	c.JSON(http.StatusOK, OIDCLinkResponse{AuthURL: start.AuthURL})
+}
+
+// setStateCookie 將已簽章的登入狀態存入 cookie，回呼時取回
+func (h *OIDCHandler) setStateCookie(c *gin.Context, start *service.OIDCLoginStart) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(
+		oidcStateCookie,
+		start.StateToken,
+		int(start.StateTTL.Seconds()),
+		oidcStateCookiePath,
+		"",
+		c.Request.TLS != nil,
+		true,
+	)
+}
+
+// Callback 處理身分提供者的回呼並簽發 JWT
+//
+//	@Summary	OIDC 登入回呼
+//	@Tags		auth
+//	@Produce	json
+//	@Param		provider	path		string	true	"身分提供者名稱"
+//	@Param		code		query		string	true	"授權碼"
+//	@Param		state		query		string	true	"登入狀態"
+//	@Success	200			{object}	service.LoginResponse
+//	@Failure	400			{object}	ErrorResponse
+//	@Failure	401			{object}	ErrorResponse
+//	@Failure	403			{object}	ErrorResponse
+//	@Failure	409			{object}	ErrorResponse
+//	@Router		/api/v1/auth/oidc/{provider}/callback [get]
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	provider := c.Param("provider")
+
+	// 登入狀態只能使用一次
+	stateToken, _ := c.Cookie(oidcStateCookie)
+	c.SetCookie(oidcStateCookie, "", -1, oidcStateCookiePath, "", c.Request.TLS != nil, true)
+
+	if errParam := c.Query("error"); errParam != "" {
+		writeError(
+			c,
+			errIdentityProviderRejected.WithMessage("identity provider returned error: "+errParam),
+		)
+
+		return
+	}
+
+	code := c.Query("code")
+	if code == "" {
+		writeError(c, errMissingAuthorizationCode)
+		return
+	}
+
+	resp, err := h.oidcService.CompleteLogin(
+		c.Request.Context(),
+		provider,
+		code,
+		c.Query("state"),
+		stateToken,
+	)
+	if err != nil {
+		if errors.Is(err, service.ErrOIDCLoginFailed) {
+			logger.FromContext(c.Request.Context(), h.logger).Warn(
+				"OIDC callback rejected",
+				zap.String("provider", provider),
+				zap.Error(err),
+			)
+		}
+
+		writeError(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "login successful",
+		"token":   resp.Token,
+		"user":    resp.User,
+	})
+}
@@ -1,7 +1,6 @@
 package handler
 
 import (
-	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -32,27 +31,13 @@ func NewUserHandler(userService service.UserService) *UserHandler {
 func (h *UserHandler) Register(c *gin.Context) {
 	var req service.RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "invalid request body: " + err.Error(),
-		})
-
+		writeBindError(c, err)
 		return
 	}
 
-	user, err := h.userService.Register(&req)
+	user, err := h.userService.Register(c.Request.Context(), &req)
 	if err != nil {
-		if errors.Is(err, service.ErrUserExists) {
-			c.JSON(http.StatusConflict, gin.H{
-				"error": "user already exists",
-			})
-
-			return
-		}
-
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "failed to register user: " + err.Error(),
-		})
-
+		writeError(c, err)
 		return
 	}
 
@@ -74,27 +59,13 @@ func (h *UserHandler) Register(c *gin.Context) {
 func (h *UserHandler) Login(c *gin.Context) {
 	var req service.LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "invalid request body: " + err.Error(),
-		})
-
+		writeBindError(c, err)
 		return
 	}
 
-	resp, err := h.userService.Login(&req)
+	resp, err := h.userService.Login(c.Request.Context(), &req)
 	if err != nil {
-		if errors.Is(err, service.ErrInvalidCredentials) {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "invalid email or password",
-			})
-
-			return
-		}
-
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "failed to login: " + err.Error(),
-		})
-
+		writeError(c, err)
 		return
 	}
 
@@ -117,27 +88,13 @@ func (h *UserHandler) GetCurrentUser(c *gin.Context) {
 	// 從 context 取得使用者 ID（由認證中間件設定）
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "user not authenticated",
-		})
-
+		writeError(c, errUnauthenticated)
 		return
 	}
 
-	user, err := h.userService.GetByID(userID.(uint))
+	user, err := h.userService.GetByID(c.Request.Context(), userID.(uint))
 	if err != nil {
-		if errors.Is(err, service.ErrUserNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{
-				"error": "user not found",
-			})
-
-			return
-		}
-
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "failed to get user: " + err.Error(),
-		})
-
+		writeError(c, err)
 		return
 	}
 
@@ -160,36 +117,19 @@ func (h *UserHandler) UpdateCurrentUser(c *gin.Context) {
 	// 從 context 取得使用者 ID
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "user not authenticated",
-		})
-
+		writeError(c, errUnauthenticated)
 		return
 	}
 
 	var req service.UpdateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "invalid request body: " + err.Error(),
-		})
-
+		writeBindError(c, err)
 		return
 	}
 
-	user, err := h.userService.Update(userID.(uint), &req)
+	user, err := h.userService.Update(c.Request.Context(), userID.(uint), &req)
 	if err != nil {
-		if errors.Is(err, service.ErrUserNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{
-				"error": "user not found",
-			})
-
-			return
-		}
-
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "failed to update user: " + err.Error(),
-		})
-
+		writeError(c, err)
 		return
 	}
 
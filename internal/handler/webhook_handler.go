@@ -0,0 +1,220 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/walnut-almonds/talkrealm/internal/service"
+)
+
+type WebhookHandler struct {
+	webhookService service.WebhookService
+}
+
+func NewWebhookHandler(webhookService service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+	}
+}
+
+// CreateWebhook 建立傳入 webhook
+//
+//	@Summary		建立傳入 webhook
+//	@Description	在頻道建立傳入 webhook（需要管理 webhook 權限），回應中的 token 與網址只會顯示一次
+//	@Tags			Webhook
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int								true	"頻道 ID"
+//	@Param			request	body		service.CreateWebhookRequest	true	"建立 webhook 請求"
+//	@Success		201		{object}	service.WebhookResponse
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		403		{object}	ErrorResponse
+//	@Failure		404		{object}	ErrorResponse
+//	@Router			/api/v1/channels/{id}/webhooks [post]
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	channelID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		writeError(c, errInvalidChannelID)
+		return
+	}
+
+	var req service.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeBindError(c, err)
+		return
+	}
+
+	webhook, err := h.webhookService.CreateWebhook(
+		auditContext(c),
+		uint(channelID),
+		c.GetUint("user_id"),
+		&req,
+	)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, webhook)
+}
+
+// ListWebhooks 列出頻道的傳入 webhook
+//
+//	@Summary	列出頻道的傳入 webhook
+//	@Tags		Webhook
+//	@Produce	json
+//	@Param		id	path		int	true	"頻道 ID"
+//	@Success	200	{array}		model.Webhook
+//	@Failure	403	{object}	ErrorResponse
+//	@Failure	404	{object}	ErrorResponse
+//	@Router		/api/v1/channels/{id}/webhooks [get]
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	channelID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		writeError(c, errInvalidChannelID)
+		return
+	}
+
+	webhooks, err := h.webhookService.ListWebhooks(
+		c.Request.Context(),
+		uint(channelID),
+		c.GetUint("user_id"),
+	)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, webhooks)
+}
+
+// UpdateWebhook 更新傳入 webhook
+//
+//	@Summary	更新傳入 webhook
+//	@Tags		Webhook
+//	@Accept		json
+//	@Produce	json
+//	@Param		id		path		int								true	"Webhook ID"
+//	@Param		request	body		service.UpdateWebhookRequest	true	"更新 webhook 請求"
+//	@Success	200		{object}	model.Webhook
+//	@Failure	400		{object}	ErrorResponse
+//	@Failure	403		{object}	ErrorResponse
+//	@Failure	404		{object}	ErrorResponse
+//	@Router		/api/v1/webhooks/{id} [patch]
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	webhookID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		writeError(c, errInvalidWebhookID)
+		return
+	}
+
+	var req service.UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeBindError(c, err)
+		return
+	}
+
+	webhook, err := h.webhookService.UpdateWebhook(
+		auditContext(c),
+		uint(webhookID),
+		c.GetUint("user_id"),
+		&req,
+	)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// DeleteWebhook 刪除傳入 webhook
+//
+//	@Summary	刪除傳入 webhook
+//	@Tags		Webhook
+//	@Produce	json
+//	@Param		id	path		int	true	"Webhook ID"
+//	@Success	200	{object}	SuccessResponse
+//	@Failure	403	{object}	ErrorResponse
+//	@Failure	404	{object}	ErrorResponse
+//	@Router		/api/v1/webhooks/{id} [delete]
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	webhookID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		writeError(c, errInvalidWebhookID)
+		return
+	}
+
+	err = h.webhookService.DeleteWebhook(auditContext(c), uint(webhookID), c.GetUint("user_id"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "webhook deleted successfully"})
+}
+
+// ExecuteWebhook 透過傳入 webhook 發送訊息
+//
+//	@Summary		透過傳入 webhook 發送訊息
+//	@Description	以 webhook 網址中的 token 驗證，不需要登入。接受 JSON 或 Slack 格式的表單欄位 payload；加上 wait=true 時回傳建立的訊息
+//	@Tags			Webhook
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int								true	"Webhook ID"
+//	@Param			token	path		string							true	"Webhook token"
+//	@Param			wait	query		bool							false	"是否回傳建立的訊息"
+//	@Param			request	body		service.ExecuteWebhookRequest	true	"訊息內容"
+//	@Success		200		{object}	model.Message
+//	@Success		204
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		401		{object}	ErrorResponse
+//	@Failure		404		{object}	ErrorResponse
+//	@Failure		429		{object}	map[string]any
+//	@Router			/api/v1/webhooks/{id}/{token} [post]
+func (h *WebhookHandler) ExecuteWebhook(c *gin.Context) {
+	webhookID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		writeError(c, errInvalidWebhookID)
+		return
+	}
+
+	var req service.ExecuteWebhookRequest
+
+	// Slack 相容的客戶端可能以表單欄位 payload 傳送 JSON
+	if c.ContentType() == binding.MIMEPOSTForm {
+		if err := json.Unmarshal([]byte(c.PostForm("payload")), &req); err != nil {
+			writeBindError(c, err)
+			return
+		}
+
+		if err := binding.Validator.ValidateStruct(&req); err != nil {
+			writeBindError(c, err)
+			return
+		}
+	} else if err := c.ShouldBindJSON(&req); err != nil {
+		writeBindError(c, err)
+		return
+	}
+
+	message, err := h.webhookService.ExecuteWebhook(
+		c.Request.Context(),
+		uint(webhookID),
+		c.Param("token"),
+		&req,
+	)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	if c.Query("wait") == "true" {
+		c.JSON(http.StatusOK, message)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -1,47 +1,177 @@
 package middleware
 
 import (
+	"context"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/walnut-almonds/talkrealm/internal/apperror"
 	"github.com/walnut-almonds/talkrealm/pkg/auth"
+	"github.com/walnut-almonds/talkrealm/pkg/config"
 	"github.com/walnut-almonds/talkrealm/pkg/logger"
+	"github.com/walnut-almonds/talkrealm/pkg/metrics"
+	"github.com/walnut-almonds/talkrealm/pkg/tracing"
+	"go.uber.org/zap"
 )
 
-// Logger 日誌中介軟體
-func Logger() gin.HandlerFunc {
+var (
+	errMissingAuthHeader = apperror.Unauthorized(
+		"missing_authorization",
+		"missing authorization header",
+	)
+	errInvalidAuthHeader = apperror.Unauthorized(
+		"invalid_authorization",
+		"invalid authorization header format",
+	)
+	errInvalidToken    = apperror.Unauthorized("invalid_token", "invalid token")
+	errExpiredToken    = apperror.Unauthorized("token_expired", "token has expired")
+	errMissingScope    = apperror.Forbidden("missing_scope", "token is missing required scope")
+	errGuildNotAllowed = apperror.Forbidden(
+		"guild_not_allowed",
+		"token is not allowed to access this guild",
+	)
+	errGuildRestrictedToken = apperror.Forbidden(
+		"guild_restricted_token",
+		"tokens restricted to specific guilds cannot use this endpoint",
+	)
+	errUserSessionRequired = apperror.Forbidden(
+		"user_session_required",
+		"this endpoint is not available to api tokens",
+	)
+)
+
+// Logger 請求日誌中介軟體
+//
+// 請求期間的 logger 附帶 request_id 與 trace_id，以 logger.FromContext 取得；
+// 路徑與查詢字串中的敏感參數會被遮蔽，符合取樣規則的路由只記錄部分成功的請求
+func Logger(baseLogger *zap.Logger, cfg *config.LogConfig) gin.HandlerFunc {
+	sampling := make(map[string]*sampledRoute, len(cfg.Sampling))
+	for _, rule := range cfg.Sampling {
+		if rule.Every > 1 {
+			sampling[rule.Route] = &sampledRoute{every: uint64(rule.Every)}
+		}
+	}
+
 	return func(c *gin.Context) {
 		start := time.Now()
-		path := c.Request.URL.Path
-		query := c.Request.URL.RawQuery
+		ctx := c.Request.Context()
+
+		fields := tracing.LogFields(ctx)
+		if requestID := logger.RequestID(ctx); requestID != "" {
+			fields = append(fields, zap.String("request_id", requestID))
+		}
+
+		reqLogger := baseLogger.With(fields...)
+		c.Request = c.Request.WithContext(logger.NewContext(ctx, reqLogger))
 
 		c.Next()
 
-		end := time.Now()
-		latency := end.Sub(start)
-
-		logger.Info("HTTP Request",
-			"status", c.Writer.Status(),
-			"method", c.Request.Method,
-			"path", path,
-			"query", query,
-			"ip", c.ClientIP(),
-			"user-agent", c.Request.UserAgent(),
-			"latency", latency,
-			"error", c.Errors.ByType(gin.ErrorTypePrivate).String(),
+		status := c.Writer.Status()
+		route := c.FullPath()
+
+		if rule, ok := sampling[route]; ok && status < http.StatusBadRequest && !rule.sample() {
+			return
+		}
+
+		reqLogger.Info("HTTP Request",
+			zap.Int("status", status),
+			zap.String("method", c.Request.Method),
+			zap.String("route", route),
+			zap.String("path", logger.RedactPath(c.Request.URL.Path, route, cfg.RedactParams)),
+			zap.String("query", logger.RedactQuery(c.Request.URL.RawQuery, cfg.RedactParams)),
+			zap.String("ip", c.ClientIP()),
+			zap.String("user-agent", c.Request.UserAgent()),
+			zap.Duration("latency", time.Since(start)),
+			zap.String("error", c.Errors.ByType(gin.ErrorTypePrivate).String()),
 		)
 	}
 }
 
+// sampledRoute 每 every 個請求記錄一筆
+type sampledRoute struct {
+	every uint64
+	count atomic.Uint64
+}
+
+func (r *sampledRoute) sample() bool {
+	return (r.count.Add(1)-1)%r.every == 0
+}
+
+// Metrics 依路由樣板與狀態碼記錄請求數與延遲
+//
+// 使用 c.FullPath 而非實際路徑，避免路徑中的 ID 造成指標序列無限增長
+func Metrics(m *metrics.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		m.ObserveHTTPRequest(c.Request.Method, route, c.Writer.Status(), time.Since(start))
+	}
+}
+
+// ErrorHandler 將處理器以 c.Error 記錄的最後一個錯誤輸出為統一的 ErrorResponse
+//
+// 類型為 gin.ErrorTypeBind 的錯誤視為請求內容解析或驗證失敗；
+// 非 apperror.Error 的錯誤一律回應 500，原始內容只寫入日誌
+func ErrorHandler(baseLogger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		last := c.Errors.Last()
+		if last == nil || c.Writer.Written() {
+			return
+		}
+
+		var appErr *apperror.Error
+		if last.IsType(gin.ErrorTypeBind) {
+			appErr = apperror.FromBinding(last.Err)
+		} else {
+			appErr = apperror.From(last.Err)
+		}
+
+		if appErr.Status >= http.StatusInternalServerError {
+			logger.FromContext(c.Request.Context(), baseLogger).Error("Request failed",
+				zap.String("code", appErr.Code),
+				zap.String("method", c.Request.Method),
+				zap.String("route", c.FullPath()),
+				zap.Error(last.Err),
+			)
+		}
+
+		if appErr.RetryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(appErr.RetryAfter.Seconds()))))
+		}
+
+		c.JSON(appErr.Status, appErr.Response())
+	}
+}
+
+// abortWithError 記錄錯誤並中止請求，由 ErrorHandler 輸出回應
+func abortWithError(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+}
+
 // CORS 跨域資源共享中介軟體
 func CORS() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
 		c.Writer.Header().
-			Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+			Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-Request-ID")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "X-Request-ID, Retry-After")
 		c.Writer.Header().
 			Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE, PATCH")
 
@@ -54,41 +184,88 @@ func CORS() gin.HandlerFunc {
 	}
 }
 
-// AuthMiddleware JWT 認證中間件
-func AuthMiddleware(jwtManager *auth.JWTManager) gin.HandlerFunc {
+// TokenAuthenticator 驗證 API token 的介面（避免循環依賴）
+type TokenAuthenticator interface {
+	AuthenticateToken(ctx context.Context, token string) (*auth.TokenPrincipal, error)
+}
+
+// SessionValidator 確認 JWT 所屬的帳號仍可使用（未被刪除）
+type SessionValidator interface {
+	ValidateSession(ctx context.Context, userID uint) error
+}
+
+// GuildResolver 從請求解析出要存取的社群 ID，無法解析時回傳 false
+type GuildResolver func(c *gin.Context) (uint, bool)
+
+// AuthMiddleware 認證中間件，支援 Bearer JWT 與 Bot API token
+//
+// JWT 另外以 sessions 檢查帳號狀態，已刪除帳號的 token 在到期前也會被拒絕
+func AuthMiddleware(
+	jwtManager *auth.JWTManager,
+	tokenAuth TokenAuthenticator,
+	sessions SessionValidator,
+) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 從 Authorization header 取得 token
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "missing authorization header",
-			})
-			c.Abort()
-
+			abortWithError(c, errMissingAuthHeader)
 			return
 		}
 
-		// 檢查 Bearer 前綴
+		// 檢查 Bearer / Bot 前綴
 		parts := strings.SplitN(authHeader, " ", 2)
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "invalid authorization header format",
-			})
-			c.Abort()
-
+		if len(parts) != 2 || (parts[0] != "Bearer" && parts[0] != "Bot") {
+			abortWithError(c, errInvalidAuthHeader)
 			return
 		}
 
 		tokenString := parts[1]
 
+		if parts[0] == "Bot" {
+			principal, err := tokenAuth.AuthenticateToken(c.Request.Context(), tokenString)
+			if err != nil {
+				if errors.Is(err, auth.ErrInvalidAPIToken) {
+					err = errInvalidToken
+				}
+
+				abortWithError(c, err)
+
+				return
+			}
+
+			// 將 token 身分與權限存入 context
+			c.Set("user_id", principal.UserID)
+			c.Set("username", principal.Username)
+			c.Set("email", principal.Email)
+			c.Set("is_bot", principal.IsBot)
+			c.Set("token_principal", principal)
+
+			c.Next()
+
+			return
+		}
+
 		// 驗證 token
 		claims, err := jwtManager.ValidateToken(tokenString)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": err.Error(),
-			})
-			c.Abort()
+			if errors.Is(err, auth.ErrExpiredToken) {
+				abortWithError(c, errExpiredToken)
+			} else {
+				abortWithError(c, errInvalidToken)
+			}
+
+			return
+		}
 
+		// 沒有使用者的 token（例如以同一把金鑰簽章的其他用途 token）一律拒絕
+		if claims.UserID == 0 {
+			abortWithError(c, errInvalidToken)
+			return
+		}
+
+		if err := sessions.ValidateSession(c.Request.Context(), claims.UserID); err != nil {
+			abortWithError(c, err)
 			return
 		}
 
@@ -96,11 +273,81 @@ func AuthMiddleware(jwtManager *auth.JWTManager) gin.HandlerFunc {
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
 		c.Set("email", claims.Email)
+		if claims.IssuedAt != nil {
+			c.Set("auth_time", claims.IssuedAt.Time)
+		}
 
 		c.Next()
 	}
 }
 
+// RequireScope 限制 API token 必須擁有指定權限範圍，JWT 登入的使用者不受限制
+//
+// resolve 不為 nil 時，也會檢查 token 是否被允許存取該社群
+func RequireScope(scope string, resolve GuildResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := TokenPrincipal(c)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		if !principal.HasScope(scope) {
+			abortWithError(
+				c,
+				errMissingScope.WithMessage("token is missing required scope: "+scope),
+			)
+			return
+		}
+
+		if resolve != nil && len(principal.GuildIDs) > 0 {
+			guildID, ok := resolve(c)
+			if !ok || !principal.AllowsGuild(guildID) {
+				abortWithError(c, errGuildNotAllowed)
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// RequireUserSession 只允許以 JWT 登入的使用者存取（例如管理 API token）
+func RequireUserSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := TokenPrincipal(c); ok {
+			abortWithError(c, errUserSessionRequired)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireUnrestrictedGuilds 拒絕限制社群的 API token，用於無法對應到單一社群的操作（例如建立社群）
+func RequireUnrestrictedGuilds() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if principal, ok := TokenPrincipal(c); ok && len(principal.GuildIDs) > 0 {
+			abortWithError(c, errGuildRestrictedToken)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// TokenPrincipal 取得以 API token 認證的身分，JWT 登入時回傳 false
+func TokenPrincipal(c *gin.Context) (*auth.TokenPrincipal, bool) {
+	value, exists := c.Get("token_principal")
+	if !exists {
+		return nil, false
+	}
+
+	principal, ok := value.(*auth.TokenPrincipal)
+
+	return principal, ok
+}
+
 // Auth 舊版相容 - 使用預設配置
 func Auth() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/walnut-almonds/talkrealm/pkg/idgen"
+	"github.com/walnut-almonds/talkrealm/pkg/logger"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader 請求 ID 的標頭名稱
+const RequestIDHeader = "X-Request-ID"
+
+// validRequestID 沿用上游（例如反向代理）提供的請求 ID 前先檢查格式，避免日誌被注入任意內容
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:/+=-]{1,128}$`)
+
+// RequestID 沿用請求標頭中的 X-Request-ID，沒有或格式不符時產生新的 ID
+//
+// 請求 ID 會放入 context（logger.RequestID）、回應標頭與追蹤的 span
+func RequestID(ids idgen.Generator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = ids.NewID()
+		}
+
+		ctx := c.Request.Context()
+		trace.SpanFromContext(ctx).SetAttributes(attribute.String("http.request_id", requestID))
+
+		c.Request = c.Request.WithContext(logger.WithRequestID(ctx, requestID))
+		c.Header(RequestIDHeader, requestID)
+
+		c.Next()
+	}
+}
@@ -0,0 +1,23 @@
+package model
+
+import (
+	"time"
+)
+
+// APIToken 長期有效的個人或機器人 API token
+//
+// 只儲存 token 的雜湊值；Prefix 為公開的查詢前綴，可用於辨識外洩的 token
+type APIToken struct {
+	ID         uint       `gorm:"primarykey"           json:"id"`
+	UserID     uint       `gorm:"not null;index"       json:"user_id"`
+	User       User       `gorm:"foreignKey:UserID"    json:"-"`
+	Name       string     `gorm:"not null"             json:"name"`
+	Prefix     string     `gorm:"uniqueIndex;not null" json:"prefix"`
+	TokenHash  string     `gorm:"not null"             json:"-"`
+	Scopes     string     `gorm:"not null"             json:"-"` // 以空白分隔的權限範圍
+	GuildIDs   string     `                            json:"-"` // 以逗號分隔的社群 ID，空值表示不限制
+	ExpiresAt  *time.Time `                            json:"expires_at"`
+	LastUsedAt *time.Time `                            json:"last_used_at"`
+	CreatedAt  time.Time  `                            json:"created_at"`
+	UpdatedAt  time.Time  `                            json:"updated_at"`
+}
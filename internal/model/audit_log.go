@@ -0,0 +1,53 @@
+package model
+
+import (
+	"time"
+)
+
+// 稽核紀錄動作類型
+const (
+	AuditActionGuildUpdate        = "guild.update"
+	AuditActionGuildDelete        = "guild.delete"
+	AuditActionGuildOwnerTransfer = "guild.owner_transfer"
+	AuditActionMemberKick         = "member.kick"
+	AuditActionMemberRoleUpdate   = "member.role_update"
+	AuditActionMemberBan          = "member.ban"
+	AuditActionMemberUnban        = "member.unban"
+	AuditActionMemberTimeout      = "member.timeout"
+	AuditActionMemberTimeoutClear = "member.timeout_clear"
+	AuditActionChannelCreate      = "channel.create"
+	AuditActionChannelUpdate      = "channel.update"
+	AuditActionChannelDelete      = "channel.delete"
+	AuditActionChannelFollow      = "channel.follow"
+	AuditActionChannelUnfollow    = "channel.unfollow"
+	AuditActionMessageDelete      = "message.delete"
+	AuditActionWebhookCreate      = "webhook.create"
+	AuditActionWebhookUpdate      = "webhook.update"
+	AuditActionWebhookDelete      = "webhook.delete"
+	AuditActionEventWebhookCreate = "event_webhook.create"
+	AuditActionEventWebhookUpdate = "event_webhook.update"
+	AuditActionEventWebhookDelete = "event_webhook.delete"
+)
+
+// 稽核紀錄目標類型
+const (
+	AuditTargetGuild        = "guild"
+	AuditTargetUser         = "user"
+	AuditTargetChannel      = "channel"
+	AuditTargetMessage      = "message"
+	AuditTargetWebhook      = "webhook"
+	AuditTargetEventWebhook = "event_webhook"
+)
+
+// AuditLogEntry 社群稽核紀錄（只新增、不修改）
+type AuditLogEntry struct {
+	ID         uint      `gorm:"primarykey"                     json:"id"`
+	GuildID    uint      `gorm:"not null;index:idx_audit_guild" json:"guild_id"`
+	ActorID    uint      `gorm:"not null;index"                 json:"actor_id"`
+	Action     string    `gorm:"not null;index"                 json:"action"`
+	TargetType string    `                                      json:"target_type"` // guild, user, channel, message
+	TargetID   uint      `                                      json:"target_id"`
+	Changes    string    `gorm:"type:text"                      json:"-"` // JSON 格式的欄位變更 {"欄位": {"old": ..., "new": ...}}
+	Reason     string    `                                      json:"reason"`
+	CreatedAt  time.Time `gorm:"index:idx_audit_guild"          json:"created_at"`
+}
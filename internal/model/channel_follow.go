@@ -0,0 +1,16 @@
+package model
+
+import (
+	"time"
+)
+
+// ChannelFollow 公告頻道的追蹤關係，來源頻道發佈的訊息會轉發到目標頻道
+type ChannelFollow struct {
+	ID              uint      `gorm:"primarykey"                                    json:"id"`
+	SourceChannelID uint      `gorm:"not null;uniqueIndex:idx_channel_follow"       json:"source_channel_id"`
+	SourceChannel   Channel   `gorm:"foreignKey:SourceChannelID"                    json:"source_channel"`
+	TargetChannelID uint      `gorm:"not null;uniqueIndex:idx_channel_follow;index" json:"target_channel_id"`
+	GuildID         uint      `gorm:"not null;index"                                json:"guild_id"` // 目標頻道所屬社群
+	CreatedByID     uint      `gorm:"not null"                                      json:"created_by_id"`
+	CreatedAt       time.Time `                                                     json:"created_at"`
+}
@@ -0,0 +1,27 @@
+package model
+
+import (
+	"time"
+)
+
+// 資料匯出狀態
+const (
+	DataExportPending    = "pending"
+	DataExportProcessing = "processing"
+	DataExportReady      = "ready"
+	DataExportFailed     = "failed"
+)
+
+// DataExport 使用者個人資料匯出工作
+type DataExport struct {
+	ID          uint       `gorm:"primarykey"                 json:"id"`
+	UserID      uint       `gorm:"not null;index"             json:"user_id"`
+	Status      string     `gorm:"not null;default:'pending'" json:"status"` // pending, processing, ready, failed
+	BlobKey     string     `                                  json:"-"`
+	Size        int64      `                                  json:"size"`
+	Error       string     `                                  json:"error,omitempty"`
+	CompletedAt *time.Time `                                  json:"completed_at"`
+	ExpiresAt   *time.Time `gorm:"index"                      json:"expires_at"`
+	CreatedAt   time.Time  `                                  json:"created_at"`
+	UpdatedAt   time.Time  `                                  json:"updated_at"`
+}
@@ -0,0 +1,77 @@
+package model
+
+import (
+	"time"
+)
+
+// 傳出事件 webhook 可訂閱的事件類型
+const (
+	EventMessageCreate = "message.create"
+	EventMessageUpdate = "message.update"
+	EventMessageDelete = "message.delete"
+	EventMemberJoin    = "member.join"
+	EventMemberLeave   = "member.leave"
+	EventMemberUpdate  = "member.update"
+	EventChannelCreate = "channel.create"
+	EventChannelUpdate = "channel.update"
+	EventChannelDelete = "channel.delete"
+	EventGuildUpdate   = "guild.update"
+)
+
+// EventAnnouncementPublished 公告訊息已發佈，由公告服務的訂閱者轉發到追蹤頻道；僅供內部使用，無法以 webhook 訂閱
+const EventAnnouncementPublished = "announcement.published"
+
+// EventTypes 所有可訂閱的事件類型
+var EventTypes = []string{
+	EventMessageCreate,
+	EventMessageUpdate,
+	EventMessageDelete,
+	EventMemberJoin,
+	EventMemberLeave,
+	EventMemberUpdate,
+	EventChannelCreate,
+	EventChannelUpdate,
+	EventChannelDelete,
+	EventGuildUpdate,
+}
+
+// 事件投遞狀態
+const (
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusSucceeded = "succeeded"
+	DeliveryStatusFailed    = "failed"
+)
+
+// EventWebhook 社群的傳出事件 webhook，社群活動發生時以 HTTPS POST 通知外部服務
+//
+// 簽章用的秘密需要以明文保存；連續失敗達到上限後會自動停用
+type EventWebhook struct {
+	ID           uint       `gorm:"primarykey"     json:"id"`
+	GuildID      uint       `gorm:"not null;index" json:"guild_id"`
+	URL          string     `gorm:"not null"       json:"url"`
+	Secret       string     `gorm:"not null"       json:"-"`
+	Events       string     `gorm:"not null"       json:"-"` // 以空白分隔的事件類型
+	Enabled      bool       `gorm:"not null"       json:"enabled"`
+	FailureCount int        `gorm:"not null"       json:"failure_count"` // 連續失敗次數，成功投遞後歸零
+	DisabledAt   *time.Time `                      json:"disabled_at"`
+	CreatedByID  uint       `gorm:"not null"       json:"created_by_id"`
+	CreatedAt    time.Time  `                      json:"created_at"`
+	UpdatedAt    time.Time  `                      json:"updated_at"`
+}
+
+// EventDelivery 傳出事件的投遞紀錄，同時作為待投遞佇列
+type EventDelivery struct {
+	ID             uint       `gorm:"primarykey"                            json:"id"`
+	WebhookID      uint       `gorm:"not null;index"                        json:"webhook_id"`
+	EventType      string     `gorm:"not null"                              json:"event_type"`
+	Payload        string     `gorm:"type:text;not null"                    json:"-"`
+	TraceParent    string     `                                             json:"-"`      // 產生事件的請求的 W3C traceparent，投遞時傳給接收端
+	Status         string     `gorm:"not null;index:idx_event_delivery_due" json:"status"` // pending, succeeded, failed
+	Attempts       int        `gorm:"not null"                              json:"attempts"`
+	NextAttemptAt  time.Time  `gorm:"index:idx_event_delivery_due"          json:"next_attempt_at"`
+	ResponseStatus int        `                                             json:"response_status"` // 最後一次嘗試的 HTTP 狀態碼
+	LastError      string     `                                             json:"last_error"`
+	DeliveredAt    *time.Time `                                             json:"delivered_at"`
+	CreatedAt      time.Time  `gorm:"index"                                 json:"created_at"`
+	UpdatedAt      time.Time  `                                             json:"updated_at"`
+}
@@ -0,0 +1,17 @@
+package model
+
+import (
+	"time"
+)
+
+// GuildBan 社群封鎖紀錄，被封鎖的使用者無法再加入社群
+type GuildBan struct {
+	ID          uint       `gorm:"primarykey"                              json:"id"`
+	GuildID     uint       `gorm:"not null;uniqueIndex:idx_guild_ban_user" json:"guild_id"`
+	UserID      uint       `gorm:"not null;uniqueIndex:idx_guild_ban_user" json:"user_id"`
+	User        User       `gorm:"foreignKey:UserID"                       json:"user"`
+	ModeratorID uint       `gorm:"not null"                                json:"moderator_id"`
+	Reason      string     `                                               json:"reason"`
+	ExpiresAt   *time.Time `gorm:"index"                                   json:"expires_at"` // 空值表示永久封鎖
+	CreatedAt   time.Time  `                                               json:"created_at"`
+}
@@ -0,0 +1,23 @@
+package model
+
+import (
+	"time"
+)
+
+// OutboxEvent 交易式 outbox 中的領域事件
+//
+// 事件與狀態變更寫在同一個交易中，提交後由 relay 依 ID 順序轉送給 WebSocket 與 webhook 等訂閱者
+type OutboxEvent struct {
+	ID           uint       `gorm:"primarykey"               json:"id"`
+	Type         string     `gorm:"not null"                 json:"type"` // message.create, member.join ...
+	GuildID      uint       `gorm:"not null"                 json:"guild_id"`
+	ChannelID    *uint      `                                json:"channel_id"` // 頻道事件；社群層級的事件為空
+	Payload      string     `gorm:"type:text;not null"       json:"-"`
+	Data         any        `gorm:"-"                        json:"-"`      // 寫入時序列化為 Payload
+	Origin       string     `gorm:"not null"                 json:"origin"` // 產生事件的伺服器實例
+	TraceParent  string     `                                json:"-"`      // 產生事件的請求的 W3C traceparent，轉送時接續同一個追蹤
+	Attempts     int        `gorm:"not null"                 json:"attempts"`
+	LastError    string     `                                json:"last_error"`
+	CreatedAt    time.Time  `gorm:"index"                    json:"created_at"`
+	DispatchedAt *time.Time `gorm:"index:idx_outbox_pending" json:"dispatched_at"`
+}
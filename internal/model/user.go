@@ -6,15 +6,42 @@ import (
 
 // User 使用者模型
 type User struct {
-	ID        uint      `gorm:"primarykey"           json:"id"`
-	Username  string    `gorm:"uniqueIndex;not null" json:"username"`
-	Email     string    `gorm:"uniqueIndex;not null" json:"email"`
-	Password  string    `gorm:"not null"             json:"-"`
-	Nickname  string    `                            json:"nickname"`
-	Avatar    string    `                            json:"avatar"`
-	Status    string    `gorm:"default:'offline'"    json:"status"` // online, offline, busy, away
-	CreatedAt time.Time `                            json:"created_at"`
-	UpdatedAt time.Time `                            json:"updated_at"`
+	ID                  uint       `gorm:"primarykey"           json:"id"`
+	Username            string     `gorm:"uniqueIndex;not null" json:"username"`
+	Email               string     `gorm:"uniqueIndex;not null" json:"email"`
+	Password            string     `gorm:"not null"             json:"-"`
+	Nickname            string     `                            json:"nickname"`
+	Avatar              string     `                            json:"avatar"`
+	Status              string     `gorm:"default:'offline'"    json:"status"`                          // online, offline, busy, away
+	IsBot               bool       `gorm:"default:false"        json:"is_bot"`                          // 機器人帳號只能透過 API token 存取
+	NoLocalPassword     *bool      `gorm:"default:false"        json:"-"`                               // 以 SSO 建立的帳號沒有可用的本地密碼，空值表示未知（遷移前建立的已連結帳號）
+	BotOwnerID          *uint      `gorm:"index"                json:"bot_owner_id,omitempty"`          // 機器人帳號的建立者
+	DeletionScheduledAt *time.Time `gorm:"index"                json:"deletion_scheduled_at,omitempty"` // 帳號預計刪除時間（寬限期結束）
+	AnonymizedAt        *time.Time `                            json:"-"`                               // 帳號資料已匿名化的時間
+	CreatedAt           time.Time  `                            json:"created_at"`
+	UpdatedAt           time.Time  `                            json:"updated_at"`
+}
+
+// HasLocalPassword 帳號確定有本地密碼
+func (u *User) HasLocalPassword() bool {
+	return u.NoLocalPassword != nil && !*u.NoLocalPassword
+}
+
+// SSOOnly 帳號確定沒有可用的本地密碼
+func (u *User) SSOOnly() bool {
+	return u.NoLocalPassword != nil && *u.NoLocalPassword
+}
+
+// UserIdentity 使用者在外部身分提供者（OIDC）的身分連結
+type UserIdentity struct {
+	ID        uint      `gorm:"primarykey"                                         json:"id"`
+	UserID    uint      `gorm:"not null;index"                                     json:"user_id"`
+	User      User      `gorm:"foreignKey:UserID"                                  json:"-"`
+	Provider  string    `gorm:"not null;uniqueIndex:idx_identity_provider_subject" json:"provider"`
+	Subject   string    `gorm:"not null;uniqueIndex:idx_identity_provider_subject" json:"subject"`
+	Email     string    `                                                          json:"email"`
+	CreatedAt time.Time `                                                          json:"created_at"`
+	UpdatedAt time.Time `                                                          json:"updated_at"`
 }
 
 // Guild 社群/伺服器模型
@@ -31,40 +58,49 @@ type Guild struct {
 
 // Channel 頻道模型
 type Channel struct {
-	ID        uint      `gorm:"primarykey"         json:"id"`
-	GuildID   uint      `gorm:"not null"           json:"guild_id"`
-	Guild     Guild     `gorm:"foreignKey:GuildID" json:"guild"`
-	Name      string    `gorm:"not null"           json:"name"`
-	Type      string    `gorm:"not null"           json:"type"` // text, voice
-	Topic     string    `                          json:"topic"`
-	Position  int       `gorm:"default:0"          json:"position"`
-	CreatedAt time.Time `                          json:"created_at"`
-	UpdatedAt time.Time `                          json:"updated_at"`
+	ID              uint      `gorm:"primarykey"         json:"id"`
+	GuildID         uint      `gorm:"not null"           json:"guild_id"`
+	Guild           Guild     `gorm:"foreignKey:GuildID" json:"guild"`
+	Name            string    `gorm:"not null"           json:"name"`
+	Type            string    `gorm:"not null"           json:"type"`      // text, voice, announcement, category
+	ParentID        *uint     `gorm:"index"              json:"parent_id"` // 所屬分類頻道，空值表示位於最上層
+	Topic           string    `                          json:"topic"`
+	Position        int       `gorm:"default:0"          json:"position"`          // 在同一分類內的排序
+	SlowModeSeconds int       `gorm:"default:0"          json:"slow_mode_seconds"` // 慢速模式：每位使用者兩則訊息之間的最短間隔，0 表示關閉
+	CreatedAt       time.Time `                          json:"created_at"`
+	UpdatedAt       time.Time `                          json:"updated_at"`
 }
 
 // Message 訊息模型
 type Message struct {
-	ID        uint      `gorm:"primarykey"           json:"id"`
-	ChannelID uint      `gorm:"not null"             json:"channel_id"`
-	Channel   Channel   `gorm:"foreignKey:ChannelID" json:"channel"`
-	UserID    uint      `gorm:"not null"             json:"user_id"`
-	User      User      `gorm:"foreignKey:UserID"    json:"user"`
-	Content   string    `gorm:"not null"             json:"content"`
-	Type      string    `gorm:"default:'text'"       json:"type"` // text, image, file
-	CreatedAt time.Time `                            json:"created_at"`
-	UpdatedAt time.Time `                            json:"updated_at"`
+	ID              uint       `gorm:"primarykey"                                 json:"id"`
+	ChannelID       uint       `gorm:"not null;uniqueIndex:idx_message_crosspost" json:"channel_id"`
+	Channel         Channel    `gorm:"foreignKey:ChannelID"                       json:"channel"`
+	UserID          uint       `gorm:"not null"                                   json:"user_id"`
+	User            User       `gorm:"foreignKey:UserID"                          json:"user"`
+	Content         string     `gorm:"not null"                                   json:"content"`
+	Type            string     `gorm:"default:'text'"                             json:"type"`                        // text, image, file
+	PublishedAt     *time.Time `                                                  json:"published_at,omitempty"`      // 公告訊息發佈到追蹤頻道的時間
+	SourceMessageID *uint      `gorm:"uniqueIndex:idx_message_crosspost"          json:"source_message_id,omitempty"` // 轉發訊息的原始訊息
+	SourceChannelID *uint      `                                                  json:"source_channel_id,omitempty"` // 轉發訊息的原始公告頻道
+	WebhookID       *uint      `gorm:"index"                                      json:"webhook_id,omitempty"`        // 透過 webhook 發送的訊息
+	AuthorName      string     `                                                  json:"author_name,omitempty"`       // webhook 訊息顯示的作者名稱
+	AuthorAvatar    string     `                                                  json:"author_avatar,omitempty"`     // webhook 訊息顯示的作者頭像
+	CreatedAt       time.Time  `                                                  json:"created_at"`
+	UpdatedAt       time.Time  `                                                  json:"updated_at"`
 }
 
 // GuildMember 社群成員模型
 type GuildMember struct {
-	ID        uint      `gorm:"primarykey"         json:"id"`
-	GuildID   uint      `gorm:"not null"           json:"guild_id"`
-	Guild     Guild     `gorm:"foreignKey:GuildID" json:"guild"`
-	UserID    uint      `gorm:"not null"           json:"user_id"`
-	User      User      `gorm:"foreignKey:UserID"  json:"user"`
-	Nickname  string    `                          json:"nickname"`
-	Role      string    `gorm:"default:'member'"   json:"role"` // owner, admin, member
-	JoinedAt  time.Time `                          json:"joined_at"`
-	CreatedAt time.Time `                          json:"created_at"`
-	UpdatedAt time.Time `                          json:"updated_at"`
+	ID           uint       `gorm:"primarykey"                                     json:"id"`
+	GuildID      uint       `gorm:"not null;uniqueIndex:idx_guild_member"          json:"guild_id"`
+	Guild        Guild      `gorm:"foreignKey:GuildID;constraint:OnDelete:CASCADE" json:"guild"`
+	UserID       uint       `gorm:"not null;uniqueIndex:idx_guild_member;index"    json:"user_id"`
+	User         User       `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"  json:"user"`
+	Nickname     string     `                                                      json:"nickname"`
+	Role         string     `gorm:"default:'member'"                               json:"role"` // owner, admin, moderator, member
+	JoinedAt     time.Time  `                                                      json:"joined_at"`
+	TimeoutUntil *time.Time `gorm:"index"                                          json:"timeout_until"` // 禁言到期時間，空值表示未被禁言
+	CreatedAt    time.Time  `                                                      json:"created_at"`
+	UpdatedAt    time.Time  `                                                      json:"updated_at"`
 }
@@ -0,0 +1,21 @@
+package model
+
+import (
+	"time"
+)
+
+// Webhook 頻道的傳入 webhook，外部系統可以透過秘密網址發送訊息
+//
+// 每個 webhook 都有一個專屬的機器人帳號作為訊息作者；只儲存 token 的雜湊值
+type Webhook struct {
+	ID          uint      `gorm:"primarykey"     json:"id"`
+	GuildID     uint      `gorm:"not null;index" json:"guild_id"`
+	ChannelID   uint      `gorm:"not null;index" json:"channel_id"`
+	UserID      uint      `gorm:"not null"       json:"user_id"` // 代表 webhook 發送訊息的機器人帳號
+	Name        string    `gorm:"not null"       json:"name"`
+	Avatar      string    `                      json:"avatar"`
+	TokenHash   string    `gorm:"not null"       json:"-"`
+	CreatedByID uint      `gorm:"not null"       json:"created_by_id"`
+	CreatedAt   time.Time `                      json:"created_at"`
+	UpdatedAt   time.Time `                      json:"updated_at"`
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/walnut-almonds/talkrealm/internal/model"
+	"gorm.io/gorm"
+)
+
+// APITokenRepository API token 資料庫操作介面
+type APITokenRepository interface {
+	Create(ctx context.Context, token *model.APIToken) error
+	GetByID(ctx context.Context, id uint) (*model.APIToken, error)
+	GetByPrefix(ctx context.Context, prefix string) (*model.APIToken, error)
+	GetByUserID(ctx context.Context, userID uint) ([]*model.APIToken, error)
+	CountByUserID(ctx context.Context, userID uint) (int64, error)
+	Delete(ctx context.Context, id uint) error
+	DeleteByUserID(ctx context.Context, userID uint) error
+	UpdateLastUsed(ctx context.Context, id uint, usedAt time.Time) error
+}
+
+type apiTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewAPITokenRepository 建立 API token repository
+func NewAPITokenRepository(db *gorm.DB) APITokenRepository {
+	return &apiTokenRepository{db: db}
+}
+
+// Create 建立新的 API token
+func (r *apiTokenRepository) Create(ctx context.Context, token *model.APIToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+// GetByID 透過 ID 取得 API token
+func (r *apiTokenRepository) GetByID(ctx context.Context, id uint) (*model.APIToken, error) {
+	var token model.APIToken
+
+	err := r.db.WithContext(ctx).First(&token, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("api token not found")
+		}
+
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// GetByPrefix 透過查詢前綴取得 API token
+func (r *apiTokenRepository) GetByPrefix(
+	ctx context.Context,
+	prefix string,
+) (*model.APIToken, error) {
+	var token model.APIToken
+
+	err := r.db.WithContext(ctx).Preload("User").Where("prefix = ?", prefix).First(&token).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("api token not found")
+		}
+
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// GetByUserID 取得使用者的所有 API token
+func (r *apiTokenRepository) GetByUserID(
+	ctx context.Context,
+	userID uint,
+) ([]*model.APIToken, error) {
+	var tokens []*model.APIToken
+
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&tokens).
+		Error
+
+	return tokens, err
+}
+
+// CountByUserID 計算使用者的 API token 數量
+func (r *apiTokenRepository) CountByUserID(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+
+	err := r.db.WithContext(ctx).
+		Model(&model.APIToken{}).
+		Where("user_id = ?", userID).
+		Count(&count).
+		Error
+
+	return count, err
+}
+
+// Delete 刪除 API token
+func (r *apiTokenRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&model.APIToken{}, id).Error
+}
+
+// DeleteByUserID 刪除使用者的所有 API token
+func (r *apiTokenRepository) DeleteByUserID(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&model.APIToken{}).Error
+}
+
+// UpdateLastUsed 更新最後使用時間
+func (r *apiTokenRepository) UpdateLastUsed(ctx context.Context, id uint, usedAt time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&model.APIToken{}).
+		Where("id = ?", id).
+		Update("last_used_at", usedAt).
+		Error
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/walnut-almonds/talkrealm/internal/model"
+	"gorm.io/gorm"
+)
+
+// AuditLogFilter 稽核紀錄查詢條件，零值表示不篩選
+type AuditLogFilter struct {
+	GuildID  uint
+	Action   string
+	ActorID  uint
+	After    *time.Time
+	Before   *time.Time
+	BeforeID uint // 分頁游標：只回傳 ID 小於此值的紀錄
+	Limit    int
+}
+
+// AuditLogRepository 稽核紀錄資料庫操作介面
+type AuditLogRepository interface {
+	Create(ctx context.Context, entry *model.AuditLogEntry) error
+	List(ctx context.Context, filter *AuditLogFilter) ([]*model.AuditLogEntry, error)
+	DeleteOlderThan(ctx context.Context, before time.Time) (int64, error)
+}
+
+type auditLogRepository struct {
+	db *gorm.DB
+}
+
+// NewAuditLogRepository 建立稽核紀錄 repository
+func NewAuditLogRepository(db *gorm.DB) AuditLogRepository {
+	return &auditLogRepository{db: db}
+}
+
+// Create 新增稽核紀錄
+func (r *auditLogRepository) Create(ctx context.Context, entry *model.AuditLogEntry) error {
+	return r.db.WithContext(ctx).Create(entry).Error
+}
+
+// AuditSink 稽核紀錄寫入資料庫後的額外輸出，例如獨立的稽核日誌
+type AuditSink interface {
+	RecordAudit(ctx context.Context, entry *model.AuditLogEntry)
+}
+
+// auditSinkRepository 寫入成功後把紀錄交給 record，由 TxManager 在提交後輸出到 sink
+type auditSinkRepository struct {
+	AuditLogRepository
+	record func(ctx context.Context, entry *model.AuditLogEntry)
+}
+
+// Create 新增稽核紀錄並在成功後交給 record
+func (r *auditSinkRepository) Create(ctx context.Context, entry *model.AuditLogEntry) error {
+	if err := r.AuditLogRepository.Create(ctx, entry); err != nil {
+		return err
+	}
+
+	r.record(ctx, entry)
+
+	return nil
+}
+
+// List 依條件列出稽核紀錄（新到舊）
+func (r *auditLogRepository) List(
+	ctx context.Context,
+	filter *AuditLogFilter,
+) ([]*model.AuditLogEntry, error) {
+	var entries []*model.AuditLogEntry
+
+	query := r.db.WithContext(ctx).Where("guild_id = ?", filter.GuildID)
+
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+
+	if filter.ActorID != 0 {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+
+	if filter.After != nil {
+		query = query.Where("created_at >= ?", *filter.After)
+	}
+
+	if filter.Before != nil {
+		query = query.Where("created_at < ?", *filter.Before)
+	}
+
+	if filter.BeforeID != 0 {
+		query = query.Where("id < ?", filter.BeforeID)
+	}
+
+	err := query.Order("id DESC").Limit(filter.Limit).Find(&entries).Error
+
+	return entries, err
+}
+
+// DeleteOlderThan 刪除超過保留期限的稽核紀錄
+func (r *auditLogRepository) DeleteOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("created_at < ?", before).Delete(&model.AuditLogEntry{})
+
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/walnut-almonds/talkrealm/internal/model"
+	"gorm.io/gorm"
+)
+
+// ChannelFollowRepository 公告頻道追蹤資料庫操作介面
+type ChannelFollowRepository interface {
+	Create(ctx context.Context, follow *model.ChannelFollow) error
+	Get(ctx context.Context, sourceChannelID, targetChannelID uint) (*model.ChannelFollow, error)
+	Delete(ctx context.Context, id uint) error
+	GetBySourceChannelID(ctx context.Context, sourceChannelID uint) ([]*model.ChannelFollow, error)
+	GetByTargetChannelID(ctx context.Context, targetChannelID uint) ([]*model.ChannelFollow, error)
+}
+
+type channelFollowRepository struct {
+	db *gorm.DB
+}
+
+// NewChannelFollowRepository 建立公告頻道追蹤 repository
+func NewChannelFollowRepository(db *gorm.DB) ChannelFollowRepository {
+	return &channelFollowRepository{db: db}
+}
+
+// Create 建立追蹤關係
+func (r *channelFollowRepository) Create(ctx context.Context, follow *model.ChannelFollow) error {
+	return r.db.WithContext(ctx).Create(follow).Error
+}
+
+// Get 取得來源頻道與目標頻道之間的追蹤關係
+func (r *channelFollowRepository) Get(
+	ctx context.Context,
+	sourceChannelID, targetChannelID uint,
+) (*model.ChannelFollow, error) {
+	var follow model.ChannelFollow
+
+	err := r.db.WithContext(ctx).
+		Where("source_channel_id = ? AND target_channel_id = ?", sourceChannelID, targetChannelID).
+		First(&follow).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("channel follow not found")
+		}
+
+		return nil, err
+	}
+
+	return &follow, nil
+}
+
+// Delete 刪除追蹤關係
+func (r *channelFollowRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&model.ChannelFollow{}, id).Error
+}
+
+// GetBySourceChannelID 取得追蹤指定公告頻道的所有關係
+func (r *channelFollowRepository) GetBySourceChannelID(
+	ctx context.Context,
+	sourceChannelID uint,
+) ([]*model.ChannelFollow, error) {
+	var follows []*model.ChannelFollow
+
+	err := r.db.WithContext(ctx).
+		Where("source_channel_id = ?", sourceChannelID).
+		Order("id ASC").
+		Find(&follows).Error
+
+	return follows, err
+}
+
+// GetByTargetChannelID 取得目標頻道追蹤的所有公告頻道
+func (r *channelFollowRepository) GetByTargetChannelID(
+	ctx context.Context,
+	targetChannelID uint,
+) ([]*model.ChannelFollow, error) {
+	var follows []*model.ChannelFollow
+
+	err := r.db.WithContext(ctx).
+		Preload("SourceChannel").
+		Where("target_channel_id = ?", targetChannelID).
+		Order("id ASC").
+		Find(&follows).Error
+
+	return follows, err
+}
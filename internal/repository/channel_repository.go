@@ -1,20 +1,25 @@
 package repository
 
 import (
+	"context"
 	"errors"
+	"fmt"
 
 	"github.com/walnut-almonds/talkrealm/internal/model"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // ChannelRepository 頻道資料庫操作介面
 type ChannelRepository interface {
-	Create(channel *model.Channel) error
-	GetByID(id uint) (*model.Channel, error)
-	Update(channel *model.Channel) error
-	Delete(id uint) error
-	GetByGuildID(guildID uint) ([]*model.Channel, error)
-	GetByType(guildID uint, channelType string) ([]*model.Channel, error)
+	Create(ctx context.Context, channel *model.Channel) error
+	GetByID(ctx context.Context, id uint) (*model.Channel, error)
+	Update(ctx context.Context, channel *model.Channel) error
+	Delete(ctx context.Context, id uint) error
+	GetByGuildID(ctx context.Context, guildID uint) ([]*model.Channel, error)
+	LockByGuildID(ctx context.Context, guildID uint) ([]*model.Channel, error)
+	GetByType(ctx context.Context, guildID uint, channelType string) ([]*model.Channel, error)
+	UpdatePositions(ctx context.Context, guildID uint, channels []*model.Channel) error
 }
 
 type channelRepository struct {
@@ -27,17 +32,17 @@ func NewChannelRepository(db *gorm.DB) ChannelRepository {
 }
 
 // Create 建立新頻道
-func (r *channelRepository) Create(channel *model.Channel) error {
-	return r.db.Create(channel).Error
+func (r *channelRepository) Create(ctx context.Context, channel *model.Channel) error {
+	return r.db.WithContext(ctx).Create(channel).Error
 }
 
 // GetByID 透過 ID 取得頻道
-func (r *channelRepository) GetByID(id uint) (*model.Channel, error) {
+func (r *channelRepository) GetByID(ctx context.Context, id uint) (*model.Channel, error) {
 	var channel model.Channel
-	err := r.db.Preload("Guild").First(&channel, id).Error
+	err := r.db.WithContext(ctx).Preload("Guild").First(&channel, id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("channel not found")
+			return nil, fmt.Errorf("channel %w", ErrNotFound)
 		}
 		return nil, err
 	}
@@ -45,28 +50,117 @@ func (r *channelRepository) GetByID(id uint) (*model.Channel, error) {
 }
 
 // Update 更新頻道資訊
-func (r *channelRepository) Update(channel *model.Channel) error {
-	return r.db.Save(channel).Error
+func (r *channelRepository) Update(ctx context.Context, channel *model.Channel) error {
+	return r.db.WithContext(ctx).Save(channel).Error
 }
 
-// Delete 刪除頻道
-func (r *channelRepository) Delete(id uint) error {
-	return r.db.Delete(&model.Channel{}, id).Error
+// Delete 刪除頻道，分類頻道底下的頻道會移到最上層，相關的追蹤關係與 webhook 一併刪除
+func (r *channelRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("source_channel_id = ? OR target_channel_id = ?", id, id).
+			Delete(&model.ChannelFollow{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("channel_id = ?", id).Delete(&model.Webhook{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&model.Channel{}).
+			Where("parent_id = ?", id).
+			Update("parent_id", nil).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&model.Channel{}, id).Error
+	})
 }
 
 // GetByGuildID 取得社群的所有頻道
-func (r *channelRepository) GetByGuildID(guildID uint) ([]*model.Channel, error) {
+func (r *channelRepository) GetByGuildID(
+	ctx context.Context,
+	guildID uint,
+) ([]*model.Channel, error) {
 	var channels []*model.Channel
-	err := r.db.Where("guild_id = ?", guildID).Order("position ASC").Find(&channels).Error
+	err := r.db.WithContext(ctx).
+		Where("guild_id = ?", guildID).
+		Order("position ASC").
+		Find(&channels).
+		Error
+	return channels, err
+}
+
+// LockByGuildID 鎖定社群並取得其所有頻道，須在交易中呼叫，鎖定持續到交易結束
+//
+// 先以 FOR UPDATE 鎖定社群本身，同一社群的頻道建立與排序因此依序執行，
+// 後到的交易取得鎖後才讀取頻道，能看到先前交易新增的頻道；SQLite 以單一寫入連線序列化，不需要列鎖
+func (r *channelRepository) LockByGuildID(
+	ctx context.Context,
+	guildID uint,
+) ([]*model.Channel, error) {
+	db := r.db.WithContext(ctx)
+
+	var guild model.Guild
+
+	err := db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id").
+		Take(&guild, guildID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("guild %w", ErrNotFound)
+		}
+
+		return nil, err
+	}
+
+	var channels []*model.Channel
+
+	err = db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("guild_id = ?", guildID).
+		Order("position ASC").
+		Find(&channels).Error
+
 	return channels, err
 }
 
 // GetByType 取得特定類型的頻道
-func (r *channelRepository) GetByType(guildID uint, channelType string) ([]*model.Channel, error) {
+func (r *channelRepository) GetByType(
+	ctx context.Context,
+	guildID uint,
+	channelType string,
+) ([]*model.Channel, error) {
 	var channels []*model.Channel
-	err := r.db.
+	err := r.db.WithContext(ctx).
 		Where("guild_id = ? AND type = ?", guildID, channelType).
 		Order("position ASC").
 		Find(&channels).Error
 	return channels, err
 }
+
+// UpdatePositions 在同一個交易中更新多個頻道的位置與所屬分類
+func (r *channelRepository) UpdatePositions(
+	ctx context.Context,
+	guildID uint,
+	channels []*model.Channel,
+) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, channel := range channels {
+			result := tx.Model(&model.Channel{}).
+				Where("id = ? AND guild_id = ?", channel.ID, guildID).
+				Updates(map[string]any{
+					"position":   channel.Position,
+					"parent_id":  channel.ParentID,
+					"updated_at": channel.UpdatedAt,
+				})
+			if result.Error != nil {
+				return result.Error
+			}
+
+			if result.RowsAffected == 0 {
+				return errors.New("channel not found")
+			}
+		}
+
+		return nil
+	})
+}
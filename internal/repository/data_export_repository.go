@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/walnut-almonds/talkrealm/internal/model"
+	"gorm.io/gorm"
+)
+
+// DataExportRepository 資料匯出工作資料庫操作介面
+type DataExportRepository interface {
+	Create(ctx context.Context, export *model.DataExport) error
+	GetByID(ctx context.Context, id uint) (*model.DataExport, error)
+	Update(ctx context.Context, export *model.DataExport) error
+	Delete(ctx context.Context, id uint) error
+	GetByUserID(ctx context.Context, userID uint) ([]*model.DataExport, error)
+	GetPending(ctx context.Context, limit int) ([]*model.DataExport, error)
+	GetExpired(ctx context.Context, before time.Time, limit int) ([]*model.DataExport, error)
+	CountActiveByUserID(ctx context.Context, userID uint) (int64, error)
+	Claim(ctx context.Context, id uint, at time.Time) (bool, error)
+}
+
+type dataExportRepository struct {
+	db *gorm.DB
+}
+
+// NewDataExportRepository 建立資料匯出 repository
+func NewDataExportRepository(db *gorm.DB) DataExportRepository {
+	return &dataExportRepository{db: db}
+}
+
+// Create 建立資料匯出工作
+func (r *dataExportRepository) Create(ctx context.Context, export *model.DataExport) error {
+	return r.db.WithContext(ctx).Create(export).Error
+}
+
+// GetByID 透過 ID 取得資料匯出工作
+func (r *dataExportRepository) GetByID(ctx context.Context, id uint) (*model.DataExport, error) {
+	var export model.DataExport
+
+	err := r.db.WithContext(ctx).First(&export, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("data export not found")
+		}
+
+		return nil, err
+	}
+
+	return &export, nil
+}
+
+// Update 更新資料匯出工作
+func (r *dataExportRepository) Update(ctx context.Context, export *model.DataExport) error {
+	return r.db.WithContext(ctx).Save(export).Error
+}
+
+// Delete 刪除資料匯出工作
+func (r *dataExportRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&model.DataExport{}, id).Error
+}
+
+// GetByUserID 取得使用者的資料匯出工作
+func (r *dataExportRepository) GetByUserID(
+	ctx context.Context,
+	userID uint,
+) ([]*model.DataExport, error) {
+	var exports []*model.DataExport
+
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&exports).
+		Error
+
+	return exports, err
+}
+
+// GetPending 取得等待處理的資料匯出工作
+func (r *dataExportRepository) GetPending(
+	ctx context.Context,
+	limit int,
+) ([]*model.DataExport, error) {
+	var exports []*model.DataExport
+
+	err := r.db.WithContext(ctx).
+		Where("status = ?", model.DataExportPending).
+		Order("created_at ASC").
+		Limit(limit).
+		Find(&exports).Error
+
+	return exports, err
+}
+
+// GetExpired 取得已過期的資料匯出工作
+func (r *dataExportRepository) GetExpired(
+	ctx context.Context,
+	before time.Time,
+	limit int,
+) ([]*model.DataExport, error) {
+	var exports []*model.DataExport
+
+	err := r.db.WithContext(ctx).
+		Where("expires_at IS NOT NULL AND expires_at <= ?", before).
+		Limit(limit).
+		Find(&exports).Error
+
+	return exports, err
+}
+
+// CountActiveByUserID 計算使用者尚未完成的資料匯出工作數量
+func (r *dataExportRepository) CountActiveByUserID(
+	ctx context.Context,
+	userID uint,
+) (int64, error) {
+	var count int64
+
+	err := r.db.WithContext(ctx).Model(&model.DataExport{}).
+		Where("user_id = ? AND status IN ?", userID, []string{
+			model.DataExportPending,
+			model.DataExportProcessing,
+		}).
+		Count(&count).Error
+
+	return count, err
+}
+
+// Claim 將等待中的工作標記為處理中，at 為更新時間；回傳是否成功取得（避免多個實例重複處理）
+func (r *dataExportRepository) Claim(ctx context.Context, id uint, at time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.DataExport{}).
+		Where("id = ? AND status = ?", id, model.DataExportPending).
+		Updates(map[string]any{
+			"status":     model.DataExportProcessing,
+			"updated_at": at,
+		})
+
+	return result.RowsAffected == 1, result.Error
+}
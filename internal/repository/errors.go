@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// ErrDuplicate 寫入違反唯一約束（例如重複加入社群）
+//
+// 資料庫連線需啟用 gorm.Config.TranslateError，驅動程式的錯誤才會轉換為此錯誤
+var ErrDuplicate = gorm.ErrDuplicatedKey
+
+// ErrNotFound 查詢的紀錄不存在，以 errors.Is 與資料庫錯誤區分
+var ErrNotFound = errors.New("not found")
+
+// ErrConflict 條件式更新的前提已被其他請求改變（例如同時進行的擁有權轉移），呼叫端可重新讀取後再試
+var ErrConflict = errors.New("conflict")
@@ -0,0 +1,272 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/walnut-almonds/talkrealm/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// EventWebhookRepository 傳出事件 webhook 與投遞佇列資料庫操作介面
+type EventWebhookRepository interface {
+	Create(ctx context.Context, webhook *model.EventWebhook) error
+	GetByID(ctx context.Context, id uint) (*model.EventWebhook, error)
+	GetByGuildID(ctx context.Context, guildID uint) ([]*model.EventWebhook, error)
+	GetEnabledByGuildID(ctx context.Context, guildID uint) ([]*model.EventWebhook, error)
+	CountByGuildID(ctx context.Context, guildID uint) (int64, error)
+	Update(ctx context.Context, webhook *model.EventWebhook) error
+	Delete(ctx context.Context, id uint) error
+	ResetFailures(ctx context.Context, id uint) error
+	RecordFailure(ctx context.Context, id uint, disableAfter int, now time.Time) (bool, error)
+
+	CreateDeliveries(ctx context.Context, deliveries []*model.EventDelivery) error
+	ClaimDueDeliveries(
+		ctx context.Context,
+		now, leaseUntil time.Time,
+		limit int,
+	) ([]*model.EventDelivery, error)
+	UpdateDelivery(ctx context.Context, delivery *model.EventDelivery) error
+	FailPendingDeliveries(ctx context.Context, webhookID uint, reason string, at time.Time) error
+	ListDeliveries(ctx context.Context, webhookID uint, limit int) ([]*model.EventDelivery, error)
+	DeleteDeliveriesBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+type eventWebhookRepository struct {
+	db *gorm.DB
+}
+
+// NewEventWebhookRepository 建立傳出事件 webhook repository
+func NewEventWebhookRepository(db *gorm.DB) EventWebhookRepository {
+	return &eventWebhookRepository{db: db}
+}
+
+// Create 建立傳出事件 webhook
+func (r *eventWebhookRepository) Create(ctx context.Context, webhook *model.EventWebhook) error {
+	return r.db.WithContext(ctx).Create(webhook).Error
+}
+
+// GetByID 透過 ID 取得傳出事件 webhook
+func (r *eventWebhookRepository) GetByID(
+	ctx context.Context,
+	id uint,
+) (*model.EventWebhook, error) {
+	var webhook model.EventWebhook
+
+	err := r.db.WithContext(ctx).First(&webhook, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("event webhook not found")
+		}
+
+		return nil, err
+	}
+
+	return &webhook, nil
+}
+
+// GetByGuildID 取得社群的所有傳出事件 webhook
+func (r *eventWebhookRepository) GetByGuildID(
+	ctx context.Context,
+	guildID uint,
+) ([]*model.EventWebhook, error) {
+	var webhooks []*model.EventWebhook
+
+	err := r.db.WithContext(ctx).
+		Where("guild_id = ?", guildID).
+		Order("id ASC").
+		Find(&webhooks).
+		Error
+
+	return webhooks, err
+}
+
+// GetEnabledByGuildID 取得社群中啟用中的傳出事件 webhook
+func (r *eventWebhookRepository) GetEnabledByGuildID(
+	ctx context.Context,
+	guildID uint,
+) ([]*model.EventWebhook, error) {
+	var webhooks []*model.EventWebhook
+
+	err := r.db.WithContext(ctx).
+		Where("guild_id = ? AND enabled = ?", guildID, true).
+		Find(&webhooks).
+		Error
+
+	return webhooks, err
+}
+
+// CountByGuildID 計算社群的傳出事件 webhook 數量
+func (r *eventWebhookRepository) CountByGuildID(ctx context.Context, guildID uint) (int64, error) {
+	var count int64
+
+	err := r.db.WithContext(ctx).
+		Model(&model.EventWebhook{}).
+		Where("guild_id = ?", guildID).
+		Count(&count).
+		Error
+
+	return count, err
+}
+
+// Update 更新傳出事件 webhook
+func (r *eventWebhookRepository) Update(ctx context.Context, webhook *model.EventWebhook) error {
+	return r.db.WithContext(ctx).Save(webhook).Error
+}
+
+// Delete 在同一個交易中刪除傳出事件 webhook 與其投遞紀錄
+func (r *eventWebhookRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", id).Delete(&model.EventDelivery{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&model.EventWebhook{}, id).Error
+	})
+}
+
+// ResetFailures 投遞成功後將連續失敗次數歸零
+func (r *eventWebhookRepository) ResetFailures(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Model(&model.EventWebhook{}).
+		Where("id = ? AND failure_count > 0", id).
+		Update("failure_count", 0).Error
+}
+
+// RecordFailure 累加連續失敗次數，達到上限時停用 webhook
+//
+// 回傳這次呼叫是否讓 webhook 被停用；計數以單一 UPDATE 累加，多個投遞同時失敗時不會遺漏
+func (r *eventWebhookRepository) RecordFailure(
+	ctx context.Context,
+	id uint,
+	disableAfter int,
+	now time.Time,
+) (bool, error) {
+	disabled := false
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.EventWebhook{}).
+			Where("id = ?", id).
+			Update("failure_count", gorm.Expr("failure_count + 1")).Error
+		if err != nil {
+			return err
+		}
+
+		if disableAfter <= 0 {
+			return nil
+		}
+
+		result := tx.Model(&model.EventWebhook{}).
+			Where("id = ? AND enabled = ? AND failure_count >= ?", id, true, disableAfter).
+			Updates(map[string]any{
+				"enabled":     false,
+				"disabled_at": now,
+				"updated_at":  now,
+			})
+		disabled = result.RowsAffected > 0
+
+		return result.Error
+	})
+
+	return disabled, err
+}
+
+// CreateDeliveries 將事件投遞排入佇列
+func (r *eventWebhookRepository) CreateDeliveries(
+	ctx context.Context,
+	deliveries []*model.EventDelivery,
+) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	return r.db.WithContext(ctx).Create(deliveries).Error
+}
+
+// ClaimDueDeliveries 取出已到期的待投遞事件，並將下次嘗試時間延後到 leaseUntil
+//
+// 使用 FOR UPDATE SKIP LOCKED，多個副本同時輪詢時不會取得相同的投遞；
+// 處理中的副本若中途停止，租約到期後會由其他副本重新投遞
+func (r *eventWebhookRepository) ClaimDueDeliveries(
+	ctx context.Context,
+	now, leaseUntil time.Time,
+	limit int,
+) ([]*model.EventDelivery, error) {
+	var deliveries []*model.EventDelivery
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", model.DeliveryStatusPending, now).
+			Order("next_attempt_at ASC, id ASC").
+			Limit(limit).
+			Find(&deliveries).Error
+		if err != nil || len(deliveries) == 0 {
+			return err
+		}
+
+		ids := make([]uint, 0, len(deliveries))
+		for _, delivery := range deliveries {
+			ids = append(ids, delivery.ID)
+			delivery.NextAttemptAt = leaseUntil
+		}
+
+		return tx.Model(&model.EventDelivery{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", leaseUntil).Error
+	})
+
+	return deliveries, err
+}
+
+// UpdateDelivery 更新投遞結果
+func (r *eventWebhookRepository) UpdateDelivery(
+	ctx context.Context,
+	delivery *model.EventDelivery,
+) error {
+	return r.db.WithContext(ctx).Save(delivery).Error
+}
+
+// FailPendingDeliveries 將 webhook 所有待投遞的事件標記為失敗，at 為更新時間
+func (r *eventWebhookRepository) FailPendingDeliveries(
+	ctx context.Context,
+	webhookID uint,
+	reason string,
+	at time.Time,
+) error {
+	return r.db.WithContext(ctx).Model(&model.EventDelivery{}).
+		Where("webhook_id = ? AND status = ?", webhookID, model.DeliveryStatusPending).
+		Updates(map[string]any{
+			"status":     model.DeliveryStatusFailed,
+			"last_error": reason,
+			"updated_at": at,
+		}).Error
+}
+
+// ListDeliveries 取得 webhook 最近的投遞紀錄（新的在前）
+func (r *eventWebhookRepository) ListDeliveries(
+	ctx context.Context,
+	webhookID uint,
+	limit int,
+) ([]*model.EventDelivery, error) {
+	var deliveries []*model.EventDelivery
+
+	err := r.db.WithContext(ctx).Where("webhook_id = ?", webhookID).
+		Order("id DESC").
+		Limit(limit).
+		Find(&deliveries).Error
+
+	return deliveries, err
+}
+
+// DeleteDeliveriesBefore 刪除超過保留期限且已完成的投遞紀錄
+func (r *eventWebhookRepository) DeleteDeliveriesBefore(
+	ctx context.Context,
+	before time.Time,
+) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("status <> ? AND created_at < ?", model.DeliveryStatusPending, before).
+		Delete(&model.EventDelivery{})
+
+	return result.RowsAffected, result.Error
+}
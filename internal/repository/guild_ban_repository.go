@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/walnut-almonds/talkrealm/internal/model"
+	"gorm.io/gorm"
+)
+
+// GuildBanRepository 社群封鎖資料庫操作介面
+type GuildBanRepository interface {
+	Ban(ctx context.Context, ban *model.GuildBan) error
+	GetBan(ctx context.Context, guildID, userID uint) (*model.GuildBan, error)
+	GetByGuildID(ctx context.Context, guildID uint) ([]*model.GuildBan, error)
+	GetExpired(ctx context.Context, before time.Time, limit int) ([]*model.GuildBan, error)
+	Delete(ctx context.Context, id uint) error
+}
+
+type guildBanRepository struct {
+	db *gorm.DB
+}
+
+// NewGuildBanRepository 建立社群封鎖 repository
+func NewGuildBanRepository(db *gorm.DB) GuildBanRepository {
+	return &guildBanRepository{db: db}
+}
+
+// Ban 建立封鎖紀錄，重複封鎖時以新的原因與期限取代舊紀錄
+func (r *guildBanRepository) Ban(ctx context.Context, ban *model.GuildBan) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("guild_id = ? AND user_id = ?", ban.GuildID, ban.UserID).
+			Delete(&model.GuildBan{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(ban).Error
+	})
+}
+
+// GetBan 取得使用者在社群中的封鎖紀錄
+func (r *guildBanRepository) GetBan(
+	ctx context.Context,
+	guildID, userID uint,
+) (*model.GuildBan, error) {
+	var ban model.GuildBan
+
+	err := r.db.WithContext(ctx).
+		Where("guild_id = ? AND user_id = ?", guildID, userID).
+		First(&ban).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("guild ban not found")
+		}
+
+		return nil, err
+	}
+
+	return &ban, nil
+}
+
+// GetByGuildID 取得社群的所有封鎖紀錄
+func (r *guildBanRepository) GetByGuildID(
+	ctx context.Context,
+	guildID uint,
+) ([]*model.GuildBan, error) {
+	var bans []*model.GuildBan
+
+	err := r.db.WithContext(ctx).
+		Preload("User").
+		Where("guild_id = ?", guildID).
+		Order("created_at DESC").
+		Find(&bans).Error
+
+	return bans, err
+}
+
+// GetExpired 取得已到期的封鎖紀錄
+func (r *guildBanRepository) GetExpired(
+	ctx context.Context,
+	before time.Time,
+	limit int,
+) ([]*model.GuildBan, error) {
+	var bans []*model.GuildBan
+
+	err := r.db.WithContext(ctx).
+		Where("expires_at IS NOT NULL AND expires_at <= ?", before).
+		Limit(limit).
+		Find(&bans).Error
+
+	return bans, err
+}
+
+// Delete 刪除封鎖紀錄
+func (r *guildBanRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&model.GuildBan{}, id).Error
+}
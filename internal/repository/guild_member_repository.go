@@ -1,7 +1,10 @@
 package repository
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/walnut-almonds/talkrealm/internal/model"
 	"gorm.io/gorm"
@@ -9,14 +12,24 @@ import (
 
 // GuildMemberRepository 社群成員資料庫操作介面
 type GuildMemberRepository interface {
-	Create(member *model.GuildMember) error
-	GetByID(id uint) (*model.GuildMember, error)
-	Update(member *model.GuildMember) error
-	Delete(id uint) error
-	GetByGuildID(guildID uint) ([]*model.GuildMember, error)
-	GetByUserID(userID uint) ([]*model.GuildMember, error)
-	GetMember(guildID, userID uint) (*model.GuildMember, error)
-	IsMember(guildID, userID uint) (bool, error)
+	Create(ctx context.Context, member *model.GuildMember) error
+	GetByID(ctx context.Context, id uint) (*model.GuildMember, error)
+	Update(ctx context.Context, member *model.GuildMember) error
+	Delete(ctx context.Context, id uint) error
+	GetByGuildID(ctx context.Context, guildID uint) ([]*model.GuildMember, error)
+	GetByUserID(ctx context.Context, userID uint) ([]*model.GuildMember, error)
+	GetMember(ctx context.Context, guildID, userID uint) (*model.GuildMember, error)
+	IsMember(ctx context.Context, guildID, userID uint) (bool, error)
+	DeleteByUserID(ctx context.Context, userID uint) error
+	RemoveMember(ctx context.Context, guildID, userID uint) (bool, error)
+	UpdateRole(ctx context.Context, guildID, userID uint, role string, at time.Time) error
+	SetTimeout(ctx context.Context, member *model.GuildMember) error
+	GetExpiredTimeouts(
+		ctx context.Context,
+		before time.Time,
+		limit int,
+	) ([]*model.GuildMember, error)
+	ClearTimeout(ctx context.Context, id uint, now time.Time) (bool, error)
 }
 
 type guildMemberRepository struct {
@@ -29,14 +42,14 @@ func NewGuildMemberRepository(db *gorm.DB) GuildMemberRepository {
 }
 
 // Create 建立新成員
-func (r *guildMemberRepository) Create(member *model.GuildMember) error {
-	return r.db.Create(member).Error
+func (r *guildMemberRepository) Create(ctx context.Context, member *model.GuildMember) error {
+	return r.db.WithContext(ctx).Create(member).Error
 }
 
 // GetByID 透過 ID 取得成員
-func (r *guildMemberRepository) GetByID(id uint) (*model.GuildMember, error) {
+func (r *guildMemberRepository) GetByID(ctx context.Context, id uint) (*model.GuildMember, error) {
 	var member model.GuildMember
-	err := r.db.Preload("User").Preload("Guild").First(&member, id).Error
+	err := r.db.WithContext(ctx).Preload("User").Preload("Guild").First(&member, id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("guild member not found")
@@ -47,33 +60,42 @@ func (r *guildMemberRepository) GetByID(id uint) (*model.GuildMember, error) {
 }
 
 // Update 更新成員資訊
-func (r *guildMemberRepository) Update(member *model.GuildMember) error {
-	return r.db.Save(member).Error
+func (r *guildMemberRepository) Update(ctx context.Context, member *model.GuildMember) error {
+	return r.db.WithContext(ctx).Save(member).Error
 }
 
 // Delete 刪除成員
-func (r *guildMemberRepository) Delete(id uint) error {
-	return r.db.Delete(&model.GuildMember{}, id).Error
+func (r *guildMemberRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&model.GuildMember{}, id).Error
 }
 
 // GetByGuildID 取得社群的所有成員
-func (r *guildMemberRepository) GetByGuildID(guildID uint) ([]*model.GuildMember, error) {
+func (r *guildMemberRepository) GetByGuildID(
+	ctx context.Context,
+	guildID uint,
+) ([]*model.GuildMember, error) {
 	var members []*model.GuildMember
-	err := r.db.Preload("User").Where("guild_id = ?", guildID).Find(&members).Error
+	err := r.db.WithContext(ctx).Preload("User").Where("guild_id = ?", guildID).Find(&members).Error
 	return members, err
 }
 
 // GetByUserID 取得使用者加入的所有社群成員資料
-func (r *guildMemberRepository) GetByUserID(userID uint) ([]*model.GuildMember, error) {
+func (r *guildMemberRepository) GetByUserID(
+	ctx context.Context,
+	userID uint,
+) ([]*model.GuildMember, error) {
 	var members []*model.GuildMember
-	err := r.db.Preload("Guild").Where("user_id = ?", userID).Find(&members).Error
+	err := r.db.WithContext(ctx).Preload("Guild").Where("user_id = ?", userID).Find(&members).Error
 	return members, err
 }
 
 // GetMember 取得特定社群的特定成員
-func (r *guildMemberRepository) GetMember(guildID, userID uint) (*model.GuildMember, error) {
+func (r *guildMemberRepository) GetMember(
+	ctx context.Context,
+	guildID, userID uint,
+) (*model.GuildMember, error) {
 	var member model.GuildMember
-	err := r.db.
+	err := r.db.WithContext(ctx).
 		Where("guild_id = ? AND user_id = ?", guildID, userID).
 		First(&member).Error
 	if err != nil {
@@ -86,10 +108,88 @@ func (r *guildMemberRepository) GetMember(guildID, userID uint) (*model.GuildMem
 }
 
 // IsMember 檢查使用者是否為社群成員
-func (r *guildMemberRepository) IsMember(guildID, userID uint) (bool, error) {
+func (r *guildMemberRepository) IsMember(ctx context.Context, guildID, userID uint) (bool, error) {
 	var count int64
-	err := r.db.Model(&model.GuildMember{}).
+	err := r.db.WithContext(ctx).Model(&model.GuildMember{}).
 		Where("guild_id = ? AND user_id = ?", guildID, userID).
 		Count(&count).Error
 	return count > 0, err
 }
+
+// DeleteByUserID 刪除使用者的所有成員資料
+func (r *guildMemberRepository) DeleteByUserID(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&model.GuildMember{}).Error
+}
+
+// RemoveMember 移除使用者在社群中的成員資格，回傳原本是否為成員
+func (r *guildMemberRepository) RemoveMember(
+	ctx context.Context,
+	guildID, userID uint,
+) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Where("guild_id = ? AND user_id = ?", guildID, userID).
+		Delete(&model.GuildMember{})
+
+	return result.RowsAffected > 0, result.Error
+}
+
+// UpdateRole 更新成員角色，at 為更新時間；成員不存在時回傳 ErrNotFound
+func (r *guildMemberRepository) UpdateRole(
+	ctx context.Context,
+	guildID, userID uint,
+	role string,
+	at time.Time,
+) error {
+	result := r.db.WithContext(ctx).Model(&model.GuildMember{}).
+		Where("guild_id = ? AND user_id = ?", guildID, userID).
+		Updates(map[string]any{"role": role, "updated_at": at})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("guild member %w", ErrNotFound)
+	}
+
+	return nil
+}
+
+// SetTimeout 更新成員的禁言時間
+func (r *guildMemberRepository) SetTimeout(ctx context.Context, member *model.GuildMember) error {
+	return r.db.WithContext(ctx).Model(&model.GuildMember{}).
+		Where("id = ?", member.ID).
+		Updates(map[string]any{
+			"timeout_until": member.TimeoutUntil,
+			"updated_at":    member.UpdatedAt,
+		}).Error
+}
+
+// GetExpiredTimeouts 取得禁言已到期但尚未清除的成員
+func (r *guildMemberRepository) GetExpiredTimeouts(
+	ctx context.Context,
+	before time.Time,
+	limit int,
+) ([]*model.GuildMember, error) {
+	var members []*model.GuildMember
+
+	err := r.db.WithContext(ctx).
+		Preload("User").
+		Where("timeout_until IS NOT NULL AND timeout_until <= ?", before).
+		Limit(limit).
+		Find(&members).Error
+
+	return members, err
+}
+
+// ClearTimeout 清除在 now 之前到期的禁言並以 now 作為更新時間，回傳是否有更新（禁言期間被延長時不會清除）
+func (r *guildMemberRepository) ClearTimeout(
+	ctx context.Context,
+	id uint,
+	now time.Time,
+) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.GuildMember{}).
+		Where("id = ? AND timeout_until <= ?", id, now).
+		Updates(map[string]any{"timeout_until": nil, "updated_at": now})
+
+	return result.RowsAffected == 1, result.Error
+}
@@ -1,7 +1,10 @@
 package repository
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/walnut-almonds/talkrealm/internal/model"
 	"gorm.io/gorm"
@@ -9,13 +12,14 @@ import (
 
 // GuildRepository 社群資料庫操作介面
 type GuildRepository interface {
-	Create(guild *model.Guild) error
-	GetByID(id uint) (*model.Guild, error)
-	Update(guild *model.Guild) error
-	Delete(id uint) error
-	List(offset, limit int) ([]*model.Guild, error)
-	GetByOwnerID(ownerID uint) ([]*model.Guild, error)
-	GetMemberGuilds(userID uint, offset, limit int) ([]*model.Guild, error)
+	Create(ctx context.Context, guild *model.Guild) error
+	GetByID(ctx context.Context, id uint) (*model.Guild, error)
+	Update(ctx context.Context, guild *model.Guild) error
+	Delete(ctx context.Context, id uint) error
+	List(ctx context.Context, offset, limit int) ([]*model.Guild, error)
+	GetByOwnerID(ctx context.Context, ownerID uint) ([]*model.Guild, error)
+	GetMemberGuilds(ctx context.Context, userID uint, offset, limit int) ([]*model.Guild, error)
+	UpdateOwner(ctx context.Context, guildID, fromUserID, toUserID uint, at time.Time) error
 }
 
 type guildRepository struct {
@@ -28,14 +32,14 @@ func NewGuildRepository(db *gorm.DB) GuildRepository {
 }
 
 // Create 建立新社群
-func (r *guildRepository) Create(guild *model.Guild) error {
-	return r.db.Create(guild).Error
+func (r *guildRepository) Create(ctx context.Context, guild *model.Guild) error {
+	return r.db.WithContext(ctx).Create(guild).Error
 }
 
 // GetByID 透過 ID 取得社群
-func (r *guildRepository) GetByID(id uint) (*model.Guild, error) {
+func (r *guildRepository) GetByID(ctx context.Context, id uint) (*model.Guild, error) {
 	var guild model.Guild
-	err := r.db.Preload("Owner").First(&guild, id).Error
+	err := r.db.WithContext(ctx).Preload("Owner").First(&guild, id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("guild not found")
@@ -46,33 +50,37 @@ func (r *guildRepository) GetByID(id uint) (*model.Guild, error) {
 }
 
 // Update 更新社群資訊
-func (r *guildRepository) Update(guild *model.Guild) error {
-	return r.db.Save(guild).Error
+func (r *guildRepository) Update(ctx context.Context, guild *model.Guild) error {
+	return r.db.WithContext(ctx).Save(guild).Error
 }
 
 // Delete 刪除社群
-func (r *guildRepository) Delete(id uint) error {
-	return r.db.Delete(&model.Guild{}, id).Error
+func (r *guildRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&model.Guild{}, id).Error
 }
 
 // List 列出所有社群（分頁）
-func (r *guildRepository) List(offset, limit int) ([]*model.Guild, error) {
+func (r *guildRepository) List(ctx context.Context, offset, limit int) ([]*model.Guild, error) {
 	var guilds []*model.Guild
-	err := r.db.Preload("Owner").Offset(offset).Limit(limit).Find(&guilds).Error
+	err := r.db.WithContext(ctx).Preload("Owner").Offset(offset).Limit(limit).Find(&guilds).Error
 	return guilds, err
 }
 
 // GetByOwnerID 取得使用者擁有的所有社群
-func (r *guildRepository) GetByOwnerID(ownerID uint) ([]*model.Guild, error) {
+func (r *guildRepository) GetByOwnerID(ctx context.Context, ownerID uint) ([]*model.Guild, error) {
 	var guilds []*model.Guild
-	err := r.db.Where("owner_id = ?", ownerID).Find(&guilds).Error
+	err := r.db.WithContext(ctx).Where("owner_id = ?", ownerID).Find(&guilds).Error
 	return guilds, err
 }
 
 // GetMemberGuilds 取得使用者加入的所有社群
-func (r *guildRepository) GetMemberGuilds(userID uint, offset, limit int) ([]*model.Guild, error) {
+func (r *guildRepository) GetMemberGuilds(
+	ctx context.Context,
+	userID uint,
+	offset, limit int,
+) ([]*model.Guild, error) {
 	var guilds []*model.Guild
-	err := r.db.
+	err := r.db.WithContext(ctx).
 		Joins("JOIN guild_members ON guild_members.guild_id = guilds.id").
 		Where("guild_members.user_id = ?", userID).
 		Offset(offset).
@@ -80,3 +88,25 @@ func (r *guildRepository) GetMemberGuilds(userID uint, offset, limit int) ([]*mo
 		Find(&guilds).Error
 	return guilds, err
 }
+
+// UpdateOwner 更新社群擁有者，at 為更新時間
+//
+// 以目前擁有者作為條件，避免同時進行的轉移互相覆蓋；擁有者已改變時回傳 ErrConflict
+func (r *guildRepository) UpdateOwner(
+	ctx context.Context,
+	guildID, fromUserID, toUserID uint,
+	at time.Time,
+) error {
+	result := r.db.WithContext(ctx).Model(&model.Guild{}).
+		Where("id = ? AND owner_id = ?", guildID, fromUserID).
+		Updates(map[string]any{"owner_id": toUserID, "updated_at": at})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("guild owner changed: %w", ErrConflict)
+	}
+
+	return nil
+}
@@ -1,7 +1,9 @@
 package repository
 
 import (
+	"context"
 	"errors"
+	"time"
 
 	"github.com/walnut-almonds/talkrealm/internal/model"
 	"gorm.io/gorm"
@@ -9,12 +11,19 @@ import (
 
 // MessageRepository 訊息資料庫操作介面
 type MessageRepository interface {
-	Create(message *model.Message) error
-	GetByID(id uint) (*model.Message, error)
-	Update(message *model.Message) error
-	Delete(id uint) error
-	GetByChannelID(channelID uint, offset, limit int) ([]*model.Message, error)
-	GetByUserID(userID uint, offset, limit int) ([]*model.Message, error)
+	Create(ctx context.Context, message *model.Message) error
+	GetByID(ctx context.Context, id uint) (*model.Message, error)
+	Update(ctx context.Context, message *model.Message) error
+	Delete(ctx context.Context, id uint) error
+	GetByChannelID(ctx context.Context, channelID uint, offset, limit int) ([]*model.Message, error)
+	GetByUserID(ctx context.Context, userID uint, offset, limit int) ([]*model.Message, error)
+	ReassignUser(ctx context.Context, fromUserID, toUserID uint) error
+	MarkPublished(ctx context.Context, id uint, at time.Time) (bool, error)
+	DeleteByUserInGuildSince(
+		ctx context.Context,
+		userID, guildID uint,
+		since time.Time,
+	) (int64, error)
 }
 
 type messageRepository struct {
@@ -27,15 +36,15 @@ func NewMessageRepository(db *gorm.DB) MessageRepository {
 }
 
 // Create 建立新訊息
-func (r *messageRepository) Create(message *model.Message) error {
-	return r.db.Create(message).Error
+func (r *messageRepository) Create(ctx context.Context, message *model.Message) error {
+	return r.db.WithContext(ctx).Create(message).Error
 }
 
 // GetByID 透過 ID 取得訊息
-func (r *messageRepository) GetByID(id uint) (*model.Message, error) {
+func (r *messageRepository) GetByID(ctx context.Context, id uint) (*model.Message, error) {
 	var message model.Message
 
-	err := r.db.Preload("User").Preload("Channel").First(&message, id).Error
+	err := r.db.WithContext(ctx).Preload("User").Preload("Channel").First(&message, id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("message not found")
@@ -48,23 +57,24 @@ func (r *messageRepository) GetByID(id uint) (*model.Message, error) {
 }
 
 // Update 更新訊息
-func (r *messageRepository) Update(message *model.Message) error {
-	return r.db.Save(message).Error
+func (r *messageRepository) Update(ctx context.Context, message *model.Message) error {
+	return r.db.WithContext(ctx).Save(message).Error
 }
 
 // Delete 刪除訊息
-func (r *messageRepository) Delete(id uint) error {
-	return r.db.Delete(&model.Message{}, id).Error
+func (r *messageRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&model.Message{}, id).Error
 }
 
 // GetByChannelID 取得頻道的訊息（分頁）
 func (r *messageRepository) GetByChannelID(
+	ctx context.Context,
 	channelID uint,
 	offset, limit int,
 ) ([]*model.Message, error) {
 	var messages []*model.Message
 
-	err := r.db.
+	err := r.db.WithContext(ctx).
 		Preload("User").
 		Where("channel_id = ?", channelID).
 		Order("created_at DESC").
@@ -76,10 +86,14 @@ func (r *messageRepository) GetByChannelID(
 }
 
 // GetByUserID 取得使用者的訊息（分頁）
-func (r *messageRepository) GetByUserID(userID uint, offset, limit int) ([]*model.Message, error) {
+func (r *messageRepository) GetByUserID(
+	ctx context.Context,
+	userID uint,
+	offset, limit int,
+) ([]*model.Message, error) {
 	var messages []*model.Message
 
-	err := r.db.
+	err := r.db.WithContext(ctx).
 		Preload("Channel").
 		Where("user_id = ?", userID).
 		Order("created_at DESC").
@@ -89,3 +103,44 @@ func (r *messageRepository) GetByUserID(userID uint, offset, limit int) ([]*mode
 
 	return messages, err
 }
+
+// ReassignUser 將使用者的所有訊息轉移給另一個使用者
+func (r *messageRepository) ReassignUser(ctx context.Context, fromUserID, toUserID uint) error {
+	return r.db.WithContext(ctx).Model(&model.Message{}).
+		Where("user_id = ?", fromUserID).
+		Update("user_id", toUserID).Error
+}
+
+// MarkPublished 標記訊息已發佈，訊息先前已發佈時回傳 false
+func (r *messageRepository) MarkPublished(
+	ctx context.Context,
+	id uint,
+	at time.Time,
+) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.Message{}).
+		Where("id = ? AND published_at IS NULL", id).
+		Update("published_at", at)
+
+	return result.RowsAffected > 0, result.Error
+}
+
+// DeleteByUserInGuildSince 刪除使用者在社群中指定時間之後發送的訊息，回傳刪除的數量
+func (r *messageRepository) DeleteByUserInGuildSince(
+	ctx context.Context,
+	userID, guildID uint,
+	since time.Time,
+) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where(
+			"user_id = ? AND created_at >= ? AND channel_id IN (?)",
+			userID,
+			since,
+			r.db.WithContext(ctx).
+				Model(&model.Channel{}).
+				Select("id").
+				Where("guild_id = ?", guildID),
+		).
+		Delete(&model.Message{})
+
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/walnut-almonds/talkrealm/internal/model"
+	"github.com/walnut-almonds/talkrealm/pkg/tracing"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OutboxRepository 領域事件 outbox 資料庫操作介面
+type OutboxRepository interface {
+	Append(ctx context.Context, events ...*model.OutboxEvent) error
+	Dispatch(
+		ctx context.Context,
+		limit int,
+		now time.Time,
+		handle func(repos *Repositories, event *model.OutboxEvent) error,
+	) (int, error)
+	DeleteDispatchedBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+type outboxRepository struct {
+	db *gorm.DB
+}
+
+// NewOutboxRepository 建立 outbox repository
+func NewOutboxRepository(db *gorm.DB) OutboxRepository {
+	return &outboxRepository{db: db}
+}
+
+// Append 寫入 outbox 事件，應與產生事件的狀態變更在同一個交易中呼叫
+//
+// 事件內容在此時才序列化，才能包含同一個交易中新產生的 ID 與重新載入的關聯資料；
+// 同時記錄 ctx 中的追蹤，轉送事件時接續產生事件的請求
+func (r *outboxRepository) Append(ctx context.Context, events ...*model.OutboxEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	traceParent := tracing.TraceParent(ctx)
+
+	for _, event := range events {
+		payload, err := json.Marshal(event.Data)
+		if err != nil {
+			return err
+		}
+
+		event.Payload = string(payload)
+
+		if event.TraceParent == "" {
+			event.TraceParent = traceParent
+		}
+	}
+
+	return r.db.WithContext(ctx).Create(events).Error
+}
+
+// Dispatch 依 ID 順序取出尚未轉送的事件並逐一交給 handle 處理
+//
+// 所有實例共同轉送所有實例產生的事件。取出的事件以 FOR UPDATE SKIP LOCKED 鎖定，多個實例可以同時轉送；
+// 若其他實例鎖定了同一頻道（或同一社群）較早的事件，本次略過該範圍的事件，避免順序錯亂。
+// 某個事件處理失敗時，同一範圍之後的事件也留待下次重試，以保持順序。
+// 轉送時間記為 now，回傳成功轉送的事件數量
+func (r *outboxRepository) Dispatch(
+	ctx context.Context,
+	limit int,
+	now time.Time,
+	handle func(repos *Repositories, event *model.OutboxEvent) error,
+) (int, error) {
+	dispatched := 0
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var events []*model.OutboxEvent
+
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("dispatched_at IS NULL").
+			Order("id ASC").
+			Limit(limit).
+			Find(&events).Error
+		if err != nil {
+			return err
+		}
+
+		if len(events) == 0 {
+			return nil
+		}
+
+		blocked, err := lockedOrderingKeys(tx, events)
+		if err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			key := orderingKey(event)
+			if blocked[key] {
+				continue
+			}
+
+			// 處理事件時的寫入與標記轉送在同一個交易中，失敗時只回滾到這個事件之前的 savepoint。
+			// SQLite 同一時間只允許一個寫入者，處理事件的寫入也必須使用這個交易
+			err := tx.Transaction(func(eventTx *gorm.DB) error {
+				return handle(NewRepositories(eventTx), event)
+			})
+			if err != nil {
+				blocked[key] = true
+
+				err = tx.Model(event).Updates(map[string]any{
+					"attempts":   gorm.Expr("attempts + 1"),
+					"last_error": err.Error(),
+				}).Error
+				if err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			err = tx.Model(event).Update("dispatched_at", now).Error
+			if err != nil {
+				return err
+			}
+
+			dispatched++
+		}
+
+		return nil
+	})
+
+	return dispatched, err
+}
+
+// DeleteDispatchedBefore 刪除已轉送且超過保留期限的事件
+func (r *outboxRepository) DeleteDispatchedBefore(
+	ctx context.Context,
+	before time.Time,
+) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("dispatched_at IS NOT NULL AND dispatched_at < ?", before).
+		Delete(&model.OutboxEvent{})
+
+	return result.RowsAffected, result.Error
+}
+
+// lockedOrderingKeys 找出被其他實例鎖定、且早於本批事件的未轉送事件所屬的排序範圍
+//
+// SKIP LOCKED 略過的事件一定早於本批最後一個事件，數量不超過其他實例正在處理的批次大小
+func lockedOrderingKeys(tx *gorm.DB, events []*model.OutboxEvent) (map[string]bool, error) {
+	claimed := make([]uint, 0, len(events))
+	for _, event := range events {
+		claimed = append(claimed, event.ID)
+	}
+
+	var skipped []*model.OutboxEvent
+
+	err := tx.Select("id", "guild_id", "channel_id").
+		Where("dispatched_at IS NULL AND id < ? AND id NOT IN ?", claimed[len(claimed)-1], claimed).
+		Find(&skipped).Error
+	if err != nil {
+		return nil, err
+	}
+
+	blocked := make(map[string]bool, len(skipped))
+	for _, event := range skipped {
+		blocked[orderingKey(event)] = true
+	}
+
+	return blocked, nil
+}
+
+// orderingKey 事件的排序範圍：頻道事件以頻道為單位，其餘以社群為單位
+func orderingKey(event *model.OutboxEvent) string {
+	if event.ChannelID != nil {
+		return "channel:" + strconv.FormatUint(uint64(*event.ChannelID), 10)
+	}
+
+	return "guild:" + strconv.FormatUint(uint64(event.GuildID), 10)
+}
@@ -0,0 +1,99 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/walnut-almonds/talkrealm/internal/model"
+)
+
+func TestConcurrentDispatchSkipsLockedEvents(t *testing.T) {
+	ctx := context.Background()
+	outbox := NewOutboxRepository(newTestPostgres(t))
+
+	if err := outbox.Append(
+		ctx,
+		channelEvent(1, 10, "replica-a"),
+		channelEvent(1, 11, "replica-a"),
+		channelEvent(1, 10, "replica-b"),
+		channelEvent(1, 12, "replica-b"),
+	); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	// 第一個副本鎖定事件 1、2 後停在處理函式中，交易保持開啟
+	var (
+		first   []uint
+		claimed = make(chan struct{})
+		release = make(chan struct{})
+		done    = make(chan error, 1)
+	)
+
+	go func() {
+		_, err := outbox.Dispatch(
+			ctx,
+			2,
+			time.Now(),
+			func(_ *Repositories, event *model.OutboxEvent) error {
+				if len(first) == 0 {
+					close(claimed)
+					<-release
+				}
+
+				first = append(first, event.ID)
+
+				return nil
+			},
+		)
+		done <- err
+	}()
+
+	select {
+	case <-claimed:
+	case err := <-done:
+		t.Fatalf("first dispatch returned before claiming events: %v", err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("first dispatch did not claim events")
+	}
+
+	// 第二個副本跳過被鎖定的事件；事件 3 與被鎖定的事件 1 同一個頻道，必須等事件 1 轉送後才能處理
+	var second []uint
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	if _, err := outbox.Dispatch(ctxTimeout, 10, time.Now(), func(_ *Repositories, event *model.OutboxEvent) error {
+		second = append(second, event.ID)
+		return nil
+	}); err != nil {
+		close(release)
+		t.Fatalf("second dispatch: %v", err)
+	}
+
+	close(release)
+
+	if err := <-done; err != nil {
+		t.Fatalf("first dispatch: %v", err)
+	}
+
+	if !slices.Equal(first, []uint{1, 2}) || !slices.Equal(second, []uint{4}) {
+		t.Fatalf("first dispatched %v, second dispatched %v; want [1 2] and [4]", first, second)
+	}
+
+	var third []uint
+
+	if _, err := outbox.Dispatch(ctx, 10, time.Now(), func(_ *Repositories, event *model.OutboxEvent) error {
+		third = append(third, event.ID)
+		return nil
+	}); err != nil {
+		t.Fatalf("third dispatch: %v", err)
+	}
+
+	if !slices.Equal(third, []uint{3}) {
+		t.Fatalf("third dispatched %v, want [3]", third)
+	}
+}
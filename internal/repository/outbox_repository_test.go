@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/walnut-almonds/talkrealm/internal/model"
+	"github.com/walnut-almonds/talkrealm/pkg/database"
+)
+
+func newTestOutbox(t *testing.T) OutboxRepository {
+	t.Helper()
+
+	db, err := database.OpenInMemory()
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { _ = database.Close(db) })
+
+	return NewOutboxRepository(db)
+}
+
+func channelEvent(guildID, channelID uint, origin string) *model.OutboxEvent {
+	return &model.OutboxEvent{
+		Type:      model.EventMessageCreate,
+		GuildID:   guildID,
+		ChannelID: &channelID,
+		Origin:    origin,
+		Data:      map[string]any{},
+	}
+}
+
+func TestDispatchIncludesEventsFromAllOrigins(t *testing.T) {
+	ctx := context.Background()
+	outbox := newTestOutbox(t)
+
+	if err := outbox.Append(
+		ctx,
+		channelEvent(1, 10, "replica-a"),
+		channelEvent(1, 10, "replica-b"),
+		channelEvent(1, 11, "replica-a"),
+	); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	var got []uint
+
+	dispatched, err := outbox.Dispatch(
+		ctx,
+		10,
+		time.Now(),
+		func(_ *Repositories, event *model.OutboxEvent) error {
+			got = append(got, event.ID)
+			return nil
+		},
+	)
+	if err != nil {
+		t.Fatalf("dispatch: %v", err)
+	}
+
+	if dispatched != 3 || len(got) != 3 || got[0] != 1 || got[1] != 2 || got[2] != 3 {
+		t.Fatalf("expected events 1, 2, 3 in order, got %v (dispatched %d)", got, dispatched)
+	}
+}
+
+func TestDispatchKeepsOrderAfterFailure(t *testing.T) {
+	ctx := context.Background()
+	outbox := newTestOutbox(t)
+
+	if err := outbox.Append(
+		ctx,
+		channelEvent(1, 10, "replica-a"),
+		channelEvent(1, 10, "replica-b"),
+		channelEvent(1, 11, "replica-b"),
+	); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	var got []uint
+
+	_, err := outbox.Dispatch(
+		ctx,
+		10,
+		time.Now(),
+		func(_ *Repositories, event *model.OutboxEvent) error {
+			if event.ID == 1 {
+				return errors.New("subscriber unavailable")
+			}
+
+			got = append(got, event.ID)
+
+			return nil
+		},
+	)
+	if err != nil {
+		t.Fatalf("dispatch: %v", err)
+	}
+
+	// 頻道 10 的第一個事件失敗，同一頻道之後的事件必須等它成功後才能轉送
+	if len(got) != 1 || got[0] != 3 {
+		t.Fatalf("expected only event 3 to be dispatched, got %v", got)
+	}
+
+	got = nil
+
+	if _, err := outbox.Dispatch(ctx, 10, time.Now(), func(_ *Repositories, event *model.OutboxEvent) error {
+		got = append(got, event.ID)
+		return nil
+	}); err != nil {
+		t.Fatalf("dispatch: %v", err)
+	}
+
+	if len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Fatalf("expected events 1, 2 on retry, got %v", got)
+	}
+}
+
+func TestLockedOrderingKeysBlocksEarlierSkippedEvents(t *testing.T) {
+	ctx := context.Background()
+	outbox := newTestOutbox(t).(*outboxRepository)
+
+	events := []*model.OutboxEvent{
+		channelEvent(1, 10, "replica-a"),
+		channelEvent(1, 10, "replica-b"),
+		channelEvent(1, 11, "replica-b"),
+	}
+	if err := outbox.Append(ctx, events...); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	// 模擬事件 1 已被其他實例鎖定：本實例只取得事件 2 與 3
+	blocked, err := lockedOrderingKeys(outbox.db, events[1:])
+	if err != nil {
+		t.Fatalf("locked ordering keys: %v", err)
+	}
+
+	if !blocked["channel:10"] || blocked["channel:11"] {
+		t.Fatalf("expected only channel 10 to be blocked, got %v", blocked)
+	}
+}
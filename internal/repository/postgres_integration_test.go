@@ -0,0 +1,174 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/walnut-almonds/talkrealm/internal/model"
+	"github.com/walnut-almonds/talkrealm/pkg/config"
+	"github.com/walnut-almonds/talkrealm/pkg/database"
+)
+
+// newTestPostgres 在 TEST_POSTGRES_HOST 指定的 PostgreSQL 上建立已套用所有遷移的測試資料庫，
+// 未設定時略過測試；測試結束後刪除該資料庫
+func newTestPostgres(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	host := os.Getenv("TEST_POSTGRES_HOST")
+	if host == "" {
+		t.Skip("TEST_POSTGRES_HOST is not set")
+	}
+
+	port := 5432
+	if value := os.Getenv("TEST_POSTGRES_PORT"); value != "" {
+		var err error
+		if port, err = strconv.Atoi(value); err != nil {
+			t.Fatalf("parse TEST_POSTGRES_PORT: %v", err)
+		}
+	}
+
+	cfg := &config.DatabaseConfig{
+		Driver:       database.DriverPostgres,
+		Host:         host,
+		Port:         port,
+		User:         os.Getenv("TEST_POSTGRES_USER"),
+		Password:     os.Getenv("TEST_POSTGRES_PASSWORD"),
+		DBName:       "postgres",
+		SSLMode:      "disable",
+		TimeZone:     "UTC",
+		MaxIdleConns: 2,
+		MaxOpenConns: 10,
+	}
+
+	admin, err := database.Open(cfg, zap.NewNop())
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { _ = database.Close(admin) })
+
+	name := fmt.Sprintf("talkrealm_test_%d", time.Now().UnixNano())
+	if err := admin.Exec(`CREATE DATABASE "` + name + `"`).Error; err != nil {
+		t.Fatalf("create database: %v", err)
+	}
+
+	// Cleanup 以後進先出執行，刪除資料庫前測試資料庫的連線已關閉
+	t.Cleanup(func() {
+		if err := admin.Exec(`DROP DATABASE IF EXISTS "` + name + `" WITH (FORCE)`).Error; err != nil {
+			t.Errorf("drop database: %v", err)
+		}
+	})
+
+	cfg.DBName = name
+
+	db, err := database.Open(cfg, zap.NewNop())
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	t.Cleanup(func() { _ = database.Close(db) })
+
+	migrator, err := database.NewMigrator(db, zap.NewNop())
+	if err != nil {
+		t.Fatalf("new migrator: %v", err)
+	}
+
+	if err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+
+	return db
+}
+
+func TestCheckConstraintsRejectUnknownValues(t *testing.T) {
+	db := newTestPostgres(t)
+
+	user := &model.User{Username: "owner", Email: "owner@example.com", Password: "hash"}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	guild := &model.Guild{Name: "guild", OwnerID: user.ID}
+	if err := db.Create(guild).Error; err != nil {
+		t.Fatalf("create guild: %v", err)
+	}
+
+	channel := &model.Channel{GuildID: guild.ID, Name: "general", Type: "text"}
+	if err := db.Create(channel).Error; err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+
+	webhook := &model.EventWebhook{
+		GuildID:     guild.ID,
+		URL:         "https://example.com/hook",
+		Secret:      "secret",
+		Events:      model.EventMessageCreate,
+		Enabled:     true,
+		CreatedByID: user.ID,
+	}
+	if err := db.Create(webhook).Error; err != nil {
+		t.Fatalf("create event webhook: %v", err)
+	}
+
+	tests := []struct {
+		constraint string
+		row        any
+	}{
+		{
+			constraint: "chk_users_status",
+			row: &model.User{
+				Username: "idle",
+				Email:    "idle@example.com",
+				Password: "hash",
+				Status:   "idle",
+			},
+		},
+		{
+			constraint: "chk_channels_type",
+			row:        &model.Channel{GuildID: guild.ID, Name: "forum", Type: "forum"},
+		},
+		{
+			constraint: "chk_messages_type",
+			row: &model.Message{
+				ChannelID: channel.ID,
+				UserID:    user.ID,
+				Content:   "hi",
+				Type:      "video",
+			},
+		},
+		{
+			constraint: "chk_guild_members_role",
+			row:        &model.GuildMember{GuildID: guild.ID, UserID: user.ID, Role: "superuser"},
+		},
+		{
+			constraint: "chk_data_exports_status",
+			row:        &model.DataExport{UserID: user.ID, Status: "queued"},
+		},
+		{
+			constraint: "chk_event_deliveries_status",
+			row: &model.EventDelivery{
+				WebhookID: webhook.ID,
+				EventType: model.EventMessageCreate,
+				Payload:   "{}",
+				Status:    "retrying",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.constraint, func(t *testing.T) {
+			err := db.Create(tt.row).Error
+			if err == nil || !strings.Contains(err.Error(), tt.constraint) {
+				t.Fatalf("create %T = %v, want %s violation", tt.row, err, tt.constraint)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/walnut-almonds/talkrealm/internal/model"
+	"gorm.io/gorm"
+)
+
+// Repositories 共用同一個資料庫連線（或交易）的所有 repository
+type Repositories struct {
+	Users          UserRepository
+	UserIdentities UserIdentityRepository
+	APITokens      APITokenRepository
+	DataExports    DataExportRepository
+	Guilds         GuildRepository
+	GuildMembers   GuildMemberRepository
+	GuildBans      GuildBanRepository
+	Channels       ChannelRepository
+	ChannelFollows ChannelFollowRepository
+	Messages       MessageRepository
+	Webhooks       WebhookRepository
+	EventWebhooks  EventWebhookRepository
+	AuditLogs      AuditLogRepository
+	Outbox         OutboxRepository
+}
+
+// NewRepositories 以同一個資料庫連線建立所有 repository
+func NewRepositories(db *gorm.DB) *Repositories {
+	return &Repositories{
+		Users:          NewUserRepository(db),
+		UserIdentities: NewUserIdentityRepository(db),
+		APITokens:      NewAPITokenRepository(db),
+		DataExports:    NewDataExportRepository(db),
+		Guilds:         NewGuildRepository(db),
+		GuildMembers:   NewGuildMemberRepository(db),
+		GuildBans:      NewGuildBanRepository(db),
+		Channels:       NewChannelRepository(db),
+		ChannelFollows: NewChannelFollowRepository(db),
+		Messages:       NewMessageRepository(db),
+		Webhooks:       NewWebhookRepository(db),
+		EventWebhooks:  NewEventWebhookRepository(db),
+		AuditLogs:      NewAuditLogRepository(db),
+		Outbox:         NewOutboxRepository(db),
+	}
+}
+
+// TxManager 跨 repository 的交易管理介面
+type TxManager interface {
+	WithinTx(ctx context.Context, fn func(repos *Repositories) error) error
+}
+
+type txManager struct {
+	db        *gorm.DB
+	auditSink AuditSink
+}
+
+// NewTxManager 建立交易管理器，auditSink 可為 nil
+func NewTxManager(db *gorm.DB, auditSink AuditSink) TxManager {
+	return &txManager{db: db, auditSink: auditSink}
+}
+
+// WithinTx 在同一個交易中執行 fn，傳入的 repository 都綁定在這個交易上
+//
+// fn 回傳錯誤或 panic 時回滾所有寫入；repository 自身的交易會以 savepoint 巢狀執行。
+// 交易中寫入的稽核紀錄在提交後才輸出到 auditSink，回滾的紀錄不會出現在稽核日誌中
+func (m *txManager) WithinTx(ctx context.Context, fn func(repos *Repositories) error) error {
+	var committed []*model.AuditLogEntry
+
+	err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		repos := NewRepositories(tx)
+
+		if m.auditSink != nil {
+			repos.AuditLogs = &auditSinkRepository{
+				AuditLogRepository: repos.AuditLogs,
+				record: func(_ context.Context, entry *model.AuditLogEntry) {
+					committed = append(committed, entry)
+				},
+			}
+		}
+
+		return fn(repos)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range committed {
+		m.auditSink.RecordAudit(ctx, entry)
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/walnut-almonds/talkrealm/internal/model"
+	"gorm.io/gorm"
+)
+
+// UserIdentityRepository 外部身分連結資料庫操作介面
+type UserIdentityRepository interface {
+	Create(ctx context.Context, identity *model.UserIdentity) error
+	GetByProviderSubject(ctx context.Context, provider, subject string) (*model.UserIdentity, error)
+	GetByUserID(ctx context.Context, userID uint) ([]*model.UserIdentity, error)
+	Delete(ctx context.Context, id uint) error
+	DeleteByUserID(ctx context.Context, userID uint) error
+}
+
+type userIdentityRepository struct {
+	db *gorm.DB
+}
+
+// NewUserIdentityRepository 建立外部身分連結 repository
+func NewUserIdentityRepository(db *gorm.DB) UserIdentityRepository {
+	return &userIdentityRepository{db: db}
+}
+
+// Create 建立新的身分連結
+func (r *userIdentityRepository) Create(ctx context.Context, identity *model.UserIdentity) error {
+	return r.db.WithContext(ctx).Create(identity).Error
+}
+
+// GetByProviderSubject 透過身分提供者與 subject 取得身分連結
+func (r *userIdentityRepository) GetByProviderSubject(
+	ctx context.Context,
+	provider, subject string,
+) (*model.UserIdentity, error) {
+	var identity model.UserIdentity
+
+	err := r.db.WithContext(ctx).
+		Where("provider = ? AND subject = ?", provider, subject).
+		First(&identity).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("user identity %w", ErrNotFound)
+		}
+
+		return nil, err
+	}
+
+	return &identity, nil
+}
+
+// GetByUserID 取得使用者的所有身分連結
+func (r *userIdentityRepository) GetByUserID(
+	ctx context.Context,
+	userID uint,
+) ([]*model.UserIdentity, error) {
+	var identities []*model.UserIdentity
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Find(&identities).Error
+
+	return identities, err
+}
+
+// Delete 刪除身分連結
+func (r *userIdentityRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&model.UserIdentity{}, id).Error
+}
+
+// DeleteByUserID 刪除使用者的所有身分連結
+func (r *userIdentityRepository) DeleteByUserID(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&model.UserIdentity{}).Error
+}
@@ -1,7 +1,10 @@
 package repository
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/walnut-almonds/talkrealm/internal/model"
 	"gorm.io/gorm"
@@ -9,14 +12,17 @@ import (
 
 // UserRepository 使用者資料庫操作介面
 type UserRepository interface {
-	Create(user *model.User) error
-	GetByID(id uint) (*model.User, error)
-	GetByEmail(email string) (*model.User, error)
-	GetByUsername(username string) (*model.User, error)
-	Update(user *model.User) error
-	Delete(id uint) error
-	List(offset, limit int) ([]*model.User, error)
-	UpdateStatus(id uint, status string) error
+	Create(ctx context.Context, user *model.User) error
+	GetByID(ctx context.Context, id uint) (*model.User, error)
+	GetByEmail(ctx context.Context, email string) (*model.User, error)
+	GetByUsername(ctx context.Context, username string) (*model.User, error)
+	Update(ctx context.Context, user *model.User) error
+	Delete(ctx context.Context, id uint) error
+	List(ctx context.Context, offset, limit int) ([]*model.User, error)
+	UpdateStatus(ctx context.Context, id uint, status string) error
+	UpdateNoLocalPassword(ctx context.Context, id uint, noLocalPassword bool) error
+	GetBotsByOwner(ctx context.Context, ownerID uint) ([]*model.User, error)
+	GetDueForDeletion(ctx context.Context, before time.Time, limit int) ([]*model.User, error)
 }
 
 type userRepository struct {
@@ -29,17 +35,17 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 }
 
 // Create 建立新使用者
-func (r *userRepository) Create(user *model.User) error {
-	return r.db.Create(user).Error
+func (r *userRepository) Create(ctx context.Context, user *model.User) error {
+	return r.db.WithContext(ctx).Create(user).Error
 }
 
 // GetByID 透過 ID 取得使用者
-func (r *userRepository) GetByID(id uint) (*model.User, error) {
+func (r *userRepository) GetByID(ctx context.Context, id uint) (*model.User, error) {
 	var user model.User
-	err := r.db.First(&user, id).Error
+	err := r.db.WithContext(ctx).First(&user, id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, fmt.Errorf("user %w", ErrNotFound)
 		}
 		return nil, err
 	}
@@ -47,12 +53,12 @@ func (r *userRepository) GetByID(id uint) (*model.User, error) {
 }
 
 // GetByEmail 透過 Email 取得使用者
-func (r *userRepository) GetByEmail(email string) (*model.User, error) {
+func (r *userRepository) GetByEmail(ctx context.Context, email string) (*model.User, error) {
 	var user model.User
-	err := r.db.Where("email = ?", email).First(&user).Error
+	err := r.db.WithContext(ctx).Where("email = ?", email).First(&user).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, fmt.Errorf("user %w", ErrNotFound)
 		}
 		return nil, err
 	}
@@ -60,12 +66,12 @@ func (r *userRepository) GetByEmail(email string) (*model.User, error) {
 }
 
 // GetByUsername 透過 Username 取得使用者
-func (r *userRepository) GetByUsername(username string) (*model.User, error) {
+func (r *userRepository) GetByUsername(ctx context.Context, username string) (*model.User, error) {
 	var user model.User
-	err := r.db.Where("username = ?", username).First(&user).Error
+	err := r.db.WithContext(ctx).Where("username = ?", username).First(&user).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, fmt.Errorf("user %w", ErrNotFound)
 		}
 		return nil, err
 	}
@@ -73,23 +79,65 @@ func (r *userRepository) GetByUsername(username string) (*model.User, error) {
 }
 
 // Update 更新使用者資訊
-func (r *userRepository) Update(user *model.User) error {
-	return r.db.Save(user).Error
+func (r *userRepository) Update(ctx context.Context, user *model.User) error {
+	return r.db.WithContext(ctx).Save(user).Error
 }
 
 // Delete 刪除使用者
-func (r *userRepository) Delete(id uint) error {
-	return r.db.Delete(&model.User{}, id).Error
+func (r *userRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&model.User{}, id).Error
 }
 
 // List 列出使用者（分頁）
-func (r *userRepository) List(offset, limit int) ([]*model.User, error) {
+func (r *userRepository) List(ctx context.Context, offset, limit int) ([]*model.User, error) {
 	var users []*model.User
-	err := r.db.Offset(offset).Limit(limit).Find(&users).Error
+	err := r.db.WithContext(ctx).Offset(offset).Limit(limit).Find(&users).Error
 	return users, err
 }
 
 // UpdateStatus 更新使用者狀態
-func (r *userRepository) UpdateStatus(id uint, status string) error {
-	return r.db.Model(&model.User{}).Where("id = ?", id).Update("status", status).Error
+func (r *userRepository) UpdateStatus(ctx context.Context, id uint, status string) error {
+	return r.db.WithContext(ctx).
+		Model(&model.User{}).
+		Where("id = ?", id).
+		Update("status", status).
+		Error
+}
+
+// UpdateNoLocalPassword 記錄帳號是否沒有本地密碼
+func (r *userRepository) UpdateNoLocalPassword(
+	ctx context.Context,
+	id uint,
+	noLocalPassword bool,
+) error {
+	return r.db.WithContext(ctx).
+		Model(&model.User{}).
+		Where("id = ?", id).
+		Update("no_local_password", noLocalPassword).
+		Error
+}
+
+// GetBotsByOwner 取得使用者建立的機器人帳號
+func (r *userRepository) GetBotsByOwner(ctx context.Context, ownerID uint) ([]*model.User, error) {
+	var users []*model.User
+	err := r.db.WithContext(ctx).
+		Where("is_bot = ? AND bot_owner_id = ?", true, ownerID).
+		Find(&users).
+		Error
+	return users, err
+}
+
+// GetDueForDeletion 取得寬限期已結束、尚未匿名化的待刪除帳號
+func (r *userRepository) GetDueForDeletion(
+	ctx context.Context,
+	before time.Time,
+	limit int,
+) ([]*model.User, error) {
+	var users []*model.User
+	err := r.db.WithContext(ctx).
+		Where("deletion_scheduled_at IS NOT NULL AND deletion_scheduled_at <= ?", before).
+		Where("anonymized_at IS NULL").
+		Limit(limit).
+		Find(&users).Error
+	return users, err
 }
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/walnut-almonds/talkrealm/internal/model"
+	"gorm.io/gorm"
+)
+
+// WebhookRepository 傳入 webhook 資料庫操作介面
+type WebhookRepository interface {
+	Create(ctx context.Context, webhook *model.Webhook, bot *model.User) error
+	GetByID(ctx context.Context, id uint) (*model.Webhook, error)
+	GetByChannelID(ctx context.Context, channelID uint) ([]*model.Webhook, error)
+	Update(ctx context.Context, webhook *model.Webhook) error
+	Delete(ctx context.Context, id uint) error
+}
+
+type webhookRepository struct {
+	db *gorm.DB
+}
+
+// NewWebhookRepository 建立傳入 webhook repository
+func NewWebhookRepository(db *gorm.DB) WebhookRepository {
+	return &webhookRepository{db: db}
+}
+
+// Create 在同一個交易中建立 webhook 與其專屬的機器人帳號
+func (r *webhookRepository) Create(
+	ctx context.Context,
+	webhook *model.Webhook,
+	bot *model.User,
+) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(bot).Error; err != nil {
+			return err
+		}
+
+		webhook.UserID = bot.ID
+
+		return tx.Create(webhook).Error
+	})
+}
+
+// GetByID 透過 ID 取得 webhook
+func (r *webhookRepository) GetByID(ctx context.Context, id uint) (*model.Webhook, error) {
+	var webhook model.Webhook
+
+	err := r.db.WithContext(ctx).First(&webhook, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("webhook not found")
+		}
+
+		return nil, err
+	}
+
+	return &webhook, nil
+}
+
+// GetByChannelID 取得頻道的所有 webhook
+func (r *webhookRepository) GetByChannelID(
+	ctx context.Context,
+	channelID uint,
+) ([]*model.Webhook, error) {
+	var webhooks []*model.Webhook
+
+	err := r.db.WithContext(ctx).
+		Where("channel_id = ?", channelID).
+		Order("id ASC").
+		Find(&webhooks).
+		Error
+
+	return webhooks, err
+}
+
+// Update 更新 webhook
+func (r *webhookRepository) Update(ctx context.Context, webhook *model.Webhook) error {
+	return r.db.WithContext(ctx).Save(webhook).Error
+}
+
+// Delete 刪除 webhook（機器人帳號保留給既有訊息使用）
+func (r *webhookRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&model.Webhook{}, id).Error
+}
@@ -0,0 +1,101 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// JobFunc 排程工作
+type JobFunc func(ctx context.Context) error
+
+type job struct {
+	name     string
+	interval time.Duration
+	run      JobFunc
+}
+
+// Scheduler 定期執行背景工作
+type Scheduler struct {
+	jobs   []job
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	logger *zap.Logger
+}
+
+// New 建立排程器
+func New(logger *zap.Logger) *Scheduler {
+	return &Scheduler{logger: logger}
+}
+
+// Add 註冊定期執行的工作，必須在 Start 之前呼叫；間隔不大於零的工作會被忽略
+func (s *Scheduler) Add(name string, interval time.Duration, run JobFunc) {
+	if interval <= 0 {
+		s.logger.Warn("Scheduled job disabled", zap.String("job", name))
+		return
+	}
+
+	s.jobs = append(s.jobs, job{
+		name:     name,
+		interval: interval,
+		run:      run,
+	})
+}
+
+// Start 啟動所有工作
+func (s *Scheduler) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	for _, j := range s.jobs {
+		s.wg.Add(1)
+
+		go func() {
+			defer s.wg.Done()
+			s.loop(ctx, j)
+		}()
+	}
+
+	s.logger.Info("Scheduler started", zap.Int("jobs", len(s.jobs)))
+}
+
+// Stop 停止所有工作並等待執行中的工作結束
+func (s *Scheduler) Stop() {
+	if s.cancel == nil {
+		return
+	}
+
+	s.cancel()
+	s.wg.Wait()
+
+	s.logger.Info("Scheduler stopped")
+}
+
+func (s *Scheduler) loop(ctx context.Context, j job) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.runOnce(ctx, j)
+		}
+	}
+}
+
+// runOnce 執行一次工作，工作內的 panic 不會影響其他工作
+func (s *Scheduler) runOnce(ctx context.Context, j job) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error("Scheduled job panicked", zap.String("job", j.name), zap.Any("panic", r))
+		}
+	}()
+
+	if err := j.run(ctx); err != nil {
+		s.logger.Error("Scheduled job failed", zap.String("job", j.name), zap.Error(err))
+	}
+}
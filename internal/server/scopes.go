@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/walnut-almonds/talkrealm/internal/middleware"
+	"github.com/walnut-almonds/talkrealm/internal/repository"
+)
+
+// guildResolvers 提供 API token 社群限制所需的社群 ID 解析，以及 WebSocket 訂閱的成員資格檢查
+type guildResolvers struct {
+	channelRepo repository.ChannelRepository
+	memberRepo  repository.GuildMemberRepository
+	messageRepo repository.MessageRepository
+	webhookRepo repository.WebhookRepository
+}
+
+// byGuildParam 從 /guilds/:id 解析社群 ID
+func (r *guildResolvers) byGuildParam() middleware.GuildResolver {
+	return func(c *gin.Context) (uint, bool) {
+		return parseIDParam(c, "id")
+	}
+}
+
+// byChannelParam 從 /channels/:id 解析頻道所屬社群 ID
+func (r *guildResolvers) byChannelParam() middleware.GuildResolver {
+	return func(c *gin.Context) (uint, bool) {
+		channelID, ok := parseIDParam(c, "id")
+		if !ok {
+			return 0, false
+		}
+
+		channel, err := r.channelRepo.GetByID(c.Request.Context(), channelID)
+		if err != nil {
+			return 0, false
+		}
+
+		return channel.GuildID, true
+	}
+}
+
+// byMessageParam 從 /messages/:id 解析訊息所屬社群 ID
+func (r *guildResolvers) byMessageParam() middleware.GuildResolver {
+	return func(c *gin.Context) (uint, bool) {
+		messageID, ok := parseIDParam(c, "id")
+		if !ok {
+			return 0, false
+		}
+
+		message, err := r.messageRepo.GetByID(c.Request.Context(), messageID)
+		if err != nil {
+			return 0, false
+		}
+
+		return message.Channel.GuildID, true
+	}
+}
+
+// byWebhookParam 從 /webhooks/:id 解析 webhook 所屬社群 ID
+func (r *guildResolvers) byWebhookParam() middleware.GuildResolver {
+	return func(c *gin.Context) (uint, bool) {
+		webhookID, ok := parseIDParam(c, "id")
+		if !ok {
+			return 0, false
+		}
+
+		webhook, err := r.webhookRepo.GetByID(c.Request.Context(), webhookID)
+		if err != nil {
+			return 0, false
+		}
+
+		return webhook.GuildID, true
+	}
+}
+
+// channelGuild 取得頻道所屬的社群 ID，供 WebSocket 訂閱檢查 token 的社群限制
+func (r *guildResolvers) channelGuild(ctx context.Context, channelID uint) (uint, error) {
+	channel, err := r.channelRepo.GetByID(ctx, channelID)
+	if err != nil {
+		return 0, err
+	}
+
+	return channel.GuildID, nil
+}
+
+func parseIDParam(c *gin.Context, name string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 32)
+	if err != nil {
+		return 0, false
+	}
+
+	return uint(id), true
+}
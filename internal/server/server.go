@@ -1,33 +1,137 @@
 package server
 
 import (
+	"context"
+	"errors"
+	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/walnut-almonds/talkrealm/internal/apperror"
 	"github.com/walnut-almonds/talkrealm/internal/handler"
 	"github.com/walnut-almonds/talkrealm/internal/middleware"
 	"github.com/walnut-almonds/talkrealm/internal/repository"
+	"github.com/walnut-almonds/talkrealm/internal/scheduler"
 	"github.com/walnut-almonds/talkrealm/internal/service"
 	"github.com/walnut-almonds/talkrealm/internal/websocket"
 	"github.com/walnut-almonds/talkrealm/pkg/auth"
+	"github.com/walnut-almonds/talkrealm/pkg/clock"
 	"github.com/walnut-almonds/talkrealm/pkg/config"
-	"github.com/walnut-almonds/talkrealm/pkg/database"
+	"github.com/walnut-almonds/talkrealm/pkg/idgen"
+	"github.com/walnut-almonds/talkrealm/pkg/metrics"
+	"github.com/walnut-almonds/talkrealm/pkg/ratelimit"
+	"github.com/walnut-almonds/talkrealm/pkg/storage"
+	"github.com/walnut-almonds/talkrealm/pkg/tracing"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 // Server 代表應用程式伺服器
 type Server struct {
-	config         *config.Config
-	router         *gin.Engine
-	jwtManager     *auth.JWTManager
-	wsManager      *websocket.Manager
-	userHandler    *handler.UserHandler
-	guildHandler   *handler.GuildHandler
-	channelHandler *handler.ChannelHandler
-	messageHandler *handler.MessageHandler
+	config              *config.Config
+	router              *gin.Engine
+	jwtManager          *auth.JWTManager
+	wsManager           *websocket.Manager
+	healthHandler       *handler.HealthHandler
+	userHandler         *handler.UserHandler
+	guildHandler        *handler.GuildHandler
+	channelHandler      *handler.ChannelHandler
+	messageHandler      *handler.MessageHandler
+	oidcHandler         *handler.OIDCHandler
+	tokenHandler        *handler.APITokenHandler
+	accountHandler      *handler.AccountHandler
+	auditLogHandler     *handler.AuditLogHandler
+	announcementHandler *handler.AnnouncementHandler
+	webhookHandler      *handler.WebhookHandler
+	eventWebhookHandler *handler.EventWebhookHandler
+	tokenService        service.APITokenService
+	userService         service.UserService
+	resolvers           *guildResolvers
+	events              service.EventBus
+	scheduler           *scheduler.Scheduler
+	limiter             ratelimit.Limiter
+	ownedBackplane      websocket.Backplane
+	metrics             *metrics.Metrics
+	logger              *zap.Logger
+}
+
+// Options 建立伺服器實例所需的相依元件
+//
+// Config 與 DB 必須提供，其餘未設定的元件依設定建立預設實作。
+// 實例之間不共用任何狀態，除非明確傳入同一個元件（例如在測試中共用 Backplane 模擬多個副本）
+type Options struct {
+	Config         *config.Config
+	DB             *gorm.DB
+	Logger         *zap.Logger          // 預設不輸出日誌
+	Clock          clock.Clock          // 預設使用系統時間
+	IDs            idgen.Generator      // 預設產生隨機識別碼
+	BlobStore      storage.BlobStore    // 預設依 storage 設定建立
+	Backplane      websocket.Backplane  // 預設依 websocket.backplane 設定建立
+	Metrics        *metrics.Metrics     // 預設為每個實例建立獨立的指標
+	TracerProvider trace.TracerProvider // 預設不產生追蹤
+	AuditLogger    *zap.Logger          // 稽核紀錄的獨立輸出，預設只寫入資料庫
 }
 
 // New 創建新的伺服器實例
-func New(cfg *config.Config) (*Server, error) {
+func New(opts Options) (*Server, error) {
+	if opts.Config == nil || opts.DB == nil {
+		return nil, errors.New("config and database are required")
+	}
+
+	cfg := opts.Config
+	db := opts.DB
+
+	logger := opts.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	clk := opts.Clock
+	if clk == nil {
+		clk = clock.Real()
+	}
+
+	ids := opts.IDs
+	if ids == nil {
+		ids = idgen.Random()
+	}
+
+	// 由伺服器建立的 backplane 在 Close 時一併關閉，外部傳入的由呼叫者負責
+	var ownedBackplane websocket.Backplane
+
+	backplane := opts.Backplane
+	if backplane == nil {
+		created, err := websocket.NewBackplane(&cfg.WebSocket, &cfg.Redis, logger)
+		if err != nil {
+			return nil, err
+		}
+
+		backplane, ownedBackplane = created, created
+	}
+
+	tracerProvider := opts.TracerProvider
+	if tracerProvider == nil {
+		tracerProvider = tracing.Noop()
+	}
+
+	tracer := tracerProvider.Tracer(tracing.ScopeName)
+
+	appMetrics := opts.Metrics
+	if appMetrics == nil {
+		appMetrics = metrics.New()
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := appMetrics.RegisterDB(sqlDB, cfg.Database.Driver); err != nil {
+		return nil, err
+	}
+
 	// 設定 Gin 模式
 	if cfg.Server.Mode == "release" {
 		gin.SetMode(gin.ReleaseMode)
@@ -37,8 +141,28 @@ func New(cfg *config.Config) (*Server, error) {
 
 	// 全局中介軟體
 	router.Use(gin.Recovery())
-	router.Use(middleware.Logger())
+	router.Use(otelgin.Middleware(
+		cfg.Tracing.ServiceName,
+		otelgin.WithTracerProvider(tracerProvider),
+		otelgin.WithPropagators(tracing.Propagator()),
+		otelgin.WithGinFilter(func(c *gin.Context) bool {
+			// 健康檢查與指標抓取的頻率高且沒有除錯價值
+			switch c.FullPath() {
+			case "/health", "/livez", "/readyz", "/ping", cfg.Metrics.Path:
+				return false
+			default:
+				return true
+			}
+		}),
+	))
+	router.Use(middleware.RequestID(ids))
+	router.Use(middleware.Logger(logger, &cfg.Log))
+	router.Use(middleware.Metrics(appMetrics))
+	router.Use(middleware.ErrorHandler(logger))
 	router.Use(middleware.CORS())
+	router.NoRoute(func(c *gin.Context) {
+		_ = c.Error(apperror.NotFound(apperror.CodeNotFound, "route not found"))
+	})
 
 	// 初始化 JWT 管理器
 	jwtManager := auth.NewJWTManager(
@@ -46,8 +170,20 @@ func New(cfg *config.Config) (*Server, error) {
 		time.Duration(cfg.JWT.ExpirationHours)*time.Hour,
 	)
 
-	// 獲取資料庫連接
-	db := database.GetDB()
+	// 初始化 OIDC 管理器
+	oidcManager := auth.NewOIDCManager(cfg.JWT.Secret, &cfg.OIDC)
+
+	// 初始化訊息限流器
+	limiter, err := ratelimit.New(&cfg.RateLimit, &cfg.Redis)
+	if err != nil {
+		return nil, err
+	}
+
+	// 稽核紀錄除了寫入資料庫，也可另外輸出到獨立的稽核日誌
+	var auditSink repository.AuditSink
+	if opts.AuditLogger != nil {
+		auditSink = service.NewAuditLogSink(opts.AuditLogger)
+	}
 
 	// 初始化 Repository
 	userRepo := repository.NewUserRepository(db)
@@ -55,36 +191,262 @@ func New(cfg *config.Config) (*Server, error) {
 	guildMemberRepo := repository.NewGuildMemberRepository(db)
 	channelRepo := repository.NewChannelRepository(db)
 	messageRepo := repository.NewMessageRepository(db)
+	userIdentityRepo := repository.NewUserIdentityRepository(db)
+	apiTokenRepo := repository.NewAPITokenRepository(db)
+	dataExportRepo := repository.NewDataExportRepository(db)
+	guildBanRepo := repository.NewGuildBanRepository(db)
+	auditLogRepo := repository.NewAuditLogRepository(db)
+	channelFollowRepo := repository.NewChannelFollowRepository(db)
+	webhookRepo := repository.NewWebhookRepository(db)
+	eventWebhookRepo := repository.NewEventWebhookRepository(db)
+	outboxRepo := repository.NewOutboxRepository(db)
+
+	// 跨 repository 寫入使用的交易管理器
+	txManager := repository.NewTxManager(db, auditSink)
+
+	// 初始化檔案儲存
+	blobStore := opts.BlobStore
+	if blobStore == nil {
+		blobStore, err = storage.New(&cfg.Storage)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	// 初始化 WebSocket 管理器
-	wsManager := websocket.NewManager()
+	wsManager := websocket.NewManager(
+		backplane,
+		cfg.Server.ReconnectJitter,
+		appMetrics,
+		tracer,
+		logger,
+	)
 	go wsManager.Run() // 啟動 WebSocket 管理器
 
+	if err := appMetrics.RegisterWebSocket(wsManager); err != nil {
+		return nil, err
+	}
+
+	// 初始化領域事件匯流排
+	events := service.NewEventBus(
+		outboxRepo,
+		service.EventBusOptions{
+			PollInterval: cfg.Outbox.PollInterval,
+			Retention:    cfg.Outbox.Retention,
+		},
+		ids,
+		tracer,
+		clk,
+		logger,
+	)
+
 	// 初始化 Service
-	userService := service.NewUserService(userRepo, jwtManager)
-	guildService := service.NewGuildService(guildRepo, guildMemberRepo)
-	guildMemberService := service.NewGuildMemberService(guildRepo, guildMemberRepo)
-	channelService := service.NewChannelService(channelRepo, guildRepo, guildMemberRepo)
-	messageService := service.NewMessageService(messageRepo, channelRepo, guildMemberRepo)
+	userService := service.NewUserService(userRepo, jwtManager, clk)
+	guildService := service.NewGuildService(
+		guildRepo,
+		guildMemberRepo,
+		userRepo,
+		txManager,
+		events,
+		cfg.Account.ReauthWindow,
+		clk,
+		logger,
+	)
+	guildMemberService := service.NewGuildMemberService(
+		guildRepo,
+		guildMemberRepo,
+		guildBanRepo,
+		txManager,
+		events,
+		clk,
+		logger,
+	)
+	channelService := service.NewChannelService(
+		channelRepo,
+		guildRepo,
+		guildMemberRepo,
+		txManager,
+		events,
+		clk,
+		logger,
+	)
+	messageService := service.NewMessageService(
+		messageRepo,
+		channelRepo,
+		guildMemberRepo,
+		txManager,
+		limiter,
+		ratelimit.Rule{Limit: cfg.RateLimit.MessagesPerWindow, Window: cfg.RateLimit.Window},
+		events,
+		appMetrics,
+		tracer,
+		clk,
+		logger,
+	)
+	oidcService := service.NewOIDCService(
+		userRepo,
+		userIdentityRepo,
+		txManager,
+		oidcManager,
+		jwtManager,
+		clk,
+	)
+	apiTokenService := service.NewAPITokenService(apiTokenRepo, userRepo, clk)
+	announcementService := service.NewAnnouncementService(
+		channelRepo,
+		channelFollowRepo,
+		messageRepo,
+		guildMemberRepo,
+		messageService,
+		txManager,
+		events,
+		clk,
+		logger,
+	)
+	webhookService := service.NewWebhookService(
+		webhookRepo,
+		channelRepo,
+		guildMemberRepo,
+		txManager,
+		messageService,
+		limiter,
+		ratelimit.Rule{
+			Limit:  cfg.RateLimit.WebhookMessagesPerWindow,
+			Window: cfg.RateLimit.WebhookWindow,
+		},
+		ids,
+		clk,
+		logger,
+	)
+	eventWebhookService := service.NewEventWebhookService(
+		eventWebhookRepo,
+		guildMemberRepo,
+		txManager,
+		service.EventWebhookOptions{
+			Timeout:           cfg.EventWebhooks.Timeout,
+			MaxAttempts:       cfg.EventWebhooks.MaxAttempts,
+			RetryBackoff:      cfg.EventWebhooks.RetryBackoff,
+			MaxRetryBackoff:   cfg.EventWebhooks.MaxRetryBackoff,
+			DisableAfter:      cfg.EventWebhooks.DisableAfter,
+			DeliveryRetention: cfg.EventWebhooks.DeliveryRetention,
+			AllowInsecureURLs: cfg.EventWebhooks.AllowInsecureURLs,
+			AllowPrivateIPs:   cfg.EventWebhooks.AllowPrivateIPs,
+		},
+		tracer,
+		clk,
+		logger,
+	)
+	auditLogService := service.NewAuditLogService(
+		auditLogRepo,
+		guildRepo,
+		guildMemberRepo,
+		cfg.AuditLog.Retention,
+		clk,
+		logger,
+	)
+	accountService := service.NewAccountService(
+		userRepo,
+		txManager,
+		events,
+		cfg.Account.DeletionGracePeriod,
+		cfg.Account.ReauthWindow,
+		clk,
+		logger,
+	)
+	dataExportService := service.NewDataExportService(
+		dataExportRepo,
+		userRepo,
+		guildMemberRepo,
+		messageRepo,
+		userIdentityRepo,
+		apiTokenRepo,
+		blobStore,
+		cfg.Account.ExportTTL,
+		clk,
+		logger,
+	)
 
-	// 設定 WebSocket 管理器到 MessageService
-	messageService.SetWebSocketManager(wsManager)
+	// 領域事件同時送往 WebSocket 與傳出事件 webhook
+	events.Subscribe(service.NewWebSocketSubscriber(wsManager))
+	events.Subscribe(eventWebhookService)
+	events.Subscribe(announcementService)
+	events.Start()
 
 	// 初始化 Handler
 	userHandler := handler.NewUserHandler(userService)
-	guildHandler := handler.NewGuildHandler(guildService, guildMemberService)
+	guildHandler := handler.NewGuildHandler(guildService, guildMemberService, logger)
 	channelHandler := handler.NewChannelHandler(channelService)
 	messageHandler := handler.NewMessageHandler(messageService)
+	oidcHandler := handler.NewOIDCHandler(oidcService, logger)
+	tokenHandler := handler.NewAPITokenHandler(apiTokenService)
+	accountHandler := handler.NewAccountHandler(accountService, dataExportService)
+	auditLogHandler := handler.NewAuditLogHandler(auditLogService)
+	announcementHandler := handler.NewAnnouncementHandler(announcementService)
+	webhookHandler := handler.NewWebhookHandler(webhookService)
+	eventWebhookHandler := handler.NewEventWebhookHandler(eventWebhookService)
+	healthHandler := handler.NewHealthHandler(
+		[]handler.ReadinessCheck{
+			{Name: "database", Check: sqlDB.PingContext},
+			{Name: "rate_limit", Check: limiter.Ping},
+			{Name: "backplane", Check: backplane.Ping},
+			{Name: "storage", Check: blobStore.Ping},
+		},
+		cfg.Server.ReadyTimeout,
+		logger,
+	)
+
+	// 初始化背景排程
+	jobs := scheduler.New(logger)
+	jobs.Add("account-purge", cfg.Account.PurgeInterval, accountService.PurgeDueAccounts)
+	jobs.Add("data-export", cfg.Account.ExportPollInterval, dataExportService.ProcessPending)
+	jobs.Add("data-export-cleanup", time.Hour, dataExportService.CleanupExpired)
+	jobs.Add("guild-ban-expiry", cfg.Moderation.ExpiryInterval, guildMemberService.LiftExpiredBans)
+	jobs.Add(
+		"member-timeout-expiry",
+		cfg.Moderation.ExpiryInterval,
+		guildMemberService.ExpireTimeouts,
+	)
+	jobs.Add("audit-log-retention", cfg.AuditLog.PurgeInterval, auditLogService.PurgeExpired)
+	jobs.Add(
+		"event-webhook-delivery",
+		cfg.EventWebhooks.PollInterval,
+		eventWebhookService.DeliverPending,
+	)
+	jobs.Add("event-delivery-retention", time.Hour, eventWebhookService.PurgeDeliveries)
+	jobs.Add("outbox-retention", time.Hour, events.PurgeDispatched)
+	jobs.Start()
 
 	s := &Server{
-		config:         cfg,
-		router:         router,
-		jwtManager:     jwtManager,
-		wsManager:      wsManager,
-		userHandler:    userHandler,
-		guildHandler:   guildHandler,
-		channelHandler: channelHandler,
-		messageHandler: messageHandler,
+		config:              cfg,
+		router:              router,
+		jwtManager:          jwtManager,
+		wsManager:           wsManager,
+		healthHandler:       healthHandler,
+		userHandler:         userHandler,
+		guildHandler:        guildHandler,
+		channelHandler:      channelHandler,
+		messageHandler:      messageHandler,
+		oidcHandler:         oidcHandler,
+		tokenHandler:        tokenHandler,
+		accountHandler:      accountHandler,
+		auditLogHandler:     auditLogHandler,
+		announcementHandler: announcementHandler,
+		webhookHandler:      webhookHandler,
+		eventWebhookHandler: eventWebhookHandler,
+		tokenService:        apiTokenService,
+		userService:         userService,
+		resolvers: &guildResolvers{
+			channelRepo: channelRepo,
+			memberRepo:  guildMemberRepo,
+			messageRepo: messageRepo,
+			webhookRepo: webhookRepo,
+		},
+		events:         events,
+		scheduler:      jobs,
+		limiter:        limiter,
+		metrics:        appMetrics,
+		ownedBackplane: ownedBackplane,
+		logger:         logger,
 	}
 
 	// 設定路由
@@ -108,81 +470,382 @@ func (s *Server) setupRoutes() {
 	// s.router.Static("/static", "./web")
 	// s.router.StaticFile("/", "./web/index.html")
 
-	// 健康檢查
-	s.router.GET("/health", handler.HealthCheck)
+	// 健康檢查：/livez 只確認行程存活，/readyz 檢查相依元件；/health 為舊路徑，等同 /readyz
+	s.router.GET("/livez", s.healthHandler.Live)
+	s.router.GET("/readyz", s.healthHandler.Ready)
+	s.router.GET("/health", s.healthHandler.Ready)
 	s.router.GET("/ping", handler.Ping)
 
+	// Prometheus 指標；設定獨立連接埠時改由 MetricsHandler 在該連接埠提供
+	if s.config.Metrics.Enabled && s.config.Metrics.Port == 0 {
+		s.router.GET(s.config.Metrics.Path, gin.WrapH(s.metrics.Handler()))
+	}
+
 	// API v1 路由群組
 	v1 := s.router.Group("/api/v1")
 	{
 		// 公開路由 - 認證相關
-		auth := v1.Group("/auth")
+		authRoutes := v1.Group("/auth")
 		{
-			auth.POST("/register", s.userHandler.Register)
-			auth.POST("/login", s.userHandler.Login)
+			authRoutes.POST("/register", s.userHandler.Register)
+			authRoutes.POST("/login", s.userHandler.Login)
+
+			// OIDC 單一登入
+			authRoutes.GET("/oidc/providers", s.oidcHandler.ListProviders)
+			authRoutes.GET("/oidc/:provider/login", s.oidcHandler.Login)
+			authRoutes.GET("/oidc/:provider/callback", s.oidcHandler.Callback)
 		}
 
-		// 需要認證的路由
+		// 傳入 webhook（以網址中的 token 驗證）
+		v1.POST("/webhooks/:id/:token", s.webhookHandler.ExecuteWebhook)
+
+		// 需要認證的路由（Bearer JWT 或 Bot API token）
 		protected := v1.Group("")
-		protected.Use(middleware.AuthMiddleware(s.jwtManager))
+		protected.Use(middleware.AuthMiddleware(s.jwtManager, s.tokenService, s.userService))
+
+		// API token 的權限範圍檢查
+		scope := middleware.RequireScope
+		byGuild := s.resolvers.byGuildParam()
+		byChannel := s.resolvers.byChannelParam()
+		byMessage := s.resolvers.byMessageParam()
+		byWebhook := s.resolvers.byWebhookParam()
 		{
 			// 使用者相關
 			users := protected.Group("/users")
 			{
-				users.GET("/me", s.userHandler.GetCurrentUser)
-				users.PATCH("/me", s.userHandler.UpdateCurrentUser)
+				users.GET("/me", scope(auth.ScopeUsersRead, nil), s.userHandler.GetCurrentUser)
+				users.PATCH(
+					"/me",
+					scope(auth.ScopeUsersWrite, nil),
+					s.userHandler.UpdateCurrentUser,
+				)
+
+				// 帳號刪除、資料匯出、API token 與機器人管理（僅限登入的使用者）
+				me := users.Group("/me", middleware.RequireUserSession())
+				{
+					me.DELETE("", s.accountHandler.DeleteAccount)
+					me.POST("/cancel-deletion", s.accountHandler.CancelDeletion)
+					me.POST("/exports", s.accountHandler.RequestExport)
+					me.GET("/exports", s.accountHandler.ListExports)
+					me.GET("/exports/:exportId/download", s.accountHandler.DownloadExport)
+
+					me.POST("/identities/:provider", s.oidcHandler.LinkIdentity)
+
+					me.POST("/tokens", s.tokenHandler.CreateToken)
+					me.GET("/tokens", s.tokenHandler.ListTokens)
+					me.DELETE("/tokens/:tokenId", s.tokenHandler.RevokeToken)
+
+					me.POST("/bots", s.tokenHandler.CreateBot)
+					me.GET("/bots", s.tokenHandler.ListBots)
+					me.POST("/bots/:botId/tokens", s.tokenHandler.CreateBotToken)
+					me.GET("/bots/:botId/tokens", s.tokenHandler.ListBotTokens)
+					me.DELETE("/bots/:botId/tokens/:tokenId", s.tokenHandler.RevokeBotToken)
+				}
 			}
 
 			// 伺服器/社群相關
 			guilds := protected.Group("/guilds")
 			{
-				guilds.POST("", s.guildHandler.CreateGuild)
-				guilds.GET("/me", s.guildHandler.ListUserGuilds)
-				guilds.GET("/:id", s.guildHandler.GetGuild)
-				guilds.PUT("/:id", s.guildHandler.UpdateGuild)
-				guilds.PATCH("/:id", s.guildHandler.UpdateGuild)
-				guilds.DELETE("/:id", s.guildHandler.DeleteGuild)
+				guilds.POST(
+					"",
+					scope(auth.ScopeGuildsWrite, nil),
+					middleware.RequireUnrestrictedGuilds(),
+					s.guildHandler.CreateGuild,
+				)
+				guilds.GET("/me", scope(auth.ScopeGuildsRead, nil), s.guildHandler.ListUserGuilds)
+				guilds.GET("/:id", scope(auth.ScopeGuildsRead, byGuild), s.guildHandler.GetGuild)
+				guilds.PUT(
+					"/:id",
+					scope(auth.ScopeGuildsWrite, byGuild),
+					s.guildHandler.UpdateGuild,
+				)
+				guilds.PATCH(
+					"/:id",
+					scope(auth.ScopeGuildsWrite, byGuild),
+					s.guildHandler.UpdateGuild,
+				)
+				guilds.DELETE(
+					"/:id",
+					scope(auth.ScopeGuildsWrite, byGuild),
+					s.guildHandler.DeleteGuild,
+				)
+				guilds.POST(
+					"/:id/transfer",
+					middleware.RequireUserSession(),
+					s.guildHandler.TransferOwnership,
+				)
 
 				// 社群成員操作
-				guilds.POST("/:id/join", s.guildHandler.JoinGuild)
-				guilds.POST("/:id/leave", s.guildHandler.LeaveGuild)
-				guilds.GET("/:id/members", s.guildHandler.ListGuildMembers)
-				guilds.DELETE("/:id/members/:userId", s.guildHandler.KickMember)
-				guilds.PUT("/:id/members/:userId/role", s.guildHandler.UpdateMemberRole)
+				guilds.POST(
+					"/:id/join",
+					scope(auth.ScopeGuildsWrite, byGuild),
+					s.guildHandler.JoinGuild,
+				)
+				guilds.POST(
+					"/:id/leave",
+					scope(auth.ScopeGuildsWrite, byGuild),
+					s.guildHandler.LeaveGuild,
+				)
+				guilds.GET(
+					"/:id/members",
+					scope(auth.ScopeGuildsRead, byGuild),
+					s.guildHandler.ListGuildMembers,
+				)
+				guilds.DELETE(
+					"/:id/members/:userId",
+					scope(auth.ScopeGuildsWrite, byGuild),
+					s.guildHandler.KickMember,
+				)
+				guilds.PUT(
+					"/:id/members/:userId/role",
+					scope(auth.ScopeGuildsWrite, byGuild),
+					s.guildHandler.UpdateMemberRole,
+				)
+				guilds.PUT(
+					"/:id/members/:userId/timeout",
+					scope(auth.ScopeGuildsWrite, byGuild),
+					s.guildHandler.TimeoutMember,
+				)
+				guilds.DELETE(
+					"/:id/members/:userId/timeout",
+					scope(auth.ScopeGuildsWrite, byGuild),
+					s.guildHandler.RemoveTimeout,
+				)
+
+				// 社群封鎖
+				guilds.GET(
+					"/:id/bans",
+					scope(auth.ScopeGuildsRead, byGuild),
+					s.guildHandler.ListBans,
+				)
+				guilds.PUT(
+					"/:id/bans/:userId",
+					scope(auth.ScopeGuildsWrite, byGuild),
+					s.guildHandler.BanMember,
+				)
+				guilds.DELETE(
+					"/:id/bans/:userId",
+					scope(auth.ScopeGuildsWrite, byGuild),
+					s.guildHandler.UnbanMember,
+				)
+
+				// 社群稽核紀錄
+				guilds.GET(
+					"/:id/audit-logs",
+					scope(auth.ScopeGuildsRead, byGuild),
+					s.auditLogHandler.ListAuditLogs,
+				)
+
+				// 傳出事件 webhook
+				guilds.GET(
+					"/:id/event-webhooks",
+					scope(auth.ScopeGuildsRead, byGuild),
+					s.eventWebhookHandler.ListEventWebhooks,
+				)
+				guilds.POST(
+					"/:id/event-webhooks",
+					scope(auth.ScopeGuildsWrite, byGuild),
+					s.eventWebhookHandler.CreateEventWebhook,
+				)
+				guilds.PATCH(
+					"/:id/event-webhooks/:webhookId",
+					scope(auth.ScopeGuildsWrite, byGuild),
+					s.eventWebhookHandler.UpdateEventWebhook,
+				)
+				guilds.DELETE(
+					"/:id/event-webhooks/:webhookId",
+					scope(auth.ScopeGuildsWrite, byGuild),
+					s.eventWebhookHandler.DeleteEventWebhook,
+				)
+				guilds.GET(
+					"/:id/event-webhooks/:webhookId/deliveries",
+					scope(auth.ScopeGuildsRead, byGuild),
+					s.eventWebhookHandler.ListDeliveries,
+				)
 
 				// 社群頻道
-				guilds.GET("/:id/channels", s.channelHandler.ListGuildChannels)
-				guilds.POST("/:id/channels", s.channelHandler.CreateChannel)
+				guilds.GET(
+					"/:id/channels",
+					scope(auth.ScopeChannelsRead, byGuild),
+					s.channelHandler.ListGuildChannels,
+				)
+				guilds.POST(
+					"/:id/channels",
+					scope(auth.ScopeChannelsWrite, byGuild),
+					s.channelHandler.CreateChannel,
+				)
+				guilds.PATCH(
+					"/:id/channels",
+					scope(auth.ScopeChannelsWrite, byGuild),
+					s.channelHandler.ReorderChannels,
+				)
 			}
 
 			// 頻道相關
 			channels := protected.Group("/channels")
 			{
-				channels.GET("/:id", s.channelHandler.GetChannel)
-				channels.PUT("/:id", s.channelHandler.UpdateChannel)
-				channels.PATCH("/:id", s.channelHandler.UpdateChannel)
-				channels.DELETE("/:id", s.channelHandler.DeleteChannel)
-				channels.PUT("/:id/position", s.channelHandler.UpdateChannelPosition)
+				channels.GET(
+					"/:id",
+					scope(auth.ScopeChannelsRead, byChannel),
+					s.channelHandler.GetChannel,
+				)
+				channels.PUT(
+					"/:id",
+					scope(auth.ScopeChannelsWrite, byChannel),
+					s.channelHandler.UpdateChannel,
+				)
+				channels.PATCH(
+					"/:id",
+					scope(auth.ScopeChannelsWrite, byChannel),
+					s.channelHandler.UpdateChannel,
+				)
+				channels.DELETE(
+					"/:id",
+					scope(auth.ScopeChannelsWrite, byChannel),
+					s.channelHandler.DeleteChannel,
+				)
+				channels.PUT(
+					"/:id/position",
+					scope(auth.ScopeChannelsWrite, byChannel),
+					s.channelHandler.UpdateChannelPosition,
+				)
 
 				// 頻道訊息
-				channels.GET("/:id/messages", s.messageHandler.ListChannelMessages)
-				channels.POST("/:id/messages", s.messageHandler.CreateMessage)
+				channels.GET(
+					"/:id/messages",
+					scope(auth.ScopeMessagesRead, byChannel),
+					s.messageHandler.ListChannelMessages,
+				)
+				channels.POST(
+					"/:id/messages",
+					scope(auth.ScopeMessagesWrite, byChannel),
+					s.messageHandler.CreateMessage,
+				)
+
+				// 傳入 webhook
+				channels.GET(
+					"/:id/webhooks",
+					scope(auth.ScopeChannelsRead, byChannel),
+					s.webhookHandler.ListWebhooks,
+				)
+				channels.POST(
+					"/:id/webhooks",
+					scope(auth.ScopeChannelsWrite, byChannel),
+					s.webhookHandler.CreateWebhook,
+				)
+
+				// 公告頻道追蹤
+				channels.GET(
+					"/:id/follows",
+					scope(auth.ScopeChannelsRead, byChannel),
+					s.announcementHandler.ListFollowing,
+				)
+				channels.POST(
+					"/:id/follows",
+					scope(auth.ScopeChannelsWrite, byChannel),
+					s.announcementHandler.FollowChannel,
+				)
+				channels.DELETE(
+					"/:id/follows/:sourceId",
+					scope(auth.ScopeChannelsWrite, byChannel),
+					s.announcementHandler.UnfollowChannel,
+				)
 			}
 
 			// 訊息相關
 			messages := protected.Group("/messages")
 			{
-				messages.GET("/:id", s.messageHandler.GetMessage)
-				messages.PUT("/:id", s.messageHandler.UpdateMessage)
-				messages.PATCH("/:id", s.messageHandler.UpdateMessage)
-				messages.DELETE("/:id", s.messageHandler.DeleteMessage)
+				messages.GET(
+					"/:id",
+					scope(auth.ScopeMessagesRead, byMessage),
+					s.messageHandler.GetMessage,
+				)
+				messages.PUT(
+					"/:id",
+					scope(auth.ScopeMessagesWrite, byMessage),
+					s.messageHandler.UpdateMessage,
+				)
+				messages.PATCH(
+					"/:id",
+					scope(auth.ScopeMessagesWrite, byMessage),
+					s.messageHandler.UpdateMessage,
+				)
+				messages.DELETE(
+					"/:id",
+					scope(auth.ScopeMessagesWrite, byMessage),
+					s.messageHandler.DeleteMessage,
+				)
+				messages.POST(
+					"/:id/publish",
+					scope(auth.ScopeMessagesWrite, byMessage),
+					s.announcementHandler.PublishMessage,
+				)
+			}
+
+			// 傳入 webhook 管理
+			webhooks := protected.Group("/webhooks")
+			{
+				webhooks.PATCH(
+					"/:id",
+					scope(auth.ScopeChannelsWrite, byWebhook),
+					s.webhookHandler.UpdateWebhook,
+				)
+				webhooks.DELETE(
+					"/:id",
+					scope(auth.ScopeChannelsWrite, byWebhook),
+					s.webhookHandler.DeleteWebhook,
+				)
 			}
 
 			// WebSocket 連線（需要認證）
-			protected.GET("/ws", websocket.HandleWebSocket(s.wsManager))
+			protected.GET(
+				"/ws",
+				scope(auth.ScopeMessagesRead, nil),
+				websocket.HandleWebSocket(
+					s.wsManager,
+					s.resolvers.channelGuild,
+					s.resolvers.memberRepo.IsMember,
+				),
+			)
 		}
 	}
-} // Router 返回 gin 路由器
+}
+
+// Router 返回 gin 路由器
 func (s *Server) Router() *gin.Engine {
 	return s.router
 }
+
+// MetricsHandler 返回 Prometheus 指標的處理器，供獨立的指標連接埠使用
+func (s *Server) MetricsHandler() http.Handler {
+	return s.metrics.Handler()
+}
+
+// BeginShutdown 讓就緒檢查開始失敗，在停止接受連線前呼叫
+func (s *Server) BeginShutdown() {
+	s.healthHandler.SetShuttingDown()
+}
+
+// ShutdownWebSockets 通知所有 WebSocket 客戶端重新連線並關閉連線
+//
+// http.Server.Shutdown 不會追蹤已升級的 WebSocket 連線，需要另外排空
+func (s *Server) ShutdownWebSockets(ctx context.Context) error {
+	return s.wsManager.Shutdown(ctx)
+}
+
+// Close 停止伺服器的背景工作並釋放資源
+func (s *Server) Close() {
+	s.scheduler.Stop()
+	s.events.Stop()
+	s.wsManager.Close()
+
+	if err := s.limiter.Close(); err != nil {
+		s.logger.Error("Failed to close rate limiter", zap.Error(err))
+	}
+
+	if s.ownedBackplane != nil {
+		if err := s.ownedBackplane.Close(); err != nil {
+			s.logger.Error("Failed to close websocket backplane", zap.Error(err))
+		}
+	}
+}
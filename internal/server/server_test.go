@@ -0,0 +1,440 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/walnut-almonds/talkrealm/pkg/auth"
+	"github.com/walnut-almonds/talkrealm/pkg/clock"
+	"github.com/walnut-almonds/talkrealm/pkg/config"
+	"github.com/walnut-almonds/talkrealm/pkg/database"
+	"github.com/walnut-almonds/talkrealm/pkg/storage"
+)
+
+// newTestServer 以記憶體 SQLite 與預設設定建立伺服器
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+
+	return newTestServerWith(t, Options{})
+}
+
+// newTestServerWith 與 newTestServer 相同，並使用 opts 中其餘的元件（例如 Clock）
+func newTestServerWith(t *testing.T, opts Options) *Server {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+
+	cfg.RateLimit.Store = "memory"
+
+	db, err := database.OpenInMemory()
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { _ = database.Close(db) })
+
+	blobs, err := storage.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("create blob store: %v", err)
+	}
+
+	opts.Config, opts.DB, opts.BlobStore = cfg, db, blobs
+
+	srv, err := New(opts)
+	if err != nil {
+		t.Fatalf("create server: %v", err)
+	}
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+// apiClient 對測試伺服器發送請求，authorization 不為空時帶上 Authorization 標頭
+type apiClient struct {
+	t             *testing.T
+	srv           *Server
+	authorization string
+}
+
+// do 發送 JSON 請求並在 out 不為 nil 時解析回應，回傳狀態碼
+func (c *apiClient) do(method, path string, body, out any) int {
+	c.t.Helper()
+
+	var reader bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			c.t.Fatalf("marshal request: %v", err)
+		}
+
+		reader.Reset(data)
+	}
+
+	req := httptest.NewRequest(method, path, &reader)
+	req.Header.Set("Content-Type", "application/json")
+
+	if c.authorization != "" {
+		req.Header.Set("Authorization", c.authorization)
+	}
+
+	rec := httptest.NewRecorder()
+	c.srv.Router().ServeHTTP(rec, req)
+
+	if out != nil && rec.Body.Len() > 0 {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			c.t.Fatalf("%s %s: unmarshal %q: %v", method, path, rec.Body.String(), err)
+		}
+	}
+
+	return rec.Code
+}
+
+// expect 發送請求並檢查狀態碼
+func (c *apiClient) expect(status int, method, path string, body, out any) {
+	c.t.Helper()
+
+	var raw json.RawMessage
+	if got := c.do(method, path, body, &raw); got != status {
+		c.t.Fatalf("%s %s = %d %s, want %d", method, path, got, raw, status)
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(raw, out); err != nil {
+			c.t.Fatalf("%s %s: unmarshal %s: %v", method, path, raw, err)
+		}
+	}
+}
+
+// registerUser 註冊並登入使用者，回傳帶有 JWT 的 client
+func registerUser(t *testing.T, srv *Server, username string) *apiClient {
+	t.Helper()
+
+	anon := &apiClient{t: t, srv: srv}
+	anon.expect(http.StatusCreated, http.MethodPost, "/api/v1/auth/register", map[string]string{
+		"username": username,
+		"email":    username + "@example.com",
+		"password": "correct-horse",
+	}, nil)
+
+	var login struct {
+		Token string `json:"token"`
+	}
+	anon.expect(http.StatusOK, http.MethodPost, "/api/v1/auth/login", map[string]string{
+		"email":    username + "@example.com",
+		"password": "correct-horse",
+	}, &login)
+
+	return &apiClient{t: t, srv: srv, authorization: "Bearer " + login.Token}
+}
+
+type idResponse struct {
+	ID uint `json:"id"`
+}
+
+type errorResponse struct {
+	Code   string `json:"code"`
+	Fields []struct {
+		Field string `json:"field"`
+		Rule  string `json:"rule"`
+	} `json:"fields"`
+}
+
+func TestAuthentication(t *testing.T) {
+	srv := newTestServer(t)
+	alice := registerUser(t, srv, "alice")
+
+	var me struct {
+		User struct {
+			Username string `json:"username"`
+		} `json:"user"`
+	}
+	alice.expect(http.StatusOK, http.MethodGet, "/api/v1/users/me", nil, &me)
+
+	if me.User.Username != "alice" {
+		t.Errorf("username = %q, want alice", me.User.Username)
+	}
+
+	for name, authorization := range map[string]string{
+		"missing":   "",
+		"malformed": "Bearer not-a-jwt",
+		"api token": "Bot not-a-token",
+	} {
+		client := &apiClient{t: t, srv: srv, authorization: authorization}
+		if status := client.do(http.MethodGet, "/api/v1/users/me", nil, nil); status != http.StatusUnauthorized {
+			t.Errorf("%s credentials = %d, want %d", name, status, http.StatusUnauthorized)
+		}
+	}
+
+	anon := &apiClient{t: t, srv: srv}
+
+	var resp errorResponse
+	if status := anon.do(http.MethodPost, "/api/v1/auth/login", map[string]string{
+		"email":    "alice@example.com",
+		"password": "wrong",
+	}, &resp); status != http.StatusUnauthorized {
+		t.Errorf("login with wrong password = %d, want %d", status, http.StatusUnauthorized)
+	}
+}
+
+func TestValidationErrorsListFields(t *testing.T) {
+	srv := newTestServer(t)
+	alice := registerUser(t, srv, "alice")
+
+	var resp errorResponse
+	if status := alice.do(http.MethodPost, "/api/v1/guilds", map[string]string{"name": "g"}, &resp); status != http.StatusBadRequest {
+		t.Fatalf("create guild with short name = %d, want %d", status, http.StatusBadRequest)
+	}
+
+	if resp.Code != "validation_failed" || len(resp.Fields) != 1 || resp.Fields[0].Field != "name" {
+		t.Errorf("error = %+v, want validation_failed on name", resp)
+	}
+}
+
+func TestGuildChannelAndMessageFlow(t *testing.T) {
+	srv := newTestServer(t)
+	alice := registerUser(t, srv, "alice")
+	bob := registerUser(t, srv, "bob")
+
+	var guild idResponse
+	alice.expect(
+		http.StatusCreated,
+		http.MethodPost,
+		"/api/v1/guilds",
+		map[string]string{"name": "guild"},
+		&guild,
+	)
+
+	var channel idResponse
+	alice.expect(
+		http.StatusCreated,
+		http.MethodPost,
+		fmt.Sprintf("/api/v1/guilds/%d/channels", guild.ID),
+		map[string]string{"name": "general", "type": "text"},
+		&channel,
+	)
+
+	messagesPath := fmt.Sprintf("/api/v1/channels/%d/messages", channel.ID)
+	alice.expect(
+		http.StatusCreated,
+		http.MethodPost,
+		messagesPath,
+		map[string]string{"content": "hello"},
+		nil,
+	)
+
+	// 非成員不能讀寫頻道訊息
+	var resp errorResponse
+	if status := bob.do(http.MethodGet, messagesPath, nil, &resp); status != http.StatusForbidden {
+		t.Errorf("list messages as non-member = %d, want %d", status, http.StatusForbidden)
+	}
+
+	bob.expect(
+		http.StatusOK,
+		http.MethodPost,
+		fmt.Sprintf("/api/v1/guilds/%d/join", guild.ID),
+		nil,
+		nil,
+	)
+	bob.expect(
+		http.StatusCreated,
+		http.MethodPost,
+		messagesPath,
+		map[string]string{"content": "hi"},
+		nil,
+	)
+
+	var list struct {
+		Messages []struct {
+			Content string `json:"content"`
+		} `json:"messages"`
+	}
+	bob.expect(http.StatusOK, http.MethodGet, messagesPath, nil, &list)
+
+	if len(list.Messages) != 2 {
+		t.Errorf("messages = %d, want 2", len(list.Messages))
+	}
+
+	// 一般成員不能管理頻道
+	if status := bob.do(http.MethodDelete, fmt.Sprintf("/api/v1/channels/%d", channel.ID), nil, &resp); status != http.StatusForbidden {
+		t.Errorf("delete channel as member = %d, want %d", status, http.StatusForbidden)
+	}
+
+	var guilds []idResponse
+	bob.expect(http.StatusOK, http.MethodGet, "/api/v1/guilds/me", nil, &guilds)
+
+	if len(guilds) != 1 || guilds[0].ID != guild.ID {
+		t.Errorf("bob's guilds = %+v, want [%d]", guilds, guild.ID)
+	}
+}
+
+func TestGuildRestrictedTokenCannotCreateGuilds(t *testing.T) {
+	srv := newTestServer(t)
+	alice := registerUser(t, srv, "alice")
+
+	var guild idResponse
+	alice.expect(
+		http.StatusCreated,
+		http.MethodPost,
+		"/api/v1/guilds",
+		map[string]string{"name": "guild"},
+		&guild,
+	)
+
+	var token struct {
+		Token string `json:"token"`
+	}
+	alice.expect(http.StatusCreated, http.MethodPost, "/api/v1/users/me/tokens", map[string]any{
+		"name":      "restricted",
+		"scopes":    []string{"guilds.read", "guilds.write"},
+		"guild_ids": []uint{guild.ID},
+	}, &token)
+
+	bot := &apiClient{t: t, srv: srv, authorization: "Bot " + token.Token}
+
+	var resp errorResponse
+	if status := bot.do(http.MethodPost, "/api/v1/guilds", map[string]string{"name": "other"}, &resp); status != http.StatusForbidden ||
+		resp.Code != "guild_restricted_token" {
+		t.Errorf("create guild with restricted token = %d %s, want %d guild_restricted_token",
+			status, resp.Code, http.StatusForbidden)
+	}
+
+	bot.expect(http.StatusOK, http.MethodGet, fmt.Sprintf("/api/v1/guilds/%d", guild.ID), nil, nil)
+
+	// 使用者 session 專用的路由不接受 API token
+	if status := bot.do(http.MethodGet, "/api/v1/users/me/tokens", nil, &resp); status != http.StatusForbidden {
+		t.Errorf("list tokens with api token = %d, want %d", status, http.StatusForbidden)
+	}
+}
+
+func TestExecuteWebhook(t *testing.T) {
+	srv := newTestServer(t)
+	alice := registerUser(t, srv, "alice")
+
+	var guild, channel idResponse
+	alice.expect(
+		http.StatusCreated,
+		http.MethodPost,
+		"/api/v1/guilds",
+		map[string]string{"name": "guild"},
+		&guild,
+	)
+	alice.expect(
+		http.StatusCreated,
+		http.MethodPost,
+		fmt.Sprintf("/api/v1/guilds/%d/channels", guild.ID),
+		map[string]string{"name": "general", "type": "text"},
+		&channel,
+	)
+
+	var webhook struct {
+		ID    uint   `json:"id"`
+		Token string `json:"token"`
+	}
+	alice.expect(
+		http.StatusCreated,
+		http.MethodPost,
+		fmt.Sprintf("/api/v1/channels/%d/webhooks", channel.ID),
+		map[string]string{"name": "ci"},
+		&webhook,
+	)
+
+	anon := &apiClient{t: t, srv: srv}
+
+	var resp errorResponse
+	if status := anon.do(
+		http.MethodPost,
+		fmt.Sprintf("/api/v1/webhooks/%d/wrong-token", webhook.ID),
+		map[string]string{"content": "build passed"},
+		&resp,
+	); status != http.StatusUnauthorized || resp.Code != "invalid_webhook_token" {
+		t.Errorf("execute webhook with wrong token = %d %s, want %d invalid_webhook_token",
+			status, resp.Code, http.StatusUnauthorized)
+	}
+
+	var message struct {
+		Content string `json:"content"`
+	}
+	anon.expect(
+		http.StatusOK,
+		http.MethodPost,
+		fmt.Sprintf("/api/v1/webhooks/%d/%s?wait=true", webhook.ID, webhook.Token),
+		map[string]string{"text": "build passed"},
+		&message,
+	)
+
+	if message.Content != "build passed" {
+		t.Errorf("content = %q, want the Slack text", message.Content)
+	}
+}
+
+func TestOIDCStateTokenIsNotAnAccessToken(t *testing.T) {
+	srv := newTestServer(t)
+
+	// 與 /auth/oidc/:provider/login 寫入 cookie 的登入狀態相同，任何人都能取得
+	manager := auth.NewOIDCManager(srv.config.JWT.Secret, &srv.config.OIDC)
+
+	state, err := manager.NewState("any")
+	if err != nil {
+		t.Fatalf("NewState() = %v", err)
+	}
+
+	stateToken, err := manager.SignState(state)
+	if err != nil {
+		t.Fatalf("SignState() = %v", err)
+	}
+
+	client := &apiClient{t: t, srv: srv, authorization: "Bearer " + stateToken}
+	if status := client.do(http.MethodGet, "/api/v1/guilds/me", nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("state token on a protected route = %d, want %d", status, http.StatusUnauthorized)
+	}
+
+	// 反過來，存取 token 也不能當作登入狀態
+	access, err := srv.jwtManager.GenerateToken(1, "alice", "alice@example.com")
+	if err != nil {
+		t.Fatalf("GenerateToken() = %v", err)
+	}
+
+	if _, err := manager.ParseState(access); !errors.Is(err, auth.ErrOIDCInvalidState) {
+		t.Errorf("ParseState(access token) = %v, want %v", err, auth.ErrOIDCInvalidState)
+	}
+}
+
+func TestDeletedAccountTokenIsRejected(t *testing.T) {
+	var now atomic.Int64
+	now.Store(time.Now().UnixNano())
+
+	srv := newTestServerWith(t, Options{
+		Clock: clock.Func(func() time.Time { return time.Unix(0, now.Load()) }),
+	})
+	alice := registerUser(t, srv, "alice")
+
+	alice.expect(http.StatusAccepted, http.MethodDelete, "/api/v1/users/me", map[string]string{
+		"password": "correct-horse",
+	}, nil)
+
+	// 寬限期內仍可使用，才能取消刪除
+	alice.expect(http.StatusOK, http.MethodGet, "/api/v1/users/me", nil, nil)
+
+	now.Add(int64(srv.config.Account.DeletionGracePeriod + time.Minute))
+
+	for _, path := range []string{"/api/v1/users/me", "/api/v1/ws"} {
+		var resp errorResponse
+		if status := alice.do(http.MethodGet, path, nil, &resp); status != http.StatusUnauthorized ||
+			resp.Code != "account_deleted" {
+			t.Errorf("GET %s after the grace period = %d %q, want %d account_deleted",
+				path, status, resp.Code, http.StatusUnauthorized)
+		}
+	}
+}
@@ -0,0 +1,380 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/walnut-almonds/talkrealm/internal/apperror"
+	"github.com/walnut-almonds/talkrealm/internal/model"
+	"github.com/walnut-almonds/talkrealm/internal/repository"
+	"github.com/walnut-almonds/talkrealm/pkg/clock"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// deletedUserUsername 已刪除帳號訊息的預留佔位使用者名稱
+const deletedUserUsername = "__deleted_user__"
+
+// purgeBatchSize 每次排程最多處理的待刪除帳號數量
+const purgeBatchSize = 50
+
+// purgeAuditReason 帳號刪除時轉移或刪除社群的稽核紀錄原因
+const purgeAuditReason = "owner account deleted"
+
+var (
+	ErrDeletionNotScheduled = apperror.Conflict(
+		"deletion_not_scheduled",
+		"account deletion is not scheduled",
+	)
+	ErrReservedUsername = apperror.InvalidArgument("reserved_username", "username is reserved")
+)
+
+// DeleteAccountRequest 刪除帳號請求
+type DeleteAccountRequest struct {
+	Password string    `json:"password"` // 有本地密碼的帳號必填，以 SSO 建立的帳號改以最近的登入確認
+	AuthTime time.Time `json:"-"`        // 目前登入的時間，由 handler 從 JWT 填入
+}
+
+// AccountService 帳號刪除服務介面
+type AccountService interface {
+	RequestDeletion(
+		ctx context.Context,
+		userID uint,
+		req *DeleteAccountRequest,
+	) (*model.User, error)
+	CancelDeletion(ctx context.Context, userID uint) (*model.User, error)
+	PurgeDueAccounts(ctx context.Context) error
+}
+
+type accountService struct {
+	userRepo     repository.UserRepository
+	tx           repository.TxManager
+	events       EventBus
+	gracePeriod  time.Duration
+	reauthWindow time.Duration
+	clock        clock.Clock
+	logger       *zap.Logger
+}
+
+// NewAccountService 建立帳號刪除服務
+func NewAccountService(
+	userRepo repository.UserRepository,
+	tx repository.TxManager,
+	events EventBus,
+	gracePeriod time.Duration,
+	reauthWindow time.Duration,
+	clk clock.Clock,
+	logger *zap.Logger,
+) AccountService {
+	return &accountService{
+		userRepo:     userRepo,
+		tx:           tx,
+		events:       events,
+		gracePeriod:  gracePeriod,
+		reauthWindow: reauthWindow,
+		clock:        clk,
+		logger:       logger,
+	}
+}
+
+// RequestDeletion 確認身分後排定帳號刪除，寬限期內可以取消
+func (s *accountService) RequestDeletion(
+	ctx context.Context,
+	userID uint,
+	req *DeleteAccountRequest,
+) (*model.User, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	if err := confirmIdentity(
+		user,
+		req.Password,
+		req.AuthTime,
+		s.clock.Now(),
+		s.reauthWindow,
+	); err != nil {
+		return nil, err
+	}
+
+	// 已排定刪除時不重設寬限期
+	if user.DeletionScheduledAt == nil {
+		scheduledAt := s.clock.Now().Add(s.gracePeriod)
+		user.DeletionScheduledAt = &scheduledAt
+		user.UpdatedAt = s.clock.Now()
+
+		if err := s.userRepo.Update(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+
+	return user, nil
+}
+
+// CancelDeletion 在寬限期內取消帳號刪除
+func (s *accountService) CancelDeletion(ctx context.Context, userID uint) (*model.User, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	if user.DeletionScheduledAt == nil {
+		return nil, ErrDeletionNotScheduled
+	}
+
+	user.DeletionScheduledAt = nil
+	user.UpdatedAt = s.clock.Now()
+
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// PurgeDueAccounts 匿名化寬限期已結束的帳號（由排程器呼叫）
+func (s *accountService) PurgeDueAccounts(ctx context.Context) error {
+	users, err := s.userRepo.GetDueForDeletion(ctx, s.clock.Now(), purgeBatchSize)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+
+	for _, user := range users {
+		if ctx.Err() != nil {
+			break
+		}
+
+		if err := s.purge(ctx, user); err != nil {
+			errs = append(errs, fmt.Errorf("purge user %d: %w", user.ID, err))
+			continue
+		}
+
+		s.logger.Info("Account purged", zap.Uint("userID", user.ID))
+	}
+
+	return errors.Join(errs...)
+}
+
+// purge 在同一個交易中匿名化帳號與其擁有的機器人，任何一步失敗時整個帳號維持原狀，下次排程重試
+func (s *accountService) purge(ctx context.Context, user *model.User) error {
+	placeholder, err := s.deletedUserPlaceholder(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = s.tx.WithinTx(ctx, func(repos *repository.Repositories) error {
+		return s.purgeUser(ctx, repos, user, placeholder)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.events.Notify()
+
+	return nil
+}
+
+// purgeUser 轉移訊息與社群、移除成員資格與憑證，最後匿名化個人資料
+func (s *accountService) purgeUser(
+	ctx context.Context,
+	repos *repository.Repositories,
+	user, placeholder *model.User,
+) error {
+	// 一併刪除此帳號擁有的機器人
+	if !user.IsBot {
+		bots, err := repos.Users.GetBotsByOwner(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+
+		for _, bot := range bots {
+			if err := s.purgeUser(ctx, repos, bot, placeholder); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := repos.Messages.ReassignUser(ctx, user.ID, placeholder.ID); err != nil {
+		return err
+	}
+
+	guilds, err := repos.Guilds.GetByOwnerID(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, guild := range guilds {
+		if err := s.transferOrDeleteGuild(ctx, repos, guild, user.ID); err != nil {
+			return err
+		}
+	}
+
+	if err := repos.GuildMembers.DeleteByUserID(ctx, user.ID); err != nil {
+		return err
+	}
+
+	if err := repos.APITokens.DeleteByUserID(ctx, user.ID); err != nil {
+		return err
+	}
+
+	if err := repos.UserIdentities.DeleteByUserID(ctx, user.ID); err != nil {
+		return err
+	}
+
+	password, err := randomPasswordHash()
+	if err != nil {
+		return err
+	}
+
+	now := s.clock.Now()
+	user.Username = fmt.Sprintf("deleted-user-%d", user.ID)
+	user.Email = fmt.Sprintf("deleted-%d@deleted.talkrealm.invalid", user.ID)
+	user.Password = password
+	user.Nickname = "Deleted User"
+	user.Avatar = ""
+	user.Status = "offline"
+	user.BotOwnerID = nil
+	user.AnonymizedAt = &now
+	user.UpdatedAt = now
+
+	return repos.Users.Update(ctx, user)
+}
+
+// transferOrDeleteGuild 將社群轉移給權限最高、加入最久的成員；沒有其他成員時刪除社群
+//
+// 與手動轉移及刪除相同，會寫入稽核紀錄，轉移時也會發出 guild.update 事件
+func (s *accountService) transferOrDeleteGuild(
+	ctx context.Context,
+	repos *repository.Repositories,
+	guild *model.Guild,
+	ownerID uint,
+) error {
+	members, err := repos.GuildMembers.GetByGuildID(ctx, guild.ID)
+	if err != nil {
+		return err
+	}
+
+	candidates := slices.DeleteFunc(members, func(m *model.GuildMember) bool {
+		return m.UserID == ownerID || m.User.IsBot
+	})
+
+	if len(candidates) == 0 {
+		if err := repos.Guilds.Delete(ctx, guild.ID); err != nil {
+			return err
+		}
+
+		entry := newAuditEntry(
+			ctx, s.clock.Now(), guild.ID, ownerID,
+			model.AuditActionGuildDelete, model.AuditTargetGuild, guild.ID,
+			nil,
+		)
+		entry.Reason = purgeAuditReason
+
+		return repos.AuditLogs.Create(ctx, entry)
+	}
+
+	slices.SortStableFunc(candidates, func(a, b *model.GuildMember) int {
+		if rank := roleRank(b.Role) - roleRank(a.Role); rank != 0 {
+			return rank
+		}
+
+		return a.JoinedAt.Compare(b.JoinedAt)
+	})
+
+	newOwner := candidates[0]
+	newOwner.Role = "owner"
+	newOwner.UpdatedAt = s.clock.Now()
+
+	if err := repos.GuildMembers.Update(ctx, newOwner); err != nil {
+		return err
+	}
+
+	guild.OwnerID = newOwner.UserID
+	guild.Owner = model.User{}
+	guild.UpdatedAt = s.clock.Now()
+
+	if err := repos.Guilds.Update(ctx, guild); err != nil {
+		return err
+	}
+
+	entry := newAuditEntry(
+		ctx, s.clock.Now(), guild.ID, ownerID,
+		model.AuditActionGuildOwnerTransfer, model.AuditTargetUser, newOwner.UserID,
+		auditChanges{}.set("owner_id", ownerID, newOwner.UserID),
+	)
+	entry.Reason = purgeAuditReason
+
+	if err := repos.AuditLogs.Create(ctx, entry); err != nil {
+		return err
+	}
+
+	guild.Owner = newOwner.User
+
+	return repos.Outbox.Append(ctx, s.events.GuildEvent(guild.ID, model.EventGuildUpdate, guild))
+}
+
+// deletedUserPlaceholder 取得（必要時建立）已刪除帳號訊息的佔位使用者
+func (s *accountService) deletedUserPlaceholder(ctx context.Context) (*model.User, error) {
+	if user, err := s.userRepo.GetByUsername(ctx, deletedUserUsername); err == nil {
+		return user, nil
+	}
+
+	password, err := randomPasswordHash()
+	if err != nil {
+		return nil, err
+	}
+
+	user := &model.User{
+		Username:  deletedUserUsername,
+		Email:     "deleted-user@system.talkrealm.invalid",
+		Password:  password,
+		Nickname:  "Deleted User",
+		Status:    "offline",
+		IsBot:     true,
+		CreatedAt: s.clock.Now(),
+		UpdatedAt: s.clock.Now(),
+	}
+
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		// 其他實例可能同時建立了佔位使用者
+		if existing, getErr := s.userRepo.GetByUsername(ctx, deletedUserUsername); getErr == nil {
+			return existing, nil
+		}
+
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// isReservedUsername 系統保留的使用者名稱
+func isReservedUsername(username string) bool {
+	return strings.HasPrefix(username, "__") || strings.HasPrefix(username, "deleted-user")
+}
+
+// randomPasswordHash 產生無法登入的隨機密碼雜湊
+func randomPasswordHash() (string, error) {
+	randomPassword := make([]byte, 32)
+	if _, err := rand.Read(randomPassword); err != nil {
+		return "", err
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword(
+		[]byte(hex.EncodeToString(randomPassword)),
+		bcrypt.DefaultCost,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hashed), nil
+}
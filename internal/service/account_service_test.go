@@ -0,0 +1,131 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/walnut-almonds/talkrealm/internal/model"
+)
+
+func TestRequestDeletionRequiresPassword(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser("alice")
+	accounts := env.accountService()
+
+	if _, err := accounts.RequestDeletion(env.ctx, user.ID, &DeleteAccountRequest{}); !errors.Is(
+		err,
+		ErrInvalidCredentials,
+	) {
+		t.Fatalf("RequestDeletion() without password = %v, want %v", err, ErrInvalidCredentials)
+	}
+
+	scheduled, err := accounts.RequestDeletion(
+		env.ctx,
+		user.ID,
+		&DeleteAccountRequest{Password: testPassword},
+	)
+	if err != nil {
+		t.Fatalf("RequestDeletion() = %v", err)
+	}
+
+	if want := env.now.Add(24 * time.Hour); scheduled.DeletionScheduledAt == nil ||
+		!scheduled.DeletionScheduledAt.Equal(want) {
+		t.Errorf("deletion scheduled at %v, want %v", scheduled.DeletionScheduledAt, want)
+	}
+
+	if _, err := accounts.CancelDeletion(env.ctx, user.ID); err != nil {
+		t.Fatalf("CancelDeletion() = %v", err)
+	}
+
+	if _, err := accounts.CancelDeletion(env.ctx, user.ID); !errors.Is(
+		err,
+		ErrDeletionNotScheduled,
+	) {
+		t.Errorf("CancelDeletion() twice = %v, want %v", err, ErrDeletionNotScheduled)
+	}
+}
+
+func TestPurgeDueAccountsTransfersOwnedGuilds(t *testing.T) {
+	env := newTestEnv(t)
+	owner := env.createUser("owner")
+	member := env.createUser("member")
+	admin := env.createUser("admin")
+	guild := env.createGuild(owner, member, admin)
+	lonely := env.createGuild(owner)
+	channel := env.createChannel(
+		guild.ID,
+		owner.ID,
+		&CreateChannelRequest{Name: "general", Type: "text"},
+	)
+	accounts := env.accountService()
+
+	env.setRole(guild.ID, admin.ID, "admin")
+
+	if _, err := env.messageService().CreateMessage(env.ctx, owner.ID, &CreateMessageRequest{
+		ChannelID: channel.ID,
+		Content:   "hello",
+	}); err != nil {
+		t.Fatalf("CreateMessage() = %v", err)
+	}
+
+	if _, err := accounts.RequestDeletion(
+		env.ctx,
+		owner.ID,
+		&DeleteAccountRequest{Password: testPassword},
+	); err != nil {
+		t.Fatalf("RequestDeletion() = %v", err)
+	}
+
+	// 寬限期結束前不會刪除
+	if err := accounts.PurgeDueAccounts(env.ctx); err != nil {
+		t.Fatalf("PurgeDueAccounts() = %v", err)
+	}
+
+	if n := env.count("users", "id = ? AND anonymized_at IS NULL", owner.ID); n != 1 {
+		t.Fatal("account purged before the grace period ended")
+	}
+
+	env.advance(25 * time.Hour)
+
+	if err := accounts.PurgeDueAccounts(env.ctx); err != nil {
+		t.Fatalf("PurgeDueAccounts() = %v", err)
+	}
+
+	purged, err := env.repos.Users.GetByID(env.ctx, owner.ID)
+	if err != nil {
+		t.Fatalf("get purged user: %v", err)
+	}
+
+	if purged.AnonymizedAt == nil || purged.Email == owner.Email {
+		t.Errorf("purged user = %+v, want anonymized", purged)
+	}
+
+	// 社群轉移給權限最高的成員
+	transferred, err := env.repos.Guilds.GetByID(env.ctx, guild.ID)
+	if err != nil {
+		t.Fatalf("get guild: %v", err)
+	}
+
+	if transferred.OwnerID != admin.ID {
+		t.Errorf("new owner = %d, want admin %d", transferred.OwnerID, admin.ID)
+	}
+
+	if n := env.count("guilds", "id = ?", lonely.ID); n != 0 {
+		t.Error("guild without other members was not deleted")
+	}
+
+	if n := env.count("messages", "user_id = ?", owner.ID); n != 0 {
+		t.Errorf("messages still attributed to the purged user = %d, want 0", n)
+	}
+
+	for action, want := range map[string]int64{
+		model.AuditActionGuildOwnerTransfer: 1,
+		model.AuditActionGuildDelete:        1,
+	} {
+		if n := env.count("audit_log_entries", "action = ? AND reason = ?",
+			action, purgeAuditReason); n != want {
+			t.Errorf("%s audit entries = %d, want %d", action, n, want)
+		}
+	}
+}
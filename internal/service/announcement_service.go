@@ -0,0 +1,326 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/walnut-almonds/talkrealm/internal/apperror"
+	"github.com/walnut-almonds/talkrealm/internal/model"
+	"github.com/walnut-almonds/talkrealm/internal/repository"
+	"github.com/walnut-almonds/talkrealm/pkg/clock"
+	"github.com/walnut-almonds/talkrealm/pkg/logger"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrNotAnnouncementChannel = apperror.InvalidArgument(
+		"not_announcement_channel",
+		"channel is not an announcement channel",
+	)
+	ErrInvalidFollowTarget = apperror.InvalidArgument(
+		"invalid_follow_target",
+		"follow target must be a text channel",
+	)
+	ErrAlreadyFollowing = apperror.Conflict(
+		"already_following",
+		"channel already follows this announcement channel",
+	)
+	ErrFollowNotFound = apperror.NotFound("follow_not_found", "channel follow not found")
+)
+
+// AnnouncementService 公告頻道追蹤與發佈服務介面
+type AnnouncementService interface {
+	FollowChannel(
+		ctx context.Context,
+		targetChannelID, sourceChannelID, userID uint,
+	) (*model.ChannelFollow, error)
+	UnfollowChannel(ctx context.Context, targetChannelID, sourceChannelID, userID uint) error
+	ListFollowing(ctx context.Context, targetChannelID, userID uint) ([]*model.ChannelFollow, error)
+	PublishMessage(ctx context.Context, messageID, userID uint) (*model.Message, error)
+	HandleEvent(ctx context.Context, repos *repository.Repositories, event *model.OutboxEvent) error
+}
+
+type announcementService struct {
+	channelRepo       repository.ChannelRepository
+	channelFollowRepo repository.ChannelFollowRepository
+	messageRepo       repository.MessageRepository
+	guildMemberRepo   repository.GuildMemberRepository
+	messageService    MessageService
+	tx                repository.TxManager
+	events            EventBus
+	clock             clock.Clock
+	logger            *zap.Logger
+}
+
+// NewAnnouncementService 建立公告頻道服務
+func NewAnnouncementService(
+	channelRepo repository.ChannelRepository,
+	channelFollowRepo repository.ChannelFollowRepository,
+	messageRepo repository.MessageRepository,
+	guildMemberRepo repository.GuildMemberRepository,
+	messageService MessageService,
+	tx repository.TxManager,
+	events EventBus,
+	clk clock.Clock,
+	logger *zap.Logger,
+) AnnouncementService {
+	return &announcementService{
+		channelRepo:       channelRepo,
+		channelFollowRepo: channelFollowRepo,
+		messageRepo:       messageRepo,
+		guildMemberRepo:   guildMemberRepo,
+		messageService:    messageService,
+		tx:                tx,
+		events:            events,
+		clock:             clk,
+		logger:            logger,
+	}
+}
+
+// FollowChannel 讓目標文字頻道追蹤公告頻道
+//
+// 需要目標社群的管理頻道權限，並且是公告頻道所屬社群的成員
+func (s *announcementService) FollowChannel(
+	ctx context.Context,
+	targetChannelID, sourceChannelID, userID uint,
+) (*model.ChannelFollow, error) {
+	target, err := s.manageableChannel(ctx, targetChannelID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if target.Type != "text" {
+		return nil, ErrInvalidFollowTarget
+	}
+
+	source, err := s.channelRepo.GetByID(ctx, sourceChannelID)
+	if err != nil {
+		return nil, ErrChannelNotFound
+	}
+
+	if source.Type != ChannelTypeAnnouncement {
+		return nil, ErrNotAnnouncementChannel
+	}
+
+	member, err := s.guildMemberRepo.GetMember(ctx, source.GuildID, userID)
+	if err != nil || member == nil {
+		return nil, ErrNotGuildMemberCh
+	}
+
+	if _, err := s.channelFollowRepo.Get(ctx, source.ID, target.ID); err == nil {
+		return nil, ErrAlreadyFollowing
+	}
+
+	follow := &model.ChannelFollow{
+		SourceChannelID: source.ID,
+		TargetChannelID: target.ID,
+		GuildID:         target.GuildID,
+		CreatedByID:     userID,
+		CreatedAt:       s.clock.Now(),
+	}
+
+	err = s.tx.WithinTx(ctx, func(repos *repository.Repositories) error {
+		if err := repos.ChannelFollows.Create(ctx, follow); err != nil {
+			return err
+		}
+
+		return repos.AuditLogs.Create(ctx, newAuditEntry(
+			ctx, s.clock.Now(), target.GuildID, userID,
+			model.AuditActionChannelFollow, model.AuditTargetChannel, target.ID,
+			auditChanges{}.
+				set("source_channel_id", nil, source.ID).
+				set("source_guild_id", nil, source.GuildID),
+		))
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			return nil, ErrAlreadyFollowing
+		}
+
+		return nil, err
+	}
+
+	follow.SourceChannel = *source
+
+	return follow, nil
+}
+
+// UnfollowChannel 取消目標頻道對公告頻道的追蹤
+func (s *announcementService) UnfollowChannel(
+	ctx context.Context,
+	targetChannelID, sourceChannelID, userID uint,
+) error {
+	target, err := s.manageableChannel(ctx, targetChannelID, userID)
+	if err != nil {
+		return err
+	}
+
+	follow, err := s.channelFollowRepo.Get(ctx, sourceChannelID, target.ID)
+	if err != nil {
+		return ErrFollowNotFound
+	}
+
+	return s.tx.WithinTx(ctx, func(repos *repository.Repositories) error {
+		if err := repos.ChannelFollows.Delete(ctx, follow.ID); err != nil {
+			return err
+		}
+
+		return repos.AuditLogs.Create(ctx, newAuditEntry(
+			ctx, s.clock.Now(), target.GuildID, userID,
+			model.AuditActionChannelUnfollow, model.AuditTargetChannel, target.ID,
+			auditChanges{}.set("source_channel_id", sourceChannelID, nil),
+		))
+	})
+}
+
+// ListFollowing 列出目標頻道追蹤的公告頻道
+func (s *announcementService) ListFollowing(
+	ctx context.Context,
+	targetChannelID, userID uint,
+) ([]*model.ChannelFollow, error) {
+	target, err := s.channelRepo.GetByID(ctx, targetChannelID)
+	if err != nil {
+		return nil, ErrChannelNotFound
+	}
+
+	member, err := s.guildMemberRepo.GetMember(ctx, target.GuildID, userID)
+	if err != nil || member == nil {
+		return nil, ErrNotGuildMemberCh
+	}
+
+	return s.channelFollowRepo.GetByTargetChannelID(ctx, target.ID)
+}
+
+// PublishMessage 將公告頻道的訊息轉發到所有追蹤頻道
+//
+// 訊息作者或擁有管理訊息權限的成員可以發佈；每則訊息只會轉發一次，重複發佈不會再次轉發。
+// 發佈標記與 announcement.published 事件寫在同一個交易中，轉發由事件訂閱者（HandleEvent）完成
+func (s *announcementService) PublishMessage(
+	ctx context.Context,
+	messageID, userID uint,
+) (*model.Message, error) {
+	message, err := s.messageRepo.GetByID(ctx, messageID)
+	if err != nil {
+		return nil, ErrMessageNotFound
+	}
+
+	if message.Channel.Type != ChannelTypeAnnouncement {
+		return nil, ErrNotAnnouncementChannel
+	}
+
+	member, err := s.guildMemberRepo.GetMember(ctx, message.Channel.GuildID, userID)
+	if err != nil || member == nil {
+		return nil, ErrNotChannelMemberMsg
+	}
+
+	if message.UserID != userID && !hasPermission(member.Role, PermissionManageMessages) {
+		return nil, ErrMissingPermission
+	}
+
+	now := s.clock.Now()
+
+	var published bool
+
+	err = s.tx.WithinTx(ctx, func(repos *repository.Repositories) error {
+		var err error
+
+		published, err = repos.Messages.MarkPublished(ctx, message.ID, now)
+		if err != nil || !published {
+			return err
+		}
+
+		message.PublishedAt = &now
+
+		return repos.Outbox.Append(ctx, s.events.ChannelEvent(
+			message.Channel.GuildID,
+			message.ChannelID,
+			model.EventAnnouncementPublished,
+			message,
+		))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 已發佈過的訊息直接回傳，不重複轉發
+	if !published {
+		return s.messageRepo.GetByID(ctx, message.ID)
+	}
+
+	s.events.Notify()
+
+	return message, nil
+}
+
+// HandleEvent 收到 announcement.published 事件時，將訊息轉發到所有追蹤頻道
+//
+// 轉發與事件的轉送紀錄在同一個交易中提交，失敗時整個事件稍後重送，不會重複轉發
+func (s *announcementService) HandleEvent(
+	ctx context.Context,
+	repos *repository.Repositories,
+	event *model.OutboxEvent,
+) error {
+	if event.Type != model.EventAnnouncementPublished {
+		return nil
+	}
+
+	var message model.Message
+	if err := json.Unmarshal([]byte(event.Payload), &message); err != nil {
+		return err
+	}
+
+	follows, err := repos.ChannelFollows.GetBySourceChannelID(ctx, message.ChannelID)
+	if err != nil {
+		return err
+	}
+
+	for _, follow := range follows {
+		_, err := s.messageService.CrosspostMessage(ctx, repos, &message, follow.TargetChannelID)
+		if err == nil {
+			continue
+		}
+
+		// 目標頻道已不存在或無法發送訊息時略過，不影響其他追蹤頻道
+		if errors.Is(err, ErrChannelNotFound) || errors.Is(err, ErrCategoryChannel) {
+			logger.FromContext(ctx, s.logger).Warn(
+				"Skipping crosspost to unavailable channel",
+				zap.Uint("messageID", message.ID),
+				zap.Uint("targetChannelID", follow.TargetChannelID),
+				zap.Error(err),
+			)
+
+			continue
+		}
+
+		return err
+	}
+
+	if len(follows) > 0 {
+		s.events.Notify()
+	}
+
+	return nil
+}
+
+// manageableChannel 取得頻道並確認使用者在該社群擁有管理頻道權限
+func (s *announcementService) manageableChannel(
+	ctx context.Context,
+	channelID, userID uint,
+) (*model.Channel, error) {
+	channel, err := s.channelRepo.GetByID(ctx, channelID)
+	if err != nil {
+		return nil, ErrChannelNotFound
+	}
+
+	member, err := s.guildMemberRepo.GetMember(ctx, channel.GuildID, userID)
+	if err != nil || member == nil {
+		return nil, ErrNotGuildMemberCh
+	}
+
+	if !hasPermission(member.Role, PermissionManageChannels) {
+		return nil, ErrMissingPermission
+	}
+
+	return channel, nil
+}
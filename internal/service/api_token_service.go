@@ -0,0 +1,348 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/walnut-almonds/talkrealm/internal/apperror"
+	"github.com/walnut-almonds/talkrealm/internal/model"
+	"github.com/walnut-almonds/talkrealm/internal/repository"
+	"github.com/walnut-almonds/talkrealm/pkg/auth"
+	"github.com/walnut-almonds/talkrealm/pkg/clock"
+)
+
+// maxAPITokensPerUser 每個帳號可擁有的 API token 上限
+const maxAPITokensPerUser = 25
+
+var (
+	ErrAPITokenNotFound = apperror.NotFound("api_token_not_found", "api token not found")
+	ErrAPITokenExpired  = apperror.Unauthorized("api_token_expired", "api token has expired")
+	ErrInvalidScope     = apperror.InvalidArgument("invalid_scope", "invalid token scope")
+	ErrTooManyAPITokens = apperror.InvalidArgument("too_many_api_tokens", "too many api tokens")
+	ErrBotNotFound      = apperror.NotFound("bot_not_found", "bot not found")
+	ErrNotBotOwner      = apperror.Forbidden("not_bot_owner", "not the owner of this bot")
+)
+
+// CreateAPITokenRequest 建立 API token 請求
+type CreateAPITokenRequest struct {
+	Name          string   `json:"name"            binding:"required,min=1,max=64"`
+	Scopes        []string `json:"scopes"          binding:"required,min=1"`
+	GuildIDs      []uint   `json:"guild_ids"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"omitempty,min=1,max=3650"`
+}
+
+// APITokenResponse API token 資訊（不含 token 本身）
+type APITokenResponse struct {
+	ID         uint       `json:"id"`
+	UserID     uint       `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	GuildIDs   []uint     `json:"guild_ids"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreateAPITokenResponse 建立 API token 回應，token 只會在此時顯示一次
+type CreateAPITokenResponse struct {
+	Token string `json:"token"`
+	*APITokenResponse
+}
+
+// CreateBotRequest 建立機器人帳號請求
+type CreateBotRequest struct {
+	Username string `json:"username" binding:"required,min=3,max=32"`
+	Nickname string `json:"nickname" binding:"max=64"`
+	Avatar   string `json:"avatar"   binding:"max=256"`
+}
+
+// APITokenService API token 與機器人帳號服務介面
+type APITokenService interface {
+	CreateToken(
+		ctx context.Context,
+		actorID, userID uint,
+		req *CreateAPITokenRequest,
+	) (*CreateAPITokenResponse, error)
+	ListTokens(ctx context.Context, actorID, userID uint) ([]*APITokenResponse, error)
+	RevokeToken(ctx context.Context, actorID, userID, tokenID uint) error
+	CreateBot(ctx context.Context, ownerID uint, req *CreateBotRequest) (*model.User, error)
+	ListBots(ctx context.Context, ownerID uint) ([]*model.User, error)
+	AuthenticateToken(ctx context.Context, token string) (*auth.TokenPrincipal, error)
+}
+
+type apiTokenService struct {
+	tokenRepo repository.APITokenRepository
+	userRepo  repository.UserRepository
+	clock     clock.Clock
+}
+
+// NewAPITokenService 建立 API token 服務
+func NewAPITokenService(
+	tokenRepo repository.APITokenRepository,
+	userRepo repository.UserRepository,
+	clk clock.Clock,
+) APITokenService {
+	return &apiTokenService{
+		tokenRepo: tokenRepo,
+		userRepo:  userRepo,
+		clock:     clk,
+	}
+}
+
+// CreateToken 為使用者本人或其機器人建立 API token
+func (s *apiTokenService) CreateToken(
+	ctx context.Context,
+	actorID, userID uint,
+	req *CreateAPITokenRequest,
+) (*CreateAPITokenResponse, error) {
+	if err := s.checkTokenOwner(ctx, actorID, userID); err != nil {
+		return nil, err
+	}
+
+	scopes := make([]string, 0, len(req.Scopes))
+	for _, scope := range req.Scopes {
+		if !auth.IsValidScope(scope) {
+			return nil, ErrInvalidScope
+		}
+
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	count, err := s.tokenRepo.CountByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if count >= maxAPITokensPerUser {
+		return nil, ErrTooManyAPITokens
+	}
+
+	raw, prefix, hash, err := auth.GenerateAPIToken()
+	if err != nil {
+		return nil, err
+	}
+
+	token := &model.APIToken{
+		UserID:    userID,
+		Name:      req.Name,
+		Prefix:    prefix,
+		TokenHash: hash,
+		Scopes:    strings.Join(scopes, " "),
+		GuildIDs:  joinGuildIDs(req.GuildIDs),
+		CreatedAt: s.clock.Now(),
+		UpdatedAt: s.clock.Now(),
+	}
+
+	if req.ExpiresInDays > 0 {
+		expiresAt := s.clock.Now().AddDate(0, 0, req.ExpiresInDays)
+		token.ExpiresAt = &expiresAt
+	}
+
+	if err := s.tokenRepo.Create(ctx, token); err != nil {
+		return nil, err
+	}
+
+	return &CreateAPITokenResponse{
+		Token:            raw,
+		APITokenResponse: toAPITokenResponse(token),
+	}, nil
+}
+
+// ListTokens 列出使用者本人或其機器人的 API token
+func (s *apiTokenService) ListTokens(
+	ctx context.Context,
+	actorID, userID uint,
+) ([]*APITokenResponse, error) {
+	if err := s.checkTokenOwner(ctx, actorID, userID); err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.tokenRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*APITokenResponse, 0, len(tokens))
+	for _, token := range tokens {
+		responses = append(responses, toAPITokenResponse(token))
+	}
+
+	return responses, nil
+}
+
+// RevokeToken 撤銷 API token
+func (s *apiTokenService) RevokeToken(ctx context.Context, actorID, userID, tokenID uint) error {
+	if err := s.checkTokenOwner(ctx, actorID, userID); err != nil {
+		return err
+	}
+
+	token, err := s.tokenRepo.GetByID(ctx, tokenID)
+	if err != nil || token.UserID != userID {
+		return ErrAPITokenNotFound
+	}
+
+	return s.tokenRepo.Delete(ctx, token.ID)
+}
+
+// CreateBot 建立由使用者擁有的機器人帳號
+func (s *apiTokenService) CreateBot(
+	ctx context.Context,
+	ownerID uint,
+	req *CreateBotRequest,
+) (*model.User, error) {
+	if isReservedUsername(req.Username) {
+		return nil, ErrReservedUsername
+	}
+
+	existingUser, _ := s.userRepo.GetByUsername(ctx, req.Username)
+	if existingUser != nil {
+		return nil, ErrUserExists
+	}
+
+	// 機器人不能以密碼登入，填入無法被猜中的隨機雜湊
+	hashedPassword, err := randomPasswordHash()
+	if err != nil {
+		return nil, err
+	}
+
+	nickname := req.Nickname
+	if nickname == "" {
+		nickname = req.Username
+	}
+
+	bot := &model.User{
+		Username:   req.Username,
+		Email:      req.Username + "@bots.talkrealm.invalid",
+		Password:   hashedPassword,
+		Nickname:   nickname,
+		Avatar:     req.Avatar,
+		Status:     "offline",
+		IsBot:      true,
+		BotOwnerID: &ownerID,
+		CreatedAt:  s.clock.Now(),
+		UpdatedAt:  s.clock.Now(),
+	}
+
+	if err := s.userRepo.Create(ctx, bot); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			return nil, ErrUserExists
+		}
+
+		return nil, err
+	}
+
+	return bot, nil
+}
+
+// ListBots 列出使用者擁有的機器人帳號
+func (s *apiTokenService) ListBots(ctx context.Context, ownerID uint) ([]*model.User, error) {
+	return s.userRepo.GetBotsByOwner(ctx, ownerID)
+}
+
+// AuthenticateToken 驗證 API token 並回傳其身分與權限
+func (s *apiTokenService) AuthenticateToken(
+	ctx context.Context,
+	raw string,
+) (*auth.TokenPrincipal, error) {
+	prefix, err := auth.ParseAPITokenPrefix(raw)
+	if err != nil {
+		return nil, auth.ErrInvalidAPIToken
+	}
+
+	token, err := s.tokenRepo.GetByPrefix(ctx, prefix)
+	if err != nil {
+		return nil, auth.ErrInvalidAPIToken
+	}
+
+	if !auth.CompareAPITokenHash(raw, token.TokenHash) {
+		return nil, auth.ErrInvalidAPIToken
+	}
+
+	now := s.clock.Now()
+	if token.ExpiresAt != nil && now.After(*token.ExpiresAt) {
+		return nil, ErrAPITokenExpired
+	}
+
+	// 帳號的 token 在匿名化時一併刪除；寬限期結束到實際匿名化之間也不再接受
+	if accountDeleted(&token.User, now) {
+		return nil, auth.ErrInvalidAPIToken
+	}
+
+	// 避免每個請求都寫入資料庫，最後使用時間只以分鐘為精度更新
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) > time.Minute {
+		_ = s.tokenRepo.UpdateLastUsed(ctx, token.ID, now)
+	}
+
+	return &auth.TokenPrincipal{
+		UserID:   token.User.ID,
+		Username: token.User.Username,
+		Email:    token.User.Email,
+		IsBot:    token.User.IsBot,
+		Scopes:   strings.Fields(token.Scopes),
+		GuildIDs: parseGuildIDs(token.GuildIDs),
+	}, nil
+}
+
+// checkTokenOwner 確認操作者可以管理該帳號的 token（本人或機器人擁有者）
+func (s *apiTokenService) checkTokenOwner(ctx context.Context, actorID, userID uint) error {
+	if actorID == userID {
+		return nil
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil || !user.IsBot {
+		return ErrBotNotFound
+	}
+
+	if user.BotOwnerID == nil || *user.BotOwnerID != actorID {
+		return ErrNotBotOwner
+	}
+
+	return nil
+}
+
+// toAPITokenResponse 轉換為不含秘密資訊的回應
+func toAPITokenResponse(token *model.APIToken) *APITokenResponse {
+	return &APITokenResponse{
+		ID:         token.ID,
+		UserID:     token.UserID,
+		Name:       token.Name,
+		Prefix:     token.Prefix,
+		Scopes:     strings.Fields(token.Scopes),
+		GuildIDs:   parseGuildIDs(token.GuildIDs),
+		ExpiresAt:  token.ExpiresAt,
+		LastUsedAt: token.LastUsedAt,
+		CreatedAt:  token.CreatedAt,
+	}
+}
+
+// joinGuildIDs 將社群 ID 列表轉為以逗號分隔的字串
+func joinGuildIDs(ids []uint) string {
+	parts := make([]string, 0, len(ids))
+	for _, id := range ids {
+		parts = append(parts, strconv.FormatUint(uint64(id), 10))
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// parseGuildIDs 解析以逗號分隔的社群 ID
+func parseGuildIDs(value string) []uint {
+	ids := []uint{}
+
+	for part := range strings.SplitSeq(value, ",") {
+		id, err := strconv.ParseUint(strings.TrimSpace(part), 10, 32)
+		if err == nil && id > 0 {
+			ids = append(ids, uint(id))
+		}
+	}
+
+	return ids
+}
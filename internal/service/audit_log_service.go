@@ -0,0 +1,265 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/walnut-almonds/talkrealm/internal/model"
+	"github.com/walnut-almonds/talkrealm/internal/repository"
+	"github.com/walnut-almonds/talkrealm/pkg/clock"
+	"github.com/walnut-almonds/talkrealm/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultAuditLogLimit = 50
+	maxAuditLogLimit     = 100
+	maxAuditReasonLength = 512
+)
+
+type auditReasonKey struct{}
+
+// WithAuditReason 將稽核紀錄原因附加到 context（來自 X-Audit-Log-Reason 標頭）
+func WithAuditReason(ctx context.Context, reason string) context.Context {
+	if reason == "" {
+		return ctx
+	}
+
+	if runes := []rune(reason); len(runes) > maxAuditReasonLength {
+		reason = string(runes[:maxAuditReasonLength])
+	}
+
+	return context.WithValue(ctx, auditReasonKey{}, reason)
+}
+
+// auditReason 取得 context 中的稽核紀錄原因
+func auditReason(ctx context.Context) string {
+	reason, _ := ctx.Value(auditReasonKey{}).(string)
+
+	return reason
+}
+
+// auditChange 單一欄位的變更前後內容
+type auditChange struct {
+	Old any `json:"old"`
+	New any `json:"new"`
+}
+
+// auditChanges 稽核紀錄中的欄位變更
+type auditChanges map[string]auditChange
+
+// set 記錄欄位變更，前後相同（包含皆為空值）時忽略
+func (c auditChanges) set(field string, oldValue, newValue any) auditChanges {
+	if isNilValue(oldValue) && isNilValue(newValue) {
+		return c
+	}
+
+	if !reflect.DeepEqual(oldValue, newValue) {
+		c[field] = auditChange{Old: oldValue, New: newValue}
+	}
+
+	return c
+}
+
+// String 轉為儲存用的 JSON 字串
+func (c auditChanges) String() string {
+	if len(c) == 0 {
+		return ""
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return ""
+	}
+
+	return string(data)
+}
+
+// isNilValue 檢查值是否為 nil（包含型別為指標的 nil）
+func isNilValue(v any) bool {
+	if v == nil {
+		return true
+	}
+
+	rv := reflect.ValueOf(v)
+
+	return rv.Kind() == reflect.Pointer && rv.IsNil()
+}
+
+// newAuditEntry 建立稽核紀錄，原因取自 context
+func newAuditEntry(
+	ctx context.Context,
+	now time.Time,
+	guildID, actorID uint,
+	action, targetType string,
+	targetID uint,
+	changes auditChanges,
+) *model.AuditLogEntry {
+	return &model.AuditLogEntry{
+		GuildID:    guildID,
+		ActorID:    actorID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Changes:    changes.String(),
+		Reason:     auditReason(ctx),
+		CreatedAt:  now,
+	}
+}
+
+type auditLogSink struct {
+	logger *zap.Logger
+}
+
+// NewAuditLogSink 建立將稽核紀錄輸出到獨立 logger 的 sink（設定於 audit_log.output）
+func NewAuditLogSink(auditLogger *zap.Logger) repository.AuditSink {
+	return &auditLogSink{logger: auditLogger}
+}
+
+// RecordAudit 以一行 JSON 輸出稽核紀錄，請求中產生的紀錄附帶 request_id
+func (s *auditLogSink) RecordAudit(ctx context.Context, entry *model.AuditLogEntry) {
+	fields := []zap.Field{
+		zap.Uint("id", entry.ID),
+		zap.Uint("guild_id", entry.GuildID),
+		zap.Uint("actor_id", entry.ActorID),
+		zap.String("action", entry.Action),
+		zap.String("target_type", entry.TargetType),
+		zap.Uint("target_id", entry.TargetID),
+		zap.String("reason", entry.Reason),
+		zap.Time("created_at", entry.CreatedAt),
+	}
+
+	if entry.Changes != "" {
+		fields = append(fields, zap.Reflect("changes", json.RawMessage(entry.Changes)))
+	}
+
+	if requestID := logger.RequestID(ctx); requestID != "" {
+		fields = append(fields, zap.String("request_id", requestID))
+	}
+
+	s.logger.Info("audit", fields...)
+}
+
+// ListAuditLogsRequest 查詢稽核紀錄條件
+type ListAuditLogsRequest struct {
+	Action   string     `form:"action"`
+	ActorID  uint       `form:"actor_id"`
+	After    *time.Time `form:"after"     time_format:"2006-01-02T15:04:05Z07:00"`
+	Before   *time.Time `form:"before"    time_format:"2006-01-02T15:04:05Z07:00"`
+	BeforeID uint       `form:"before_id"`
+	Limit    int        `form:"limit"                                             binding:"omitempty,min=1,max=100"`
+}
+
+// AuditLogEntryResponse 稽核紀錄回應
+type AuditLogEntryResponse struct {
+	*model.AuditLogEntry
+	Changes json.RawMessage `json:"changes,omitempty"`
+}
+
+// AuditLogService 稽核紀錄服務介面
+type AuditLogService interface {
+	ListEntries(
+		ctx context.Context,
+		guildID, userID uint,
+		req *ListAuditLogsRequest,
+	) ([]*AuditLogEntryResponse, error)
+	PurgeExpired(ctx context.Context) error
+}
+
+type auditLogService struct {
+	auditLogRepo    repository.AuditLogRepository
+	guildRepo       repository.GuildRepository
+	guildMemberRepo repository.GuildMemberRepository
+	retention       time.Duration
+	clock           clock.Clock
+	logger          *zap.Logger
+}
+
+// NewAuditLogService 建立稽核紀錄服務
+func NewAuditLogService(
+	auditLogRepo repository.AuditLogRepository,
+	guildRepo repository.GuildRepository,
+	guildMemberRepo repository.GuildMemberRepository,
+	retention time.Duration,
+	clk clock.Clock,
+	logger *zap.Logger,
+) AuditLogService {
+	return &auditLogService{
+		auditLogRepo:    auditLogRepo,
+		guildRepo:       guildRepo,
+		guildMemberRepo: guildMemberRepo,
+		retention:       retention,
+		clock:           clk,
+		logger:          logger,
+	}
+}
+
+// ListEntries 列出社群的稽核紀錄，需要檢視稽核紀錄權限
+func (s *auditLogService) ListEntries(
+	ctx context.Context,
+	guildID, userID uint,
+	req *ListAuditLogsRequest,
+) ([]*AuditLogEntryResponse, error) {
+	if _, err := s.guildRepo.GetByID(ctx, guildID); err != nil {
+		return nil, ErrGuildNotFound
+	}
+
+	member, err := s.guildMemberRepo.GetMember(ctx, guildID, userID)
+	if err != nil || member == nil {
+		return nil, ErrNotGuildMember
+	}
+
+	if !hasPermission(member.Role, PermissionViewAuditLog) {
+		return nil, ErrMissingPermission
+	}
+
+	limit := req.Limit
+	if limit <= 0 || limit > maxAuditLogLimit {
+		limit = defaultAuditLogLimit
+	}
+
+	entries, err := s.auditLogRepo.List(ctx, &repository.AuditLogFilter{
+		GuildID:  guildID,
+		Action:   req.Action,
+		ActorID:  req.ActorID,
+		After:    req.After,
+		Before:   req.Before,
+		BeforeID: req.BeforeID,
+		Limit:    limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*AuditLogEntryResponse, 0, len(entries))
+	for _, entry := range entries {
+		response := &AuditLogEntryResponse{AuditLogEntry: entry}
+		if entry.Changes != "" {
+			response.Changes = json.RawMessage(entry.Changes)
+		}
+
+		responses = append(responses, response)
+	}
+
+	return responses, nil
+}
+
+// PurgeExpired 刪除超過保留期限的稽核紀錄（由排程器呼叫）
+func (s *auditLogService) PurgeExpired(ctx context.Context) error {
+	if s.retention <= 0 {
+		return nil
+	}
+
+	deleted, err := s.auditLogRepo.DeleteOlderThan(ctx, s.clock.Now().Add(-s.retention))
+	if err != nil {
+		return err
+	}
+
+	if deleted > 0 {
+		s.logger.Info("Audit log entries purged", zap.Int64("count", deleted))
+	}
+
+	return nil
+}
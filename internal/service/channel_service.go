@@ -1,50 +1,107 @@
 package service
 
 import (
+	"cmp"
+	"context"
 	"errors"
+	"slices"
 	"time"
 
+	"github.com/walnut-almonds/talkrealm/internal/apperror"
 	"github.com/walnut-almonds/talkrealm/internal/model"
 	"github.com/walnut-almonds/talkrealm/internal/repository"
+	"github.com/walnut-almonds/talkrealm/pkg/clock"
+	"go.uber.org/zap"
 )
 
 var (
-	ErrChannelNotFound    = errors.New("channel not found")
-	ErrNotGuildMemberCh   = errors.New("not a member of this guild")
-	ErrInvalidChannelType = errors.New("invalid channel type")
+	ErrChannelNotFound  = apperror.NotFound("channel_not_found", "channel not found")
+	ErrNotGuildMemberCh = apperror.Forbidden(
+		"not_channel_guild_member",
+		"not a member of this guild",
+	)
+	ErrInvalidChannelType = apperror.InvalidArgument(
+		"invalid_channel_type",
+		"invalid channel type",
+	)
+	ErrInvalidChannelParent = apperror.InvalidArgument(
+		"invalid_channel_parent",
+		"parent must be a category channel in the same guild",
+	)
+	ErrInvalidChannelPositions = apperror.InvalidArgument(
+		"invalid_channel_positions",
+		"invalid channel positions",
+	)
+	ErrNotChannelManager = apperror.Forbidden(
+		"not_channel_manager",
+		"only owner or admin can manage channels",
+	)
+)
+
+// 特殊頻道類型
+const (
+	ChannelTypeCategory     = "category"     // 分類頻道，用來分組其他頻道，本身不能發送訊息
+	ChannelTypeAnnouncement = "announcement" // 公告頻道，其他社群可以追蹤並接收發佈的訊息
 )
 
 // CreateChannelRequest 建立頻道請求
 type CreateChannelRequest struct {
-	GuildID  uint   `json:"guild_id"`
-	Name     string `json:"name"     binding:"required,min=1,max=100"`
-	Type     string `json:"type"     binding:"required,oneof=text voice"`
-	Topic    string `json:"topic"    binding:"max=1024"`
-	Position int    `json:"position"`
+	GuildID         uint   `json:"guild_id"`
+	Name            string `json:"name"              binding:"required,min=1,max=100"`
+	Type            string `json:"type"              binding:"required,oneof=text voice announcement category"`
+	ParentID        *uint  `json:"parent_id"`
+	Topic           string `json:"topic"             binding:"max=1024"`
+	Position        int    `json:"position"`
+	SlowModeSeconds int    `json:"slow_mode_seconds" binding:"min=0,max=21600"`
 }
 
 // UpdateChannelRequest 更新頻道請求
 type UpdateChannelRequest struct {
-	Name     string `json:"name"     binding:"omitempty,min=1,max=100"`
-	Type     string `json:"type"     binding:"omitempty,oneof=text voice"`
-	Topic    string `json:"topic"    binding:"max=1024"`
-	Position *int   `json:"position"`
+	Name            string `json:"name"              binding:"omitempty,min=1,max=100"`
+	Type            string `json:"type"              binding:"omitempty,oneof=text voice announcement"`
+	Topic           string `json:"topic"             binding:"max=1024"`
+	Position        *int   `json:"position"`
+	SlowModeSeconds *int   `json:"slow_mode_seconds" binding:"omitempty,min=0,max=21600"`
+}
+
+// ChannelPositionUpdate 批次調整頻道排序的單筆設定
+type ChannelPositionUpdate struct {
+	ID       uint  `json:"id"        binding:"required"`
+	Position int   `json:"position"  binding:"min=0"`
+	ParentID *uint `json:"parent_id"` // 省略表示不變，0 表示移到最上層
 }
 
 // ChannelService 頻道服務介面
 type ChannelService interface {
-	CreateChannel(userID uint, req *CreateChannelRequest) (*model.Channel, error)
-	GetChannel(channelID, userID uint) (*model.Channel, error)
-	ListGuildChannels(guildID, userID uint) ([]*model.Channel, error)
-	UpdateChannel(channelID, userID uint, req *UpdateChannelRequest) (*model.Channel, error)
-	DeleteChannel(channelID, userID uint) error
-	UpdateChannelPosition(channelID, userID uint, position int) error
+	CreateChannel(
+		ctx context.Context,
+		userID uint,
+		req *CreateChannelRequest,
+	) (*model.Channel, error)
+	GetChannel(ctx context.Context, channelID, userID uint) (*model.Channel, error)
+	ListGuildChannels(ctx context.Context, guildID, userID uint) ([]*model.Channel, error)
+	UpdateChannel(
+		ctx context.Context,
+		channelID, userID uint,
+		req *UpdateChannelRequest,
+	) (*model.Channel, error)
+	DeleteChannel(ctx context.Context, channelID, userID uint) error
+	UpdateChannelPosition(ctx context.Context, channelID, userID uint, position int) error
+	ReorderChannels(
+		ctx context.Context,
+		guildID, userID uint,
+		updates []ChannelPositionUpdate,
+	) ([]*model.Channel, error)
 }
 
 type channelService struct {
 	channelRepo     repository.ChannelRepository
 	guildRepo       repository.GuildRepository
 	guildMemberRepo repository.GuildMemberRepository
+	tx              repository.TxManager
+	events          EventBus
+	clock           clock.Clock
+	logger          *zap.Logger
 }
 
 // NewChannelService 建立頻道服務
@@ -52,21 +109,30 @@ func NewChannelService(
 	channelRepo repository.ChannelRepository,
 	guildRepo repository.GuildRepository,
 	guildMemberRepo repository.GuildMemberRepository,
+	tx repository.TxManager,
+	events EventBus,
+	clk clock.Clock,
+	logger *zap.Logger,
 ) ChannelService {
 	return &channelService{
 		channelRepo:     channelRepo,
 		guildRepo:       guildRepo,
 		guildMemberRepo: guildMemberRepo,
+		tx:              tx,
+		events:          events,
+		clock:           clk,
+		logger:          logger,
 	}
 }
 
 // CreateChannel 建立頻道
 func (s *channelService) CreateChannel(
+	ctx context.Context,
 	userID uint,
 	req *CreateChannelRequest,
 ) (*model.Channel, error) {
 	// 檢查社群是否存在
-	guild, err := s.guildRepo.GetByID(req.GuildID)
+	guild, err := s.guildRepo.GetByID(ctx, req.GuildID)
 	if err != nil {
 		return nil, ErrGuildNotFound
 	}
@@ -74,58 +140,132 @@ func (s *channelService) CreateChannel(
 	// 檢查使用者是否為社群擁有者或管理員
 	if guild.OwnerID != userID {
 		// 檢查是否為管理員
-		member, err := s.guildMemberRepo.GetMember(req.GuildID, userID)
+		member, err := s.guildMemberRepo.GetMember(ctx, req.GuildID, userID)
 		if err != nil || member == nil {
 			return nil, ErrNotGuildMemberCh
 		}
 
 		//nolint:goconst // 足夠清晰不需要 const
 		if member.Role != "admin" && member.Role != "owner" {
-			return nil, errors.New("only owner or admin can create channels")
+			return nil, ErrNotChannelManager
 		}
 	}
 
 	// 驗證頻道類型
 	//nolint:goconst // 足夠清晰不需要 const
-	if req.Type != "text" && req.Type != "voice" {
+	if req.Type != "text" && req.Type != "voice" && req.Type != ChannelTypeAnnouncement &&
+		req.Type != ChannelTypeCategory {
 		return nil, ErrInvalidChannelType
 	}
 
-	// 如果沒有指定位置，自動設定為最後
-	position := req.Position
-	if position == 0 {
-		channels, err := s.channelRepo.GetByGuildID(req.GuildID)
-		if err == nil {
-			position = len(channels)
-		}
+	// 分類頻道只能放在最上層，其他頻道只能放在同社群的分類底下
+	parentID := req.ParentID
+	if parentID != nil && *parentID == 0 {
+		parentID = nil
 	}
 
+	now := s.clock.Now()
 	channel := &model.Channel{
-		GuildID:   req.GuildID,
-		Name:      req.Name,
-		Type:      req.Type,
-		Topic:     req.Topic,
-		Position:  position,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		GuildID:         req.GuildID,
+		Name:            req.Name,
+		Type:            req.Type,
+		ParentID:        parentID,
+		Topic:           req.Topic,
+		SlowModeSeconds: req.SlowModeSeconds,
+		CreatedAt:       now,
+		UpdatedAt:       now,
 	}
 
-	if err := s.channelRepo.Create(channel); err != nil {
+	err = s.tx.WithinTx(ctx, func(repos *repository.Repositories) error {
+		channels, err := repos.Channels.LockByGuildID(ctx, req.GuildID)
+		if err != nil {
+			return err
+		}
+
+		if parentID != nil {
+			parent := findChannel(channels, *parentID)
+			if req.Type == ChannelTypeCategory || parent == nil ||
+				parent.Type != ChannelTypeCategory {
+				return ErrInvalidChannelParent
+			}
+		}
+
+		// 新頻道先排在同一分類的最後，有指定位置時插入該位置，之後的頻道依序後移
+		channel.Position = countSiblings(channels, parentID)
+
+		var shifted []*model.Channel
+
+		var before map[uint]channelPlacement
+
+		if req.Position > 0 && req.Position < channel.Position {
+			// 尚未寫入的新頻道 ID 為 0，不會與既有頻道重複
+			shifted, before, err = arrangeChannels(
+				append(channels, channel),
+				[]ChannelPositionUpdate{{ID: 0, Position: req.Position}},
+				now,
+			)
+			if err != nil {
+				return err
+			}
+
+			shifted = slices.DeleteFunc(
+				shifted,
+				func(c *model.Channel) bool { return c == channel },
+			)
+		}
+
+		if err := s.savePositions(ctx, repos, req.GuildID, userID, shifted, before); err != nil {
+			return err
+		}
+
+		if err := repos.Channels.Create(ctx, channel); err != nil {
+			return err
+		}
+
+		if err := repos.AuditLogs.Create(ctx, newAuditEntry(
+			ctx, now, channel.GuildID, userID,
+			model.AuditActionChannelCreate, model.AuditTargetChannel, channel.ID,
+			auditChanges{}.
+				set("name", nil, channel.Name).
+				set("type", nil, channel.Type).
+				set("parent_id", nil, channel.ParentID).
+				set("topic", nil, channel.Topic).
+				set("position", nil, channel.Position).
+				set("slow_mode_seconds", nil, channel.SlowModeSeconds),
+		)); err != nil {
+			return err
+		}
+
+		return repos.Outbox.Append(
+			ctx,
+			s.events.GuildEvent(req.GuildID, model.EventChannelCreate, channel),
+		)
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrGuildNotFound
+		}
+
 		return nil, err
 	}
 
+	s.events.Notify()
+
 	return channel, nil
 }
 
 // GetChannel 取得頻道詳情
-func (s *channelService) GetChannel(channelID, userID uint) (*model.Channel, error) {
-	channel, err := s.channelRepo.GetByID(channelID)
+func (s *channelService) GetChannel(
+	ctx context.Context,
+	channelID, userID uint,
+) (*model.Channel, error) {
+	channel, err := s.channelRepo.GetByID(ctx, channelID)
 	if err != nil {
 		return nil, ErrChannelNotFound
 	}
 
 	// 檢查使用者是否為該社群成員
-	member, err := s.guildMemberRepo.GetMember(channel.GuildID, userID)
+	member, err := s.guildMemberRepo.GetMember(ctx, channel.GuildID, userID)
 	if err != nil || member == nil {
 		return nil, ErrNotGuildMemberCh
 	}
@@ -134,135 +274,532 @@ func (s *channelService) GetChannel(channelID, userID uint) (*model.Channel, err
 }
 
 // ListGuildChannels 列出社群的所有頻道
-func (s *channelService) ListGuildChannels(guildID, userID uint) ([]*model.Channel, error) {
+func (s *channelService) ListGuildChannels(
+	ctx context.Context,
+	guildID, userID uint,
+) ([]*model.Channel, error) {
 	// 檢查社群是否存在
-	_, err := s.guildRepo.GetByID(guildID)
+	_, err := s.guildRepo.GetByID(ctx, guildID)
 	if err != nil {
 		return nil, ErrGuildNotFound
 	}
 
 	// 檢查使用者是否為社群成員
-	member, err := s.guildMemberRepo.GetMember(guildID, userID)
+	member, err := s.guildMemberRepo.GetMember(ctx, guildID, userID)
 	if err != nil || member == nil {
 		return nil, ErrNotGuildMemberCh
 	}
 
-	return s.channelRepo.GetByGuildID(guildID)
+	return s.channelRepo.GetByGuildID(ctx, guildID)
 }
 
 // UpdateChannel 更新頻道資訊
 func (s *channelService) UpdateChannel(
+	ctx context.Context,
 	channelID, userID uint,
 	req *UpdateChannelRequest,
 ) (*model.Channel, error) {
 	// 取得頻道
-	channel, err := s.channelRepo.GetByID(channelID)
+	channel, err := s.channelRepo.GetByID(ctx, channelID)
 	if err != nil {
 		return nil, ErrChannelNotFound
 	}
 
 	// 檢查權限（只有擁有者或管理員可以更新）
-	guild, err := s.guildRepo.GetByID(channel.GuildID)
+	guild, err := s.guildRepo.GetByID(ctx, channel.GuildID)
 	if err != nil {
 		return nil, ErrGuildNotFound
 	}
 
 	if guild.OwnerID != userID {
-		member, err := s.guildMemberRepo.GetMember(channel.GuildID, userID)
+		member, err := s.guildMemberRepo.GetMember(ctx, channel.GuildID, userID)
 		if err != nil || member == nil {
 			return nil, ErrNotGuildMemberCh
 		}
 
 		if member.Role != "admin" && member.Role != "owner" {
-			return nil, errors.New("only owner or admin can update channels")
+			return nil, ErrNotChannelManager
 		}
 	}
 
+	changes := auditChanges{}
+
 	// 更新欄位
 	if req.Name != "" {
+		changes.set("name", channel.Name, req.Name)
 		channel.Name = req.Name
 	}
 
 	if req.Type != "" {
-		if req.Type != "text" && req.Type != "voice" {
+		if req.Type != "text" && req.Type != "voice" && req.Type != ChannelTypeAnnouncement {
 			return nil, ErrInvalidChannelType
 		}
 
+		// 分類頻道與一般頻道不能互相轉換
+		if channel.Type == ChannelTypeCategory {
+			return nil, ErrInvalidChannelType
+		}
+
+		changes.set("type", channel.Type, req.Type)
 		channel.Type = req.Type
 	}
 
 	if req.Topic != "" {
+		changes.set("topic", channel.Topic, req.Topic)
 		channel.Topic = req.Topic
 	}
 
-	if req.Position != nil {
-		channel.Position = *req.Position
+	if req.SlowModeSeconds != nil {
+		changes.set("slow_mode_seconds", channel.SlowModeSeconds, *req.SlowModeSeconds)
+		channel.SlowModeSeconds = *req.SlowModeSeconds
 	}
 
-	channel.UpdatedAt = time.Now()
+	now := s.clock.Now()
+	channel.UpdatedAt = now
+
+	err = s.tx.WithinTx(ctx, func(repos *repository.Repositories) error {
+		channels, err := repos.Channels.LockByGuildID(ctx, channel.GuildID)
+		if err != nil {
+			return err
+		}
 
-	if err := s.channelRepo.Update(channel); err != nil {
+		// 位置與所屬分類以鎖定後讀到的為準，避免覆寫同時進行的排序變更
+		locked := findChannel(channels, channel.ID)
+		if locked == nil {
+			return ErrChannelNotFound
+		}
+
+		channel.Position = locked.Position
+		channel.ParentID = locked.ParentID
+		channels[slices.Index(channels, locked)] = channel
+
+		// 位置變更需要重新編排同一分類的其他頻道
+		var moved []*model.Channel
+
+		var before map[uint]channelPlacement
+
+		if req.Position != nil {
+			moved, before, err = arrangeChannels(
+				channels,
+				[]ChannelPositionUpdate{{ID: channel.ID, Position: *req.Position}},
+				now,
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(changes) == 0 && len(moved) == 0 {
+			return nil
+		}
+
+		if err := repos.Channels.Update(ctx, channel); err != nil {
+			return err
+		}
+
+		if len(changes) > 0 {
+			if err := repos.AuditLogs.Create(ctx, newAuditEntry(
+				ctx, now, channel.GuildID, userID,
+				model.AuditActionChannelUpdate, model.AuditTargetChannel, channel.ID,
+				changes,
+			)); err != nil {
+				return err
+			}
+		}
+
+		if err := s.savePositions(ctx, repos, channel.GuildID, userID, moved, before); err != nil {
+			return err
+		}
+
+		// channel.update 一律帶頻道陣列；位置有變更時帶社群所有頻道的新排序
+		updated := []*model.Channel{channel}
+		if len(moved) > 0 {
+			updated = channels
+		}
+
+		return repos.Outbox.Append(ctx, s.events.GuildEvent(
+			channel.GuildID,
+			model.EventChannelUpdate,
+			updated,
+		))
+	})
+	if err != nil {
 		return nil, err
 	}
 
+	s.events.Notify()
+
 	return channel, nil
 }
 
 // DeleteChannel 刪除頻道
-func (s *channelService) DeleteChannel(channelID, userID uint) error {
+func (s *channelService) DeleteChannel(ctx context.Context, channelID, userID uint) error {
 	// 取得頻道
-	channel, err := s.channelRepo.GetByID(channelID)
+	channel, err := s.channelRepo.GetByID(ctx, channelID)
 	if err != nil {
 		return ErrChannelNotFound
 	}
 
 	// 檢查權限（只有擁有者或管理員可以刪除）
-	guild, err := s.guildRepo.GetByID(channel.GuildID)
+	guild, err := s.guildRepo.GetByID(ctx, channel.GuildID)
 	if err != nil {
 		return ErrGuildNotFound
 	}
 
 	if guild.OwnerID != userID {
-		member, err := s.guildMemberRepo.GetMember(channel.GuildID, userID)
+		member, err := s.guildMemberRepo.GetMember(ctx, channel.GuildID, userID)
 		if err != nil || member == nil {
 			return ErrNotGuildMemberCh
 		}
 
 		if member.Role != "admin" && member.Role != "owner" {
-			return errors.New("only owner or admin can delete channels")
+			return ErrNotChannelManager
 		}
 	}
 
-	return s.channelRepo.Delete(channelID)
+	err = s.tx.WithinTx(ctx, func(repos *repository.Repositories) error {
+		if _, err := repos.Channels.LockByGuildID(ctx, channel.GuildID); err != nil {
+			return err
+		}
+
+		if err := repos.Channels.Delete(ctx, channelID); err != nil {
+			return err
+		}
+
+		now := s.clock.Now()
+
+		if err := repos.AuditLogs.Create(ctx, newAuditEntry(
+			ctx, now, channel.GuildID, userID,
+			model.AuditActionChannelDelete, model.AuditTargetChannel, channel.ID,
+			auditChanges{}.
+				set("name", channel.Name, nil).
+				set("type", channel.Type, nil),
+		)); err != nil {
+			return err
+		}
+
+		if err := repos.Outbox.Append(
+			ctx,
+			s.events.GuildEvent(channel.GuildID, model.EventChannelDelete, channel),
+		); err != nil {
+			return err
+		}
+
+		// 補齊刪除後的位置空缺（分類底下的頻道已移到最上層）
+		_, err := s.repositionChannels(ctx, repos, channel.GuildID, userID, nil, now)
+
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	s.events.Notify()
+
+	return nil
 }
 
 // UpdateChannelPosition 更新頻道位置
-func (s *channelService) UpdateChannelPosition(channelID, userID uint, position int) error {
+func (s *channelService) UpdateChannelPosition(
+	ctx context.Context,
+	channelID, userID uint,
+	position int,
+) error {
 	// 取得頻道
-	channel, err := s.channelRepo.GetByID(channelID)
+	channel, err := s.channelRepo.GetByID(ctx, channelID)
 	if err != nil {
 		return ErrChannelNotFound
 	}
 
 	// 檢查權限
-	guild, err := s.guildRepo.GetByID(channel.GuildID)
+	guild, err := s.guildRepo.GetByID(ctx, channel.GuildID)
 	if err != nil {
 		return ErrGuildNotFound
 	}
 
 	if guild.OwnerID != userID {
-		member, err := s.guildMemberRepo.GetMember(channel.GuildID, userID)
+		member, err := s.guildMemberRepo.GetMember(ctx, channel.GuildID, userID)
 		if err != nil || member == nil {
 			return ErrNotGuildMemberCh
 		}
 
 		if member.Role != "admin" && member.Role != "owner" {
-			return errors.New("only owner or admin can update channel position")
+			return ErrNotChannelManager
 		}
 	}
 
-	channel.Position = position
-	channel.UpdatedAt = time.Now()
+	_, err = s.applyPositions(ctx, channel.GuildID, userID, []ChannelPositionUpdate{
+		{ID: channel.ID, Position: position},
+	})
+
+	return err
+}
+
+// ReorderChannels 批次調整社群頻道的排序與所屬分類
+//
+// 所有頻道會依分類重新編號，並在同一個交易中寫入，完成後只廣播一次 channel_update
+func (s *channelService) ReorderChannels(
+	ctx context.Context,
+	guildID, userID uint,
+	updates []ChannelPositionUpdate,
+) ([]*model.Channel, error) {
+	if _, err := s.guildRepo.GetByID(ctx, guildID); err != nil {
+		return nil, ErrGuildNotFound
+	}
+
+	member, err := s.guildMemberRepo.GetMember(ctx, guildID, userID)
+	if err != nil || member == nil {
+		return nil, ErrNotGuildMemberCh
+	}
+
+	if !hasPermission(member.Role, PermissionManageChannels) {
+		return nil, ErrMissingPermission
+	}
+
+	if len(updates) == 0 {
+		return nil, ErrInvalidChannelPositions
+	}
+
+	return s.applyPositions(ctx, guildID, userID, updates)
+}
+
+// applyPositions 在單一交易中套用排序變更，回傳排序後的所有頻道
+func (s *channelService) applyPositions(
+	ctx context.Context,
+	guildID, userID uint,
+	updates []ChannelPositionUpdate,
+) ([]*model.Channel, error) {
+	var channels []*model.Channel
+
+	err := s.tx.WithinTx(ctx, func(repos *repository.Repositories) error {
+		var err error
+
+		channels, err = s.repositionChannels(ctx, repos, guildID, userID, updates, s.clock.Now())
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.events.Notify()
+
+	return channels, nil
+}
+
+// repositionChannels 鎖定社群的頻道後套用排序變更，有頻道移動時寫入新位置並發出一次 channel.update
+//
+// 須在交易中呼叫，回傳排序後的所有頻道
+func (s *channelService) repositionChannels(
+	ctx context.Context,
+	repos *repository.Repositories,
+	guildID, userID uint,
+	updates []ChannelPositionUpdate,
+	now time.Time,
+) ([]*model.Channel, error) {
+	channels, err := repos.Channels.LockByGuildID(ctx, guildID)
+	if err != nil {
+		return nil, err
+	}
+
+	changed, before, err := arrangeChannels(channels, updates, now)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(changed) == 0 {
+		return channels, nil
+	}
+
+	if err := s.savePositions(ctx, repos, guildID, userID, changed, before); err != nil {
+		return nil, err
+	}
+
+	// 所有客戶端同時收到完整的新排序
+	return channels, repos.Outbox.Append(
+		ctx,
+		s.events.GuildEvent(guildID, model.EventChannelUpdate, channels),
+	)
+}
+
+// savePositions 寫入頻道的新位置與所屬分類，並為每個頻道記錄稽核
+func (s *channelService) savePositions(
+	ctx context.Context,
+	repos *repository.Repositories,
+	guildID, userID uint,
+	changed []*model.Channel,
+	before map[uint]channelPlacement,
+) error {
+	if len(changed) == 0 {
+		return nil
+	}
+
+	if err := repos.Channels.UpdatePositions(ctx, guildID, changed); err != nil {
+		return err
+	}
+
+	for _, channel := range changed {
+		previous := before[channel.ID]
+		if err := repos.AuditLogs.Create(ctx, newAuditEntry(
+			ctx, s.clock.Now(), guildID, userID,
+			model.AuditActionChannelUpdate, model.AuditTargetChannel, channel.ID,
+			auditChanges{}.
+				set("position", previous.position, channel.Position).
+				set("parent_id", previous.parentID, channel.ParentID),
+		)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// channelPlacement 頻道排序變更前的位置
+type channelPlacement struct {
+	parentID *uint
+	position int
+}
+
+// arrangeChannels 套用排序變更並重新編號每個分類內的頻道位置，channels 會就地修改並依位置排序
+//
+// 指定的頻道會插入到目標位置，其餘頻道維持原本的相對順序。
+// 回傳位置或所屬分類有變更的頻道，以及所有頻道變更前的位置
+func arrangeChannels(
+	channels []*model.Channel,
+	updates []ChannelPositionUpdate,
+	now time.Time,
+) ([]*model.Channel, map[uint]channelPlacement, error) {
+	before := make(map[uint]channelPlacement, len(channels))
+	for _, channel := range channels {
+		before[channel.ID] = channelPlacement{
+			parentID: channel.ParentID,
+			position: channel.Position,
+		}
+	}
+
+	// 套用所屬分類的變更，並記錄指定的目標位置
+	targets := make(map[uint]int, len(updates))
+
+	for _, update := range updates {
+		channel := findChannel(channels, update.ID)
+		if channel == nil {
+			return nil, nil, ErrChannelNotFound
+		}
+
+		if _, duplicated := targets[update.ID]; duplicated {
+			return nil, nil, ErrInvalidChannelPositions
+		}
+
+		targets[update.ID] = update.Position
+
+		if update.ParentID == nil {
+			continue
+		}
+
+		if *update.ParentID == 0 {
+			channel.ParentID = nil
+			continue
+		}
+
+		parent := findChannel(channels, *update.ParentID)
+		if channel.Type == ChannelTypeCategory || parent == nil ||
+			parent.Type != ChannelTypeCategory {
+			return nil, nil, ErrInvalidChannelParent
+		}
+
+		parentID := parent.ID
+		channel.ParentID = &parentID
+	}
+
+	// 分類被移除時，底下的頻道回到最上層
+	for _, channel := range channels {
+		if channel.ParentID != nil {
+			if parent := findChannel(channels, *channel.ParentID); parent == nil {
+				channel.ParentID = nil
+			}
+		}
+	}
+
+	// 依所屬分類分組後重新編號
+	groups := make(map[uint][]*model.Channel)
+	for _, channel := range channels {
+		key := parentKey(channel.ParentID)
+		groups[key] = append(groups[key], channel)
+	}
+
+	byPosition := func(a, b *model.Channel) int {
+		return cmp.Or(cmp.Compare(a.Position, b.Position), cmp.Compare(a.ID, b.ID))
+	}
+
+	var changed []*model.Channel
+
+	for _, group := range groups {
+		var ordered, moved []*model.Channel
+
+		for _, channel := range group {
+			if _, ok := targets[channel.ID]; ok {
+				moved = append(moved, channel)
+			} else {
+				ordered = append(ordered, channel)
+			}
+		}
+
+		slices.SortFunc(ordered, byPosition)
+		slices.SortFunc(moved, func(a, b *model.Channel) int {
+			return cmp.Or(cmp.Compare(targets[a.ID], targets[b.ID]), cmp.Compare(a.ID, b.ID))
+		})
+
+		for _, channel := range moved {
+			index := min(targets[channel.ID], len(ordered))
+			ordered = slices.Insert(ordered, index, channel)
+		}
+
+		for index, channel := range ordered {
+			channel.Position = index
+
+			previous := before[channel.ID]
+			if previous.position != index ||
+				parentKey(previous.parentID) != parentKey(channel.ParentID) {
+				channel.UpdatedAt = now
+				changed = append(changed, channel)
+			}
+		}
+	}
+
+	slices.SortFunc(channels, byPosition)
+
+	return changed, before, nil
+}
+
+// findChannel 在頻道列表中尋找指定 ID 的頻道
+func findChannel(channels []*model.Channel, id uint) *model.Channel {
+	for _, channel := range channels {
+		if channel.ID == id {
+			return channel
+		}
+	}
+
+	return nil
+}
+
+// countSiblings 計算同一分類下的頻道數量
+func countSiblings(channels []*model.Channel, parentID *uint) int {
+	count := 0
+
+	for _, channel := range channels {
+		if parentKey(channel.ParentID) == parentKey(parentID) {
+			count++
+		}
+	}
+
+	return count
+}
+
+// parentKey 將所屬分類轉為分組用的鍵值，0 表示最上層
+func parentKey(parentID *uint) uint {
+	if parentID == nil {
+		return 0
+	}
 
-	return s.channelRepo.Update(channel)
+	return *parentID
 }
@@ -0,0 +1,244 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/walnut-almonds/talkrealm/internal/model"
+)
+
+func TestCreateChannelRequiresManager(t *testing.T) {
+	env := newTestEnv(t)
+	owner := env.createUser("owner")
+	member := env.createUser("member")
+	outsider := env.createUser("outsider")
+	guild := env.createGuild(owner, member)
+	channels := env.channelService()
+
+	for _, tt := range []struct {
+		user *model.User
+		want error
+	}{
+		{outsider, ErrNotGuildMemberCh},
+		{member, ErrNotChannelManager},
+	} {
+		_, err := channels.CreateChannel(env.ctx, tt.user.ID, &CreateChannelRequest{
+			GuildID: guild.ID,
+			Name:    "general",
+			Type:    "text",
+		})
+		if !errors.Is(err, tt.want) {
+			t.Errorf("CreateChannel() by %s = %v, want %v", tt.user.Username, err, tt.want)
+		}
+	}
+
+	env.setRole(guild.ID, member.ID, "admin")
+
+	channel := env.createChannel(
+		guild.ID,
+		member.ID,
+		&CreateChannelRequest{Name: "general", Type: "text"},
+	)
+
+	if n := env.count("audit_log_entries", "action = ? AND target_id = ?",
+		model.AuditActionChannelCreate, channel.ID); n != 1 {
+		t.Errorf("channel.create audit entries = %d, want 1", n)
+	}
+
+	if n := env.count("outbox_events", "type = ?", model.EventChannelCreate); n != 1 {
+		t.Errorf("channel.create events = %d, want 1", n)
+	}
+}
+
+func TestCreateChannelValidatesParent(t *testing.T) {
+	env := newTestEnv(t)
+	owner := env.createUser("owner")
+	guild := env.createGuild(owner)
+
+	text := env.createChannel(guild.ID, owner.ID, &CreateChannelRequest{Name: "text", Type: "text"})
+	category := env.createChannel(
+		guild.ID,
+		owner.ID,
+		&CreateChannelRequest{Name: "category", Type: ChannelTypeCategory},
+	)
+
+	for _, req := range []*CreateChannelRequest{
+		{GuildID: guild.ID, Name: "child", Type: "text", ParentID: &text.ID},
+		{GuildID: guild.ID, Name: "nested", Type: ChannelTypeCategory, ParentID: &category.ID},
+	} {
+		if _, err := env.channelService().CreateChannel(env.ctx, owner.ID, req); !errors.Is(
+			err,
+			ErrInvalidChannelParent,
+		) {
+			t.Errorf("CreateChannel(%s) = %v, want %v", req.Name, err, ErrInvalidChannelParent)
+		}
+	}
+
+	child := env.createChannel(
+		guild.ID,
+		owner.ID,
+		&CreateChannelRequest{Name: "child", Type: "text", ParentID: &category.ID},
+	)
+	if child.ParentID == nil || *child.ParentID != category.ID {
+		t.Errorf("parent = %v, want %d", child.ParentID, category.ID)
+	}
+}
+
+func TestDeleteChannelRemovesMessages(t *testing.T) {
+	env := newTestEnv(t)
+	owner := env.createUser("owner")
+	guild := env.createGuild(owner)
+	channel := env.createChannel(
+		guild.ID,
+		owner.ID,
+		&CreateChannelRequest{Name: "general", Type: "text"},
+	)
+
+	if _, err := env.messageService().CreateMessage(env.ctx, owner.ID, &CreateMessageRequest{
+		ChannelID: channel.ID,
+		Content:   "hello",
+	}); err != nil {
+		t.Fatalf("CreateMessage() = %v", err)
+	}
+
+	if err := env.channelService().DeleteChannel(env.ctx, channel.ID, owner.ID); err != nil {
+		t.Fatalf("DeleteChannel() = %v", err)
+	}
+
+	if _, err := env.channelService().GetChannel(env.ctx, channel.ID, owner.ID); !errors.Is(
+		err,
+		ErrChannelNotFound,
+	) {
+		t.Errorf("GetChannel() after delete = %v, want %v", err, ErrChannelNotFound)
+	}
+
+	if n := env.count("messages", "channel_id = ?", channel.ID); n != 0 {
+		t.Errorf("messages left in deleted channel = %d, want 0", n)
+	}
+
+	if n := env.count("audit_log_entries", "action = ?", model.AuditActionChannelDelete); n != 1 {
+		t.Errorf("channel.delete audit entries = %d, want 1", n)
+	}
+}
+
+// channelOrder 依位置列出社群的頻道名稱
+func (e *testEnv) channelOrder(guildID uint) []string {
+	e.t.Helper()
+
+	channels, err := e.repos.Channels.GetByGuildID(e.ctx, guildID)
+	if err != nil {
+		e.t.Fatalf("list channels: %v", err)
+	}
+
+	names := make([]string, 0, len(channels))
+	for _, channel := range channels {
+		names = append(names, fmt.Sprintf("%s:%d", channel.Name, channel.Position))
+	}
+
+	return names
+}
+
+func TestCreateChannelAtPositionIsOneTransaction(t *testing.T) {
+	env := newTestEnv(t)
+	owner := env.createUser("owner")
+	guild := env.createGuild(owner)
+
+	for _, name := range []string{"a", "b", "c"} {
+		env.createChannel(guild.ID, owner.ID, &CreateChannelRequest{Name: name, Type: "text"})
+	}
+
+	events := env.count("outbox_events", "")
+
+	created := env.createChannel(
+		guild.ID,
+		owner.ID,
+		&CreateChannelRequest{Name: "d", Type: "text", Position: 1},
+	)
+
+	if created.Position != 1 {
+		t.Errorf("created position = %d, want 1", created.Position)
+	}
+
+	want := []string{"a:0", "d:1", "b:2", "c:3"}
+	if got := env.channelOrder(guild.ID); !slices.Equal(got, want) {
+		t.Errorf("order = %v, want %v", got, want)
+	}
+
+	if n := env.count("outbox_events", "") - events; n != 1 {
+		t.Errorf("events = %d, want 1", n)
+	}
+
+	// 事件寫入失敗時，新頻道與其他頻道的位移一起回滾
+	env.failWrites("outbox_events")
+
+	_, err := env.channelService().CreateChannel(env.ctx, owner.ID, &CreateChannelRequest{
+		GuildID:  guild.ID,
+		Name:     "e",
+		Type:     "text",
+		Position: 1,
+	})
+	if !errors.Is(err, errInjected) {
+		t.Fatalf("CreateChannel() = %v, want %v", err, errInjected)
+	}
+
+	if got := env.channelOrder(guild.ID); !slices.Equal(got, want) {
+		t.Errorf("order after failed create = %v, want %v", got, want)
+	}
+}
+
+func TestUpdateChannelPositionEmitsOneEvent(t *testing.T) {
+	env := newTestEnv(t)
+	owner := env.createUser("owner")
+	guild := env.createGuild(owner)
+
+	var last *model.Channel
+	for _, name := range []string{"a", "b", "c"} {
+		last = env.createChannel(
+			guild.ID,
+			owner.ID,
+			&CreateChannelRequest{Name: name, Type: "text"},
+		)
+	}
+
+	events := env.count("outbox_events", "")
+	position := 0
+
+	updated, err := env.channelService().
+		UpdateChannel(env.ctx, last.ID, owner.ID, &UpdateChannelRequest{
+			Topic:    "moved",
+			Position: &position,
+		})
+	if err != nil {
+		t.Fatalf("UpdateChannel() = %v", err)
+	}
+
+	if updated.Position != 0 || updated.Topic != "moved" {
+		t.Errorf("updated = %+v, want topic and position 0", updated)
+	}
+
+	want := []string{"c:0", "a:1", "b:2"}
+	if got := env.channelOrder(guild.ID); !slices.Equal(got, want) {
+		t.Errorf("order = %v, want %v", got, want)
+	}
+
+	var published []*model.OutboxEvent
+	if err := env.db.Where("id > ?", events).Find(&published).Error; err != nil {
+		t.Fatalf("list events: %v", err)
+	}
+
+	if len(published) != 1 || published[0].Type != model.EventChannelUpdate {
+		t.Fatalf("events = %+v, want one %s", published, model.EventChannelUpdate)
+	}
+
+	var channels []*model.Channel
+	if err := json.Unmarshal([]byte(published[0].Payload), &channels); err != nil {
+		t.Fatalf("unmarshal payload: %v", err)
+	}
+
+	if len(channels) != 3 || channels[0].ID != last.ID || channels[0].Topic != "moved" {
+		t.Errorf("payload = %s, want every channel with the moved one first", published[0].Payload)
+	}
+}
@@ -0,0 +1,439 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/walnut-almonds/talkrealm/internal/apperror"
+	"github.com/walnut-almonds/talkrealm/internal/model"
+	"github.com/walnut-almonds/talkrealm/internal/repository"
+	"github.com/walnut-almonds/talkrealm/pkg/clock"
+	"github.com/walnut-almonds/talkrealm/pkg/storage"
+	"go.uber.org/zap"
+)
+
+const (
+	// exportBatchSize 每次排程最多處理的匯出工作數量
+	exportBatchSize = 5
+	// exportMessagePageSize 匯出訊息時每頁讀取的數量
+	exportMessagePageSize = 500
+)
+
+var (
+	ErrDataExportNotFound   = apperror.NotFound("data_export_not_found", "data export not found")
+	ErrDataExportNotReady   = apperror.Conflict("data_export_not_ready", "data export is not ready")
+	ErrDataExportInProgress = apperror.Conflict(
+		"data_export_in_progress",
+		"data export already in progress",
+	)
+)
+
+// exportProfile 匯出檔中的個人資料
+type exportProfile struct {
+	ID                  uint       `json:"id"`
+	Username            string     `json:"username"`
+	Email               string     `json:"email"`
+	Nickname            string     `json:"nickname"`
+	Avatar              string     `json:"avatar"`
+	Status              string     `json:"status"`
+	IsBot               bool       `json:"is_bot"`
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+// exportMembership 匯出檔中的社群成員資格
+type exportMembership struct {
+	GuildID   uint      `json:"guild_id"`
+	GuildName string    `json:"guild_name"`
+	Nickname  string    `json:"nickname"`
+	Role      string    `json:"role"`
+	JoinedAt  time.Time `json:"joined_at"`
+}
+
+// exportMessage 匯出檔中的訊息
+type exportMessage struct {
+	ID          uint      `json:"id"`
+	ChannelID   uint      `json:"channel_id"`
+	ChannelName string    `json:"channel_name"`
+	Content     string    `json:"content"`
+	Type        string    `json:"type"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// DataExportService 個人資料匯出服務介面
+type DataExportService interface {
+	RequestExport(ctx context.Context, userID uint) (*model.DataExport, error)
+	ListExports(ctx context.Context, userID uint) ([]*model.DataExport, error)
+	OpenExport(
+		ctx context.Context,
+		userID, exportID uint,
+	) (*model.DataExport, io.ReadCloser, error)
+	ProcessPending(ctx context.Context) error
+	CleanupExpired(ctx context.Context) error
+}
+
+type dataExportService struct {
+	exportRepo      repository.DataExportRepository
+	userRepo        repository.UserRepository
+	guildMemberRepo repository.GuildMemberRepository
+	messageRepo     repository.MessageRepository
+	identityRepo    repository.UserIdentityRepository
+	apiTokenRepo    repository.APITokenRepository
+	store           storage.BlobStore
+	ttl             time.Duration
+	clock           clock.Clock
+	logger          *zap.Logger
+}
+
+// NewDataExportService 建立個人資料匯出服務
+func NewDataExportService(
+	exportRepo repository.DataExportRepository,
+	userRepo repository.UserRepository,
+	guildMemberRepo repository.GuildMemberRepository,
+	messageRepo repository.MessageRepository,
+	identityRepo repository.UserIdentityRepository,
+	apiTokenRepo repository.APITokenRepository,
+	store storage.BlobStore,
+	ttl time.Duration,
+	clk clock.Clock,
+	logger *zap.Logger,
+) DataExportService {
+	return &dataExportService{
+		exportRepo:      exportRepo,
+		userRepo:        userRepo,
+		guildMemberRepo: guildMemberRepo,
+		messageRepo:     messageRepo,
+		identityRepo:    identityRepo,
+		apiTokenRepo:    apiTokenRepo,
+		store:           store,
+		ttl:             ttl,
+		clock:           clk,
+		logger:          logger,
+	}
+}
+
+// RequestExport 建立資料匯出工作，實際打包由背景排程處理
+func (s *dataExportService) RequestExport(
+	ctx context.Context,
+	userID uint,
+) (*model.DataExport, error) {
+	count, err := s.exportRepo.CountActiveByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if count > 0 {
+		return nil, ErrDataExportInProgress
+	}
+
+	export := &model.DataExport{
+		UserID:    userID,
+		Status:    model.DataExportPending,
+		CreatedAt: s.clock.Now(),
+		UpdatedAt: s.clock.Now(),
+	}
+
+	if err := s.exportRepo.Create(ctx, export); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			return nil, ErrDataExportInProgress
+		}
+
+		return nil, err
+	}
+
+	return export, nil
+}
+
+// ListExports 列出使用者的資料匯出工作
+func (s *dataExportService) ListExports(
+	ctx context.Context,
+	userID uint,
+) ([]*model.DataExport, error) {
+	return s.exportRepo.GetByUserID(ctx, userID)
+}
+
+// OpenExport 開啟已完成的匯出檔供下載
+func (s *dataExportService) OpenExport(
+	ctx context.Context,
+	userID, exportID uint,
+) (*model.DataExport, io.ReadCloser, error) {
+	export, err := s.exportRepo.GetByID(ctx, exportID)
+	if err != nil || export.UserID != userID {
+		return nil, nil, ErrDataExportNotFound
+	}
+
+	if export.Status != model.DataExportReady {
+		return nil, nil, ErrDataExportNotReady
+	}
+
+	if export.ExpiresAt != nil && s.clock.Now().After(*export.ExpiresAt) {
+		return nil, nil, ErrDataExportNotFound
+	}
+
+	rc, err := s.store.Open(ctx, export.BlobKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotFound) {
+			return nil, nil, ErrDataExportNotFound
+		}
+
+		return nil, nil, err
+	}
+
+	return export, rc, nil
+}
+
+// ProcessPending 處理等待中的匯出工作（由排程器呼叫）
+func (s *dataExportService) ProcessPending(ctx context.Context) error {
+	exports, err := s.exportRepo.GetPending(ctx, exportBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, export := range exports {
+		if ctx.Err() != nil {
+			break
+		}
+
+		claimed, err := s.exportRepo.Claim(ctx, export.ID, s.clock.Now())
+		if err != nil {
+			return err
+		}
+
+		if !claimed {
+			continue
+		}
+
+		s.process(ctx, export)
+	}
+
+	return nil
+}
+
+// CleanupExpired 刪除已過期的匯出檔（由排程器呼叫）
+func (s *dataExportService) CleanupExpired(ctx context.Context) error {
+	exports, err := s.exportRepo.GetExpired(ctx, s.clock.Now(), exportBatchSize*10)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+
+	for _, export := range exports {
+		if export.BlobKey != "" {
+			err := s.store.Delete(ctx, export.BlobKey)
+			if err != nil && !errors.Is(err, storage.ErrObjectNotFound) {
+				errs = append(errs, fmt.Errorf("delete export %d: %w", export.ID, err))
+				continue
+			}
+		}
+
+		if err := s.exportRepo.Delete(ctx, export.ID); err != nil {
+			errs = append(errs, fmt.Errorf("delete export %d: %w", export.ID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// process 打包單一匯出工作並更新狀態
+func (s *dataExportService) process(ctx context.Context, export *model.DataExport) {
+	key := fmt.Sprintf("exports/%d/%d.zip", export.UserID, export.ID)
+
+	size, err := s.writeArchive(ctx, export.UserID, key)
+
+	now := s.clock.Now()
+	export.UpdatedAt = now
+
+	if err != nil {
+		s.logger.Error("Data export failed", zap.Uint("exportID", export.ID), zap.Error(err))
+
+		export.Status = model.DataExportFailed
+		export.Error = "failed to build export archive"
+	} else {
+		expiresAt := now.Add(s.ttl)
+		export.Status = model.DataExportReady
+		export.BlobKey = key
+		export.Size = size
+		export.CompletedAt = &now
+		export.ExpiresAt = &expiresAt
+	}
+
+	if err := s.exportRepo.Update(ctx, export); err != nil {
+		s.logger.Error(
+			"Failed to update data export",
+			zap.Uint("exportID", export.ID),
+			zap.Error(err),
+		)
+	}
+}
+
+// writeArchive 以串流方式產生 zip 匯出檔並寫入檔案儲存
+func (s *dataExportService) writeArchive(
+	ctx context.Context,
+	userID uint,
+	key string,
+) (int64, error) {
+	pr, pw := io.Pipe()
+
+	go func() {
+		zw := zip.NewWriter(pw)
+
+		err := s.writeEntries(ctx, zw, userID)
+		if closeErr := zw.Close(); err == nil {
+			err = closeErr
+		}
+
+		pw.CloseWithError(err)
+	}()
+
+	size, err := s.store.Put(ctx, key, pr)
+	// 確保寫入端在儲存失敗時也會結束
+	_ = pr.CloseWithError(io.ErrClosedPipe)
+
+	return size, err
+}
+
+// writeEntries 寫入匯出檔中的各個 JSON 檔案
+func (s *dataExportService) writeEntries(ctx context.Context, zw *zip.Writer, userID uint) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	profile := exportProfile{
+		ID:                  user.ID,
+		Username:            user.Username,
+		Email:               user.Email,
+		Nickname:            user.Nickname,
+		Avatar:              user.Avatar,
+		Status:              user.Status,
+		IsBot:               user.IsBot,
+		DeletionScheduledAt: user.DeletionScheduledAt,
+		CreatedAt:           user.CreatedAt,
+		UpdatedAt:           user.UpdatedAt,
+	}
+	if err := writeJSONEntry(zw, "profile.json", profile); err != nil {
+		return err
+	}
+
+	identities, err := s.identityRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := writeJSONEntry(zw, "identities.json", identities); err != nil {
+		return err
+	}
+
+	members, err := s.guildMemberRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	memberships := make([]exportMembership, 0, len(members))
+	for _, m := range members {
+		memberships = append(memberships, exportMembership{
+			GuildID:   m.GuildID,
+			GuildName: m.Guild.Name,
+			Nickname:  m.Nickname,
+			Role:      m.Role,
+			JoinedAt:  m.JoinedAt,
+		})
+	}
+
+	if err := writeJSONEntry(zw, "memberships.json", memberships); err != nil {
+		return err
+	}
+
+	tokens, err := s.apiTokenRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	tokenResponses := make([]*APITokenResponse, 0, len(tokens))
+	for _, token := range tokens {
+		tokenResponses = append(tokenResponses, toAPITokenResponse(token))
+	}
+
+	if err := writeJSONEntry(zw, "api_tokens.json", tokenResponses); err != nil {
+		return err
+	}
+
+	return s.writeMessages(ctx, zw, userID)
+}
+
+// writeMessages 分頁讀取使用者訊息並寫成 JSON 陣列，避免一次載入全部訊息
+func (s *dataExportService) writeMessages(ctx context.Context, zw *zip.Writer, userID uint) error {
+	w, err := zw.Create("messages.json")
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+
+	first := true
+
+	for offset := 0; ; offset += exportMessagePageSize {
+		messages, err := s.messageRepo.GetByUserID(ctx, userID, offset, exportMessagePageSize)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range messages {
+			data, err := json.Marshal(exportMessage{
+				ID:          m.ID,
+				ChannelID:   m.ChannelID,
+				ChannelName: m.Channel.Name,
+				Content:     m.Content,
+				Type:        m.Type,
+				CreatedAt:   m.CreatedAt,
+				UpdatedAt:   m.UpdatedAt,
+			})
+			if err != nil {
+				return err
+			}
+
+			if !first {
+				if _, err := io.WriteString(w, ","); err != nil {
+					return err
+				}
+			}
+
+			first = false
+
+			if _, err := w.Write(data); err != nil {
+				return err
+			}
+		}
+
+		if len(messages) < exportMessagePageSize {
+			break
+		}
+	}
+
+	_, err = io.WriteString(w, "]")
+
+	return err
+}
+
+// writeJSONEntry 將資料以 JSON 格式寫入 zip 檔案
+func writeJSONEntry(zw *zip.Writer, name string, v any) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(v)
+}
@@ -0,0 +1,329 @@
+package service
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/walnut-almonds/talkrealm/internal/model"
+	"github.com/walnut-almonds/talkrealm/internal/repository"
+	"github.com/walnut-almonds/talkrealm/pkg/clock"
+	"github.com/walnut-almonds/talkrealm/pkg/idgen"
+	"github.com/walnut-almonds/talkrealm/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+const (
+	// outboxBatchSize 每次從 outbox 取出的事件數量
+	outboxBatchSize = 100
+	// maxOutboxAttempts 單一事件最多轉送次數，超過後記錄錯誤並略過，避免阻塞同一頻道之後的事件
+	maxOutboxAttempts = 20
+)
+
+// WebSocketManager 定義 WebSocket 管理器的介面（避免循環依賴）
+type WebSocketManager interface {
+	BroadcastToChannel(ctx context.Context, channelID uint, msgType string, data any)
+	BroadcastToGuild(ctx context.Context, guildID uint, msgType string, data any)
+}
+
+// EventSubscriber 領域事件的訂閱者
+//
+// 事件至少會送達一次，同一頻道（或同一社群）的事件依序送達；回傳錯誤時稍後重試。
+// 處理事件時的資料庫寫入須透過 repos，才會與事件的轉送紀錄一起提交或回滾
+type EventSubscriber interface {
+	HandleEvent(ctx context.Context, repos *repository.Repositories, event *model.OutboxEvent) error
+}
+
+// EventBusOptions 事件匯流排設定
+type EventBusOptions struct {
+	PollInterval time.Duration // 檢查未轉送事件的間隔
+	Retention    time.Duration // 已轉送事件的保留時間
+}
+
+// EventBus 領域事件匯流排
+//
+// 服務以 ChannelEvent/GuildEvent 建立事件，在寫入狀態的同一個交易（TxManager.WithinTx）中
+// 以 Outbox.Append 寫入；提交後呼叫 Notify，relay 會依 ID 順序把事件轉送給所有訂閱者
+type EventBus interface {
+	ChannelEvent(guildID, channelID uint, eventType string, data any) *model.OutboxEvent
+	GuildEvent(guildID uint, eventType string, data any) *model.OutboxEvent
+	Subscribe(subscriber EventSubscriber)
+	Notify()
+	Start()
+	Stop()
+	PurgeDispatched(ctx context.Context) error
+}
+
+type eventBus struct {
+	outboxRepo  repository.OutboxRepository
+	origin      string
+	options     EventBusOptions
+	tracer      trace.Tracer
+	clock       clock.Clock
+	logger      *zap.Logger
+	subscribers []EventSubscriber
+	wake        chan struct{}
+	cancel      context.CancelFunc
+	done        chan struct{}
+	mu          sync.RWMutex
+}
+
+// NewEventBus 建立事件匯流排
+func NewEventBus(
+	outboxRepo repository.OutboxRepository,
+	options EventBusOptions,
+	ids idgen.Generator,
+	tracer trace.Tracer,
+	clk clock.Clock,
+	logger *zap.Logger,
+) EventBus {
+	return &eventBus{
+		outboxRepo: outboxRepo,
+		origin:     instanceID(ids),
+		options:    options,
+		tracer:     tracer,
+		clock:      clk,
+		logger:     logger,
+		wake:       make(chan struct{}, 1),
+	}
+}
+
+// instanceID 產生本伺服器實例的識別碼（主機名稱加上唯一字尾，避免重啟後沿用舊的識別碼）
+func instanceID(ids idgen.Generator) string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "talkrealm"
+	}
+
+	return host + "-" + ids.NewID()
+}
+
+// ChannelEvent 建立頻道事件
+func (b *eventBus) ChannelEvent(
+	guildID, channelID uint,
+	eventType string,
+	data any,
+) *model.OutboxEvent {
+	event := b.GuildEvent(guildID, eventType, data)
+	event.ChannelID = &channelID
+
+	return event
+}
+
+// GuildEvent 建立社群事件
+func (b *eventBus) GuildEvent(guildID uint, eventType string, data any) *model.OutboxEvent {
+	return &model.OutboxEvent{
+		Type:      eventType,
+		GuildID:   guildID,
+		Data:      data,
+		Origin:    b.origin,
+		CreatedAt: b.clock.Now(),
+	}
+}
+
+// Subscribe 註冊訂閱者（需在 Start 之前呼叫）
+func (b *eventBus) Subscribe(subscriber EventSubscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.subscribers = append(b.subscribers, subscriber)
+}
+
+// Notify 通知 relay 有新事件已提交
+func (b *eventBus) Notify() {
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start 啟動 relay
+func (b *eventBus) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	b.cancel = cancel
+	b.done = make(chan struct{})
+
+	go b.run(ctx)
+}
+
+// Stop 停止 relay 並等待進行中的轉送完成
+func (b *eventBus) Stop() {
+	if b.cancel == nil {
+		return
+	}
+
+	b.cancel()
+	<-b.done
+}
+
+// PurgeDispatched 刪除超過保留期限的已轉送事件（由排程器呼叫）
+func (b *eventBus) PurgeDispatched(ctx context.Context) error {
+	if b.options.Retention <= 0 {
+		return nil
+	}
+
+	deleted, err := b.outboxRepo.DeleteDispatchedBefore(
+		ctx,
+		b.clock.Now().Add(-b.options.Retention),
+	)
+	if err != nil {
+		return err
+	}
+
+	if deleted > 0 {
+		b.logger.Info("Outbox events purged", zap.Int64("count", deleted))
+	}
+
+	return nil
+}
+
+// run relay 主迴圈：收到通知或定期輪詢時轉送所有未轉送的事件
+func (b *eventBus) run(ctx context.Context) {
+	defer close(b.done)
+
+	ticker := time.NewTicker(b.options.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-b.wake:
+		case <-ticker.C:
+		}
+
+		b.dispatchPending(ctx)
+	}
+}
+
+// dispatchPending 分批轉送事件，直到沒有可轉送的事件為止
+func (b *eventBus) dispatchPending(ctx context.Context) {
+	for ctx.Err() == nil {
+		dispatched, err := b.outboxRepo.Dispatch(
+			ctx,
+			outboxBatchSize,
+			b.clock.Now(),
+			func(repos *repository.Repositories, event *model.OutboxEvent) error {
+				return b.deliver(ctx, repos, event)
+			},
+		)
+		if err != nil {
+			b.logger.Error("Failed to dispatch outbox events", zap.Error(err))
+			return
+		}
+
+		if dispatched < outboxBatchSize {
+			return
+		}
+	}
+}
+
+// deliver 將事件交給所有訂閱者；任一訂閱者失敗時整個事件稍後重送
+//
+// 轉送的 span 接續產生事件的請求，讓同一個追蹤包含寫入、廣播與 webhook 投遞
+func (b *eventBus) deliver(
+	ctx context.Context,
+	repos *repository.Repositories,
+	event *model.OutboxEvent,
+) error {
+	ctx, span := b.tracer.Start(
+		tracing.WithTraceParent(ctx, event.TraceParent),
+		"EventBus.deliver",
+		trace.WithAttributes(
+			attribute.Int64("outbox.event_id", int64(event.ID)),
+			attribute.String("outbox.event_type", event.Type),
+			attribute.Int("outbox.attempts", event.Attempts),
+		),
+	)
+	defer span.End()
+
+	logger := b.logger.With(tracing.LogFields(ctx)...)
+
+	b.mu.RLock()
+	subscribers := b.subscribers
+	b.mu.RUnlock()
+
+	for _, subscriber := range subscribers {
+		err := subscriber.HandleEvent(ctx, repos, event)
+		if err == nil {
+			continue
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "subscriber failed")
+
+		if event.Attempts+1 >= maxOutboxAttempts {
+			logger.Error(
+				"Dropping outbox event after repeated failures",
+				zap.Uint("eventID", event.ID),
+				zap.String("type", event.Type),
+				zap.Error(err),
+			)
+
+			return nil
+		}
+
+		logger.Warn(
+			"Outbox event delivery failed",
+			zap.Uint("eventID", event.ID),
+			zap.String("type", event.Type),
+			zap.Error(err),
+		)
+
+		return err
+	}
+
+	return nil
+}
+
+// webSocketEventTypes 領域事件與 WebSocket 訊息類型的對應
+var webSocketEventTypes = map[string]string{
+	model.EventMessageCreate: "new_message",
+	model.EventMessageUpdate: "message_update",
+	model.EventMessageDelete: "message_delete",
+	model.EventMemberJoin:    "member_join",
+	model.EventMemberLeave:   "member_leave",
+	model.EventMemberUpdate:  "member_update",
+	model.EventChannelCreate: "channel_create",
+	model.EventChannelUpdate: "channel_update",
+	model.EventChannelDelete: "channel_delete",
+	model.EventGuildUpdate:   "guild_update",
+}
+
+type webSocketSubscriber struct {
+	wsManager WebSocketManager
+}
+
+// NewWebSocketSubscriber 建立將領域事件推送給 WebSocket 客戶端的訂閱者
+func NewWebSocketSubscriber(wsManager WebSocketManager) EventSubscriber {
+	return &webSocketSubscriber{wsManager: wsManager}
+}
+
+// HandleEvent 依事件範圍廣播給訂閱該頻道或社群的客戶端
+func (s *webSocketSubscriber) HandleEvent(
+	ctx context.Context,
+	_ *repository.Repositories,
+	event *model.OutboxEvent,
+) error {
+	msgType, ok := webSocketEventTypes[event.Type]
+	if !ok {
+		return nil
+	}
+
+	data, err := publicEventData(event)
+	if err != nil {
+		return err
+	}
+
+	if event.ChannelID != nil {
+		s.wsManager.BroadcastToChannel(ctx, *event.ChannelID, msgType, data)
+	} else {
+		s.wsManager.BroadcastToGuild(ctx, event.GuildID, msgType, data)
+	}
+
+	return nil
+}
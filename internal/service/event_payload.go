@@ -0,0 +1,195 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/walnut-almonds/talkrealm/internal/model"
+)
+
+// EventUser 事件中公開的使用者資料，不含 email、刪除排程等私人欄位
+type EventUser struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username"`
+	Nickname string `json:"nickname"`
+	Avatar   string `json:"avatar"`
+	Status   string `json:"status"`
+	IsBot    bool   `json:"is_bot"`
+}
+
+// EventGuild guild.update 事件的內容
+type EventGuild struct {
+	ID          uint      `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Icon        string    `json:"icon"`
+	OwnerID     uint      `json:"owner_id"`
+	Owner       EventUser `json:"owner"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// EventChannel channel.* 事件的內容
+type EventChannel struct {
+	ID              uint      `json:"id"`
+	GuildID         uint      `json:"guild_id"`
+	Name            string    `json:"name"`
+	Type            string    `json:"type"`
+	ParentID        *uint     `json:"parent_id"`
+	Topic           string    `json:"topic"`
+	Position        int       `json:"position"`
+	SlowModeSeconds int       `json:"slow_mode_seconds"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// EventMessage message.create、message.update 與 announcement.published 事件的內容
+type EventMessage struct {
+	ID              uint       `json:"id"`
+	ChannelID       uint       `json:"channel_id"`
+	UserID          uint       `json:"user_id"`
+	User            EventUser  `json:"user"`
+	Content         string     `json:"content"`
+	Type            string     `json:"type"`
+	PublishedAt     *time.Time `json:"published_at,omitempty"`
+	SourceMessageID *uint      `json:"source_message_id,omitempty"`
+	SourceChannelID *uint      `json:"source_channel_id,omitempty"`
+	WebhookID       *uint      `json:"webhook_id,omitempty"`
+	AuthorName      string     `json:"author_name,omitempty"`
+	AuthorAvatar    string     `json:"author_avatar,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// EventMember member.join 與 member.update 事件的內容
+type EventMember struct {
+	ID           uint       `json:"id"`
+	GuildID      uint       `json:"guild_id"`
+	UserID       uint       `json:"user_id"`
+	User         EventUser  `json:"user"`
+	Nickname     string     `json:"nickname"`
+	Role         string     `json:"role"`
+	JoinedAt     time.Time  `json:"joined_at"`
+	TimeoutUntil *time.Time `json:"timeout_until"`
+}
+
+// publicEventData 將 outbox 中的事件內容轉為對外公開的格式
+//
+// outbox 保存的是寫入時的模型，其中的使用者資料包含 email 等私人欄位；
+// 推送給 WebSocket 客戶端與 webhook 前一律經過此轉換，只送出明確列出的欄位
+func publicEventData(event *model.OutboxEvent) (any, error) {
+	payload := []byte(event.Payload)
+
+	switch event.Type {
+	case model.EventGuildUpdate:
+		return decodeEventData(payload, newEventGuild)
+	case model.EventChannelCreate, model.EventChannelDelete:
+		return decodeEventData(payload, newEventChannel)
+	case model.EventChannelUpdate:
+		return decodeEventData(payload, func(channels []*model.Channel) []*EventChannel {
+			public := make([]*EventChannel, 0, len(channels))
+			for _, channel := range channels {
+				public = append(public, newEventChannel(channel))
+			}
+
+			return public
+		})
+	case model.EventMessageCreate, model.EventMessageUpdate, model.EventAnnouncementPublished:
+		return decodeEventData(payload, newEventMessage)
+	case model.EventMessageDelete:
+		return decodeEventData(payload, func(event *MessageDeleteEvent) *MessageDeleteEvent {
+			return event
+		})
+	case model.EventMemberJoin, model.EventMemberUpdate:
+		return decodeEventData(payload, newEventMember)
+	case model.EventMemberLeave:
+		return decodeEventData(payload, func(event *MemberLeaveEvent) *MemberLeaveEvent {
+			return event
+		})
+	default:
+		return nil, fmt.Errorf("no public payload for event type %q", event.Type)
+	}
+}
+
+// decodeEventData 解析事件內容後以 convert 轉為公開格式
+func decodeEventData[T, P any](payload []byte, convert func(T) P) (P, error) {
+	var data T
+	if err := json.Unmarshal(payload, &data); err != nil {
+		var zero P
+		return zero, fmt.Errorf("decode event payload: %w", err)
+	}
+
+	return convert(data), nil
+}
+
+func newEventUser(user *model.User) EventUser {
+	return EventUser{
+		ID:       user.ID,
+		Username: user.Username,
+		Nickname: user.Nickname,
+		Avatar:   user.Avatar,
+		Status:   user.Status,
+		IsBot:    user.IsBot,
+	}
+}
+
+func newEventGuild(guild *model.Guild) *EventGuild {
+	return &EventGuild{
+		ID:          guild.ID,
+		Name:        guild.Name,
+		Description: guild.Description,
+		Icon:        guild.Icon,
+		OwnerID:     guild.OwnerID,
+		Owner:       newEventUser(&guild.Owner),
+		CreatedAt:   guild.CreatedAt,
+		UpdatedAt:   guild.UpdatedAt,
+	}
+}
+
+func newEventChannel(channel *model.Channel) *EventChannel {
+	return &EventChannel{
+		ID:              channel.ID,
+		GuildID:         channel.GuildID,
+		Name:            channel.Name,
+		Type:            channel.Type,
+		ParentID:        channel.ParentID,
+		Topic:           channel.Topic,
+		Position:        channel.Position,
+		SlowModeSeconds: channel.SlowModeSeconds,
+		CreatedAt:       channel.CreatedAt,
+		UpdatedAt:       channel.UpdatedAt,
+	}
+}
+
+func newEventMessage(message *model.Message) *EventMessage {
+	return &EventMessage{
+		ID:              message.ID,
+		ChannelID:       message.ChannelID,
+		UserID:          message.UserID,
+		User:            newEventUser(&message.User),
+		Content:         message.Content,
+		Type:            message.Type,
+		PublishedAt:     message.PublishedAt,
+		SourceMessageID: message.SourceMessageID,
+		SourceChannelID: message.SourceChannelID,
+		WebhookID:       message.WebhookID,
+		AuthorName:      message.AuthorName,
+		AuthorAvatar:    message.AuthorAvatar,
+		CreatedAt:       message.CreatedAt,
+		UpdatedAt:       message.UpdatedAt,
+	}
+}
+
+func newEventMember(member *model.GuildMember) *EventMember {
+	return &EventMember{
+		ID:           member.ID,
+		GuildID:      member.GuildID,
+		UserID:       member.UserID,
+		User:         newEventUser(&member.User),
+		Nickname:     member.Nickname,
+		Role:         member.Role,
+		JoinedAt:     member.JoinedAt,
+		TimeoutUntil: member.TimeoutUntil,
+	}
+}
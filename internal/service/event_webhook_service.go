@@ -0,0 +1,803 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/walnut-almonds/talkrealm/internal/apperror"
+	"github.com/walnut-almonds/talkrealm/internal/model"
+	"github.com/walnut-almonds/talkrealm/internal/repository"
+	"github.com/walnut-almonds/talkrealm/pkg/clock"
+	"github.com/walnut-almonds/talkrealm/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxEventWebhooksPerGuild 每個社群可建立的傳出事件 webhook 上限
+	maxEventWebhooksPerGuild = 10
+	// eventDeliveryBatchSize 每次輪詢最多取出的投遞數量
+	eventDeliveryBatchSize = 100
+	// eventDeliveryConcurrency 同時進行的投遞請求數量
+	eventDeliveryConcurrency = 8
+	// maxDeliveryErrorLength 投遞紀錄中錯誤訊息的最大長度
+	maxDeliveryErrorLength = 512
+)
+
+// 傳出事件請求的標頭
+const (
+	EventHeaderType      = "X-TalkRealm-Event"
+	EventHeaderDelivery  = "X-TalkRealm-Delivery"
+	EventHeaderTimestamp = "X-TalkRealm-Timestamp"
+	EventHeaderSignature = "X-TalkRealm-Signature"
+)
+
+var (
+	ErrEventWebhookNotFound = apperror.NotFound(
+		"event_webhook_not_found",
+		"event webhook not found",
+	)
+	ErrInvalidEventType   = apperror.InvalidArgument("invalid_event_type", "invalid event type")
+	ErrInsecureWebhookURL = apperror.InvalidArgument(
+		"insecure_webhook_url",
+		"event webhook url must be an absolute https url",
+	)
+	ErrPrivateWebhookURL = apperror.InvalidArgument(
+		"private_webhook_url",
+		"event webhook url must not point to a private network address",
+	)
+	ErrTooManyEventWebhooks = apperror.InvalidArgument(
+		"too_many_event_webhooks",
+		"too many event webhooks in this guild",
+	)
+	errEventWebhookDisabled  = errors.New("endpoint disabled after repeated failures")
+	errEventWebhookRemoved   = errors.New("event webhook no longer exists")
+	errUnexpectedEventStatus = errors.New("endpoint responded with a non-2xx status")
+)
+
+// EventWebhookOptions 傳出事件投遞設定
+type EventWebhookOptions struct {
+	Timeout           time.Duration // 單次投遞請求的逾時
+	MaxAttempts       int           // 單一事件最多嘗試次數
+	RetryBackoff      time.Duration // 第一次重試前的等待時間，之後每次加倍
+	MaxRetryBackoff   time.Duration // 重試等待時間上限
+	DisableAfter      int           // 連續失敗幾次後自動停用 webhook，0 表示不停用
+	DeliveryRetention time.Duration // 已完成投遞紀錄的保留時間，0 表示永久保留
+	AllowInsecureURLs bool          // 允許 http 網址（僅供開發環境使用）
+	AllowPrivateIPs   bool          // 允許投遞到迴路與私有網路位址（僅供開發環境使用）
+}
+
+// CreateEventWebhookRequest 建立傳出事件 webhook 請求
+type CreateEventWebhookRequest struct {
+	URL    string   `json:"url"    binding:"required,url,max=2048"`
+	Events []string `json:"events" binding:"required,min=1"`
+}
+
+// UpdateEventWebhookRequest 更新傳出事件 webhook 請求
+//
+// 重新啟用 webhook 時會將連續失敗次數歸零
+type UpdateEventWebhookRequest struct {
+	URL          *string  `json:"url"           binding:"omitempty,url,max=2048"`
+	Events       []string `json:"events"        binding:"omitempty,min=1"`
+	Enabled      *bool    `json:"enabled"`
+	RotateSecret bool     `json:"rotate_secret"`
+}
+
+// EventWebhookResponse 傳出事件 webhook 資訊（不含簽章秘密）
+type EventWebhookResponse struct {
+	*model.EventWebhook
+	Events []string `json:"events"`
+}
+
+// EventWebhookSecretResponse 建立 webhook 或更換秘密時的回應，秘密只會在此時顯示一次
+type EventWebhookSecretResponse struct {
+	*EventWebhookResponse
+	Secret string `json:"secret,omitempty"`
+}
+
+// EventPayload 傳出事件的請求內容
+type EventPayload struct {
+	ID        string    `json:"id"` // 事件 ID，重試時不變，可用於去除重複
+	Type      string    `json:"type"`
+	GuildID   uint      `json:"guild_id"`
+	Timestamp time.Time `json:"timestamp"`
+	Data      any       `json:"data"`
+}
+
+// EventWebhookService 傳出事件 webhook 服務介面
+type EventWebhookService interface {
+	CreateEventWebhook(
+		ctx context.Context,
+		guildID, userID uint,
+		req *CreateEventWebhookRequest,
+	) (*EventWebhookSecretResponse, error)
+	ListEventWebhooks(ctx context.Context, guildID, userID uint) ([]*EventWebhookResponse, error)
+	UpdateEventWebhook(
+		ctx context.Context,
+		guildID, webhookID, userID uint,
+		req *UpdateEventWebhookRequest,
+	) (*EventWebhookSecretResponse, error)
+	DeleteEventWebhook(ctx context.Context, guildID, webhookID, userID uint) error
+	ListDeliveries(
+		ctx context.Context,
+		guildID, webhookID, userID uint,
+		limit int,
+	) ([]*model.EventDelivery, error)
+	HandleEvent(ctx context.Context, repos *repository.Repositories, event *model.OutboxEvent) error
+	DeliverPending(ctx context.Context) error
+	PurgeDeliveries(ctx context.Context) error
+}
+
+type eventWebhookService struct {
+	eventWebhookRepo repository.EventWebhookRepository
+	guildMemberRepo  repository.GuildMemberRepository
+	tx               repository.TxManager
+	client           *http.Client
+	options          EventWebhookOptions
+	tracer           trace.Tracer
+	clock            clock.Clock
+	logger           *zap.Logger
+}
+
+// NewEventWebhookService 建立傳出事件 webhook 服務
+func NewEventWebhookService(
+	eventWebhookRepo repository.EventWebhookRepository,
+	guildMemberRepo repository.GuildMemberRepository,
+	tx repository.TxManager,
+	options EventWebhookOptions,
+	tracer trace.Tracer,
+	clk clock.Clock,
+	logger *zap.Logger,
+) EventWebhookService {
+	return &eventWebhookService{
+		eventWebhookRepo: eventWebhookRepo,
+		guildMemberRepo:  guildMemberRepo,
+		tx:               tx,
+		client: &http.Client{
+			Timeout:   options.Timeout,
+			Transport: newEventWebhookTransport(options.AllowPrivateIPs),
+			// 不跟隨轉址，避免被導向未經驗證的網址
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		options: options,
+		tracer:  tracer,
+		clock:   clk,
+		logger:  logger,
+	}
+}
+
+// CreateEventWebhook 在社群建立傳出事件 webhook，需要管理 webhook 權限
+func (s *eventWebhookService) CreateEventWebhook(
+	ctx context.Context,
+	guildID, userID uint,
+	req *CreateEventWebhookRequest,
+) (*EventWebhookSecretResponse, error) {
+	if err := s.requireManageWebhooks(ctx, guildID, userID); err != nil {
+		return nil, err
+	}
+
+	if err := s.validateURL(req.URL); err != nil {
+		return nil, err
+	}
+
+	events, err := normalizeEventTypes(req.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := s.eventWebhookRepo.CountByGuildID(ctx, guildID)
+	if err != nil {
+		return nil, err
+	}
+
+	if count >= maxEventWebhooksPerGuild {
+		return nil, ErrTooManyEventWebhooks
+	}
+
+	secret, err := generateWebhookToken()
+	if err != nil {
+		return nil, err
+	}
+
+	webhook := &model.EventWebhook{
+		GuildID:     guildID,
+		URL:         req.URL,
+		Secret:      secret,
+		Events:      strings.Join(events, " "),
+		Enabled:     true,
+		CreatedByID: userID,
+		CreatedAt:   s.clock.Now(),
+		UpdatedAt:   s.clock.Now(),
+	}
+
+	err = s.tx.WithinTx(ctx, func(repos *repository.Repositories) error {
+		if err := repos.EventWebhooks.Create(ctx, webhook); err != nil {
+			return err
+		}
+
+		return repos.AuditLogs.Create(ctx, newAuditEntry(
+			ctx, s.clock.Now(), guildID, userID,
+			model.AuditActionEventWebhookCreate, model.AuditTargetEventWebhook, webhook.ID,
+			auditChanges{}.
+				set("url", nil, webhook.URL).
+				set("events", nil, webhook.Events),
+		))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &EventWebhookSecretResponse{
+		EventWebhookResponse: toEventWebhookResponse(webhook),
+		Secret:               secret,
+	}, nil
+}
+
+// ListEventWebhooks 列出社群的傳出事件 webhook
+func (s *eventWebhookService) ListEventWebhooks(
+	ctx context.Context,
+	guildID, userID uint,
+) ([]*EventWebhookResponse, error) {
+	if err := s.requireManageWebhooks(ctx, guildID, userID); err != nil {
+		return nil, err
+	}
+
+	webhooks, err := s.eventWebhookRepo.GetByGuildID(ctx, guildID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*EventWebhookResponse, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		responses = append(responses, toEventWebhookResponse(webhook))
+	}
+
+	return responses, nil
+}
+
+// UpdateEventWebhook 更新傳出事件 webhook 的網址、訂閱事件或啟用狀態
+func (s *eventWebhookService) UpdateEventWebhook(
+	ctx context.Context,
+	guildID, webhookID, userID uint,
+	req *UpdateEventWebhookRequest,
+) (*EventWebhookSecretResponse, error) {
+	webhook, err := s.getGuildWebhook(ctx, guildID, webhookID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := auditChanges{}
+
+	if req.URL != nil {
+		if err := s.validateURL(*req.URL); err != nil {
+			return nil, err
+		}
+
+		changes.set("url", webhook.URL, *req.URL)
+		webhook.URL = *req.URL
+	}
+
+	if req.Events != nil {
+		events, err := normalizeEventTypes(req.Events)
+		if err != nil {
+			return nil, err
+		}
+
+		joined := strings.Join(events, " ")
+		changes.set("events", webhook.Events, joined)
+		webhook.Events = joined
+	}
+
+	if req.Enabled != nil && *req.Enabled != webhook.Enabled {
+		changes.set("enabled", webhook.Enabled, *req.Enabled)
+		webhook.Enabled = *req.Enabled
+
+		if webhook.Enabled {
+			webhook.FailureCount = 0
+			webhook.DisabledAt = nil
+		} else {
+			now := s.clock.Now()
+			webhook.DisabledAt = &now
+		}
+	}
+
+	var secret string
+
+	if req.RotateSecret {
+		secret, err = generateWebhookToken()
+		if err != nil {
+			return nil, err
+		}
+
+		// 不記錄秘密本身，只記錄已更換
+		changes.set("secret_rotated", nil, true)
+		webhook.Secret = secret
+	}
+
+	webhook.UpdatedAt = s.clock.Now()
+
+	err = s.tx.WithinTx(ctx, func(repos *repository.Repositories) error {
+		if err := repos.EventWebhooks.Update(ctx, webhook); err != nil {
+			return err
+		}
+
+		if len(changes) == 0 {
+			return nil
+		}
+
+		return repos.AuditLogs.Create(ctx, newAuditEntry(
+			ctx, s.clock.Now(), guildID, userID,
+			model.AuditActionEventWebhookUpdate, model.AuditTargetEventWebhook, webhook.ID,
+			changes,
+		))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &EventWebhookSecretResponse{
+		EventWebhookResponse: toEventWebhookResponse(webhook),
+		Secret:               secret,
+	}, nil
+}
+
+// DeleteEventWebhook 刪除傳出事件 webhook 與其投遞紀錄
+func (s *eventWebhookService) DeleteEventWebhook(
+	ctx context.Context,
+	guildID, webhookID, userID uint,
+) error {
+	webhook, err := s.getGuildWebhook(ctx, guildID, webhookID, userID)
+	if err != nil {
+		return err
+	}
+
+	return s.tx.WithinTx(ctx, func(repos *repository.Repositories) error {
+		if err := repos.EventWebhooks.Delete(ctx, webhook.ID); err != nil {
+			return err
+		}
+
+		return repos.AuditLogs.Create(ctx, newAuditEntry(
+			ctx, s.clock.Now(), guildID, userID,
+			model.AuditActionEventWebhookDelete, model.AuditTargetEventWebhook, webhook.ID,
+			auditChanges{}.
+				set("url", webhook.URL, nil).
+				set("events", webhook.Events, nil),
+		))
+	})
+}
+
+// ListDeliveries 取得 webhook 最近的投遞紀錄
+func (s *eventWebhookService) ListDeliveries(
+	ctx context.Context,
+	guildID, webhookID, userID uint,
+	limit int,
+) ([]*model.EventDelivery, error) {
+	webhook, err := s.getGuildWebhook(ctx, guildID, webhookID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+
+	return s.eventWebhookRepo.ListDeliveries(ctx, webhook.ID, limit)
+}
+
+// HandleEvent 將領域事件排入所有訂閱該事件的 webhook 的投遞佇列
+//
+// 由事件匯流排呼叫；回傳錯誤時匯流排會重送，webhook 事件 ID 沿用 outbox 事件 ID，接收端可以此去除重複
+func (s *eventWebhookService) HandleEvent(
+	ctx context.Context,
+	repos *repository.Repositories,
+	event *model.OutboxEvent,
+) error {
+	webhooks, err := repos.EventWebhooks.GetEnabledByGuildID(ctx, event.GuildID)
+	if err != nil {
+		return err
+	}
+
+	webhooks = slices.DeleteFunc(webhooks, func(webhook *model.EventWebhook) bool {
+		return !slices.Contains(strings.Fields(webhook.Events), event.Type)
+	})
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	data, err := publicEventData(event)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(&EventPayload{
+		ID:        strconv.FormatUint(uint64(event.ID), 10),
+		Type:      event.Type,
+		GuildID:   event.GuildID,
+		Timestamp: event.CreatedAt,
+		Data:      data,
+	})
+	if err != nil {
+		return err
+	}
+
+	now := s.clock.Now()
+
+	deliveries := make([]*model.EventDelivery, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		deliveries = append(deliveries, &model.EventDelivery{
+			WebhookID:     webhook.ID,
+			EventType:     event.Type,
+			Payload:       string(payload),
+			TraceParent:   tracing.TraceParent(ctx),
+			Status:        model.DeliveryStatusPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		})
+	}
+
+	return repos.EventWebhooks.CreateDeliveries(ctx, deliveries)
+}
+
+// DeliverPending 投遞所有到期的事件（由排程器呼叫）
+func (s *eventWebhookService) DeliverPending(ctx context.Context) error {
+	// 租約需涵蓋整批投遞的最長時間，避免其他副本在處理中重複取出
+	now := s.clock.Now()
+	lease := s.options.Timeout*eventDeliveryBatchSize/eventDeliveryConcurrency + time.Minute
+
+	deliveries, err := s.eventWebhookRepo.ClaimDueDeliveries(
+		ctx,
+		now,
+		now.Add(lease),
+		eventDeliveryBatchSize,
+	)
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+
+	slots := make(chan struct{}, eventDeliveryConcurrency)
+	webhooks := make(map[uint]*model.EventWebhook)
+
+	for _, delivery := range deliveries {
+		if ctx.Err() != nil {
+			break
+		}
+
+		webhook, ok := webhooks[delivery.WebhookID]
+		if !ok {
+			webhook, _ = s.eventWebhookRepo.GetByID(ctx, delivery.WebhookID)
+			webhooks[delivery.WebhookID] = webhook
+		}
+
+		slots <- struct{}{}
+
+		wg.Go(func() {
+			defer func() { <-slots }()
+
+			s.deliver(ctx, webhook, delivery)
+		})
+	}
+
+	wg.Wait()
+
+	return nil
+}
+
+// PurgeDeliveries 刪除超過保留期限的投遞紀錄（由排程器呼叫）
+func (s *eventWebhookService) PurgeDeliveries(ctx context.Context) error {
+	if s.options.DeliveryRetention <= 0 {
+		return nil
+	}
+
+	deleted, err := s.eventWebhookRepo.DeleteDeliveriesBefore(
+		ctx,
+		s.clock.Now().Add(-s.options.DeliveryRetention),
+	)
+	if err != nil {
+		return err
+	}
+
+	if deleted > 0 {
+		s.logger.Info("Event deliveries purged", zap.Int64("count", deleted))
+	}
+
+	return nil
+}
+
+// deliver 投遞單一事件並依結果安排重試或停用 webhook
+//
+// 投遞的 span 接續產生事件的請求，並以 traceparent 標頭傳給接收端
+func (s *eventWebhookService) deliver(
+	ctx context.Context,
+	webhook *model.EventWebhook,
+	delivery *model.EventDelivery,
+) {
+	ctx, span := s.tracer.Start(
+		tracing.WithTraceParent(ctx, delivery.TraceParent),
+		"EventWebhook.deliver",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.Int64("event_webhook.id", int64(delivery.WebhookID)),
+			attribute.Int64("event_webhook.delivery_id", int64(delivery.ID)),
+			attribute.String("event_webhook.event_type", delivery.EventType),
+			attribute.Int("event_webhook.attempt", delivery.Attempts+1),
+		),
+	)
+	defer span.End()
+
+	now := s.clock.Now()
+
+	switch {
+	case webhook == nil:
+		s.finishDelivery(ctx, delivery, model.DeliveryStatusFailed, errEventWebhookRemoved)
+		return
+	case !webhook.Enabled:
+		s.finishDelivery(ctx, delivery, model.DeliveryStatusFailed, errEventWebhookDisabled)
+		return
+	}
+
+	delivery.Attempts++
+	status, err := s.send(ctx, webhook, delivery)
+	delivery.ResponseStatus = status
+
+	if status > 0 {
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+	}
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "delivery failed")
+	}
+
+	if err == nil {
+		delivery.DeliveredAt = &now
+		s.finishDelivery(ctx, delivery, model.DeliveryStatusSucceeded, nil)
+
+		if err := s.eventWebhookRepo.ResetFailures(ctx, webhook.ID); err != nil {
+			s.logger.Error(
+				"Failed to reset event webhook failures",
+				zap.Uint("webhookID", webhook.ID),
+				zap.Error(err),
+			)
+		}
+
+		return
+	}
+
+	if delivery.Attempts >= s.options.MaxAttempts {
+		s.finishDelivery(ctx, delivery, model.DeliveryStatusFailed, err)
+	} else {
+		delivery.NextAttemptAt = now.Add(s.retryBackoff(delivery.Attempts))
+		s.finishDelivery(ctx, delivery, model.DeliveryStatusPending, err)
+	}
+
+	disabled, recordErr := s.eventWebhookRepo.RecordFailure(
+		ctx,
+		webhook.ID,
+		s.options.DisableAfter,
+		now,
+	)
+	if recordErr != nil {
+		s.logger.Error(
+			"Failed to record event webhook failure",
+			zap.Uint("webhookID", webhook.ID),
+			zap.Error(recordErr),
+		)
+
+		return
+	}
+
+	if disabled {
+		s.logger.Warn(
+			"Event webhook disabled after repeated failures",
+			zap.Uint("webhookID", webhook.ID),
+			zap.Uint("guildID", webhook.GuildID),
+		)
+
+		err := s.eventWebhookRepo.FailPendingDeliveries(
+			ctx,
+			webhook.ID,
+			errEventWebhookDisabled.Error(),
+			s.clock.Now(),
+		)
+		if err != nil {
+			s.logger.Error(
+				"Failed to cancel pending event deliveries",
+				zap.Uint("webhookID", webhook.ID),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// send 以 HMAC-SHA256 簽章送出事件，回傳 HTTP 狀態碼
+func (s *eventWebhookService) send(
+	ctx context.Context,
+	webhook *model.EventWebhook,
+	delivery *model.EventDelivery,
+) (int, error) {
+	body := []byte(delivery.Payload)
+	timestamp := strconv.FormatInt(s.clock.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "TalkRealm-Webhooks/1.0")
+	req.Header.Set(EventHeaderType, delivery.EventType)
+	req.Header.Set(EventHeaderDelivery, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(EventHeaderTimestamp, timestamp)
+	req.Header.Set(
+		EventHeaderSignature,
+		"sha256="+SignEventPayload(webhook.Secret, timestamp, body),
+	)
+	tracing.Propagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// 讀完部分回應內容，讓連線可以重複使用
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("%w: %d", errUnexpectedEventStatus, resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// finishDelivery 寫入投遞結果
+func (s *eventWebhookService) finishDelivery(
+	ctx context.Context,
+	delivery *model.EventDelivery,
+	status string,
+	deliveryErr error,
+) {
+	delivery.Status = status
+	delivery.LastError = ""
+
+	if deliveryErr != nil {
+		delivery.LastError = deliveryErr.Error()
+		if runes := []rune(delivery.LastError); len(runes) > maxDeliveryErrorLength {
+			delivery.LastError = string(runes[:maxDeliveryErrorLength])
+		}
+	}
+
+	delivery.UpdatedAt = s.clock.Now()
+
+	if err := s.eventWebhookRepo.UpdateDelivery(ctx, delivery); err != nil {
+		s.logger.Error(
+			"Failed to update event delivery",
+			zap.Uint("deliveryID", delivery.ID),
+			zap.Error(err),
+		)
+	}
+}
+
+// retryBackoff 計算第 attempts 次失敗後的等待時間（指數退避加上最多 10% 的隨機延遲）
+func (s *eventWebhookService) retryBackoff(attempts int) time.Duration {
+	backoff := s.options.RetryBackoff
+	for i := 1; i < attempts && backoff < s.options.MaxRetryBackoff; i++ {
+		backoff *= 2
+	}
+
+	backoff = min(backoff, s.options.MaxRetryBackoff)
+
+	return backoff + rand.N(backoff/10+1)
+}
+
+// validateURL 確認 webhook 網址為 https（開發環境可允許 http），且不指向內部網路
+//
+// 主機名稱解析到的位址在每次投遞連線時才檢查（見 newEventWebhookTransport）
+func (s *eventWebhookService) validateURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" {
+		return ErrInsecureWebhookURL
+	}
+
+	if !s.options.AllowPrivateIPs && isPrivateHost(parsed.Hostname()) {
+		return ErrPrivateWebhookURL
+	}
+
+	if parsed.Scheme == "https" || (parsed.Scheme == "http" && s.options.AllowInsecureURLs) {
+		return nil
+	}
+
+	return ErrInsecureWebhookURL
+}
+
+// getGuildWebhook 取得社群中的傳出事件 webhook，並確認使用者有管理 webhook 權限
+func (s *eventWebhookService) getGuildWebhook(
+	ctx context.Context,
+	guildID, webhookID, userID uint,
+) (*model.EventWebhook, error) {
+	if err := s.requireManageWebhooks(ctx, guildID, userID); err != nil {
+		return nil, err
+	}
+
+	webhook, err := s.eventWebhookRepo.GetByID(ctx, webhookID)
+	if err != nil || webhook.GuildID != guildID {
+		return nil, ErrEventWebhookNotFound
+	}
+
+	return webhook, nil
+}
+
+// requireManageWebhooks 確認使用者在社群中擁有管理 webhook 權限
+func (s *eventWebhookService) requireManageWebhooks(
+	ctx context.Context,
+	guildID, userID uint,
+) error {
+	member, err := s.guildMemberRepo.GetMember(ctx, guildID, userID)
+	if err != nil || member == nil {
+		return ErrNotGuildMember
+	}
+
+	if !hasPermission(member.Role, PermissionManageWebhooks) {
+		return ErrMissingPermission
+	}
+
+	return nil
+}
+
+// SignEventPayload 計算傳出事件的簽章：HMAC-SHA256(secret, timestamp + "." + body) 的十六進位值
+func SignEventPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// normalizeEventTypes 驗證事件類型並移除重複
+func normalizeEventTypes(events []string) ([]string, error) {
+	normalized := make([]string, 0, len(events))
+
+	for _, event := range events {
+		if !slices.Contains(model.EventTypes, event) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidEventType, event)
+		}
+
+		if !slices.Contains(normalized, event) {
+			normalized = append(normalized, event)
+		}
+	}
+
+	return normalized, nil
+}
+
+// toEventWebhookResponse 轉換為不含秘密的回應
+func toEventWebhookResponse(webhook *model.EventWebhook) *EventWebhookResponse {
+	return &EventWebhookResponse{
+		EventWebhook: webhook,
+		Events:       strings.Fields(webhook.Events),
+	}
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/walnut-almonds/talkrealm/internal/model"
+	"go.opentelemetry.io/otel/trace/noop"
+	"go.uber.org/zap"
+)
+
+func (e *testEnv) eventWebhookService() EventWebhookService {
+	return NewEventWebhookService(
+		e.repos.EventWebhooks,
+		e.repos.GuildMembers,
+		e.tx,
+		EventWebhookOptions{
+			Timeout:           5 * time.Second,
+			MaxAttempts:       1,
+			AllowInsecureURLs: true,
+			AllowPrivateIPs:   true,
+		},
+		noop.NewTracerProvider().Tracer("test"),
+		e.clock,
+		zap.NewNop(),
+	)
+}
+
+// findKey 回傳 JSON 值中是否有任何一層物件包含 key
+func findKey(value any, key string) bool {
+	switch v := value.(type) {
+	case map[string]any:
+		for k, child := range v {
+			if k == key || findKey(child, key) {
+				return true
+			}
+		}
+	case []any:
+		for _, child := range v {
+			if findKey(child, key) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func TestEventWebhookPayloadOmitsPrivateUserFields(t *testing.T) {
+	env := newTestEnv(t)
+	owner := env.createUser("owner")
+	member := env.createUser("member")
+	guild := env.createGuild(owner, member)
+	channel := env.createChannel(
+		guild.ID,
+		owner.ID,
+		&CreateChannelRequest{Name: "general", Type: "text"},
+	)
+
+	if _, err := env.messageService().CreateMessage(env.ctx, member.ID, &CreateMessageRequest{
+		ChannelID: channel.ID,
+		Content:   "hello",
+	}); err != nil {
+		t.Fatalf("CreateMessage() = %v", err)
+	}
+
+	if _, err := env.guildService().
+		UpdateGuild(env.ctx, guild.ID, owner.ID, &UpdateGuildRequest{Name: "renamed"}); err != nil {
+		t.Fatalf("UpdateGuild() = %v", err)
+	}
+
+	var (
+		mu     sync.Mutex
+		bodies = map[string][]byte{}
+	)
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		mu.Lock()
+		bodies[r.Header.Get("X-TalkRealm-Event")] = body
+		mu.Unlock()
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	webhooks := env.eventWebhookService()
+	if _, err := webhooks.CreateEventWebhook(env.ctx, guild.ID, owner.ID, &CreateEventWebhookRequest{
+		URL: receiver.URL,
+		Events: []string{
+			model.EventMemberJoin,
+			model.EventMessageCreate,
+			model.EventGuildUpdate,
+			model.EventChannelCreate,
+		},
+	}); err != nil {
+		t.Fatalf("CreateEventWebhook() = %v", err)
+	}
+
+	var events []*model.OutboxEvent
+	if err := env.db.Order("id").Find(&events).Error; err != nil {
+		t.Fatalf("list outbox events: %v", err)
+	}
+
+	for _, event := range events {
+		if err := webhooks.HandleEvent(env.ctx, env.repos, event); err != nil {
+			t.Fatalf("HandleEvent(%s) = %v", event.Type, err)
+		}
+	}
+
+	if err := webhooks.DeliverPending(env.ctx); err != nil {
+		t.Fatalf("DeliverPending() = %v", err)
+	}
+
+	for _, eventType := range []string{
+		model.EventMemberJoin,
+		model.EventMessageCreate,
+		model.EventGuildUpdate,
+		model.EventChannelCreate,
+	} {
+		body, ok := bodies[eventType]
+		if !ok {
+			t.Errorf("%s was not delivered", eventType)
+			continue
+		}
+
+		var payload map[string]any
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Fatalf("unmarshal %s body %s: %v", eventType, body, err)
+		}
+
+		if payload["data"] == nil {
+			t.Errorf("%s body has no data: %s", eventType, body)
+		}
+
+		if findKey(payload, "email") || strings.Contains(string(body), "@example.com") {
+			t.Errorf("%s body leaks email: %s", eventType, body)
+		}
+	}
+
+	var message struct {
+		Data EventMessage `json:"data"`
+	}
+	if err := json.Unmarshal(bodies[model.EventMessageCreate], &message); err != nil {
+		t.Fatalf("unmarshal message.create: %v", err)
+	}
+
+	if message.Data.Content != "hello" || message.Data.User.Username != member.Username {
+		t.Errorf("message.create data = %+v, want content and author", message.Data)
+	}
+}
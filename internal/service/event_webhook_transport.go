@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// errPrivateAddress 傳出事件 webhook 的網址解析到內部網路位址
+var errPrivateAddress = errors.New("event webhook address is not publicly routable")
+
+// sharedAddressSpace 電信業者級 NAT 使用的位址範圍（RFC 6598），netip 不視為私有位址
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// newEventWebhookTransport 建立投遞 webhook 用的 HTTP transport
+//
+// allowPrivate 為 false 時，在連線建立前檢查實際連線的 IP，拒絕迴路、私有、鏈路本地與未指定位址；
+// 檢查發生在 DNS 解析之後，因此也能防止 DNS rebinding。不使用環境變數的 proxy，避免繞過檢查
+func newEventWebhookTransport(allowPrivate bool) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+
+	if !allowPrivate {
+		dialer.ControlContext = func(_ context.Context, _, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+
+			if !isPublicAddress(addrPort.Addr()) {
+				return errPrivateAddress
+			}
+
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return transport
+}
+
+// isPublicAddress 檢查位址是否可以從網際網路路由
+func isPublicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	return addr.IsValid() &&
+		!addr.IsLoopback() &&
+		!addr.IsPrivate() &&
+		!addr.IsLinkLocalUnicast() &&
+		!addr.IsLinkLocalMulticast() &&
+		!addr.IsInterfaceLocalMulticast() &&
+		!addr.IsMulticast() &&
+		!addr.IsUnspecified() &&
+		!sharedAddressSpace.Contains(addr)
+}
+
+// isPrivateHost 網址的主機名稱明顯指向內部網路（IP 位址或 localhost），建立時即可拒絕
+func isPrivateHost(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+
+	return !isPublicAddress(addr)
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestIsPublicAddress(t *testing.T) {
+	cases := map[string]bool{
+		"8.8.8.8":          true,
+		"2606:4700::1111":  true,
+		"127.0.0.1":        false,
+		"10.1.2.3":         false,
+		"172.16.0.1":       false,
+		"192.168.1.1":      false,
+		"169.254.169.254":  false,
+		"100.64.0.1":       false,
+		"0.0.0.0":          false,
+		"::1":              false,
+		"fe80::1":          false,
+		"fc00::1":          false,
+		"::ffff:127.0.0.1": false,
+	}
+
+	for raw, want := range cases {
+		if got := isPublicAddress(netip.MustParseAddr(raw)); got != want {
+			t.Errorf("isPublicAddress(%s) = %v, want %v", raw, got, want)
+		}
+	}
+}
+
+func TestIsPrivateHost(t *testing.T) {
+	cases := map[string]bool{
+		"example.com":     false,
+		"localhost":       true,
+		"api.localhost.":  true,
+		"127.0.0.1":       true,
+		"169.254.169.254": true,
+		"::1":             true,
+		"93.184.216.34":   false,
+	}
+
+	for host, want := range cases {
+		if got := isPrivateHost(host); got != want {
+			t.Errorf("isPrivateHost(%s) = %v, want %v", host, got, want)
+		}
+	}
+}
+
+func TestEventWebhookTransportRejectsPrivateAddressAtDial(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: newEventWebhookTransport(false)}
+
+	resp, err := client.Get(server.URL)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatal("expected request to loopback address to be rejected")
+	}
+
+	if !errors.Is(err, errPrivateAddress) {
+		t.Fatalf("expected errPrivateAddress, got %v", err)
+	}
+
+	client = &http.Client{Transport: newEventWebhookTransport(true)}
+
+	resp, err = client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("expected request to succeed when private addresses are allowed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("unexpected status %d", resp.StatusCode)
+	}
+}
@@ -1,19 +1,56 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"time"
 
+	"github.com/walnut-almonds/talkrealm/internal/apperror"
 	"github.com/walnut-almonds/talkrealm/internal/model"
 	"github.com/walnut-almonds/talkrealm/internal/repository"
+	"github.com/walnut-almonds/talkrealm/pkg/clock"
+	"go.uber.org/zap"
 )
 
 var (
-	ErrGuildNotFound      = errors.New("guild not found")
-	ErrNotGuildOwner      = errors.New("not guild owner")
-	ErrAlreadyInGuild     = errors.New("already in guild")
-	ErrNotGuildMember     = errors.New("not guild member")
-	ErrCannotLeaveAsOwner = errors.New("owner cannot leave guild, transfer ownership first")
+	ErrGuildNotFound      = apperror.NotFound("guild_not_found", "guild not found")
+	ErrNotGuildOwner      = apperror.Forbidden("not_guild_owner", "not guild owner")
+	ErrAlreadyInGuild     = apperror.Conflict("already_in_guild", "already in guild")
+	ErrNotGuildMember     = apperror.Forbidden("not_guild_member", "not guild member")
+	ErrCannotLeaveAsOwner = apperror.Forbidden(
+		"owner_cannot_leave",
+		"owner cannot leave guild, transfer ownership first",
+	)
+	ErrInvalidNewOwner   = apperror.InvalidArgument("invalid_new_owner", "invalid new owner")
+	ErrGuildOwnerChanged = apperror.Conflict(
+		"guild_owner_changed",
+		"guild owner changed, reload and try again",
+	)
+	ErrBannedFromGuild   = apperror.Forbidden("banned_from_guild", "banned from guild")
+	ErrBanNotFound       = apperror.NotFound("ban_not_found", "ban not found")
+	ErrMissingPermission = apperror.Forbidden("missing_permission", "missing guild permission")
+	ErrRoleHierarchy     = apperror.Forbidden(
+		"role_hierarchy",
+		"cannot moderate a member with an equal or higher role",
+	)
+	ErrMemberTimedOut       = apperror.Forbidden("member_timed_out", "member is timed out")
+	ErrTargetNotGuildMember = apperror.NotFound(
+		"target_not_guild_member",
+		"target user is not a guild member",
+	)
+	ErrCannotKickSelf      = apperror.InvalidArgument("cannot_kick_self", "cannot kick yourself")
+	ErrCannotChangeOwnRole = apperror.InvalidArgument(
+		"cannot_change_own_role",
+		"cannot modify your own role",
+	)
+)
+
+const (
+	// expiredBanBatchSize 每次排程最多解除的到期封鎖數量
+	expiredBanBatchSize = 100
+	// expiredTimeoutBatchSize 每次排程最多清除的到期禁言數量
+	expiredTimeoutBatchSize = 100
 )
 
 // CreateGuildRequest 建立社群請求
@@ -30,61 +67,99 @@ type UpdateGuildRequest struct {
 	Icon        string `json:"icon"        binding:"max=256"`
 }
 
+// TransferOwnershipRequest 轉移社群擁有權請求
+type TransferOwnershipRequest struct {
+	NewOwnerID uint      `json:"new_owner_id" binding:"required"`
+	Password   string    `json:"password"` // 有本地密碼的帳號必填，以 SSO 建立的帳號改以最近的登入確認
+	AuthTime   time.Time `json:"-"`        // 目前登入的時間，由 handler 從 JWT 填入
+}
+
 // GuildService 社群服務介面
 type GuildService interface {
-	CreateGuild(ownerID uint, req *CreateGuildRequest) (*model.Guild, error)
-	GetGuild(guildID uint) (*model.Guild, error)
-	ListUserGuilds(userID uint) ([]*model.Guild, error)
-	UpdateGuild(guildID, userID uint, req *UpdateGuildRequest) (*model.Guild, error)
-	DeleteGuild(guildID, userID uint) error
-	IsGuildOwner(guildID, userID uint) (bool, error)
-	IsGuildMember(guildID, userID uint) (bool, error)
+	CreateGuild(ctx context.Context, ownerID uint, req *CreateGuildRequest) (*model.Guild, error)
+	GetGuild(ctx context.Context, guildID uint) (*model.Guild, error)
+	ListUserGuilds(ctx context.Context, userID uint) ([]*model.Guild, error)
+	UpdateGuild(
+		ctx context.Context,
+		guildID, userID uint,
+		req *UpdateGuildRequest,
+	) (*model.Guild, error)
+	DeleteGuild(ctx context.Context, guildID, userID uint) error
+	IsGuildOwner(ctx context.Context, guildID, userID uint) (bool, error)
+	IsGuildMember(ctx context.Context, guildID, userID uint) (bool, error)
+	TransferOwnership(
+		ctx context.Context,
+		guildID, ownerID uint,
+		req *TransferOwnershipRequest,
+	) (*model.Guild, error)
 }
 
 type guildService struct {
 	guildRepo       repository.GuildRepository
 	guildMemberRepo repository.GuildMemberRepository
+	userRepo        repository.UserRepository
+	tx              repository.TxManager
+	events          EventBus
+	reauthWindow    time.Duration
+	clock           clock.Clock
+	logger          *zap.Logger
 }
 
 // NewGuildService 建立社群服務
 func NewGuildService(
 	guildRepo repository.GuildRepository,
 	guildMemberRepo repository.GuildMemberRepository,
+	userRepo repository.UserRepository,
+	tx repository.TxManager,
+	events EventBus,
+	reauthWindow time.Duration,
+	clk clock.Clock,
+	logger *zap.Logger,
 ) GuildService {
 	return &guildService{
 		guildRepo:       guildRepo,
 		guildMemberRepo: guildMemberRepo,
+		userRepo:        userRepo,
+		tx:              tx,
+		events:          events,
+		reauthWindow:    reauthWindow,
+		clock:           clk,
+		logger:          logger,
 	}
 }
 
 // CreateGuild 建立社群
-func (s *guildService) CreateGuild(ownerID uint, req *CreateGuildRequest) (*model.Guild, error) {
+func (s *guildService) CreateGuild(
+	ctx context.Context,
+	ownerID uint,
+	req *CreateGuildRequest,
+) (*model.Guild, error) {
 	guild := &model.Guild{
 		Name:        req.Name,
 		Description: req.Description,
 		Icon:        req.Icon,
 		OwnerID:     ownerID,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
-	}
-
-	if err := s.guildRepo.Create(guild); err != nil {
-		return nil, err
+		CreatedAt:   s.clock.Now(),
+		UpdatedAt:   s.clock.Now(),
 	}
 
-	// 自動將擁有者加入為成員
-	member := &model.GuildMember{
-		GuildID:   guild.ID,
-		UserID:    ownerID,
-		Role:      "owner",
-		JoinedAt:  time.Now(),
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-	}
+	// 社群與擁有者的成員資格在同一個交易中建立
+	err := s.tx.WithinTx(ctx, func(repos *repository.Repositories) error {
+		if err := repos.Guilds.Create(ctx, guild); err != nil {
+			return err
+		}
 
-	if err := s.guildMemberRepo.Create(member); err != nil {
-		// 如果加入成員失敗，刪除已建立的社群
-		_ = s.guildRepo.Delete(guild.ID)
+		// 自動將擁有者加入為成員
+		return repos.GuildMembers.Create(ctx, &model.GuildMember{
+			GuildID:   guild.ID,
+			UserID:    ownerID,
+			Role:      "owner",
+			JoinedAt:  s.clock.Now(),
+			CreatedAt: s.clock.Now(),
+			UpdatedAt: s.clock.Now(),
+		})
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -92,8 +167,8 @@ func (s *guildService) CreateGuild(ownerID uint, req *CreateGuildRequest) (*mode
 }
 
 // GetGuild 取得社群詳情
-func (s *guildService) GetGuild(guildID uint) (*model.Guild, error) {
-	guild, err := s.guildRepo.GetByID(guildID)
+func (s *guildService) GetGuild(ctx context.Context, guildID uint) (*model.Guild, error) {
+	guild, err := s.guildRepo.GetByID(ctx, guildID)
 	if err != nil {
 		return nil, ErrGuildNotFound
 	}
@@ -102,17 +177,18 @@ func (s *guildService) GetGuild(guildID uint) (*model.Guild, error) {
 }
 
 // ListUserGuilds 列出使用者所屬的所有社群
-func (s *guildService) ListUserGuilds(userID uint) ([]*model.Guild, error) {
-	return s.guildRepo.GetMemberGuilds(userID, 0, 100)
+func (s *guildService) ListUserGuilds(ctx context.Context, userID uint) ([]*model.Guild, error) {
+	return s.guildRepo.GetMemberGuilds(ctx, userID, 0, 100)
 }
 
 // UpdateGuild 更新社群資訊
 func (s *guildService) UpdateGuild(
+	ctx context.Context,
 	guildID, userID uint,
 	req *UpdateGuildRequest,
 ) (*model.Guild, error) {
 	// 檢查是否為擁有者
-	isOwner, err := s.IsGuildOwner(guildID, userID)
+	isOwner, err := s.IsGuildOwner(ctx, guildID, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -122,37 +198,61 @@ func (s *guildService) UpdateGuild(
 	}
 
 	// 取得社群
-	guild, err := s.guildRepo.GetByID(guildID)
+	guild, err := s.guildRepo.GetByID(ctx, guildID)
 	if err != nil {
 		return nil, ErrGuildNotFound
 	}
 
+	changes := auditChanges{}
+
 	// 更新欄位
 	if req.Name != "" {
+		changes.set("name", guild.Name, req.Name)
 		guild.Name = req.Name
 	}
 
 	if req.Description != "" {
+		changes.set("description", guild.Description, req.Description)
 		guild.Description = req.Description
 	}
 
 	if req.Icon != "" {
+		changes.set("icon", guild.Icon, req.Icon)
 		guild.Icon = req.Icon
 	}
 
-	guild.UpdatedAt = time.Now()
+	guild.UpdatedAt = s.clock.Now()
 
-	if err := s.guildRepo.Update(guild); err != nil {
+	err = s.tx.WithinTx(ctx, func(repos *repository.Repositories) error {
+		if err := repos.Guilds.Update(ctx, guild); err != nil {
+			return err
+		}
+
+		if len(changes) > 0 {
+			if err := repos.AuditLogs.Create(ctx, newAuditEntry(
+				ctx, s.clock.Now(), guildID, userID,
+				model.AuditActionGuildUpdate, model.AuditTargetGuild, guildID,
+				changes,
+			)); err != nil {
+				return err
+			}
+		}
+
+		return repos.Outbox.Append(ctx, s.events.GuildEvent(guildID, model.EventGuildUpdate, guild))
+	})
+	if err != nil {
 		return nil, err
 	}
 
+	s.events.Notify()
+
 	return guild, nil
 }
 
 // DeleteGuild 刪除社群
-func (s *guildService) DeleteGuild(guildID, userID uint) error {
+func (s *guildService) DeleteGuild(ctx context.Context, guildID, userID uint) error {
 	// 檢查是否為擁有者
-	isOwner, err := s.IsGuildOwner(guildID, userID)
+	isOwner, err := s.IsGuildOwner(ctx, guildID, userID)
 	if err != nil {
 		return err
 	}
@@ -161,13 +261,23 @@ func (s *guildService) DeleteGuild(guildID, userID uint) error {
 		return ErrNotGuildOwner
 	}
 
-	// 刪除社群（會級聯刪除成員、頻道等）
-	return s.guildRepo.Delete(guildID)
+	// 刪除社群（會級聯刪除成員、頻道等）與稽核紀錄寫在同一個交易中
+	return s.tx.WithinTx(ctx, func(repos *repository.Repositories) error {
+		if err := repos.Guilds.Delete(ctx, guildID); err != nil {
+			return err
+		}
+
+		return repos.AuditLogs.Create(ctx, newAuditEntry(
+			ctx, s.clock.Now(), guildID, userID,
+			model.AuditActionGuildDelete, model.AuditTargetGuild, guildID,
+			nil,
+		))
+	})
 }
 
 // IsGuildOwner 檢查是否為社群擁有者
-func (s *guildService) IsGuildOwner(guildID, userID uint) (bool, error) {
-	guild, err := s.guildRepo.GetByID(guildID)
+func (s *guildService) IsGuildOwner(ctx context.Context, guildID, userID uint) (bool, error) {
+	guild, err := s.guildRepo.GetByID(ctx, guildID)
 	if err != nil {
 		return false, ErrGuildNotFound
 	}
@@ -176,8 +286,8 @@ func (s *guildService) IsGuildOwner(guildID, userID uint) (bool, error) {
 }
 
 // IsGuildMember 檢查是否為社群成員
-func (s *guildService) IsGuildMember(guildID, userID uint) (bool, error) {
-	member, err := s.guildMemberRepo.GetMember(guildID, userID)
+func (s *guildService) IsGuildMember(ctx context.Context, guildID, userID uint) (bool, error) {
+	member, err := s.guildMemberRepo.GetMember(ctx, guildID, userID)
 	if err != nil {
 		return false, nil //nolint:nilerr // 成員不存在不是錯誤
 	}
@@ -185,63 +295,248 @@ func (s *guildService) IsGuildMember(guildID, userID uint) (bool, error) {
 	return member != nil, nil
 }
 
+// TransferOwnership 確認擁有者身分後，將社群擁有權轉移給其他成員
+//
+// 原擁有者會降為管理員，轉移與稽核紀錄在同一個交易中完成
+func (s *guildService) TransferOwnership(
+	ctx context.Context,
+	guildID, ownerID uint,
+	req *TransferOwnershipRequest,
+) (*model.Guild, error) {
+	guild, err := s.guildRepo.GetByID(ctx, guildID)
+	if err != nil {
+		return nil, ErrGuildNotFound
+	}
+
+	if guild.OwnerID != ownerID {
+		return nil, ErrNotGuildOwner
+	}
+
+	owner, err := s.userRepo.GetByID(ctx, ownerID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	if err := confirmIdentity(
+		owner,
+		req.Password,
+		req.AuthTime,
+		s.clock.Now(),
+		s.reauthWindow,
+	); err != nil {
+		return nil, err
+	}
+
+	if req.NewOwnerID == ownerID {
+		return nil, ErrInvalidNewOwner
+	}
+
+	if member, err := s.guildMemberRepo.GetMember(ctx, guildID, req.NewOwnerID); err != nil ||
+		member == nil {
+		return nil, ErrInvalidNewOwner.WithMessage("new owner is not a member of this guild")
+	}
+
+	// 機器人帳號不能成為擁有者
+	newOwner, err := s.userRepo.GetByID(ctx, req.NewOwnerID)
+	if err != nil || newOwner.IsBot {
+		return nil, ErrInvalidNewOwner
+	}
+
+	entry := newAuditEntry(
+		ctx, s.clock.Now(), guildID, ownerID,
+		model.AuditActionGuildOwnerTransfer, model.AuditTargetUser, req.NewOwnerID,
+		auditChanges{}.set("owner_id", ownerID, req.NewOwnerID),
+	)
+
+	now := s.clock.Now()
+
+	err = s.tx.WithinTx(ctx, func(repos *repository.Repositories) error {
+		err := repos.Guilds.UpdateOwner(ctx, guildID, ownerID, req.NewOwnerID, now)
+		if errors.Is(err, repository.ErrConflict) {
+			// 同時進行的另一次轉移已先完成
+			return ErrGuildOwnerChanged
+		}
+
+		if err != nil {
+			return err
+		}
+
+		err = repos.GuildMembers.UpdateRole(ctx, guildID, req.NewOwnerID, "owner", now)
+		if errors.Is(err, repository.ErrNotFound) {
+			// 新擁有者在檢查後離開了社群
+			return ErrInvalidNewOwner.WithMessage("new owner is not a member of this guild")
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if err := repos.GuildMembers.UpdateRole(ctx, guildID, ownerID, "admin", now); err != nil {
+			return err
+		}
+
+		if err := repos.AuditLogs.Create(ctx, entry); err != nil {
+			return err
+		}
+
+		guild.OwnerID = req.NewOwnerID
+		guild.Owner = *newOwner
+		guild.UpdatedAt = now
+
+		return repos.Outbox.Append(ctx, s.events.GuildEvent(guildID, model.EventGuildUpdate, guild))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.events.Notify()
+
+	return guild, nil
+}
+
+// MemberLeaveEvent 成員離開社群事件內容
+type MemberLeaveEvent struct {
+	GuildID uint `json:"guild_id"`
+	UserID  uint `json:"user_id"`
+}
+
+// BanMemberRequest 封鎖成員請求
+type BanMemberRequest struct {
+	Reason            string `json:"reason"              binding:"max=512"`
+	DurationMinutes   int    `json:"duration_minutes"    binding:"omitempty,min=1"`
+	DeleteMessageDays int    `json:"delete_message_days" binding:"omitempty,min=0,max=7"`
+}
+
+// TimeoutMemberRequest 禁言成員請求（最長 28 天）
+type TimeoutMemberRequest struct {
+	DurationMinutes int    `json:"duration_minutes" binding:"required,min=1,max=40320"`
+	Reason          string `json:"reason"           binding:"max=512"`
+}
+
 // GuildMemberService 社群成員服務介面
 type GuildMemberService interface {
-	JoinGuild(guildID, userID uint) error
-	LeaveGuild(guildID, userID uint) error
-	KickMember(guildID, targetUserID, operatorUserID uint) error
-	ListGuildMembers(guildID uint) ([]*model.GuildMember, error)
-	GetMember(guildID, userID uint) (*model.GuildMember, error)
-	UpdateMemberRole(guildID, targetUserID, operatorUserID uint, role string) error
+	JoinGuild(ctx context.Context, guildID, userID uint) error
+	LeaveGuild(ctx context.Context, guildID, userID uint) error
+	KickMember(ctx context.Context, guildID, targetUserID, operatorUserID uint) error
+	ListGuildMembers(ctx context.Context, guildID uint) ([]*model.GuildMember, error)
+	GetMember(ctx context.Context, guildID, userID uint) (*model.GuildMember, error)
+	UpdateMemberRole(
+		ctx context.Context,
+		guildID, targetUserID, operatorUserID uint,
+		role string,
+	) error
+	BanMember(
+		ctx context.Context,
+		guildID, targetUserID, operatorUserID uint,
+		req *BanMemberRequest,
+	) (*model.GuildBan, int64, error)
+	UnbanMember(ctx context.Context, guildID, targetUserID, operatorUserID uint) error
+	ListBans(ctx context.Context, guildID, operatorUserID uint) ([]*model.GuildBan, error)
+	LiftExpiredBans(ctx context.Context) error
+	TimeoutMember(
+		ctx context.Context,
+		guildID, targetUserID, operatorUserID uint,
+		req *TimeoutMemberRequest,
+	) (*model.GuildMember, error)
+	RemoveTimeout(
+		ctx context.Context,
+		guildID, targetUserID, operatorUserID uint,
+	) (*model.GuildMember, error)
+	ExpireTimeouts(ctx context.Context) error
 }
 
 type guildMemberService struct {
 	guildRepo       repository.GuildRepository
 	guildMemberRepo repository.GuildMemberRepository
+	guildBanRepo    repository.GuildBanRepository
+	tx              repository.TxManager
+	events          EventBus
+	clock           clock.Clock
+	logger          *zap.Logger
 }
 
 // NewGuildMemberService 建立社群成員服務
 func NewGuildMemberService(
 	guildRepo repository.GuildRepository,
 	guildMemberRepo repository.GuildMemberRepository,
+	guildBanRepo repository.GuildBanRepository,
+	tx repository.TxManager,
+	events EventBus,
+	clk clock.Clock,
+	logger *zap.Logger,
 ) GuildMemberService {
 	return &guildMemberService{
 		guildRepo:       guildRepo,
 		guildMemberRepo: guildMemberRepo,
+		guildBanRepo:    guildBanRepo,
+		tx:              tx,
+		events:          events,
+		clock:           clk,
+		logger:          logger,
 	}
 }
 
 // JoinGuild 加入社群
-func (s *guildMemberService) JoinGuild(guildID, userID uint) error {
+func (s *guildMemberService) JoinGuild(ctx context.Context, guildID, userID uint) error {
 	// 檢查社群是否存在
-	_, err := s.guildRepo.GetByID(guildID)
+	_, err := s.guildRepo.GetByID(ctx, guildID)
 	if err != nil {
 		return ErrGuildNotFound
 	}
 
 	// 檢查是否已是成員
-	existingMember, _ := s.guildMemberRepo.GetMember(guildID, userID)
+	existingMember, _ := s.guildMemberRepo.GetMember(ctx, guildID, userID)
 	if existingMember != nil {
 		return ErrAlreadyInGuild
 	}
 
+	// 被封鎖的使用者不能加入
+	if ban, err := s.guildBanRepo.GetBan(ctx, guildID, userID); err == nil &&
+		banActive(ban, s.clock.Now()) {
+		return ErrBannedFromGuild
+	}
+
 	// 加入社群
 	member := &model.GuildMember{
 		GuildID:   guildID,
 		UserID:    userID,
 		Role:      "member",
-		JoinedAt:  time.Now(),
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		JoinedAt:  s.clock.Now(),
+		CreatedAt: s.clock.Now(),
+		UpdatedAt: s.clock.Now(),
 	}
 
-	return s.guildMemberRepo.Create(member)
+	err = s.tx.WithinTx(ctx, func(repos *repository.Repositories) error {
+		if err := repos.GuildMembers.Create(ctx, member); err != nil {
+			return err
+		}
+
+		// 重新取得成員以包含使用者資料
+		joined, err := repos.GuildMembers.GetByID(ctx, member.ID)
+		if err != nil {
+			return err
+		}
+
+		return repos.Outbox.Append(ctx, s.events.GuildEvent(guildID, model.EventMemberJoin, joined))
+	})
+	if errors.Is(err, repository.ErrDuplicate) {
+		// 同時送出的加入請求由唯一約束擋下
+		return ErrAlreadyInGuild
+	}
+
+	if err != nil {
+		return err
+	}
+
+	s.events.Notify()
+
+	return nil
 }
 
 // LeaveGuild 離開社群
-func (s *guildMemberService) LeaveGuild(guildID, userID uint) error {
+func (s *guildMemberService) LeaveGuild(ctx context.Context, guildID, userID uint) error {
 	// 檢查社群是否存在
-	guild, err := s.guildRepo.GetByID(guildID)
+	guild, err := s.guildRepo.GetByID(ctx, guildID)
 	if err != nil {
 		return ErrGuildNotFound
 	}
@@ -252,18 +547,27 @@ func (s *guildMemberService) LeaveGuild(guildID, userID uint) error {
 	}
 
 	// 檢查是否為成員
-	member, err := s.guildMemberRepo.GetMember(guildID, userID)
+	member, err := s.guildMemberRepo.GetMember(ctx, guildID, userID)
 	if err != nil || member == nil {
 		return ErrNotGuildMember
 	}
 
-	return s.guildMemberRepo.Delete(member.ID)
+	if err := s.removeMember(ctx, member, nil); err != nil {
+		return err
+	}
+
+	s.events.Notify()
+
+	return nil
 }
 
 // KickMember 踢出成員
-func (s *guildMemberService) KickMember(guildID, targetUserID, operatorUserID uint) error {
+func (s *guildMemberService) KickMember(
+	ctx context.Context,
+	guildID, targetUserID, operatorUserID uint,
+) error {
 	// 檢查操作者是否為擁有者
-	guild, err := s.guildRepo.GetByID(guildID)
+	guild, err := s.guildRepo.GetByID(ctx, guildID)
 	if err != nil {
 		return ErrGuildNotFound
 	}
@@ -274,32 +578,50 @@ func (s *guildMemberService) KickMember(guildID, targetUserID, operatorUserID ui
 
 	// 不能踢出自己
 	if targetUserID == operatorUserID {
-		return errors.New("cannot kick yourself")
+		return ErrCannotKickSelf
 	}
 
 	// 檢查目標是否為成員
-	member, err := s.guildMemberRepo.GetMember(guildID, targetUserID)
+	member, err := s.guildMemberRepo.GetMember(ctx, guildID, targetUserID)
 	if err != nil || member == nil {
 		return ErrNotGuildMember
 	}
 
-	return s.guildMemberRepo.Delete(member.ID)
+	entry := newAuditEntry(
+		ctx, s.clock.Now(), guildID, operatorUserID,
+		model.AuditActionMemberKick, model.AuditTargetUser, targetUserID,
+		nil,
+	)
+
+	if err := s.removeMember(ctx, member, entry); err != nil {
+		return err
+	}
+
+	s.events.Notify()
+
+	return nil
 }
 
 // ListGuildMembers 列出社群成員
-func (s *guildMemberService) ListGuildMembers(guildID uint) ([]*model.GuildMember, error) {
+func (s *guildMemberService) ListGuildMembers(
+	ctx context.Context,
+	guildID uint,
+) ([]*model.GuildMember, error) {
 	// 檢查社群是否存在
-	_, err := s.guildRepo.GetByID(guildID)
+	_, err := s.guildRepo.GetByID(ctx, guildID)
 	if err != nil {
 		return nil, ErrGuildNotFound
 	}
 
-	return s.guildMemberRepo.GetByGuildID(guildID)
+	return s.guildMemberRepo.GetByGuildID(ctx, guildID)
 }
 
 // GetMember 取得特定成員資訊
-func (s *guildMemberService) GetMember(guildID, userID uint) (*model.GuildMember, error) {
-	member, err := s.guildMemberRepo.GetMember(guildID, userID)
+func (s *guildMemberService) GetMember(
+	ctx context.Context,
+	guildID, userID uint,
+) (*model.GuildMember, error) {
+	member, err := s.guildMemberRepo.GetMember(ctx, guildID, userID)
 	if err != nil || member == nil {
 		return nil, ErrNotGuildMember
 	}
@@ -309,11 +631,12 @@ func (s *guildMemberService) GetMember(guildID, userID uint) (*model.GuildMember
 
 // UpdateMemberRole 更新成員角色
 func (s *guildMemberService) UpdateMemberRole(
+	ctx context.Context,
 	guildID, targetUserID, operatorUserID uint,
 	role string,
 ) error {
 	// 檢查操作者是否為擁有者
-	guild, err := s.guildRepo.GetByID(guildID)
+	guild, err := s.guildRepo.GetByID(ctx, guildID)
 	if err != nil {
 		return ErrGuildNotFound
 	}
@@ -324,18 +647,442 @@ func (s *guildMemberService) UpdateMemberRole(
 
 	// 不能修改自己的角色
 	if targetUserID == operatorUserID {
-		return errors.New("cannot modify your own role")
+		return ErrCannotChangeOwnRole
 	}
 
 	// 取得目標成員
-	member, err := s.guildMemberRepo.GetMember(guildID, targetUserID)
+	member, err := s.guildMemberRepo.GetMember(ctx, guildID, targetUserID)
 	if err != nil || member == nil {
 		return ErrNotGuildMember
 	}
 
 	// 更新角色
+	var entry *model.AuditLogEntry
+	if changes := (auditChanges{}).set("role", member.Role, role); len(changes) > 0 {
+		entry = newAuditEntry(
+			ctx, s.clock.Now(), guildID, operatorUserID,
+			model.AuditActionMemberRoleUpdate, model.AuditTargetUser, targetUserID,
+			changes,
+		)
+	}
+
 	member.Role = role
-	member.UpdatedAt = time.Now()
+	member.UpdatedAt = s.clock.Now()
+
+	if err := s.updateMember(ctx, member, entry, repository.GuildMemberRepository.Update); err != nil {
+		return err
+	}
+
+	s.events.Notify()
+
+	return nil
+}
+
+// BanMember 封鎖使用者：移除成員資格、可選擇刪除最近 N 天的訊息
+//
+// 回傳封鎖紀錄與被刪除的訊息數量
+func (s *guildMemberService) BanMember(
+	ctx context.Context,
+	guildID, targetUserID, operatorUserID uint,
+	req *BanMemberRequest,
+) (*model.GuildBan, int64, error) {
+	guild, err := s.guildRepo.GetByID(ctx, guildID)
+	if err != nil {
+		return nil, 0, ErrGuildNotFound
+	}
+
+	if targetUserID == operatorUserID || targetUserID == guild.OwnerID {
+		return nil, 0, ErrRoleHierarchy
+	}
+
+	operator, err := s.requirePermission(ctx, guildID, operatorUserID, PermissionBanMembers)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// 目標仍是成員時，操作者的角色必須高於目標
+	target, err := s.guildMemberRepo.GetMember(ctx, guildID, targetUserID)
+	if err == nil && !outranks(operator.Role, target.Role) {
+		return nil, 0, ErrRoleHierarchy
+	}
+
+	// 請求內容未提供原因時使用 X-Audit-Log-Reason
+	reason := req.Reason
+	if reason == "" {
+		reason = auditReason(ctx)
+	}
+
+	now := s.clock.Now()
+	ban := &model.GuildBan{
+		GuildID:     guildID,
+		UserID:      targetUserID,
+		ModeratorID: operatorUserID,
+		Reason:      reason,
+		CreatedAt:   now,
+	}
+
+	if req.DurationMinutes > 0 {
+		expiresAt := now.Add(time.Duration(req.DurationMinutes) * time.Minute)
+		ban.ExpiresAt = &expiresAt
+	}
+
+	var purgeSince *time.Time
+
+	if req.DeleteMessageDays > 0 {
+		since := now.AddDate(0, 0, -req.DeleteMessageDays)
+		purgeSince = &since
+	}
+
+	entry := newAuditEntry(
+		ctx, s.clock.Now(), guildID, operatorUserID,
+		model.AuditActionMemberBan, model.AuditTargetUser, targetUserID,
+		auditChanges{}.
+			set("expires_at", nil, ban.ExpiresAt).
+			set("delete_message_days", 0, req.DeleteMessageDays),
+	)
+	entry.Reason = reason
+
+	// 封鎖紀錄、移除成員資格、刪除近期訊息與稽核紀錄在同一個交易中完成
+	var purged int64
+
+	err = s.tx.WithinTx(ctx, func(repos *repository.Repositories) error {
+		if err := repos.GuildBans.Ban(ctx, ban); err != nil {
+			return err
+		}
+
+		removed, err := repos.GuildMembers.RemoveMember(ctx, guildID, targetUserID)
+		if err != nil {
+			return err
+		}
+
+		if purgeSince != nil {
+			purged, err = repos.Messages.DeleteByUserInGuildSince(
+				ctx,
+				targetUserID,
+				guildID,
+				*purgeSince,
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := repos.AuditLogs.Create(ctx, entry); err != nil {
+			return err
+		}
+
+		// 只有原本是成員時才會產生離開事件
+		if !removed {
+			return nil
+		}
+
+		return repos.Outbox.Append(ctx, s.memberLeaveEvent(guildID, targetUserID))
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	s.events.Notify()
+
+	return ban, purged, nil
+}
+
+// UnbanMember 解除封鎖
+func (s *guildMemberService) UnbanMember(
+	ctx context.Context,
+	guildID, targetUserID, operatorUserID uint,
+) error {
+	if _, err := s.guildRepo.GetByID(ctx, guildID); err != nil {
+		return ErrGuildNotFound
+	}
+
+	if _, err := s.requirePermission(ctx, guildID, operatorUserID, PermissionBanMembers); err != nil {
+		return err
+	}
+
+	ban, err := s.guildBanRepo.GetBan(ctx, guildID, targetUserID)
+	if err != nil {
+		return ErrBanNotFound
+	}
+
+	entry := newAuditEntry(
+		ctx, s.clock.Now(), guildID, operatorUserID,
+		model.AuditActionMemberUnban, model.AuditTargetUser, targetUserID,
+		nil,
+	)
+
+	return s.tx.WithinTx(ctx, func(repos *repository.Repositories) error {
+		if err := repos.GuildBans.Delete(ctx, ban.ID); err != nil {
+			return err
+		}
+
+		return repos.AuditLogs.Create(ctx, entry)
+	})
+}
+
+// ListBans 列出社群的封鎖紀錄
+func (s *guildMemberService) ListBans(
+	ctx context.Context,
+	guildID, operatorUserID uint,
+) ([]*model.GuildBan, error) {
+	if _, err := s.guildRepo.GetByID(ctx, guildID); err != nil {
+		return nil, ErrGuildNotFound
+	}
+
+	if _, err := s.requirePermission(ctx, guildID, operatorUserID, PermissionBanMembers); err != nil {
+		return nil, err
+	}
+
+	return s.guildBanRepo.GetByGuildID(ctx, guildID)
+}
+
+// LiftExpiredBans 解除已到期的封鎖（由排程器呼叫）
+func (s *guildMemberService) LiftExpiredBans(ctx context.Context) error {
+	bans, err := s.guildBanRepo.GetExpired(ctx, s.clock.Now(), expiredBanBatchSize)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+
+	for _, ban := range bans {
+		if ctx.Err() != nil {
+			break
+		}
+
+		if err := s.guildBanRepo.Delete(ctx, ban.ID); err != nil {
+			errs = append(errs, fmt.Errorf("lift ban %d: %w", ban.ID, err))
+			continue
+		}
+
+		s.logger.Info(
+			"Guild ban expired",
+			zap.Uint("guildID", ban.GuildID),
+			zap.Uint("userID", ban.UserID),
+		)
+	}
+
+	return errors.Join(errs...)
+}
+
+// TimeoutMember 禁言成員，期間內成員無法發送訊息
+func (s *guildMemberService) TimeoutMember(
+	ctx context.Context,
+	guildID, targetUserID, operatorUserID uint,
+	req *TimeoutMemberRequest,
+) (*model.GuildMember, error) {
+	target, err := s.moderatableMember(ctx, guildID, targetUserID, operatorUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	until := now.Add(time.Duration(req.DurationMinutes) * time.Minute)
+
+	entry := newAuditEntry(
+		ctx, s.clock.Now(), guildID, operatorUserID,
+		model.AuditActionMemberTimeout, model.AuditTargetUser, targetUserID,
+		auditChanges{}.set("timeout_until", target.TimeoutUntil, &until),
+	)
+	if req.Reason != "" {
+		entry.Reason = req.Reason
+	}
+
+	target.TimeoutUntil = &until
+	target.UpdatedAt = now
+
+	if err := s.updateMember(ctx, target, entry, repository.GuildMemberRepository.SetTimeout); err != nil {
+		return nil, err
+	}
+
+	s.events.Notify()
+
+	return target, nil
+}
+
+// RemoveTimeout 提前解除成員的禁言
+func (s *guildMemberService) RemoveTimeout(
+	ctx context.Context,
+	guildID, targetUserID, operatorUserID uint,
+) (*model.GuildMember, error) {
+	target, err := s.moderatableMember(ctx, guildID, targetUserID, operatorUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if target.TimeoutUntil == nil {
+		return target, nil
+	}
+
+	entry := newAuditEntry(
+		ctx, s.clock.Now(), guildID, operatorUserID,
+		model.AuditActionMemberTimeoutClear, model.AuditTargetUser, targetUserID,
+		auditChanges{}.set("timeout_until", target.TimeoutUntil, nil),
+	)
+
+	target.TimeoutUntil = nil
+	target.UpdatedAt = s.clock.Now()
+
+	if err := s.updateMember(ctx, target, entry, repository.GuildMemberRepository.SetTimeout); err != nil {
+		return nil, err
+	}
+
+	s.events.Notify()
+
+	return target, nil
+}
+
+// ExpireTimeouts 清除已到期的禁言並通知客戶端（由排程器呼叫）
+//
+// 禁言是否有效以到期時間判斷，即使排程延遲執行，到期後成員也能立即發言
+func (s *guildMemberService) ExpireTimeouts(ctx context.Context) error {
+	now := s.clock.Now()
+
+	members, err := s.guildMemberRepo.GetExpiredTimeouts(ctx, now, expiredTimeoutBatchSize)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+
+	for _, member := range members {
+		if ctx.Err() != nil {
+			break
+		}
+
+		err := s.tx.WithinTx(ctx, func(repos *repository.Repositories) error {
+			cleared, err := repos.GuildMembers.ClearTimeout(ctx, member.ID, now)
+			if err != nil || !cleared {
+				return err
+			}
+
+			// 事件只在實際清除禁言時寫入
+			member.TimeoutUntil = nil
+
+			return repos.Outbox.Append(ctx, s.memberUpdateEvent(member))
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("clear timeout of member %d: %w", member.ID, err))
+			continue
+		}
+	}
+
+	s.events.Notify()
+
+	return errors.Join(errs...)
+}
+
+// moderatableMember 確認操作者擁有管理成員權限，且角色高於目標成員
+func (s *guildMemberService) moderatableMember(
+	ctx context.Context,
+	guildID, targetUserID, operatorUserID uint,
+) (*model.GuildMember, error) {
+	guild, err := s.guildRepo.GetByID(ctx, guildID)
+	if err != nil {
+		return nil, ErrGuildNotFound
+	}
+
+	if targetUserID == operatorUserID || targetUserID == guild.OwnerID {
+		return nil, ErrRoleHierarchy
+	}
+
+	operator, err := s.requirePermission(ctx, guildID, operatorUserID, PermissionModerateMembers)
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := s.guildMemberRepo.GetMember(ctx, guildID, targetUserID)
+	if err != nil || target == nil {
+		return nil, ErrTargetNotGuildMember
+	}
+
+	if !outranks(operator.Role, target.Role) {
+		return nil, ErrRoleHierarchy
+	}
+
+	return target, nil
+}
+
+// removeMember 在同一個交易中刪除成員、寫入稽核紀錄（可為空）與離開事件
+func (s *guildMemberService) removeMember(
+	ctx context.Context,
+	member *model.GuildMember,
+	entry *model.AuditLogEntry,
+) error {
+	return s.tx.WithinTx(ctx, func(repos *repository.Repositories) error {
+		if err := repos.GuildMembers.Delete(ctx, member.ID); err != nil {
+			return err
+		}
+
+		if entry != nil {
+			if err := repos.AuditLogs.Create(ctx, entry); err != nil {
+				return err
+			}
+		}
+
+		return repos.Outbox.Append(ctx, s.memberLeaveEvent(member.GuildID, member.UserID))
+	})
+}
+
+// updateMember 在同一個交易中寫入成員變更、稽核紀錄（可為空）與更新事件
+func (s *guildMemberService) updateMember(
+	ctx context.Context,
+	member *model.GuildMember,
+	entry *model.AuditLogEntry,
+	write func(repo repository.GuildMemberRepository, ctx context.Context, member *model.GuildMember) error,
+) error {
+	return s.tx.WithinTx(ctx, func(repos *repository.Repositories) error {
+		if err := write(repos.GuildMembers, ctx, member); err != nil {
+			return err
+		}
+
+		if entry != nil {
+			if err := repos.AuditLogs.Create(ctx, entry); err != nil {
+				return err
+			}
+		}
+
+		return repos.Outbox.Append(ctx, s.memberUpdateEvent(member))
+	})
+}
+
+// memberLeaveEvent 建立成員離開事件（包含被踢出與封鎖）
+func (s *guildMemberService) memberLeaveEvent(guildID, userID uint) *model.OutboxEvent {
+	return s.events.GuildEvent(guildID, model.EventMemberLeave, &MemberLeaveEvent{
+		GuildID: guildID,
+		UserID:  userID,
+	})
+}
+
+// memberUpdateEvent 建立成員資料更新事件
+func (s *guildMemberService) memberUpdateEvent(member *model.GuildMember) *model.OutboxEvent {
+	return s.events.GuildEvent(member.GuildID, model.EventMemberUpdate, member)
+}
+
+// requirePermission 確認操作者是社群成員且擁有指定權限
+func (s *guildMemberService) requirePermission(
+	ctx context.Context,
+	guildID, userID uint,
+	permission Permission,
+) (*model.GuildMember, error) {
+	member, err := s.guildMemberRepo.GetMember(ctx, guildID, userID)
+	if err != nil || member == nil {
+		return nil, ErrNotGuildMember
+	}
+
+	if !hasPermission(member.Role, permission) {
+		return nil, ErrMissingPermission
+	}
+
+	return member, nil
+}
+
+// banActive 檢查封鎖在指定時間是否仍然有效
+func banActive(ban *model.GuildBan, now time.Time) bool {
+	return ban.ExpiresAt == nil || now.Before(*ban.ExpiresAt)
+}
 
-	return s.guildMemberRepo.Update(member)
+// timedOut 檢查成員在指定時間是否處於禁言狀態
+func timedOut(member *model.GuildMember, now time.Time) bool {
+	return member.TimeoutUntil != nil && now.Before(*member.TimeoutUntil)
 }
@@ -0,0 +1,348 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/walnut-almonds/talkrealm/internal/apperror"
+	"github.com/walnut-almonds/talkrealm/internal/model"
+	"gorm.io/gorm"
+)
+
+func TestCreateGuildAddsOwnerAsMember(t *testing.T) {
+	env := newTestEnv(t)
+	owner := env.createUser("owner")
+
+	guild, err := env.guildService().
+		CreateGuild(env.ctx, owner.ID, &CreateGuildRequest{Name: "guild"})
+	if err != nil {
+		t.Fatalf("CreateGuild() = %v", err)
+	}
+
+	member, err := env.guildMemberService().GetMember(env.ctx, guild.ID, owner.ID)
+	if err != nil {
+		t.Fatalf("GetMember() = %v", err)
+	}
+
+	if member.Role != "owner" {
+		t.Errorf("owner role = %q, want owner", member.Role)
+	}
+
+	guilds, err := env.guildService().ListUserGuilds(env.ctx, owner.ID)
+	if err != nil || len(guilds) != 1 || guilds[0].ID != guild.ID {
+		t.Errorf("ListUserGuilds() = %v, %v, want the new guild", guilds, err)
+	}
+}
+
+func TestUpdateGuildRequiresOwner(t *testing.T) {
+	env := newTestEnv(t)
+	owner := env.createUser("owner")
+	member := env.createUser("member")
+	guild := env.createGuild(owner, member)
+
+	_, err := env.guildService().UpdateGuild(
+		env.ctx, guild.ID, member.ID, &UpdateGuildRequest{Name: "renamed"},
+	)
+	if !errors.Is(err, ErrNotGuildOwner) {
+		t.Fatalf("UpdateGuild() by member = %v, want %v", err, ErrNotGuildOwner)
+	}
+
+	updated, err := env.guildService().UpdateGuild(
+		env.ctx, guild.ID, owner.ID, &UpdateGuildRequest{Name: "renamed"},
+	)
+	if err != nil {
+		t.Fatalf("UpdateGuild() by owner = %v", err)
+	}
+
+	if updated.Name != "renamed" {
+		t.Errorf("name = %q, want renamed", updated.Name)
+	}
+
+	if n := env.count("audit_log_entries", "action = ?", model.AuditActionGuildUpdate); n != 1 {
+		t.Errorf("guild.update audit entries = %d, want 1", n)
+	}
+}
+
+func TestTransferOwnership(t *testing.T) {
+	env := newTestEnv(t)
+	owner := env.createUser("owner")
+	member := env.createUser("member")
+	guild := env.createGuild(owner, member)
+	guilds := env.guildService()
+
+	_, err := guilds.TransferOwnership(env.ctx, guild.ID, owner.ID, &TransferOwnershipRequest{
+		NewOwnerID: member.ID,
+		Password:   "wrong",
+	})
+	if !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf(
+			"TransferOwnership() with wrong password = %v, want %v",
+			err,
+			ErrInvalidCredentials,
+		)
+	}
+
+	env.advance(time.Hour)
+
+	updated, err := guilds.TransferOwnership(env.ctx, guild.ID, owner.ID, &TransferOwnershipRequest{
+		NewOwnerID: member.ID,
+		Password:   testPassword,
+	})
+	if err != nil {
+		t.Fatalf("TransferOwnership() = %v", err)
+	}
+
+	if updated.OwnerID != member.ID {
+		t.Errorf("owner = %d, want %d", updated.OwnerID, member.ID)
+	}
+
+	stored, err := env.repos.Guilds.GetByID(env.ctx, guild.ID)
+	if err != nil {
+		t.Fatalf("get guild: %v", err)
+	}
+
+	// 更新時間來自注入的時鐘
+	if !stored.UpdatedAt.Equal(env.now) {
+		t.Errorf("guild updated_at = %v, want %v", stored.UpdatedAt, env.now)
+	}
+
+	for userID, want := range map[uint]string{owner.ID: "admin", member.ID: "owner"} {
+		got, err := env.guildMemberService().GetMember(env.ctx, guild.ID, userID)
+		if err != nil || got.Role != want {
+			t.Errorf("role of user %d = %v, %v, want %s", userID, got, err, want)
+			continue
+		}
+
+		if !got.UpdatedAt.Equal(env.now) {
+			t.Errorf("member %d updated_at = %v, want %v", userID, got.UpdatedAt, env.now)
+		}
+	}
+
+	if n := env.count("audit_log_entries", "action = ?", model.AuditActionGuildOwnerTransfer); n != 1 {
+		t.Errorf("owner transfer audit entries = %d, want 1", n)
+	}
+
+	if n := env.count("outbox_events", "type = ?", model.EventGuildUpdate); n != 1 {
+		t.Errorf("guild.update events = %d, want 1", n)
+	}
+}
+
+func TestTransferOwnershipConflictsWithConcurrentTransfer(t *testing.T) {
+	env := newTestEnv(t)
+	owner := env.createUser("owner")
+	member := env.createUser("member")
+	other := env.createUser("other")
+	guild := env.createGuild(owner, member, other)
+
+	// 在擁有者檢查之後、寫入之前，另一個請求先把社群轉移給 other
+	transferred := false
+
+	err := env.db.Callback().Update().Before("gorm:update").
+		Register("test:concurrent_transfer", func(tx *gorm.DB) {
+			if transferred || tx.Statement.Table != "guilds" {
+				return
+			}
+
+			transferred = true
+
+			if err := tx.Session(&gorm.Session{NewDB: true}).
+				Exec("UPDATE guilds SET owner_id = ? WHERE id = ?", other.ID, guild.ID).
+				Error; err != nil {
+				t.Errorf("concurrent transfer: %v", err)
+			}
+		})
+	if err != nil {
+		t.Fatalf("register callback: %v", err)
+	}
+
+	_, err = env.guildService().
+		TransferOwnership(env.ctx, guild.ID, owner.ID, &TransferOwnershipRequest{
+			NewOwnerID: member.ID,
+			Password:   testPassword,
+		})
+	if !errors.Is(err, ErrGuildOwnerChanged) {
+		t.Fatalf("TransferOwnership() = %v, want %v", err, ErrGuildOwnerChanged)
+	}
+
+	if got := apperror.From(err).Status; got != http.StatusConflict {
+		t.Errorf("status = %d, want %d", got, http.StatusConflict)
+	}
+
+	got, err := env.guildMemberService().GetMember(env.ctx, guild.ID, member.ID)
+	if err != nil || got.Role != "member" {
+		t.Errorf("role of member = %v, %v, want member", got, err)
+	}
+}
+
+func TestTransferOwnershipWithSSOAccountRequiresRecentLogin(t *testing.T) {
+	env := newTestEnv(t)
+	owner := env.createUser("owner")
+	member := env.createUser("member")
+	guild := env.createGuild(owner, member)
+
+	noLocalPassword := true
+	owner.NoLocalPassword = &noLocalPassword
+	if err := env.repos.Users.Update(env.ctx, owner); err != nil {
+		t.Fatalf("update owner: %v", err)
+	}
+
+	_, err := env.guildService().
+		TransferOwnership(env.ctx, guild.ID, owner.ID, &TransferOwnershipRequest{
+			NewOwnerID: member.ID,
+			AuthTime:   env.now.Add(-time.Hour),
+		})
+	if !errors.Is(err, ErrReauthenticationRequired) {
+		t.Fatalf(
+			"TransferOwnership() with stale login = %v, want %v",
+			err,
+			ErrReauthenticationRequired,
+		)
+	}
+
+	if _, err := env.guildService().TransferOwnership(env.ctx, guild.ID, owner.ID, &TransferOwnershipRequest{
+		NewOwnerID: member.ID,
+		AuthTime:   env.now.Add(-time.Minute),
+	}); err != nil {
+		t.Fatalf("TransferOwnership() with recent login = %v", err)
+	}
+}
+
+func TestTransferOwnershipWithUnknownLocalPasswordAcceptsEither(t *testing.T) {
+	env := newTestEnv(t)
+	owner := env.createUser("owner")
+	member := env.createUser("member")
+	guild := env.createGuild(owner, member)
+
+	if err := env.db.Exec("UPDATE users SET no_local_password = NULL WHERE id = ?", owner.ID).Error; err != nil {
+		t.Fatalf("reset no_local_password: %v", err)
+	}
+
+	stale := env.now.Add(-time.Hour)
+
+	for _, tt := range []struct {
+		password string
+		want     error
+	}{
+		{password: "", want: ErrReauthenticationRequired},
+		{password: "wrong-password", want: ErrInvalidCredentials},
+	} {
+		_, err := env.guildService().
+			TransferOwnership(env.ctx, guild.ID, owner.ID, &TransferOwnershipRequest{
+				NewOwnerID: member.ID,
+				Password:   tt.password,
+				AuthTime:   stale,
+			})
+		if !errors.Is(err, tt.want) {
+			t.Fatalf(
+				"TransferOwnership() with password %q = %v, want %v",
+				tt.password,
+				err,
+				tt.want,
+			)
+		}
+	}
+
+	if _, err := env.guildService().TransferOwnership(env.ctx, guild.ID, owner.ID, &TransferOwnershipRequest{
+		NewOwnerID: member.ID,
+		Password:   testPassword,
+		AuthTime:   stale,
+	}); err != nil {
+		t.Fatalf("TransferOwnership() with password = %v", err)
+	}
+}
+
+func TestKickMemberRequiresOwner(t *testing.T) {
+	env := newTestEnv(t)
+	owner := env.createUser("owner")
+	admin := env.createUser("admin")
+	member := env.createUser("member")
+	guild := env.createGuild(owner, admin, member)
+	members := env.guildMemberService()
+
+	env.setRole(guild.ID, admin.ID, "admin")
+
+	if err := members.KickMember(env.ctx, guild.ID, member.ID, admin.ID); !errors.Is(
+		err,
+		ErrNotGuildOwner,
+	) {
+		t.Fatalf("KickMember() by admin = %v, want %v", err, ErrNotGuildOwner)
+	}
+
+	if err := members.KickMember(env.ctx, guild.ID, member.ID, owner.ID); err != nil {
+		t.Fatalf("KickMember() by owner = %v", err)
+	}
+
+	if _, err := members.GetMember(env.ctx, guild.ID, member.ID); err == nil {
+		t.Error("kicked user is still a member")
+	}
+
+	if n := env.count("audit_log_entries", "action = ?", model.AuditActionMemberKick); n != 1 {
+		t.Errorf("member.kick audit entries = %d, want 1", n)
+	}
+}
+
+func TestBanMemberRespectsRoleHierarchy(t *testing.T) {
+	env := newTestEnv(t)
+	owner := env.createUser("owner")
+	admin := env.createUser("admin")
+	otherAdmin := env.createUser("other-admin")
+	moderator := env.createUser("moderator")
+	member := env.createUser("member")
+	guild := env.createGuild(owner, admin, otherAdmin, moderator, member)
+	members := env.guildMemberService()
+
+	env.setRole(guild.ID, admin.ID, "admin")
+	env.setRole(guild.ID, otherAdmin.ID, "admin")
+	env.setRole(guild.ID, moderator.ID, "moderator")
+
+	_, _, err := members.BanMember(env.ctx, guild.ID, member.ID, moderator.ID, &BanMemberRequest{})
+	if !errors.Is(err, ErrMissingPermission) {
+		t.Fatalf("BanMember() by moderator = %v, want %v", err, ErrMissingPermission)
+	}
+
+	_, _, err = members.BanMember(env.ctx, guild.ID, otherAdmin.ID, admin.ID, &BanMemberRequest{})
+	if !errors.Is(err, ErrRoleHierarchy) {
+		t.Fatalf("BanMember() of admin by admin = %v, want %v", err, ErrRoleHierarchy)
+	}
+
+	if _, _, err := members.BanMember(
+		env.ctx, guild.ID, member.ID, admin.ID, &BanMemberRequest{},
+	); err != nil {
+		t.Fatalf("BanMember() of member by admin = %v", err)
+	}
+
+	if n := env.count("audit_log_entries", "action = ?", model.AuditActionMemberBan); n != 1 {
+		t.Errorf("member.ban audit entries = %d, want 1", n)
+	}
+}
+
+func TestBanMemberBlocksRejoinUntilExpiry(t *testing.T) {
+	env := newTestEnv(t)
+	owner := env.createUser("owner")
+	member := env.createUser("member")
+	guild := env.createGuild(owner, member)
+	members := env.guildMemberService()
+
+	if _, _, err := members.BanMember(env.ctx, guild.ID, member.ID, owner.ID, &BanMemberRequest{
+		Reason:          "spam",
+		DurationMinutes: 60,
+	}); err != nil {
+		t.Fatalf("BanMember() = %v", err)
+	}
+
+	if _, err := members.GetMember(env.ctx, guild.ID, member.ID); err == nil {
+		t.Error("banned user is still a member")
+	}
+
+	if err := members.JoinGuild(env.ctx, guild.ID, member.ID); !errors.Is(err, ErrBannedFromGuild) {
+		t.Fatalf("JoinGuild() while banned = %v, want %v", err, ErrBannedFromGuild)
+	}
+
+	env.advance(2 * time.Hour)
+
+	if err := members.JoinGuild(env.ctx, guild.ID, member.ID); err != nil {
+		t.Fatalf("JoinGuild() after ban expired = %v", err)
+	}
+}
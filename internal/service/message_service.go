@@ -1,41 +1,128 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"strconv"
 	"time"
 
+	"github.com/walnut-almonds/talkrealm/internal/apperror"
 	"github.com/walnut-almonds/talkrealm/internal/model"
 	"github.com/walnut-almonds/talkrealm/internal/repository"
+	"github.com/walnut-almonds/talkrealm/pkg/clock"
+	"github.com/walnut-almonds/talkrealm/pkg/logger"
+	"github.com/walnut-almonds/talkrealm/pkg/metrics"
+	"github.com/walnut-almonds/talkrealm/pkg/ratelimit"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
 )
 
 var (
-	ErrMessageNotFound     = errors.New("message not found")
-	ErrNotChannelMemberMsg = errors.New("not a member of this channel's guild")
-	ErrNotMessageOwner     = errors.New("not the owner of this message")
-	ErrEmptyMessageContent = errors.New("message content cannot be empty")
-	ErrInvalidMessageType  = errors.New("invalid message type")
+	ErrMessageNotFound     = apperror.NotFound("message_not_found", "message not found")
+	ErrNotChannelMemberMsg = apperror.Forbidden(
+		"not_channel_member",
+		"not a member of this channel's guild",
+	)
+	ErrNotMessageOwner = apperror.Forbidden(
+		"not_message_owner",
+		"not the owner of this message",
+	)
+	ErrEmptyMessageContent = apperror.InvalidArgument(
+		"empty_message_content",
+		"message content cannot be empty",
+	)
+	ErrInvalidMessageType = apperror.InvalidArgument(
+		"invalid_message_type",
+		"invalid message type",
+	)
+	ErrCategoryChannel = apperror.InvalidArgument(
+		"category_channel",
+		"cannot send messages to a category channel",
+	)
+	ErrRateLimited = apperror.TooManyRequests("rate_limited", "rate limited")
 )
 
-// WebSocketManager 定義 WebSocket 管理器的介面（避免循環依賴）
-type WebSocketManager interface {
-	BroadcastToChannel(channelID uint, msgType string, data any)
+// RateLimitError 發送訊息過於頻繁，RetryAfter 後才能再次發送
+type RateLimitError struct {
+	Scope      string // slow_mode（頻道慢速模式）或 user（全域使用者限制）
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limited (%s), retry after %s", e.Scope, e.RetryAfter)
+}
+
+// Is 讓 errors.Is(err, ErrRateLimited) 成立
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// As 讓 apperror.From 取得附帶限流範圍與等待時間的 ErrRateLimited
+func (e *RateLimitError) As(target any) bool {
+	appErr, ok := target.(**apperror.Error)
+	if !ok {
+		return false
+	}
+
+	*appErr = ErrRateLimited.
+		WithMessage("you are sending messages too quickly").
+		WithDetail(e.Error()).
+		WithDetails(map[string]any{
+			"scope":       e.Scope,
+			"retry_after": e.RetryAfter.Seconds(),
+		}).
+		WithRetryAfter(e.RetryAfter)
+
+	return true
+}
+
+// MessageDeleteEvent 訊息刪除事件內容
+type MessageDeleteEvent struct {
+	ID        uint `json:"id"`
+	ChannelID uint `json:"channel_id"`
 }
 
 // MessageService 訊息服務介面
 type MessageService interface {
-	CreateMessage(userID uint, req *CreateMessageRequest) (*model.Message, error)
-	GetMessage(messageID, userID uint) (*model.Message, error)
-	ListChannelMessages(channelID, userID uint, page, pageSize int) (*MessageListResponse, error)
-	UpdateMessage(messageID, userID uint, req *UpdateMessageRequest) (*model.Message, error)
-	DeleteMessage(messageID, userID uint) error
-	SetWebSocketManager(manager WebSocketManager)
+	CreateMessage(
+		ctx context.Context,
+		userID uint,
+		req *CreateMessageRequest,
+	) (*model.Message, error)
+	GetMessage(ctx context.Context, messageID, userID uint) (*model.Message, error)
+	ListChannelMessages(
+		ctx context.Context,
+		channelID, userID uint,
+		page, pageSize int,
+	) (*MessageListResponse, error)
+	UpdateMessage(
+		ctx context.Context,
+		messageID, userID uint,
+		req *UpdateMessageRequest,
+	) (*model.Message, error)
+	DeleteMessage(ctx context.Context, messageID, userID uint) error
+	CrosspostMessage(
+		ctx context.Context,
+		repos *repository.Repositories,
+		source *model.Message,
+		targetChannelID uint,
+	) (*model.Message, error)
 }
 
 type messageService struct {
 	messageRepo     repository.MessageRepository
 	channelRepo     repository.ChannelRepository
 	guildMemberRepo repository.GuildMemberRepository
-	wsManager       WebSocketManager
+	tx              repository.TxManager
+	limiter         ratelimit.Limiter
+	userRateLimit   ratelimit.Rule
+	events          EventBus
+	metrics         *metrics.Metrics
+	tracer          trace.Tracer
+	clock           clock.Clock
+	logger          *zap.Logger
 }
 
 // NewMessageService 建立訊息服務實例
@@ -43,25 +130,45 @@ func NewMessageService(
 	messageRepo repository.MessageRepository,
 	channelRepo repository.ChannelRepository,
 	guildMemberRepo repository.GuildMemberRepository,
+	tx repository.TxManager,
+	limiter ratelimit.Limiter,
+	userRateLimit ratelimit.Rule,
+	events EventBus,
+	metrics *metrics.Metrics,
+	tracer trace.Tracer,
+	clk clock.Clock,
+	logger *zap.Logger,
 ) MessageService {
 	return &messageService{
 		messageRepo:     messageRepo,
 		channelRepo:     channelRepo,
 		guildMemberRepo: guildMemberRepo,
-		wsManager:       nil, // 稍後設定
+		tx:              tx,
+		limiter:         limiter,
+		userRateLimit:   userRateLimit,
+		events:          events,
+		metrics:         metrics,
+		tracer:          tracer,
+		clock:           clk,
+		logger:          logger,
 	}
 }
 
-// SetWebSocketManager 設定 WebSocket 管理器
-func (s *messageService) SetWebSocketManager(manager WebSocketManager) {
-	s.wsManager = manager
-}
-
 // CreateMessageRequest 建立訊息請求
 type CreateMessageRequest struct {
 	ChannelID uint   `json:"channel_id"`
 	Content   string `json:"content"    binding:"required"`
 	Type      string `json:"type"` // text, image, file (預設: text)
+
+	// Webhook 透過傳入 webhook 發送時的作者資訊；由 webhook token 授權
+	Webhook *WebhookAuthor `json:"-"`
+}
+
+// WebhookAuthor 透過 webhook 發送訊息時顯示的作者
+type WebhookAuthor struct {
+	WebhookID uint
+	Name      string
+	Avatar    string
 }
 
 // UpdateMessageRequest 更新訊息請求
@@ -80,9 +187,16 @@ type MessageListResponse struct {
 
 // CreateMessage 建立訊息
 func (s *messageService) CreateMessage(
+	ctx context.Context,
 	userID uint,
 	req *CreateMessageRequest,
 ) (*model.Message, error) {
+	ctx, span := s.tracer.Start(ctx, "MessageService.CreateMessage", trace.WithAttributes(
+		attribute.Int64("channel.id", int64(req.ChannelID)),
+		attribute.String("message.source", messageSource(req)),
+	))
+	defer span.End()
+
 	// 驗證訊息內容
 	if req.Content == "" {
 		return nil, ErrEmptyMessageContent
@@ -99,15 +213,34 @@ func (s *messageService) CreateMessage(
 	}
 
 	// 檢查頻道是否存在
-	channel, err := s.channelRepo.GetByID(req.ChannelID)
+	channel, err := s.channelRepo.GetByID(ctx, req.ChannelID)
 	if err != nil {
-		return nil, errors.New("channel not found")
+		return nil, ErrChannelNotFound
 	}
 
-	// 檢查使用者是否為該社群成員
-	member, err := s.guildMemberRepo.GetMember(channel.GuildID, userID)
-	if err != nil || member == nil {
-		return nil, ErrNotChannelMemberMsg
+	// 分類頻道只用來分組，不能發送訊息
+	if channel.Type == ChannelTypeCategory {
+		return nil, ErrCategoryChannel
+	}
+
+	var release func(context.Context)
+
+	if req.Webhook == nil {
+		// 檢查使用者是否為該社群成員
+		member, err := s.guildMemberRepo.GetMember(ctx, channel.GuildID, userID)
+		if err != nil || member == nil {
+			return nil, ErrNotChannelMemberMsg
+		}
+
+		// 禁言中的成員不能發送訊息
+		if timedOut(member, s.clock.Now()) {
+			return nil, ErrMemberTimedOut
+		}
+
+		release, err = s.reserveRateLimits(ctx, channel, member)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// 建立訊息
@@ -116,43 +249,145 @@ func (s *messageService) CreateMessage(
 		UserID:    userID,
 		Content:   req.Content,
 		Type:      msgType,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		CreatedAt: s.clock.Now(),
+		UpdatedAt: s.clock.Now(),
+	}
+
+	if req.Webhook != nil {
+		message.WebhookID = &req.Webhook.WebhookID
+		message.AuthorName = req.Webhook.Name
+		message.AuthorAvatar = req.Webhook.Avatar
+	}
+
+	// 訊息與 message.create 事件寫在同一個交易中
+	err = s.tx.WithinTx(ctx, func(repos *repository.Repositories) error {
+		message, err = s.insertMessage(ctx, repos, channel, message)
+		return err
+	})
+	if err != nil {
+		// 訊息沒有送出，撤回已取得的速率限制額度
+		if release != nil {
+			release(context.WithoutCancel(ctx))
+		}
+
+		return nil, err
+	}
+
+	s.events.Notify()
+	s.metrics.MessageCreated(messageSource(req))
+
+	return message, nil
+}
+
+// CrosspostMessage 將公告訊息轉發到追蹤頻道，由 announcement.published 事件的訂閱者呼叫
+//
+// 轉發由追蹤關係授權，作者不需要是目標社群成員；寫入透過 repos，與事件的轉送紀錄一起提交
+func (s *messageService) CrosspostMessage(
+	ctx context.Context,
+	repos *repository.Repositories,
+	source *model.Message,
+	targetChannelID uint,
+) (*model.Message, error) {
+	ctx, span := s.tracer.Start(ctx, "MessageService.CrosspostMessage", trace.WithAttributes(
+		attribute.Int64("channel.id", int64(targetChannelID)),
+		attribute.Int64("message.source_id", int64(source.ID)),
+	))
+	defer span.End()
+
+	channel, err := repos.Channels.GetByID(ctx, targetChannelID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrChannelNotFound
+		}
+
+		return nil, err
+	}
+
+	if channel.Type == ChannelTypeCategory {
+		return nil, ErrCategoryChannel
+	}
+
+	message := &model.Message{
+		ChannelID:       channel.ID,
+		UserID:          source.UserID,
+		Content:         source.Content,
+		Type:            source.Type,
+		SourceMessageID: &source.ID,
+		SourceChannelID: &source.ChannelID,
+		CreatedAt:       s.clock.Now(),
+		UpdatedAt:       s.clock.Now(),
+	}
+
+	message, err = s.insertMessage(ctx, repos, channel, message)
+	if err != nil {
+		return nil, err
+	}
+
+	s.metrics.MessageCreated("crosspost")
+
+	return message, nil
+}
+
+// insertMessage 在 repos 的交易中寫入訊息與 message.create 事件，回傳包含關聯資料的訊息
+func (s *messageService) insertMessage(
+	ctx context.Context,
+	repos *repository.Repositories,
+	channel *model.Channel,
+	message *model.Message,
+) (*model.Message, error) {
+	if err := repos.Messages.Create(ctx, message); err != nil {
+		return nil, err
 	}
 
-	if err := s.messageRepo.Create(message); err != nil {
+	// 重新取得訊息（包含關聯資料），作為回應與事件內容
+	created, err := repos.Messages.GetByID(ctx, message.ID)
+	if err != nil {
 		return nil, err
 	}
 
-	// 重新取得訊息（包含關聯資料）
-	fullMessage, err := s.messageRepo.GetByID(message.ID)
+	err = repos.Outbox.Append(
+		ctx,
+		s.events.ChannelEvent(channel.GuildID, channel.ID, model.EventMessageCreate, created),
+	)
 	if err != nil {
 		return nil, err
 	}
 
-	// 如果有 WebSocket 管理器，即時推送新訊息
-	if s.wsManager != nil {
-		s.wsManager.BroadcastToChannel(req.ChannelID, "new_message", fullMessage)
+	return created, nil
+}
+
+// messageSource 訊息的來源種類，作為指標的標籤
+func messageSource(req *CreateMessageRequest) string {
+	if req.Webhook != nil {
+		return "webhook"
 	}
 
-	return fullMessage, nil
+	return "user"
 }
 
 // GetMessage 取得訊息
-func (s *messageService) GetMessage(messageID, userID uint) (*model.Message, error) {
+func (s *messageService) GetMessage(
+	ctx context.Context,
+	messageID, userID uint,
+) (*model.Message, error) {
+	ctx, span := s.tracer.Start(ctx, "MessageService.GetMessage", trace.WithAttributes(
+		attribute.Int64("message.id", int64(messageID)),
+	))
+	defer span.End()
+
 	// 取得訊息
-	message, err := s.messageRepo.GetByID(messageID)
+	message, err := s.messageRepo.GetByID(ctx, messageID)
 	if err != nil {
 		return nil, ErrMessageNotFound
 	}
 
 	// 檢查使用者是否為該社群成員
-	channel, err := s.channelRepo.GetByID(message.ChannelID)
+	channel, err := s.channelRepo.GetByID(ctx, message.ChannelID)
 	if err != nil {
-		return nil, errors.New("channel not found")
+		return nil, ErrChannelNotFound
 	}
 
-	member, err := s.guildMemberRepo.GetMember(channel.GuildID, userID)
+	member, err := s.guildMemberRepo.GetMember(ctx, channel.GuildID, userID)
 	if err != nil || member == nil {
 		return nil, ErrNotChannelMemberMsg
 	}
@@ -162,17 +397,23 @@ func (s *messageService) GetMessage(messageID, userID uint) (*model.Message, err
 
 // ListChannelMessages 列出頻道的訊息
 func (s *messageService) ListChannelMessages(
+	ctx context.Context,
 	channelID, userID uint,
 	page, pageSize int,
 ) (*MessageListResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "MessageService.ListChannelMessages", trace.WithAttributes(
+		attribute.Int64("channel.id", int64(channelID)),
+	))
+	defer span.End()
+
 	// 檢查頻道是否存在
-	channel, err := s.channelRepo.GetByID(channelID)
+	channel, err := s.channelRepo.GetByID(ctx, channelID)
 	if err != nil {
-		return nil, errors.New("channel not found")
+		return nil, ErrChannelNotFound
 	}
 
 	// 檢查使用者是否為該社群成員
-	member, err := s.guildMemberRepo.GetMember(channel.GuildID, userID)
+	member, err := s.guildMemberRepo.GetMember(ctx, channel.GuildID, userID)
 	if err != nil || member == nil {
 		return nil, ErrNotChannelMemberMsg
 	}
@@ -190,7 +431,7 @@ func (s *messageService) ListChannelMessages(
 	offset := (page - 1) * pageSize
 
 	// 取得訊息列表
-	messages, err := s.messageRepo.GetByChannelID(channelID, offset, pageSize)
+	messages, err := s.messageRepo.GetByChannelID(ctx, channelID, offset, pageSize)
 	if err != nil {
 		return nil, err
 	}
@@ -213,16 +454,22 @@ func (s *messageService) ListChannelMessages(
 
 // UpdateMessage 更新訊息
 func (s *messageService) UpdateMessage(
+	ctx context.Context,
 	messageID, userID uint,
 	req *UpdateMessageRequest,
 ) (*model.Message, error) {
+	ctx, span := s.tracer.Start(ctx, "MessageService.UpdateMessage", trace.WithAttributes(
+		attribute.Int64("message.id", int64(messageID)),
+	))
+	defer span.End()
+
 	// 驗證訊息內容
 	if req.Content == "" {
 		return nil, ErrEmptyMessageContent
 	}
 
 	// 取得訊息
-	message, err := s.messageRepo.GetByID(messageID)
+	message, err := s.messageRepo.GetByID(ctx, messageID)
 	if err != nil {
 		return nil, ErrMessageNotFound
 	}
@@ -234,33 +481,54 @@ func (s *messageService) UpdateMessage(
 
 	// 更新訊息
 	message.Content = req.Content
-	message.UpdatedAt = time.Now()
+	message.UpdatedAt = s.clock.Now()
+
+	err = s.tx.WithinTx(ctx, func(repos *repository.Repositories) error {
+		if err := repos.Messages.Update(ctx, message); err != nil {
+			return err
+		}
 
-	if err := s.messageRepo.Update(message); err != nil {
+		return repos.Outbox.Append(ctx, s.events.ChannelEvent(
+			message.Channel.GuildID,
+			message.ChannelID,
+			model.EventMessageUpdate,
+			message,
+		))
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	// 重新取得訊息（包含關聯資料）
-	return s.messageRepo.GetByID(message.ID)
+	s.events.Notify()
+
+	return message, nil
 }
 
 // DeleteMessage 刪除訊息
-func (s *messageService) DeleteMessage(messageID, userID uint) error {
+func (s *messageService) DeleteMessage(ctx context.Context, messageID, userID uint) error {
+	ctx, span := s.tracer.Start(ctx, "MessageService.DeleteMessage", trace.WithAttributes(
+		attribute.Int64("message.id", int64(messageID)),
+	))
+	defer span.End()
+
 	// 取得訊息
-	message, err := s.messageRepo.GetByID(messageID)
+	message, err := s.messageRepo.GetByID(ctx, messageID)
 	if err != nil {
 		return ErrMessageNotFound
 	}
 
+	// 刪除他人訊息時需要寫入稽核紀錄
+	var entry *model.AuditLogEntry
+
 	// 檢查是否為訊息擁有者或社群管理員
 	if message.UserID != userID {
 		// 檢查是否為社群管理員
-		channel, err := s.channelRepo.GetByID(message.ChannelID)
+		channel, err := s.channelRepo.GetByID(ctx, message.ChannelID)
 		if err != nil {
-			return errors.New("channel not found")
+			return ErrChannelNotFound
 		}
 
-		member, err := s.guildMemberRepo.GetMember(channel.GuildID, userID)
+		member, err := s.guildMemberRepo.GetMember(ctx, channel.GuildID, userID)
 		if err != nil || member == nil {
 			return ErrNotChannelMemberMsg
 		}
@@ -269,8 +537,151 @@ func (s *messageService) DeleteMessage(messageID, userID uint) error {
 		if member.Role != "owner" && member.Role != "admin" {
 			return ErrNotMessageOwner
 		}
+
+		entry = newAuditEntry(
+			ctx, s.clock.Now(), channel.GuildID, userID,
+			model.AuditActionMessageDelete, model.AuditTargetMessage, message.ID,
+			auditChanges{}.
+				set("channel_id", message.ChannelID, nil).
+				set("author_id", message.UserID, nil),
+		)
 	}
 
 	// 刪除訊息
-	return s.messageRepo.Delete(messageID)
+	err = s.tx.WithinTx(ctx, func(repos *repository.Repositories) error {
+		if err := repos.Messages.Delete(ctx, messageID); err != nil {
+			return err
+		}
+
+		if entry != nil {
+			if err := repos.AuditLogs.Create(ctx, entry); err != nil {
+				return err
+			}
+		}
+
+		return repos.Outbox.Append(ctx, s.events.ChannelEvent(
+			message.Channel.GuildID,
+			message.ChannelID,
+			model.EventMessageDelete,
+			&MessageDeleteEvent{ID: message.ID, ChannelID: message.ChannelID},
+		))
+	})
+	if err != nil {
+		return err
+	}
+
+	s.events.Notify()
+
+	return nil
+}
+
+// reserveRateLimits 依序取得使用者的全域訊息額度與頻道慢速模式的額度，回傳撤回這些額度的函式
+//
+// 慢速模式的額度最後取得，被全域限制擋下時不會佔用慢速模式的間隔；任一項被拒絕時撤回已取得的額度，
+// 訊息寫入失敗時呼叫端也應以回傳的函式撤回。
+// 擁有管理訊息權限的成員不受慢速模式限制；限流器發生錯誤時放行，避免計數儲存故障導致無法發言。
+// 分類頻道的慢速模式會套用到底下的頻道，取兩者中較長的間隔
+func (s *messageService) reserveRateLimits(
+	ctx context.Context,
+	channel *model.Channel,
+	member *model.GuildMember,
+) (func(context.Context), error) {
+	ctx, span := s.tracer.Start(ctx, "MessageService.reserveRateLimits")
+	defer span.End()
+
+	var reserved []string
+
+	release := func(ctx context.Context) {
+		for _, key := range reserved {
+			if err := s.limiter.Release(ctx, key); err != nil {
+				logger.FromContext(ctx, s.logger).Warn(
+					"Failed to release rate limit reservation",
+					zap.String("key", key),
+					zap.Error(err),
+				)
+			}
+		}
+	}
+
+	if s.limiter == nil {
+		return release, nil
+	}
+
+	userKey := strconv.FormatUint(uint64(member.UserID), 10)
+
+	if s.userRateLimit.Enabled() {
+		key := "messages:" + userKey
+
+		ok, err := s.allow(ctx, "user", key, s.userRateLimit)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			reserved = append(reserved, key)
+		}
+	}
+
+	slowMode := s.effectiveSlowMode(ctx, channel)
+	if slowMode > 0 && !hasPermission(member.Role, PermissionManageMessages) {
+		key := "slowmode:" + strconv.FormatUint(uint64(channel.ID), 10) + ":" + userKey
+		interval := time.Duration(slowMode) * time.Second
+
+		ok, err := s.allow(ctx, "slow_mode", key, ratelimit.Rule{Limit: 1, Window: interval})
+		if err != nil {
+			release(ctx)
+			return nil, err
+		}
+
+		if ok {
+			reserved = append(reserved, key)
+		}
+	}
+
+	return release, nil
+}
+
+// effectiveSlowMode 頻道實際套用的慢速模式秒數，所屬分類的設定較長時以分類為準
+func (s *messageService) effectiveSlowMode(ctx context.Context, channel *model.Channel) int {
+	if channel.ParentID == nil {
+		return channel.SlowModeSeconds
+	}
+
+	parent, err := s.channelRepo.GetByID(ctx, *channel.ParentID)
+	if err != nil {
+		logger.FromContext(ctx, s.logger).Warn(
+			"Failed to load parent category, using channel slow mode",
+			zap.Uint("channelID", channel.ID),
+			zap.Error(err),
+		)
+
+		return channel.SlowModeSeconds
+	}
+
+	return max(channel.SlowModeSeconds, parent.SlowModeSeconds)
+}
+
+// allow 依規則計數一次，超過限制時回傳 RateLimitError
+//
+// 回傳是否實際取得額度；限流器無法使用而直接放行時為 false，不需要撤回
+func (s *messageService) allow(
+	ctx context.Context,
+	scope, key string,
+	rule ratelimit.Rule,
+) (bool, error) {
+	allowed, retryAfter, err := s.limiter.Allow(ctx, key, rule.Limit, rule.Window)
+	if err != nil {
+		logger.FromContext(ctx, s.logger).Warn(
+			"Rate limiter unavailable, allowing message",
+			zap.String("scope", scope),
+			zap.Error(err),
+		)
+		return false, nil
+	}
+
+	if !allowed {
+		return false, &RateLimitError{Scope: scope, RetryAfter: retryAfter}
+	}
+
+	return true, nil
 }
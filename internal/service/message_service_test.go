@@ -0,0 +1,259 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/walnut-almonds/talkrealm/internal/model"
+	"github.com/walnut-almonds/talkrealm/pkg/ratelimit"
+)
+
+func TestCreateMessageRequiresMembership(t *testing.T) {
+	env := newTestEnv(t)
+	owner := env.createUser("owner")
+	outsider := env.createUser("outsider")
+	guild := env.createGuild(owner)
+	channel := env.createChannel(
+		guild.ID,
+		owner.ID,
+		&CreateChannelRequest{Name: "general", Type: "text"},
+	)
+	messages := env.messageService()
+
+	_, err := messages.CreateMessage(env.ctx, outsider.ID, &CreateMessageRequest{
+		ChannelID: channel.ID,
+		Content:   "hello",
+	})
+	if !errors.Is(err, ErrNotChannelMemberMsg) {
+		t.Fatalf("CreateMessage() by outsider = %v, want %v", err, ErrNotChannelMemberMsg)
+	}
+
+	_, err = messages.CreateMessage(env.ctx, owner.ID, &CreateMessageRequest{
+		ChannelID: channel.ID,
+		Content:   "",
+	})
+	if !errors.Is(err, ErrEmptyMessageContent) {
+		t.Fatalf("CreateMessage() with empty content = %v, want %v", err, ErrEmptyMessageContent)
+	}
+
+	message, err := messages.CreateMessage(env.ctx, owner.ID, &CreateMessageRequest{
+		ChannelID: channel.ID,
+		Content:   "hello",
+	})
+	if err != nil {
+		t.Fatalf("CreateMessage() = %v", err)
+	}
+
+	if message.Type != "text" {
+		t.Errorf("type = %q, want text", message.Type)
+	}
+
+	if n := env.count("outbox_events", "type = ? AND channel_id = ?",
+		model.EventMessageCreate, channel.ID); n != 1 {
+		t.Errorf("message.create events = %d, want 1", n)
+	}
+}
+
+func TestCategorySlowModeAppliesToChildChannels(t *testing.T) {
+	env := newTestEnv(t)
+	owner := env.createUser("owner")
+	member := env.createUser("member")
+	guild := env.createGuild(owner, member)
+	category := env.createChannel(guild.ID, owner.ID, &CreateChannelRequest{
+		Name:            "category",
+		Type:            ChannelTypeCategory,
+		SlowModeSeconds: 60,
+	})
+	channel := env.createChannel(guild.ID, owner.ID, &CreateChannelRequest{
+		Name:     "child",
+		Type:     "text",
+		ParentID: &category.ID,
+	})
+	messages := env.messageService()
+
+	send := func(user *model.User) error {
+		_, err := messages.CreateMessage(env.ctx, user.ID, &CreateMessageRequest{
+			ChannelID: channel.ID,
+			Content:   "hello",
+		})
+
+		return err
+	}
+
+	if err := send(member); err != nil {
+		t.Fatalf("first message = %v", err)
+	}
+
+	if err := send(member); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("second message within slow mode = %v, want %v", err, ErrRateLimited)
+	}
+
+	// 擁有管理訊息權限的角色不受慢速模式限制
+	for range 2 {
+		if err := send(owner); err != nil {
+			t.Fatalf("message by owner = %v", err)
+		}
+	}
+}
+
+func TestListChannelMessagesPaginates(t *testing.T) {
+	env := newTestEnv(t)
+	owner := env.createUser("owner")
+	guild := env.createGuild(owner)
+	channel := env.createChannel(
+		guild.ID,
+		owner.ID,
+		&CreateChannelRequest{Name: "general", Type: "text"},
+	)
+	messages := env.messageService()
+
+	for range 5 {
+		if _, err := messages.CreateMessage(env.ctx, owner.ID, &CreateMessageRequest{
+			ChannelID: channel.ID,
+			Content:   "hello",
+		}); err != nil {
+			t.Fatalf("CreateMessage() = %v", err)
+		}
+	}
+
+	seen := make(map[uint]bool)
+
+	for page, want := range map[int]int{1: 2, 2: 2, 3: 1} {
+		list, err := messages.ListChannelMessages(env.ctx, channel.ID, owner.ID, page, 2)
+		if err != nil {
+			t.Fatalf("ListChannelMessages(page %d) = %v", page, err)
+		}
+
+		if len(list.Messages) != want {
+			t.Errorf("page %d has %d messages, want %d", page, len(list.Messages), want)
+		}
+
+		for _, message := range list.Messages {
+			if seen[message.ID] {
+				t.Errorf("message %d appears on more than one page", message.ID)
+			}
+
+			seen[message.ID] = true
+		}
+	}
+}
+
+func TestDeleteMessageOfOthersRequiresAdmin(t *testing.T) {
+	env := newTestEnv(t)
+	owner := env.createUser("owner")
+	author := env.createUser("author")
+	member := env.createUser("member")
+	guild := env.createGuild(owner, author, member)
+	channel := env.createChannel(
+		guild.ID,
+		owner.ID,
+		&CreateChannelRequest{Name: "general", Type: "text"},
+	)
+	messages := env.messageService()
+
+	message, err := messages.CreateMessage(env.ctx, author.ID, &CreateMessageRequest{
+		ChannelID: channel.ID,
+		Content:   "hello",
+	})
+	if err != nil {
+		t.Fatalf("CreateMessage() = %v", err)
+	}
+
+	if err := messages.DeleteMessage(env.ctx, message.ID, member.ID); !errors.Is(
+		err,
+		ErrNotMessageOwner,
+	) {
+		t.Fatalf("DeleteMessage() by member = %v, want %v", err, ErrNotMessageOwner)
+	}
+
+	if err := messages.DeleteMessage(env.ctx, message.ID, owner.ID); err != nil {
+		t.Fatalf("DeleteMessage() by owner = %v", err)
+	}
+
+	if n := env.count("audit_log_entries", "action = ?", model.AuditActionMessageDelete); n != 1 {
+		t.Errorf("message.delete audit entries = %d, want 1", n)
+	}
+}
+
+func TestSlowModeSlotReleasedWhenMessageFails(t *testing.T) {
+	env := newTestEnv(t)
+	owner := env.createUser("owner")
+	member := env.createUser("member")
+	guild := env.createGuild(owner, member)
+	channel := env.createChannel(guild.ID, owner.ID, &CreateChannelRequest{
+		Name:            "slow",
+		Type:            "text",
+		SlowModeSeconds: 60,
+	})
+	messages := env.messageService()
+
+	send := func() error {
+		_, err := messages.CreateMessage(env.ctx, member.ID, &CreateMessageRequest{
+			ChannelID: channel.ID,
+			Content:   "hello",
+		})
+
+		return err
+	}
+
+	restore := env.failWrites("messages")
+
+	if err := send(); !errors.Is(err, errInjected) {
+		t.Fatalf("message with failing insert = %v, want %v", err, errInjected)
+	}
+
+	restore()
+
+	// 寫入失敗的訊息沒有佔用慢速模式的間隔
+	if err := send(); err != nil {
+		t.Fatalf("message after failed insert = %v", err)
+	}
+
+	if err := send(); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("second message within slow mode = %v, want %v", err, ErrRateLimited)
+	}
+}
+
+func TestUserRateLimitCheckedBeforeSlowMode(t *testing.T) {
+	env := newTestEnv(t)
+	owner := env.createUser("owner")
+	member := env.createUser("member")
+	guild := env.createGuild(owner, member)
+	fast := env.createChannel(guild.ID, owner.ID, &CreateChannelRequest{
+		Name: "fast",
+		Type: "text",
+	})
+	slow := env.createChannel(guild.ID, owner.ID, &CreateChannelRequest{
+		Name:            "slow",
+		Type:            "text",
+		SlowModeSeconds: 60,
+	})
+
+	limiter := ratelimit.NewMemoryLimiter()
+	limited := env.messageServiceWith(limiter, ratelimit.Rule{Limit: 1, Window: time.Hour})
+
+	send := func(messages MessageService, channel *model.Channel) error {
+		_, err := messages.CreateMessage(env.ctx, member.ID, &CreateMessageRequest{
+			ChannelID: channel.ID,
+			Content:   "hello",
+		})
+
+		return err
+	}
+
+	if err := send(limited, fast); err != nil {
+		t.Fatalf("first message = %v", err)
+	}
+
+	var rateLimited *RateLimitError
+	if err := send(limited, slow); !errors.As(err, &rateLimited) || rateLimited.Scope != "user" {
+		t.Fatalf("message over the user limit = %v, want user rate limit", err)
+	}
+
+	// 被使用者限制擋下的訊息沒有佔用慢速模式的間隔
+	unlimited := env.messageServiceWith(limiter, ratelimit.Rule{})
+	if err := send(unlimited, slow); err != nil {
+		t.Fatalf("message in slow mode channel = %v", err)
+	}
+}
@@ -0,0 +1,457 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/walnut-almonds/talkrealm/internal/apperror"
+	"github.com/walnut-almonds/talkrealm/internal/model"
+	"github.com/walnut-almonds/talkrealm/internal/repository"
+	"github.com/walnut-almonds/talkrealm/pkg/auth"
+	"github.com/walnut-almonds/talkrealm/pkg/clock"
+)
+
+var (
+	ErrOIDCProviderNotFound = apperror.NotFound(
+		"oidc_provider_not_found",
+		"oidc provider not found",
+	)
+	ErrOIDCInvalidState = apperror.InvalidArgument(
+		"oidc_invalid_state",
+		"invalid or expired login state",
+	)
+	ErrOIDCLoginFailed      = apperror.Unauthorized("oidc_login_failed", "oidc login failed")
+	ErrOIDCEmailNotVerified = apperror.Forbidden(
+		"oidc_email_not_verified",
+		"email is not verified by identity provider",
+	)
+	ErrOIDCEmailNotAllowed = apperror.Forbidden(
+		"oidc_email_not_allowed",
+		"email domain is not allowed",
+	)
+	ErrOIDCAccountNotAllowed = apperror.Forbidden(
+		"oidc_account_not_linked",
+		"no account linked to this identity",
+	)
+	ErrOIDCLinkRequired = apperror.Conflict(
+		"oidc_link_required",
+		"an account with this email already exists; sign in and link this identity first",
+	)
+	ErrOIDCIdentityInUse = apperror.Conflict(
+		"oidc_identity_in_use",
+		"this identity is already linked to another account",
+	)
+)
+
+// OIDCProviderInfo 可用的身分提供者資訊
+type OIDCProviderInfo struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	LoginURL    string `json:"login_url"`
+}
+
+// OIDCLoginStart 開始 OIDC 登入流程的結果
+type OIDCLoginStart struct {
+	// 導向身分提供者的授權網址
+	AuthURL string
+	// 需存放於瀏覽器 cookie 的已簽章登入狀態
+	StateToken string
+	StateTTL   time.Duration
+}
+
+// OIDCService OIDC 單一登入服務介面
+type OIDCService interface {
+	ListProviders(ctx context.Context) []*OIDCProviderInfo
+	BeginLogin(ctx context.Context, provider string) (*OIDCLoginStart, error)
+	BeginLink(ctx context.Context, userID uint, provider string) (*OIDCLoginStart, error)
+	CompleteLogin(
+		ctx context.Context,
+		provider, code, state, stateToken string,
+	) (*LoginResponse, error)
+}
+
+type oidcService struct {
+	userRepo     repository.UserRepository
+	identityRepo repository.UserIdentityRepository
+	tx           repository.TxManager
+	oidcManager  *auth.OIDCManager
+	jwtManager   *auth.JWTManager
+	clock        clock.Clock
+}
+
+// NewOIDCService 建立 OIDC 單一登入服務
+func NewOIDCService(
+	userRepo repository.UserRepository,
+	identityRepo repository.UserIdentityRepository,
+	tx repository.TxManager,
+	oidcManager *auth.OIDCManager,
+	jwtManager *auth.JWTManager,
+	clk clock.Clock,
+) OIDCService {
+	return &oidcService{
+		userRepo:     userRepo,
+		identityRepo: identityRepo,
+		tx:           tx,
+		oidcManager:  oidcManager,
+		jwtManager:   jwtManager,
+		clock:        clk,
+	}
+}
+
+// ListProviders 列出已設定的身分提供者
+func (s *oidcService) ListProviders(ctx context.Context) []*OIDCProviderInfo {
+	providers := s.oidcManager.Providers()
+
+	infos := make([]*OIDCProviderInfo, 0, len(providers))
+	for _, p := range providers {
+		displayName := p.DisplayName
+		if displayName == "" {
+			displayName = p.Name
+		}
+
+		infos = append(infos, &OIDCProviderInfo{
+			Name:        p.Name,
+			DisplayName: displayName,
+			LoginURL:    "/api/v1/auth/oidc/" + p.Name + "/login",
+		})
+	}
+
+	return infos
+}
+
+// BeginLogin 產生授權網址與登入狀態
+func (s *oidcService) BeginLogin(ctx context.Context, provider string) (*OIDCLoginStart, error) {
+	return s.begin(ctx, provider, 0)
+}
+
+// BeginLink 讓已登入的使用者把身分提供者的帳號連結到自己，回呼時不比對 email
+func (s *oidcService) BeginLink(
+	ctx context.Context,
+	userID uint,
+	provider string,
+) (*OIDCLoginStart, error) {
+	return s.begin(ctx, provider, userID)
+}
+
+// begin 產生授權網址與登入狀態，linkUserID 不為 0 時回呼會連結到該使用者
+func (s *oidcService) begin(
+	ctx context.Context,
+	provider string,
+	linkUserID uint,
+) (*OIDCLoginStart, error) {
+	p, err := s.oidcManager.Provider(provider)
+	if err != nil {
+		return nil, ErrOIDCProviderNotFound
+	}
+
+	state, err := s.oidcManager.NewState(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	state.LinkUserID = linkUserID
+
+	authURL, err := p.AuthCodeURL(ctx, state)
+	if err != nil {
+		return nil, err
+	}
+
+	stateToken, err := s.oidcManager.SignState(state)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OIDCLoginStart{
+		AuthURL:    authURL,
+		StateToken: stateToken,
+		StateTTL:   s.oidcManager.StateTTL(),
+	}, nil
+}
+
+// CompleteLogin 驗證回呼、連結或建立使用者，並簽發 JWT
+func (s *oidcService) CompleteLogin(
+	ctx context.Context,
+	provider, code, state, stateToken string,
+) (*LoginResponse, error) {
+	p, err := s.oidcManager.Provider(provider)
+	if err != nil {
+		return nil, ErrOIDCProviderNotFound
+	}
+
+	// 驗證登入狀態，防止 CSRF 與授權碼注入
+	loginState, err := s.oidcManager.ParseState(stateToken)
+	if err != nil || loginState.Provider != provider || loginState.State != state {
+		return nil, ErrOIDCInvalidState
+	}
+
+	identity, err := p.Exchange(ctx, code, loginState)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrOIDCLoginFailed, err)
+	}
+
+	var user *model.User
+	if loginState.LinkUserID != 0 {
+		user, err = s.linkUser(ctx, identity, loginState.LinkUserID)
+	} else {
+		user, err = s.resolveUser(ctx, identity, p.Config().AutoCreate)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	// 與密碼登入簽發相同的 JWT
+	token, err := s.jwtManager.GenerateToken(user.ID, user.Username, user.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	_ = s.userRepo.UpdateStatus(ctx, user.ID, "online")
+
+	return &LoginResponse{
+		Token: token,
+		User:  user,
+	}, nil
+}
+
+// resolveUser 依序以身分連結、已驗證的 email 找出使用者，必要時自動建立
+//
+// 本地註冊不驗證 email，任何人都能先以他人的 email 註冊；因此 email 相同時只自動連結沒有本地密碼的帳號，
+// 有密碼的帳號必須登入後以 BeginLink 明確連結，避免身分提供者的使用者登入到他人預先建立的帳號
+func (s *oidcService) resolveUser(
+	ctx context.Context,
+	identity *auth.OIDCIdentity,
+	autoCreate bool,
+) (*model.User, error) {
+	// 已連結過的身分直接登入
+	linked, err := s.linkedIdentity(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	if linked != nil {
+		user, err := s.userRepo.GetByID(ctx, linked.UserID)
+		if err != nil {
+			return nil, ErrUserNotFound
+		}
+
+		return user, nil
+	}
+
+	// 只有經過身分提供者驗證的 email 才能用來連結或建立帳號
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, ErrOIDCEmailNotVerified
+	}
+
+	p, err := s.oidcManager.Provider(identity.Provider)
+	if err != nil {
+		return nil, ErrOIDCProviderNotFound
+	}
+
+	if !p.EmailAllowed(identity.Email) {
+		return nil, ErrOIDCEmailNotAllowed
+	}
+
+	user, err := s.userRepo.GetByEmail(ctx, identity.Email)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return nil, err
+	}
+
+	created := user == nil
+
+	// 無法確定沒有本地密碼的帳號（包含未知）一律要求手動連結
+	if !created && (user.IsBot || !user.SSOOnly()) {
+		return nil, ErrOIDCLinkRequired
+	}
+
+	if created {
+		if !autoCreate {
+			return nil, ErrOIDCAccountNotAllowed
+		}
+
+		user, err = s.newUser(ctx, identity)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// 新建立的使用者與身分連結在同一個交易中寫入，避免留下沒有身分的帳號
+	err = s.tx.WithinTx(ctx, func(repos *repository.Repositories) error {
+		if created {
+			if err := repos.Users.Create(ctx, user); err != nil {
+				return err
+			}
+		}
+
+		return repos.UserIdentities.Create(ctx, s.newIdentity(user.ID, identity))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// linkUser 將身分連結到發起連結的使用者；身分已連結到其他帳號時拒絕
+func (s *oidcService) linkUser(
+	ctx context.Context,
+	identity *auth.OIDCIdentity,
+	userID uint,
+) (*model.User, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	linked, err := s.linkedIdentity(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	if linked != nil {
+		if linked.UserID != userID {
+			return nil, ErrOIDCIdentityInUse
+		}
+
+		return user, nil
+	}
+
+	p, err := s.oidcManager.Provider(identity.Provider)
+	if err != nil {
+		return nil, ErrOIDCProviderNotFound
+	}
+
+	if !p.EmailAllowed(identity.Email) {
+		return nil, ErrOIDCEmailNotAllowed
+	}
+
+	if err := s.identityRepo.Create(ctx, s.newIdentity(userID, identity)); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			return nil, ErrOIDCIdentityInUse
+		}
+
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// linkedIdentity 取得已連結的身分，尚未連結時回傳 nil
+func (s *oidcService) linkedIdentity(
+	ctx context.Context,
+	identity *auth.OIDCIdentity,
+) (*model.UserIdentity, error) {
+	linked, err := s.identityRepo.GetByProviderSubject(ctx, identity.Provider, identity.Subject)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return linked, nil
+}
+
+// newIdentity 建立身分連結紀錄（尚未寫入）
+func (s *oidcService) newIdentity(userID uint, identity *auth.OIDCIdentity) *model.UserIdentity {
+	return &model.UserIdentity{
+		UserID:    userID,
+		Provider:  identity.Provider,
+		Subject:   identity.Subject,
+		Email:     identity.Email,
+		CreatedAt: s.clock.Now(),
+		UpdatedAt: s.clock.Now(),
+	}
+}
+
+// newUser 依 ID token 的資料產生新使用者（尚未寫入）
+func (s *oidcService) newUser(
+	ctx context.Context,
+	identity *auth.OIDCIdentity,
+) (*model.User, error) {
+	username, err := s.uniqueUsername(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	// SSO 使用者沒有本地密碼，填入無法被猜中的隨機雜湊
+	hashedPassword, err := randomPasswordHash()
+	if err != nil {
+		return nil, err
+	}
+
+	noLocalPassword := true
+
+	nickname := identity.Name
+	if nickname == "" {
+		nickname = username
+	}
+
+	if len([]rune(nickname)) > 64 {
+		nickname = string([]rune(nickname)[:64])
+	}
+
+	return &model.User{
+		Username:        username,
+		Email:           identity.Email,
+		Password:        hashedPassword,
+		NoLocalPassword: &noLocalPassword,
+		Nickname:        nickname,
+		Avatar:          identity.Picture,
+		Status:          "offline",
+		CreatedAt:       s.clock.Now(),
+		UpdatedAt:       s.clock.Now(),
+	}, nil
+}
+
+// uniqueUsername 由 preferred_username 或 email 產生不重複的使用者名稱
+func (s *oidcService) uniqueUsername(
+	ctx context.Context,
+	identity *auth.OIDCIdentity,
+) (string, error) {
+	base := sanitizeUsername(identity.PreferredUsername)
+	if len(base) < 3 {
+		base = sanitizeUsername(strings.SplitN(identity.Email, "@", 2)[0])
+	}
+
+	if len(base) < 3 || isReservedUsername(base) {
+		base = "user"
+	}
+
+	if len(base) > 24 {
+		base = base[:24]
+	}
+
+	candidate := base
+	for i := 1; i <= 100; i++ {
+		_, err := s.userRepo.GetByUsername(ctx, candidate)
+		if errors.Is(err, repository.ErrNotFound) {
+			return candidate, nil
+		}
+
+		if err != nil {
+			return "", err
+		}
+
+		candidate = fmt.Sprintf("%s%d", base, i)
+	}
+
+	return "", fmt.Errorf("failed to allocate username for %s", identity.Email)
+}
+
+// sanitizeUsername 只保留使用者名稱允許的字元
+func sanitizeUsername(name string) string {
+	var b strings.Builder
+
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' || r == '.' || r == '-' {
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,408 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/walnut-almonds/talkrealm/internal/model"
+	"github.com/walnut-almonds/talkrealm/pkg/auth"
+	"github.com/walnut-almonds/talkrealm/pkg/config"
+)
+
+const (
+	fakeOIDCProvider = "fake"
+	fakeOIDCClientID = "talkrealm"
+	fakeOIDCKeyID    = "test-key"
+)
+
+// fakeOIDC 以 httptest 實作的身分提供者，提供 discovery、JWKS 與 token 端點
+//
+// 測試以 authorize 模擬使用者在提供者端完成登入，取得授權碼後交給 CompleteLogin
+type fakeOIDC struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu     sync.Mutex
+	grants map[string]fakeOIDCGrant
+}
+
+// fakeOIDCGrant 授權碼對應的 PKCE challenge 與 ID token claims
+type fakeOIDCGrant struct {
+	challenge string
+	claims    jwt.MapClaims
+}
+
+func newFakeOIDC(t *testing.T) *fakeOIDC {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	f := &fakeOIDC{t: t, key: key, grants: make(map[string]fakeOIDCGrant)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", f.discovery)
+	mux.HandleFunc("GET /jwks", f.jwks)
+	mux.HandleFunc("POST /token", f.token)
+
+	f.server = httptest.NewServer(mux)
+	t.Cleanup(f.server.Close)
+
+	return f
+}
+
+func (f *fakeOIDC) discovery(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                f.server.URL,
+		"authorization_endpoint":                f.server.URL + "/authorize",
+		"token_endpoint":                        f.server.URL + "/token",
+		"jwks_uri":                              f.server.URL + "/jwks",
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+func (f *fakeOIDC) jwks(w http.ResponseWriter, _ *http.Request) {
+	pub := f.key.PublicKey
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": fakeOIDCKeyID,
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+// token 驗證授權碼與 PKCE verifier 後簽發 ID token
+func (f *fakeOIDC) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	f.mu.Lock()
+	grant, ok := f.grants[r.PostForm.Get("code")]
+	delete(f.grants, r.PostForm.Get("code"))
+	f.mu.Unlock()
+
+	verifier := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(verifier[:]) != grant.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, grant.claims)
+	token.Header["kid"] = fakeOIDCKeyID
+
+	idToken, err := token.SignedString(f.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": "access-token",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+// authorize 模擬使用者在提供者端登入，回傳授權碼與授權網址中的 state
+//
+// ID token 預設帶入授權網址中的 nonce，claims 可覆寫任何欄位
+func (f *fakeOIDC) authorize(authURL string, claims jwt.MapClaims) (code, state string) {
+	f.t.Helper()
+
+	u, err := url.Parse(authURL)
+	if err != nil {
+		f.t.Fatalf("parse auth url: %v", err)
+	}
+
+	query := u.Query()
+	if query.Get("client_id") != fakeOIDCClientID || query.Get("code_challenge_method") != "S256" {
+		f.t.Fatalf("auth url %s is missing the client id or PKCE challenge", authURL)
+	}
+
+	now := time.Now()
+	idClaims := jwt.MapClaims{
+		"iss":   f.server.URL,
+		"aud":   fakeOIDCClientID,
+		"sub":   "subject-1",
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+		"nonce": query.Get("nonce"),
+	}
+	for k, v := range claims {
+		idClaims[k] = v
+	}
+
+	code = rand.Text()
+
+	f.mu.Lock()
+	f.grants[code] = fakeOIDCGrant{challenge: query.Get("code_challenge"), claims: idClaims}
+	f.mu.Unlock()
+
+	return code, query.Get("state")
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func (e *testEnv) oidcService(issuerURL string, autoCreate bool) OIDCService {
+	manager := auth.NewOIDCManager("test-secret", &config.OIDCConfig{
+		Providers: []config.OIDCProviderConfig{{
+			Name:        fakeOIDCProvider,
+			IssuerURL:   issuerURL,
+			ClientID:    fakeOIDCClientID,
+			RedirectURL: "http://localhost/api/v1/auth/oidc/fake/callback",
+			AutoCreate:  autoCreate,
+		}},
+	})
+
+	return NewOIDCService(
+		e.repos.Users,
+		e.repos.UserIdentities,
+		e.tx,
+		manager,
+		auth.NewJWTManager("test-secret", time.Hour),
+		e.clock,
+	)
+}
+
+// oidcLogin 走完一次登入流程，claims 覆寫提供者簽發的 ID token 內容
+func (e *testEnv) oidcLogin(
+	provider *fakeOIDC,
+	oidc OIDCService,
+	claims jwt.MapClaims,
+) (*LoginResponse, error) {
+	e.t.Helper()
+
+	start, err := oidc.BeginLogin(e.ctx, fakeOIDCProvider)
+	if err != nil {
+		e.t.Fatalf("BeginLogin() = %v", err)
+	}
+
+	code, state := provider.authorize(start.AuthURL, claims)
+
+	return oidc.CompleteLogin(e.ctx, fakeOIDCProvider, code, state, start.StateToken)
+}
+
+func TestOIDCLoginCreatesAccountJustInTime(t *testing.T) {
+	env := newTestEnv(t)
+	provider := newFakeOIDC(t)
+	oidc := env.oidcService(provider.server.URL, true)
+	claims := jwt.MapClaims{
+		"email":              "Alice@Example.com",
+		"email_verified":     true,
+		"preferred_username": "alice",
+		"name":               "Alice",
+	}
+
+	resp, err := env.oidcLogin(provider, oidc, claims)
+	if err != nil {
+		t.Fatalf("CompleteLogin() = %v", err)
+	}
+
+	if resp.Token == "" || resp.User.Username != "alice" ||
+		resp.User.Email != "alice@example.com" || !resp.User.SSOOnly() {
+		t.Errorf("user = %+v, want a new SSO account for alice@example.com", resp.User)
+	}
+
+	if n := env.count("user_identities", "provider = ? AND subject = ? AND user_id = ?",
+		fakeOIDCProvider, "subject-1", resp.User.ID); n != 1 {
+		t.Errorf("linked identities = %d, want 1", n)
+	}
+
+	// 再次登入使用已連結的身分，不會重複建立帳號
+	again, err := env.oidcLogin(provider, oidc, claims)
+	if err != nil {
+		t.Fatalf("second CompleteLogin() = %v", err)
+	}
+
+	if again.User.ID != resp.User.ID {
+		t.Errorf("second login user = %d, want %d", again.User.ID, resp.User.ID)
+	}
+
+	if n := env.count("users", ""); n != 1 {
+		t.Errorf("users = %d, want 1", n)
+	}
+}
+
+func TestOIDCLoginWithoutAutoCreateRequiresExistingAccount(t *testing.T) {
+	env := newTestEnv(t)
+	provider := newFakeOIDC(t)
+
+	_, err := env.oidcLogin(provider, env.oidcService(provider.server.URL, false), jwt.MapClaims{
+		"email":          "alice@example.com",
+		"email_verified": true,
+	})
+	if !errors.Is(err, ErrOIDCAccountNotAllowed) {
+		t.Fatalf("CompleteLogin() = %v, want %v", err, ErrOIDCAccountNotAllowed)
+	}
+
+	if n := env.count("users", ""); n != 0 {
+		t.Errorf("users = %d, want 0", n)
+	}
+}
+
+func TestOIDCLoginRejectsUnverifiedEmail(t *testing.T) {
+	env := newTestEnv(t)
+	provider := newFakeOIDC(t)
+	oidc := env.oidcService(provider.server.URL, true)
+
+	for name, verified := range map[string]any{
+		"false":   false,
+		"string":  "false",
+		"missing": nil,
+	} {
+		claims := jwt.MapClaims{"email": "alice@example.com"}
+		if verified != nil {
+			claims["email_verified"] = verified
+		}
+
+		if _, err := env.oidcLogin(provider, oidc, claims); !errors.Is(
+			err,
+			ErrOIDCEmailNotVerified,
+		) {
+			t.Errorf("CompleteLogin() with %s email_verified = %v, want %v",
+				name, err, ErrOIDCEmailNotVerified)
+		}
+	}
+
+	if n := env.count("users", ""); n != 0 {
+		t.Errorf("users = %d, want 0", n)
+	}
+}
+
+func TestOIDCLoginDoesNotTakeOverPasswordAccounts(t *testing.T) {
+	env := newTestEnv(t)
+	env.createUser("alice")
+	provider := newFakeOIDC(t)
+
+	_, err := env.oidcLogin(provider, env.oidcService(provider.server.URL, true), jwt.MapClaims{
+		"email":          "alice@example.com",
+		"email_verified": true,
+	})
+	if !errors.Is(err, ErrOIDCLinkRequired) {
+		t.Fatalf("CompleteLogin() = %v, want %v", err, ErrOIDCLinkRequired)
+	}
+
+	if n := env.count("user_identities", ""); n != 0 {
+		t.Errorf("linked identities = %d, want 0", n)
+	}
+}
+
+func TestOIDCLoginRejectsNonceMismatch(t *testing.T) {
+	env := newTestEnv(t)
+	provider := newFakeOIDC(t)
+
+	_, err := env.oidcLogin(provider, env.oidcService(provider.server.URL, true), jwt.MapClaims{
+		"email":          "alice@example.com",
+		"email_verified": true,
+		"nonce":          "replayed-nonce",
+	})
+	if !errors.Is(err, ErrOIDCLoginFailed) || !errors.Is(err, auth.ErrOIDCInvalidIDToken) {
+		t.Fatalf("CompleteLogin() = %v, want %v", err, auth.ErrOIDCInvalidIDToken)
+	}
+
+	if n := env.count("users", ""); n != 0 {
+		t.Errorf("users = %d, want 0", n)
+	}
+}
+
+func TestOIDCLoginRejectsStateMismatch(t *testing.T) {
+	env := newTestEnv(t)
+	provider := newFakeOIDC(t)
+	oidc := env.oidcService(provider.server.URL, true)
+
+	start, err := oidc.BeginLogin(env.ctx, fakeOIDCProvider)
+	if err != nil {
+		t.Fatalf("BeginLogin() = %v", err)
+	}
+
+	other, err := oidc.BeginLogin(env.ctx, fakeOIDCProvider)
+	if err != nil {
+		t.Fatalf("BeginLogin() = %v", err)
+	}
+
+	code, state := provider.authorize(start.AuthURL, jwt.MapClaims{
+		"email":          "alice@example.com",
+		"email_verified": true,
+	})
+
+	for name, tt := range map[string]struct {
+		state, stateToken string
+	}{
+		"state from another login": {state: state, stateToken: other.StateToken},
+		"tampered state":           {state: state + "x", stateToken: start.StateToken},
+		"missing cookie":           {state: state, stateToken: ""},
+	} {
+		if _, err := oidc.CompleteLogin(
+			env.ctx, fakeOIDCProvider, code, tt.state, tt.stateToken,
+		); !errors.Is(err, ErrOIDCInvalidState) {
+			t.Errorf("CompleteLogin() with %s = %v, want %v", name, err, ErrOIDCInvalidState)
+		}
+	}
+
+	// 狀態檢查在交換授權碼之前，授權碼仍然有效
+	if _, err := oidc.CompleteLogin(
+		env.ctx, fakeOIDCProvider, code, state, start.StateToken,
+	); err != nil {
+		t.Fatalf("CompleteLogin() with matching state = %v", err)
+	}
+}
+
+func TestOIDCLinkAttachesIdentityToSignedInUser(t *testing.T) {
+	env := newTestEnv(t)
+	alice := env.createUser("alice")
+	provider := newFakeOIDC(t)
+	oidc := env.oidcService(provider.server.URL, false)
+
+	start, err := oidc.BeginLink(env.ctx, alice.ID, fakeOIDCProvider)
+	if err != nil {
+		t.Fatalf("BeginLink() = %v", err)
+	}
+
+	// 連結時不要求 email 相同或已驗證
+	code, state := provider.authorize(start.AuthURL, jwt.MapClaims{"email": "a@corp.example"})
+
+	resp, err := oidc.CompleteLogin(env.ctx, fakeOIDCProvider, code, state, start.StateToken)
+	if err != nil {
+		t.Fatalf("CompleteLogin() = %v", err)
+	}
+
+	if resp.User.ID != alice.ID {
+		t.Errorf("linked user = %d, want %d", resp.User.ID, alice.ID)
+	}
+
+	var identity model.UserIdentity
+	if err := env.db.Where("provider = ?", fakeOIDCProvider).First(&identity).Error; err != nil {
+		t.Fatalf("get identity: %v", err)
+	}
+
+	if identity.UserID != alice.ID || identity.Subject != "subject-1" {
+		t.Errorf("identity = %+v, want subject-1 linked to %d", identity, alice.ID)
+	}
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"slices"
+)
+
+// Permission 社群內的操作權限
+type Permission string
+
+// 社群權限
+const (
+	PermissionBanMembers      Permission = "ban_members"
+	PermissionModerateMembers Permission = "moderate_members"
+	PermissionViewAuditLog    Permission = "view_audit_log"
+	PermissionManageChannels  Permission = "manage_channels"
+	PermissionManageMessages  Permission = "manage_messages"
+	PermissionManageWebhooks  Permission = "manage_webhooks"
+)
+
+// rolePermissions 各社群角色擁有的權限（擁有者擁有所有權限）
+var rolePermissions = map[string][]Permission{
+	"admin": {
+		PermissionBanMembers,
+		PermissionModerateMembers,
+		PermissionViewAuditLog,
+		PermissionManageChannels,
+		PermissionManageMessages,
+		PermissionManageWebhooks,
+	},
+	"moderator": {
+		PermissionModerateMembers,
+	},
+}
+
+// hasPermission 檢查社群角色是否擁有指定權限
+func hasPermission(role string, permission Permission) bool {
+	if role == "owner" {
+		return true
+	}
+
+	return slices.Contains(rolePermissions[role], permission)
+}
+
+// roleRank 社群角色的權限高低
+func roleRank(role string) int {
+	switch role {
+	case "owner":
+		return 3
+	case "admin":
+		return 2
+	case "moderator":
+		return 1
+	default:
+		return 0
+	}
+}
+
+// outranks 檢查角色 a 的權限是否高於角色 b
+func outranks(a, b string) bool {
+	return roleRank(a) > roleRank(b)
+}
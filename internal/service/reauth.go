@@ -0,0 +1,56 @@
+package service
+
+import (
+	"time"
+
+	"github.com/walnut-almonds/talkrealm/internal/apperror"
+	"github.com/walnut-almonds/talkrealm/internal/model"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrReauthenticationRequired 以 SSO 建立的帳號必須在最近重新登入後才能執行敏感操作
+var ErrReauthenticationRequired = apperror.Forbidden(
+	"reauthentication_required",
+	"sign in again to confirm this action",
+)
+
+// confirmIdentity 確認敏感操作的執行者身分
+//
+// 有本地密碼的帳號必須提供正確的密碼；以 SSO 建立的帳號沒有可用的密碼，
+// 改為要求登入時間（JWT 的簽發時間）在 window 之內。
+// 無法確定是否有本地密碼的帳號接受兩者之一
+func confirmIdentity(
+	user *model.User,
+	password string,
+	authTime, now time.Time,
+	window time.Duration,
+) error {
+	passwordMatches := func() bool {
+		return bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) == nil
+	}
+
+	recentLogin := !authTime.IsZero() && now.Sub(authTime) <= window
+
+	switch {
+	case user.HasLocalPassword():
+		if !passwordMatches() {
+			return ErrInvalidCredentials
+		}
+	case user.SSOOnly():
+		if !recentLogin {
+			return ErrReauthenticationRequired
+		}
+	default:
+		if recentLogin || (password != "" && passwordMatches()) {
+			return nil
+		}
+
+		if password != "" {
+			return ErrInvalidCredentials
+		}
+
+		return ErrReauthenticationRequired
+	}
+
+	return nil
+}
@@ -0,0 +1,273 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/walnut-almonds/talkrealm/internal/model"
+	"github.com/walnut-almonds/talkrealm/internal/repository"
+	"github.com/walnut-almonds/talkrealm/pkg/auth"
+	"github.com/walnut-almonds/talkrealm/pkg/clock"
+	"github.com/walnut-almonds/talkrealm/pkg/database"
+	"github.com/walnut-almonds/talkrealm/pkg/idgen"
+	"github.com/walnut-almonds/talkrealm/pkg/metrics"
+	"github.com/walnut-almonds/talkrealm/pkg/ratelimit"
+	"go.opentelemetry.io/otel/trace/noop"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// testPassword 測試使用者的密碼
+const testPassword = "correct-horse"
+
+// errInjected failWrites 注入的寫入錯誤
+var errInjected = errors.New("injected write failure")
+
+// testEnv 以記憶體 SQLite 與實際的 repository 組成的服務測試環境
+//
+// 事件只寫入 outbox、不會轉送給訂閱者；時間固定為 now，可用 advance 推進
+type testEnv struct {
+	t      *testing.T
+	ctx    context.Context
+	db     *gorm.DB
+	repos  *repository.Repositories
+	tx     repository.TxManager
+	events EventBus
+	clock  clock.Clock
+	now    time.Time
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	db, err := database.OpenInMemory()
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { _ = database.Close(db) })
+
+	env := &testEnv{
+		t:     t,
+		ctx:   context.Background(),
+		db:    db,
+		repos: repository.NewRepositories(db),
+		tx:    repository.NewTxManager(db, nil),
+		now:   time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC),
+	}
+	env.clock = clock.Func(func() time.Time { return env.now })
+	env.events = NewEventBus(
+		env.repos.Outbox,
+		EventBusOptions{},
+		idgen.Random(),
+		noop.NewTracerProvider().Tracer("test"),
+		env.clock,
+		zap.NewNop(),
+	)
+
+	return env
+}
+
+// advance 推進目前時間
+func (e *testEnv) advance(d time.Duration) {
+	e.now = e.now.Add(d)
+}
+
+func (e *testEnv) userService() UserService {
+	return NewUserService(e.repos.Users, auth.NewJWTManager("test-secret", time.Hour), e.clock)
+}
+
+func (e *testEnv) guildService() GuildService {
+	return NewGuildService(
+		e.repos.Guilds,
+		e.repos.GuildMembers,
+		e.repos.Users,
+		e.tx,
+		e.events,
+		10*time.Minute,
+		e.clock,
+		zap.NewNop(),
+	)
+}
+
+func (e *testEnv) guildMemberService() GuildMemberService {
+	return NewGuildMemberService(
+		e.repos.Guilds,
+		e.repos.GuildMembers,
+		e.repos.GuildBans,
+		e.tx,
+		e.events,
+		e.clock,
+		zap.NewNop(),
+	)
+}
+
+func (e *testEnv) channelService() ChannelService {
+	return NewChannelService(
+		e.repos.Channels,
+		e.repos.Guilds,
+		e.repos.GuildMembers,
+		e.tx,
+		e.events,
+		e.clock,
+		zap.NewNop(),
+	)
+}
+
+func (e *testEnv) messageService() MessageService {
+	return e.messageServiceWith(ratelimit.NewMemoryLimiter(), ratelimit.Rule{})
+}
+
+// messageServiceWith 以指定的限流器與使用者訊息速率限制建立訊息服務
+func (e *testEnv) messageServiceWith(
+	limiter ratelimit.Limiter,
+	userRateLimit ratelimit.Rule,
+) MessageService {
+	return NewMessageService(
+		e.repos.Messages,
+		e.repos.Channels,
+		e.repos.GuildMembers,
+		e.tx,
+		limiter,
+		userRateLimit,
+		e.events,
+		metrics.New(),
+		noop.NewTracerProvider().Tracer("test"),
+		e.clock,
+		zap.NewNop(),
+	)
+}
+
+func (e *testEnv) accountService() AccountService {
+	return NewAccountService(
+		e.repos.Users,
+		e.tx,
+		e.events,
+		24*time.Hour,
+		10*time.Minute,
+		e.clock,
+		zap.NewNop(),
+	)
+}
+
+// createUser 建立密碼為 testPassword 的使用者
+func (e *testEnv) createUser(username string) *model.User {
+	e.t.Helper()
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		e.t.Fatalf("hash password: %v", err)
+	}
+
+	user := &model.User{
+		Username:  username,
+		Email:     username + "@example.com",
+		Password:  string(hashed),
+		Nickname:  username,
+		Status:    "offline",
+		CreatedAt: e.now,
+		UpdatedAt: e.now,
+	}
+	if err := e.repos.Users.Create(e.ctx, user); err != nil {
+		e.t.Fatalf("create user %s: %v", username, err)
+	}
+
+	return user
+}
+
+// createGuild 建立 owner 擁有的社群，members 依序以一般成員身分加入
+func (e *testEnv) createGuild(owner *model.User, members ...*model.User) *model.Guild {
+	e.t.Helper()
+
+	guild, err := e.guildService().CreateGuild(e.ctx, owner.ID, &CreateGuildRequest{Name: "guild"})
+	if err != nil {
+		e.t.Fatalf("create guild: %v", err)
+	}
+
+	for _, member := range members {
+		e.advance(time.Minute)
+
+		if err := e.guildMemberService().JoinGuild(e.ctx, guild.ID, member.ID); err != nil {
+			e.t.Fatalf("join guild as %s: %v", member.Username, err)
+		}
+	}
+
+	return guild
+}
+
+// setRole 直接修改成員角色
+func (e *testEnv) setRole(guildID, userID uint, role string) {
+	e.t.Helper()
+
+	if err := e.repos.GuildMembers.UpdateRole(e.ctx, guildID, userID, role, e.now); err != nil {
+		e.t.Fatalf("set role: %v", err)
+	}
+}
+
+// createChannel 由 userID 在社群中建立頻道
+func (e *testEnv) createChannel(guildID, userID uint, req *CreateChannelRequest) *model.Channel {
+	e.t.Helper()
+
+	req.GuildID = guildID
+
+	channel, err := e.channelService().CreateChannel(e.ctx, userID, req)
+	if err != nil {
+		e.t.Fatalf("create channel %s: %v", req.Name, err)
+	}
+
+	return channel
+}
+
+// count 回傳資料表中符合條件的列數
+func (e *testEnv) count(table, query string, args ...any) int64 {
+	e.t.Helper()
+
+	var n int64
+
+	db := e.db.Table(table)
+	if query != "" {
+		db = db.Where(query, args...)
+	}
+
+	if err := db.Count(&n).Error; err != nil {
+		e.t.Fatalf("count %s: %v", table, err)
+	}
+
+	return n
+}
+
+// failWrites 讓之後對 table 的新增、更新與刪除回傳 errInjected，用於驗證交易回滾；呼叫 restore 恢復正常寫入
+func (e *testEnv) failWrites(table string) (restore func()) {
+	e.t.Helper()
+
+	inject := func(tx *gorm.DB) {
+		if tx.Statement.Table == table {
+			_ = tx.AddError(errInjected)
+		}
+	}
+
+	callbacks := e.db.Callback()
+	for _, err := range []error{
+		callbacks.Create().Before("gorm:create").Register("test:fail_create", inject),
+		callbacks.Update().Before("gorm:update").Register("test:fail_update", inject),
+		callbacks.Delete().Before("gorm:delete").Register("test:fail_delete", inject),
+	} {
+		if err != nil {
+			e.t.Fatalf("register failure callback: %v", err)
+		}
+	}
+
+	return func() {
+		for _, err := range []error{
+			callbacks.Create().Remove("test:fail_create"),
+			callbacks.Update().Remove("test:fail_update"),
+			callbacks.Delete().Remove("test:fail_delete"),
+		} {
+			if err != nil {
+				e.t.Fatalf("remove failure callback: %v", err)
+			}
+		}
+	}
+}
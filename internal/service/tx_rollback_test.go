@@ -0,0 +1,186 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCreateGuildRollsBackWhenOwnerMembershipFails(t *testing.T) {
+	env := newTestEnv(t)
+	owner := env.createUser("owner")
+
+	env.failWrites("guild_members")
+
+	_, err := env.guildService().
+		CreateGuild(env.ctx, owner.ID, &CreateGuildRequest{Name: "guild"})
+	if !errors.Is(err, errInjected) {
+		t.Fatalf("CreateGuild() = %v, want %v", err, errInjected)
+	}
+
+	if n := env.count("guilds", ""); n != 0 {
+		t.Errorf("guilds = %d, want 0", n)
+	}
+}
+
+func TestTransferOwnershipRollsBackWhenEventFails(t *testing.T) {
+	env := newTestEnv(t)
+	owner := env.createUser("owner")
+	member := env.createUser("member")
+	guild := env.createGuild(owner, member)
+	events := env.count("outbox_events", "")
+
+	env.failWrites("outbox_events")
+
+	_, err := env.guildService().
+		TransferOwnership(env.ctx, guild.ID, owner.ID, &TransferOwnershipRequest{
+			NewOwnerID: member.ID,
+			Password:   testPassword,
+		})
+	if !errors.Is(err, errInjected) {
+		t.Fatalf("TransferOwnership() = %v, want %v", err, errInjected)
+	}
+
+	unchanged, err := env.repos.Guilds.GetByID(env.ctx, guild.ID)
+	if err != nil {
+		t.Fatalf("get guild: %v", err)
+	}
+
+	if unchanged.OwnerID != owner.ID {
+		t.Errorf("owner = %d, want %d", unchanged.OwnerID, owner.ID)
+	}
+
+	for userID, want := range map[uint]string{owner.ID: "owner", member.ID: "member"} {
+		got, err := env.guildMemberService().GetMember(env.ctx, guild.ID, userID)
+		if err != nil || got.Role != want {
+			t.Errorf("role of user %d = %v, %v, want %s", userID, got, err, want)
+		}
+	}
+
+	if n := env.count("audit_log_entries", ""); n != 0 {
+		t.Errorf("audit entries = %d, want 0", n)
+	}
+
+	if n := env.count("outbox_events", ""); n != events {
+		t.Errorf("outbox events = %d, want %d", n, events)
+	}
+}
+
+func TestBanMemberRollsBackWhenAuditFails(t *testing.T) {
+	env := newTestEnv(t)
+	owner := env.createUser("owner")
+	member := env.createUser("member")
+	guild := env.createGuild(owner, member)
+	channel := env.createChannel(
+		guild.ID,
+		owner.ID,
+		&CreateChannelRequest{Name: "general", Type: "text"},
+	)
+
+	if _, err := env.messageService().CreateMessage(env.ctx, member.ID, &CreateMessageRequest{
+		ChannelID: channel.ID,
+		Content:   "spam",
+	}); err != nil {
+		t.Fatalf("CreateMessage() = %v", err)
+	}
+
+	env.failWrites("audit_log_entries")
+
+	_, _, err := env.guildMemberService().
+		BanMember(env.ctx, guild.ID, member.ID, owner.ID, &BanMemberRequest{
+			Reason:            "spam",
+			DeleteMessageDays: 1,
+		})
+	if !errors.Is(err, errInjected) {
+		t.Fatalf("BanMember() = %v, want %v", err, errInjected)
+	}
+
+	if _, err := env.guildMemberService().GetMember(env.ctx, guild.ID, member.ID); err != nil {
+		t.Errorf("member removed despite the failed ban: %v", err)
+	}
+
+	if n := env.count("guild_bans", ""); n != 0 {
+		t.Errorf("bans = %d, want 0", n)
+	}
+
+	if n := env.count("messages", "user_id = ?", member.ID); n != 1 {
+		t.Errorf("messages by member = %d, want 1", n)
+	}
+}
+
+func TestPurgeRollsBackWhenAnyStepFails(t *testing.T) {
+	env := newTestEnv(t)
+	owner := env.createUser("owner")
+	member := env.createUser("member")
+	guild := env.createGuild(owner, member)
+	channel := env.createChannel(
+		guild.ID,
+		owner.ID,
+		&CreateChannelRequest{Name: "general", Type: "text"},
+	)
+	accounts := env.accountService()
+
+	if _, err := env.messageService().CreateMessage(env.ctx, owner.ID, &CreateMessageRequest{
+		ChannelID: channel.ID,
+		Content:   "hello",
+	}); err != nil {
+		t.Fatalf("CreateMessage() = %v", err)
+	}
+
+	if _, err := accounts.RequestDeletion(
+		env.ctx,
+		owner.ID,
+		&DeleteAccountRequest{Password: testPassword},
+	); err != nil {
+		t.Fatalf("RequestDeletion() = %v", err)
+	}
+
+	env.advance(25 * time.Hour)
+
+	// 訊息與社群轉移完成後才刪除 API token，失敗時前面的步驟也必須回滾
+	events := env.count("outbox_events", "")
+	env.failWrites("api_tokens")
+
+	if err := accounts.PurgeDueAccounts(env.ctx); !errors.Is(err, errInjected) {
+		t.Fatalf("PurgeDueAccounts() = %v, want %v", err, errInjected)
+	}
+
+	unchanged, err := env.repos.Users.GetByID(env.ctx, owner.ID)
+	if err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+
+	if unchanged.AnonymizedAt != nil || unchanged.Email != owner.Email {
+		t.Errorf("user = %+v, want untouched", unchanged)
+	}
+
+	kept, err := env.repos.Guilds.GetByID(env.ctx, guild.ID)
+	if err != nil {
+		t.Fatalf("get guild: %v", err)
+	}
+
+	if kept.OwnerID != owner.ID {
+		t.Errorf("guild owner = %d, want %d", kept.OwnerID, owner.ID)
+	}
+
+	if n := env.count("messages", "user_id = ?", owner.ID); n != 1 {
+		t.Errorf("messages by user = %d, want 1", n)
+	}
+
+	if n := env.count("guild_members", "user_id = ?", owner.ID); n != 1 {
+		t.Errorf("memberships = %d, want 1", n)
+	}
+
+	if n := env.count("audit_log_entries", "reason = ?", purgeAuditReason); n != 0 {
+		t.Errorf("purge audit entries = %d, want 0", n)
+	}
+
+	if n := env.count("outbox_events", ""); n != events {
+		t.Errorf("outbox events = %d, want %d", n, events)
+	}
+
+	// 故障排除後下次排程會重試
+	if n := env.count("users", "id = ? AND deletion_scheduled_at IS NOT NULL", owner.ID); n != 1 {
+		t.Errorf("deletion no longer scheduled after the failed purge")
+	}
+}
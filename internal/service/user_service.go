@@ -1,19 +1,23 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"time"
 
+	"github.com/walnut-almonds/talkrealm/internal/apperror"
 	"github.com/walnut-almonds/talkrealm/internal/model"
 	"github.com/walnut-almonds/talkrealm/internal/repository"
 	"github.com/walnut-almonds/talkrealm/pkg/auth"
+	"github.com/walnut-almonds/talkrealm/pkg/clock"
 	"golang.org/x/crypto/bcrypt"
 )
 
 var (
-	ErrUserExists         = errors.New("user already exists")
-	ErrInvalidCredentials = errors.New("invalid credentials")
-	ErrUserNotFound       = errors.New("user not found")
+	ErrUserExists         = apperror.Conflict("user_exists", "user already exists")
+	ErrInvalidCredentials = apperror.Unauthorized("invalid_credentials", "invalid credentials")
+	ErrUserNotFound       = apperror.NotFound("user_not_found", "user not found")
+	ErrAccountDeleted     = apperror.Unauthorized("account_deleted", "account has been deleted")
 )
 
 // RegisterRequest 註冊請求
@@ -45,36 +49,48 @@ type UpdateUserRequest struct {
 
 // UserService 使用者服務介面
 type UserService interface {
-	Register(req *RegisterRequest) (*model.User, error)
-	Login(req *LoginRequest) (*LoginResponse, error)
-	GetByID(id uint) (*model.User, error)
-	Update(id uint, req *UpdateUserRequest) (*model.User, error)
-	UpdateStatus(id uint, status string) error
+	Register(ctx context.Context, req *RegisterRequest) (*model.User, error)
+	Login(ctx context.Context, req *LoginRequest) (*LoginResponse, error)
+	GetByID(ctx context.Context, id uint) (*model.User, error)
+	Update(ctx context.Context, id uint, req *UpdateUserRequest) (*model.User, error)
+	UpdateStatus(ctx context.Context, id uint, status string) error
+	ValidateSession(ctx context.Context, userID uint) error
 }
 
 type userService struct {
 	repo       repository.UserRepository
 	jwtManager *auth.JWTManager
+	clock      clock.Clock
 }
 
 // NewUserService 建立使用者服務
-func NewUserService(repo repository.UserRepository, jwtManager *auth.JWTManager) UserService {
+func NewUserService(
+	repo repository.UserRepository,
+	jwtManager *auth.JWTManager,
+	clk clock.Clock,
+) UserService {
 	return &userService{
 		repo:       repo,
 		jwtManager: jwtManager,
+		clock:      clk,
 	}
 }
 
 // Register 註冊新使用者
-func (s *userService) Register(req *RegisterRequest) (*model.User, error) {
+func (s *userService) Register(ctx context.Context, req *RegisterRequest) (*model.User, error) {
+	// 系統保留的使用者名稱不可註冊
+	if isReservedUsername(req.Username) {
+		return nil, ErrReservedUsername
+	}
+
 	// 檢查 email 是否已存在
-	existingUser, _ := s.repo.GetByEmail(req.Email)
+	existingUser, _ := s.repo.GetByEmail(ctx, req.Email)
 	if existingUser != nil {
 		return nil, ErrUserExists
 	}
 
 	// 檢查 username 是否已存在
-	existingUser, _ = s.repo.GetByUsername(req.Username)
+	existingUser, _ = s.repo.GetByUsername(ctx, req.Username)
 	if existingUser != nil {
 		return nil, ErrUserExists
 	}
@@ -92,8 +108,8 @@ func (s *userService) Register(req *RegisterRequest) (*model.User, error) {
 		Password:  string(hashedPassword),
 		Nickname:  req.Nickname,
 		Status:    "offline",
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		CreatedAt: s.clock.Now(),
+		UpdatedAt: s.clock.Now(),
 	}
 
 	// 如果沒有提供 nickname，使用 username
@@ -101,7 +117,11 @@ func (s *userService) Register(req *RegisterRequest) (*model.User, error) {
 		user.Nickname = user.Username
 	}
 
-	if err := s.repo.Create(user); err != nil {
+	if err := s.repo.Create(ctx, user); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			return nil, ErrUserExists
+		}
+
 		return nil, err
 	}
 
@@ -109,18 +129,33 @@ func (s *userService) Register(req *RegisterRequest) (*model.User, error) {
 }
 
 // Login 使用者登入
-func (s *userService) Login(req *LoginRequest) (*LoginResponse, error) {
+func (s *userService) Login(ctx context.Context, req *LoginRequest) (*LoginResponse, error) {
 	// 查找使用者
-	user, err := s.repo.GetByEmail(req.Email)
+	user, err := s.repo.GetByEmail(ctx, req.Email)
 	if err != nil {
 		return nil, ErrInvalidCredentials
 	}
 
+	// 機器人帳號只能透過 API token 存取
+	if user.IsBot {
+		return nil, ErrInvalidCredentials
+	}
+
 	// 驗證密碼
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
 		return nil, ErrInvalidCredentials
 	}
 
+	// 遷移前建立的已連結帳號是否有本地密碼未知，以密碼登入成功即可確定
+	if user.NoLocalPassword == nil {
+		if err := s.repo.UpdateNoLocalPassword(ctx, user.ID, false); err != nil {
+			return nil, err
+		}
+
+		noLocalPassword := false
+		user.NoLocalPassword = &noLocalPassword
+	}
+
 	// 生成 JWT token
 	token, err := s.jwtManager.GenerateToken(user.ID, user.Username, user.Email)
 	if err != nil {
@@ -128,7 +163,7 @@ func (s *userService) Login(req *LoginRequest) (*LoginResponse, error) {
 	}
 
 	// 更新使用者狀態為上線
-	_ = s.repo.UpdateStatus(user.ID, "online")
+	_ = s.repo.UpdateStatus(ctx, user.ID, "online")
 
 	return &LoginResponse{
 		Token: token,
@@ -136,9 +171,36 @@ func (s *userService) Login(req *LoginRequest) (*LoginResponse, error) {
 	}, nil
 }
 
+// ValidateSession 確認 JWT 所屬的帳號仍可使用
+//
+// JWT 在到期前無法撤銷，因此每個請求都重新檢查帳號：已匿名化或寬限期已結束的帳號一律拒絕。
+// 寬限期內的帳號仍可使用，才能取消刪除
+func (s *userService) ValidateSession(ctx context.Context, userID uint) error {
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrAccountDeleted
+		}
+
+		return err
+	}
+
+	if accountDeleted(user, s.clock.Now()) {
+		return ErrAccountDeleted
+	}
+
+	return nil
+}
+
+// accountDeleted 帳號已匿名化或刪除寬限期已結束（等待排程匿名化）
+func accountDeleted(user *model.User, now time.Time) bool {
+	return user.AnonymizedAt != nil ||
+		(user.DeletionScheduledAt != nil && !now.Before(*user.DeletionScheduledAt))
+}
+
 // GetByID 透過 ID 取得使用者
-func (s *userService) GetByID(id uint) (*model.User, error) {
-	user, err := s.repo.GetByID(id)
+func (s *userService) GetByID(ctx context.Context, id uint) (*model.User, error) {
+	user, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		return nil, ErrUserNotFound
 	}
@@ -147,8 +209,12 @@ func (s *userService) GetByID(id uint) (*model.User, error) {
 }
 
 // Update 更新使用者資訊
-func (s *userService) Update(id uint, req *UpdateUserRequest) (*model.User, error) {
-	user, err := s.repo.GetByID(id)
+func (s *userService) Update(
+	ctx context.Context,
+	id uint,
+	req *UpdateUserRequest,
+) (*model.User, error) {
+	user, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		return nil, ErrUserNotFound
 	}
@@ -166,9 +232,9 @@ func (s *userService) Update(id uint, req *UpdateUserRequest) (*model.User, erro
 		user.Status = req.Status
 	}
 
-	user.UpdatedAt = time.Now()
+	user.UpdatedAt = s.clock.Now()
 
-	if err := s.repo.Update(user); err != nil {
+	if err := s.repo.Update(ctx, user); err != nil {
 		return nil, err
 	}
 
@@ -176,6 +242,6 @@ func (s *userService) Update(id uint, req *UpdateUserRequest) (*model.User, erro
 }
 
 // UpdateStatus 更新使用者狀態
-func (s *userService) UpdateStatus(id uint, status string) error {
-	return s.repo.UpdateStatus(id, status)
+func (s *userService) UpdateStatus(ctx context.Context, id uint, status string) error {
+	return s.repo.UpdateStatus(ctx, id, status)
 }
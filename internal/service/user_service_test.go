@@ -0,0 +1,134 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRegisterAndLogin(t *testing.T) {
+	env := newTestEnv(t)
+	users := env.userService()
+
+	user, err := users.Register(env.ctx, &RegisterRequest{
+		Username: "alice",
+		Email:    "alice@example.com",
+		Password: testPassword,
+	})
+	if err != nil {
+		t.Fatalf("Register() = %v", err)
+	}
+
+	if user.Nickname != "alice" {
+		t.Errorf("nickname = %q, want the username", user.Nickname)
+	}
+
+	for _, req := range []*RegisterRequest{
+		{Username: "alice2", Email: "alice@example.com", Password: testPassword},
+		{Username: "alice", Email: "other@example.com", Password: testPassword},
+	} {
+		if _, err := users.Register(env.ctx, req); !errors.Is(err, ErrUserExists) {
+			t.Errorf("Register(%s, %s) = %v, want %v", req.Username, req.Email, err, ErrUserExists)
+		}
+	}
+
+	if _, err := users.Register(env.ctx, &RegisterRequest{
+		Username: "deleted-user-1",
+		Email:    "deleted@example.com",
+		Password: testPassword,
+	}); !errors.Is(err, ErrReservedUsername) {
+		t.Errorf("Register() with reserved username = %v, want %v", err, ErrReservedUsername)
+	}
+
+	if _, err := users.Login(env.ctx, &LoginRequest{
+		Email:    "alice@example.com",
+		Password: "wrong",
+	}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Login() with wrong password = %v, want %v", err, ErrInvalidCredentials)
+	}
+
+	resp, err := users.Login(env.ctx, &LoginRequest{
+		Email:    "alice@example.com",
+		Password: testPassword,
+	})
+	if err != nil {
+		t.Fatalf("Login() = %v", err)
+	}
+
+	if resp.Token == "" || resp.User.ID != user.ID {
+		t.Errorf(
+			"Login() = token %q, user %d, want a token for user %d",
+			resp.Token,
+			resp.User.ID,
+			user.ID,
+		)
+	}
+}
+
+func TestValidateSessionRejectsDeletedAccounts(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser("user")
+	users := env.userService()
+	accounts := env.accountService()
+
+	if err := users.ValidateSession(env.ctx, user.ID); err != nil {
+		t.Fatalf("ValidateSession() = %v, want nil", err)
+	}
+
+	if _, err := accounts.RequestDeletion(
+		env.ctx,
+		user.ID,
+		&DeleteAccountRequest{Password: testPassword},
+	); err != nil {
+		t.Fatalf("RequestDeletion() = %v", err)
+	}
+
+	// 寬限期內仍可登入取消刪除
+	if err := users.ValidateSession(env.ctx, user.ID); err != nil {
+		t.Fatalf("ValidateSession() in the grace period = %v, want nil", err)
+	}
+
+	env.advance(25 * time.Hour)
+
+	if err := users.ValidateSession(env.ctx, user.ID); !errors.Is(err, ErrAccountDeleted) {
+		t.Fatalf("ValidateSession() after the grace period = %v, want %v", err, ErrAccountDeleted)
+	}
+
+	if err := accounts.PurgeDueAccounts(env.ctx); err != nil {
+		t.Fatalf("PurgeDueAccounts() = %v", err)
+	}
+
+	if err := users.ValidateSession(env.ctx, user.ID); !errors.Is(err, ErrAccountDeleted) {
+		t.Fatalf("ValidateSession() after purge = %v, want %v", err, ErrAccountDeleted)
+	}
+
+	if err := users.ValidateSession(env.ctx, user.ID+100); !errors.Is(err, ErrAccountDeleted) {
+		t.Fatalf("ValidateSession() for a missing user = %v, want %v", err, ErrAccountDeleted)
+	}
+}
+
+func TestLoginResolvesUnknownLocalPassword(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser("user")
+
+	// 遷移前建立的已連結帳號無法確定是否有本地密碼
+	if err := env.db.Exec("UPDATE users SET no_local_password = NULL WHERE id = ?", user.ID).Error; err != nil {
+		t.Fatalf("reset no_local_password: %v", err)
+	}
+
+	if _, err := env.userService().Login(env.ctx, &LoginRequest{
+		Email:    user.Email,
+		Password: testPassword,
+	}); err != nil {
+		t.Fatalf("Login() = %v", err)
+	}
+
+	got, err := env.repos.Users.GetByID(env.ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetByID() = %v", err)
+	}
+
+	if !got.HasLocalPassword() {
+		t.Errorf("no_local_password = %v after a password login, want false", got.NoLocalPassword)
+	}
+}
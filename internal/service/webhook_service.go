@@ -0,0 +1,440 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/walnut-almonds/talkrealm/internal/apperror"
+	"github.com/walnut-almonds/talkrealm/internal/model"
+	"github.com/walnut-almonds/talkrealm/internal/repository"
+	"github.com/walnut-almonds/talkrealm/pkg/auth"
+	"github.com/walnut-almonds/talkrealm/pkg/clock"
+	"github.com/walnut-almonds/talkrealm/pkg/idgen"
+	"github.com/walnut-almonds/talkrealm/pkg/logger"
+	"github.com/walnut-almonds/talkrealm/pkg/ratelimit"
+	"go.uber.org/zap"
+)
+
+// webhookTokenLength webhook token 的隨機位元組數
+const webhookTokenLength = 32
+
+var (
+	ErrWebhookNotFound     = apperror.NotFound("webhook_not_found", "webhook not found")
+	ErrInvalidWebhookToken = apperror.Unauthorized(
+		"invalid_webhook_token",
+		"invalid webhook token",
+	)
+	ErrInvalidWebhookChannel = apperror.InvalidArgument(
+		"invalid_webhook_channel",
+		"webhooks can only post to text or announcement channels",
+	)
+)
+
+// CreateWebhookRequest 建立傳入 webhook 請求
+type CreateWebhookRequest struct {
+	Name   string `json:"name"   binding:"required,min=1,max=80"`
+	Avatar string `json:"avatar" binding:"max=2048"`
+}
+
+// UpdateWebhookRequest 更新傳入 webhook 請求
+type UpdateWebhookRequest struct {
+	Name   string  `json:"name"   binding:"omitempty,min=1,max=80"`
+	Avatar *string `json:"avatar" binding:"omitempty,max=2048"`
+}
+
+// WebhookResponse 建立 webhook 的回應，token 與網址只會顯示這一次
+type WebhookResponse struct {
+	*model.Webhook
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+// ExecuteWebhookRequest 透過 webhook 發送訊息的內容
+//
+// 支援 Slack incoming webhook 的子集：text、username、icon_url 與 attachments
+type ExecuteWebhookRequest struct {
+	Content   string `json:"content"    binding:"max=4000"`
+	Username  string `json:"username"   binding:"max=80"`
+	AvatarURL string `json:"avatar_url" binding:"max=2048"`
+
+	// Slack 相容欄位
+	Text        string            `json:"text"        binding:"max=4000"`
+	IconURL     string            `json:"icon_url"    binding:"max=2048"`
+	Attachments []SlackAttachment `json:"attachments" binding:"max=10"`
+}
+
+// SlackAttachment Slack 訊息附件中會轉成文字內容的欄位
+type SlackAttachment struct {
+	Fallback  string `json:"fallback"`
+	Pretext   string `json:"pretext"`
+	Title     string `json:"title"`
+	TitleLink string `json:"title_link"`
+	Text      string `json:"text"`
+}
+
+// WebhookService 傳入 webhook 服務介面
+type WebhookService interface {
+	CreateWebhook(
+		ctx context.Context,
+		channelID, userID uint,
+		req *CreateWebhookRequest,
+	) (*WebhookResponse, error)
+	ListWebhooks(ctx context.Context, channelID, userID uint) ([]*model.Webhook, error)
+	UpdateWebhook(
+		ctx context.Context,
+		webhookID, userID uint,
+		req *UpdateWebhookRequest,
+	) (*model.Webhook, error)
+	DeleteWebhook(ctx context.Context, webhookID, userID uint) error
+	ExecuteWebhook(
+		ctx context.Context,
+		webhookID uint,
+		token string,
+		req *ExecuteWebhookRequest,
+	) (*model.Message, error)
+}
+
+type webhookService struct {
+	webhookRepo     repository.WebhookRepository
+	channelRepo     repository.ChannelRepository
+	guildMemberRepo repository.GuildMemberRepository
+	tx              repository.TxManager
+	messageService  MessageService
+	limiter         ratelimit.Limiter
+	rateLimit       ratelimit.Rule
+	ids             idgen.Generator
+	clock           clock.Clock
+	logger          *zap.Logger
+}
+
+// NewWebhookService 建立傳入 webhook 服務
+func NewWebhookService(
+	webhookRepo repository.WebhookRepository,
+	channelRepo repository.ChannelRepository,
+	guildMemberRepo repository.GuildMemberRepository,
+	tx repository.TxManager,
+	messageService MessageService,
+	limiter ratelimit.Limiter,
+	rateLimit ratelimit.Rule,
+	ids idgen.Generator,
+	clk clock.Clock,
+	logger *zap.Logger,
+) WebhookService {
+	return &webhookService{
+		webhookRepo:     webhookRepo,
+		channelRepo:     channelRepo,
+		guildMemberRepo: guildMemberRepo,
+		tx:              tx,
+		messageService:  messageService,
+		limiter:         limiter,
+		rateLimit:       rateLimit,
+		ids:             ids,
+		clock:           clk,
+		logger:          logger,
+	}
+}
+
+// CreateWebhook 在頻道建立傳入 webhook，需要管理 webhook 權限
+func (s *webhookService) CreateWebhook(
+	ctx context.Context,
+	channelID, userID uint,
+	req *CreateWebhookRequest,
+) (*WebhookResponse, error) {
+	channel, err := s.channelRepo.GetByID(ctx, channelID)
+	if err != nil {
+		return nil, ErrChannelNotFound
+	}
+
+	if channel.Type != "text" && channel.Type != ChannelTypeAnnouncement {
+		return nil, ErrInvalidWebhookChannel
+	}
+
+	if err := s.requireManageWebhooks(ctx, channel.GuildID, userID); err != nil {
+		return nil, err
+	}
+
+	token, err := generateWebhookToken()
+	if err != nil {
+		return nil, err
+	}
+
+	bot, err := s.newWebhookBot(req)
+	if err != nil {
+		return nil, err
+	}
+
+	webhook := &model.Webhook{
+		GuildID:     channel.GuildID,
+		ChannelID:   channel.ID,
+		Name:        req.Name,
+		Avatar:      req.Avatar,
+		TokenHash:   auth.HashAPIToken(token),
+		CreatedByID: userID,
+		CreatedAt:   s.clock.Now(),
+		UpdatedAt:   s.clock.Now(),
+	}
+
+	err = s.tx.WithinTx(ctx, func(repos *repository.Repositories) error {
+		if err := repos.Webhooks.Create(ctx, webhook, bot); err != nil {
+			return err
+		}
+
+		return repos.AuditLogs.Create(ctx, newAuditEntry(
+			ctx, s.clock.Now(), webhook.GuildID, userID,
+			model.AuditActionWebhookCreate, model.AuditTargetWebhook, webhook.ID,
+			auditChanges{}.
+				set("name", nil, webhook.Name).
+				set("channel_id", nil, webhook.ChannelID),
+		))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &WebhookResponse{
+		Webhook: webhook,
+		Token:   token,
+		URL:     fmt.Sprintf("/api/v1/webhooks/%d/%s", webhook.ID, token),
+	}, nil
+}
+
+// ListWebhooks 列出頻道的傳入 webhook
+func (s *webhookService) ListWebhooks(
+	ctx context.Context,
+	channelID, userID uint,
+) ([]*model.Webhook, error) {
+	channel, err := s.channelRepo.GetByID(ctx, channelID)
+	if err != nil {
+		return nil, ErrChannelNotFound
+	}
+
+	if err := s.requireManageWebhooks(ctx, channel.GuildID, userID); err != nil {
+		return nil, err
+	}
+
+	return s.webhookRepo.GetByChannelID(ctx, channel.ID)
+}
+
+// UpdateWebhook 更新 webhook 的預設名稱與頭像
+func (s *webhookService) UpdateWebhook(
+	ctx context.Context,
+	webhookID, userID uint,
+	req *UpdateWebhookRequest,
+) (*model.Webhook, error) {
+	webhook, err := s.webhookRepo.GetByID(ctx, webhookID)
+	if err != nil {
+		return nil, ErrWebhookNotFound
+	}
+
+	if err := s.requireManageWebhooks(ctx, webhook.GuildID, userID); err != nil {
+		return nil, err
+	}
+
+	changes := auditChanges{}
+
+	if req.Name != "" {
+		changes.set("name", webhook.Name, req.Name)
+		webhook.Name = req.Name
+	}
+
+	if req.Avatar != nil {
+		changes.set("avatar", webhook.Avatar, *req.Avatar)
+		webhook.Avatar = *req.Avatar
+	}
+
+	webhook.UpdatedAt = s.clock.Now()
+
+	err = s.tx.WithinTx(ctx, func(repos *repository.Repositories) error {
+		if err := repos.Webhooks.Update(ctx, webhook); err != nil {
+			return err
+		}
+
+		if len(changes) == 0 {
+			return nil
+		}
+
+		return repos.AuditLogs.Create(ctx, newAuditEntry(
+			ctx, s.clock.Now(), webhook.GuildID, userID,
+			model.AuditActionWebhookUpdate, model.AuditTargetWebhook, webhook.ID,
+			changes,
+		))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return webhook, nil
+}
+
+// DeleteWebhook 刪除 webhook，之後該網址無法再發送訊息
+func (s *webhookService) DeleteWebhook(ctx context.Context, webhookID, userID uint) error {
+	webhook, err := s.webhookRepo.GetByID(ctx, webhookID)
+	if err != nil {
+		return ErrWebhookNotFound
+	}
+
+	if err := s.requireManageWebhooks(ctx, webhook.GuildID, userID); err != nil {
+		return err
+	}
+
+	return s.tx.WithinTx(ctx, func(repos *repository.Repositories) error {
+		if err := repos.Webhooks.Delete(ctx, webhook.ID); err != nil {
+			return err
+		}
+
+		return repos.AuditLogs.Create(ctx, newAuditEntry(
+			ctx, s.clock.Now(), webhook.GuildID, userID,
+			model.AuditActionWebhookDelete, model.AuditTargetWebhook, webhook.ID,
+			auditChanges{}.
+				set("name", webhook.Name, nil).
+				set("channel_id", webhook.ChannelID, nil),
+		))
+	})
+}
+
+// ExecuteWebhook 驗證 token 後透過 messageService 發送訊息
+func (s *webhookService) ExecuteWebhook(
+	ctx context.Context,
+	webhookID uint,
+	token string,
+	req *ExecuteWebhookRequest,
+) (*model.Message, error) {
+	webhook, err := s.webhookRepo.GetByID(ctx, webhookID)
+	if err != nil {
+		return nil, ErrWebhookNotFound
+	}
+
+	if !auth.CompareAPITokenHash(token, webhook.TokenHash) {
+		return nil, ErrInvalidWebhookToken
+	}
+
+	content := req.messageContent()
+	if content == "" {
+		return nil, ErrEmptyMessageContent
+	}
+
+	if err := s.checkRateLimit(ctx, webhook.ID); err != nil {
+		return nil, err
+	}
+
+	return s.messageService.CreateMessage(ctx, webhook.UserID, &CreateMessageRequest{
+		ChannelID: webhook.ChannelID,
+		Content:   content,
+		Webhook: &WebhookAuthor{
+			WebhookID: webhook.ID,
+			Name:      cmp.Or(req.Username, webhook.Name),
+			Avatar:    cmp.Or(req.AvatarURL, req.IconURL, webhook.Avatar),
+		},
+	})
+}
+
+// checkRateLimit 檢查 webhook 的發送頻率；限流器故障時放行
+func (s *webhookService) checkRateLimit(ctx context.Context, webhookID uint) error {
+	if s.limiter == nil || !s.rateLimit.Enabled() {
+		return nil
+	}
+
+	key := "webhook:" + strconv.FormatUint(uint64(webhookID), 10)
+
+	allowed, retryAfter, err := s.limiter.Allow(
+		ctx,
+		key,
+		s.rateLimit.Limit,
+		s.rateLimit.Window,
+	)
+	if err != nil {
+		logger.FromContext(ctx, s.logger).Warn(
+			"Rate limiter unavailable, allowing webhook",
+			zap.Uint("webhookID", webhookID),
+			zap.Error(err),
+		)
+		return nil
+	}
+
+	if !allowed {
+		return &RateLimitError{Scope: "webhook", RetryAfter: retryAfter}
+	}
+
+	return nil
+}
+
+// requireManageWebhooks 確認使用者在社群擁有管理 webhook 權限
+func (s *webhookService) requireManageWebhooks(ctx context.Context, guildID, userID uint) error {
+	member, err := s.guildMemberRepo.GetMember(ctx, guildID, userID)
+	if err != nil || member == nil {
+		return ErrNotGuildMemberCh
+	}
+
+	if !hasPermission(member.Role, PermissionManageWebhooks) {
+		return ErrMissingPermission
+	}
+
+	return nil
+}
+
+// messageContent 組合訊息內容；content 優先，其次為 Slack 的 text 與附件
+func (r *ExecuteWebhookRequest) messageContent() string {
+	parts := []string{cmp.Or(r.Content, r.Text)}
+
+	for _, attachment := range r.Attachments {
+		title := attachment.Title
+		if title != "" && attachment.TitleLink != "" {
+			title = fmt.Sprintf("[%s](%s)", title, attachment.TitleLink)
+		}
+
+		text := attachment.Text
+		if text == "" && title == "" {
+			text = attachment.Fallback
+		}
+
+		parts = append(parts, attachment.Pretext, title, text)
+	}
+
+	lines := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			lines = append(lines, part)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// newWebhookBot 建立 webhook 專屬的機器人帳號
+//
+// 使用保留的使用者名稱前綴，且不屬於任何使用者，因此無法登入或另外建立 API token
+func (s *webhookService) newWebhookBot(req *CreateWebhookRequest) (*model.User, error) {
+	hashedPassword, err := randomPasswordHash()
+	if err != nil {
+		return nil, err
+	}
+
+	username := "__webhook_" + s.ids.NewID()
+
+	return &model.User{
+		Username:  username,
+		Email:     username + "@webhooks.talkrealm.invalid",
+		Password:  hashedPassword,
+		Nickname:  req.Name,
+		Avatar:    req.Avatar,
+		Status:    "offline",
+		IsBot:     true,
+		CreatedAt: s.clock.Now(),
+		UpdatedAt: s.clock.Now(),
+	}, nil
+}
+
+// generateWebhookToken 產生 webhook 網址中的秘密 token
+func generateWebhookToken() (string, error) {
+	token := make([]byte, webhookTokenLength)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(token), nil
+}
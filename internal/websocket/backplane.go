@@ -0,0 +1,114 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/walnut-almonds/talkrealm/pkg/config"
+	"go.uber.org/zap"
+)
+
+// Envelope 經由 backplane 轉送的廣播，依目標欄位決定推送給哪些客戶端；目標都未設定時推送給所有客戶端
+type Envelope struct {
+	ChannelID uint   `json:"channel_id,omitempty"` // 訂閱該頻道的客戶端
+	GuildID   uint   `json:"guild_id,omitempty"`   // 訂閱該社群的客戶端
+	UserID    uint   `json:"user_id,omitempty"`    // 該使用者的所有連線
+	Payload   []byte `json:"payload"`              // 已序列化的 Message
+
+	TraceParent string `json:"trace_parent,omitempty"` // 發出廣播的 W3C traceparent，各實例推送時接續同一個追蹤
+}
+
+// target 廣播的對象種類，作為指標的標籤
+func (e *Envelope) target() string {
+	switch {
+	case e.ChannelID != 0:
+		return "channel"
+	case e.GuildID != 0:
+		return "guild"
+	case e.UserID != 0:
+		return "user"
+	default:
+		return "all"
+	}
+}
+
+// Backplane 在伺服器實例之間轉送 WebSocket 廣播
+//
+// 每個實例只持有自己的連線，廣播先送到 backplane，再由所有實例的 Manager 推送給本地的客戶端
+type Backplane interface {
+	Publish(env *Envelope) error
+	Subscribe(deliver func(env *Envelope)) (unsubscribe func())
+	// Ping 檢查 backplane 是否可用，供就緒檢查使用
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+// NewBackplane 依設定建立 backplane；多個伺服器副本需要使用 redis，否則只有轉送事件的實例上的客戶端會收到廣播
+func NewBackplane(
+	cfg *config.WebSocketConfig,
+	redisCfg *config.RedisConfig,
+	logger *zap.Logger,
+) (Backplane, error) {
+	switch cfg.Backplane {
+	case "", "memory":
+		return NewMemoryBackplane(), nil
+	case "redis":
+		return NewRedisBackplane(redisCfg, logger), nil
+	default:
+		return nil, fmt.Errorf("unsupported websocket backplane: %s", cfg.Backplane)
+	}
+}
+
+// MemoryBackplane 在同一個行程內轉送廣播的 backplane
+//
+// 單一實例部署使用（websocket.backplane: memory）；測試中多個 Manager 共用同一個 MemoryBackplane 即可模擬多個副本
+type MemoryBackplane struct {
+	mu          sync.RWMutex
+	subscribers map[uint64]func(env *Envelope)
+	nextID      uint64
+}
+
+// NewMemoryBackplane 建立行程內的 backplane
+func NewMemoryBackplane() *MemoryBackplane {
+	return &MemoryBackplane{subscribers: make(map[uint64]func(env *Envelope))}
+}
+
+// Publish 同步將廣播交給所有訂閱者
+func (b *MemoryBackplane) Publish(env *Envelope) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, deliver := range b.subscribers {
+		deliver(env)
+	}
+
+	return nil
+}
+
+// Subscribe 註冊接收廣播的函式，回傳取消訂閱的函式
+func (b *MemoryBackplane) Subscribe(deliver func(env *Envelope)) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.nextID
+	b.nextID++
+	b.subscribers[id] = deliver
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		delete(b.subscribers, id)
+	}
+}
+
+// Ping 行程內的 backplane 永遠可用
+func (b *MemoryBackplane) Ping(context.Context) error {
+	return nil
+}
+
+// Close 行程內的 backplane 沒有需要釋放的資源
+func (b *MemoryBackplane) Close() error {
+	return nil
+}
@@ -1,11 +1,15 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/walnut-almonds/talkrealm/internal/apperror"
+	"github.com/walnut-almonds/talkrealm/pkg/auth"
+	"go.uber.org/zap"
 )
 
 const (
@@ -22,6 +26,24 @@ const (
 	maxMessageSize = 512 * 1024 // 512KB
 )
 
+// 客戶端送出無法處理的消息時回傳的錯誤，代碼與 REST API 的 ErrorResponse 相同
+var (
+	errInvalidFrame       = apperror.BadRequest("invalid message format")
+	errUnknownMessageType = apperror.InvalidArgument("unknown_message_type", "unknown message type")
+	errGuildNotAllowed    = apperror.Forbidden(
+		"guild_not_allowed",
+		"token is not allowed to access this guild",
+	)
+	errNotGuildMember  = apperror.Forbidden("not_guild_member", "not guild member")
+	errChannelNotFound = apperror.NotFound("channel_not_found", "channel not found")
+)
+
+// ChannelGuildResolver 取得頻道所屬的社群 ID，訂閱頻道時據此檢查社群成員資格與 token 的社群限制
+type ChannelGuildResolver func(ctx context.Context, channelID uint) (uint, error)
+
+// GuildMemberChecker 檢查使用者是否為社群成員，只有成員能訂閱社群與其頻道的事件
+type GuildMemberChecker func(ctx context.Context, guildID, userID uint) (bool, error)
+
 // Client 代表單個 WebSocket 客戶端連接
 type Client struct {
 	// WebSocket 連接
@@ -36,21 +58,44 @@ type Client struct {
 	// 使用者名稱
 	username string
 
+	// 保護訂閱列表，讀取端在廣播與指標抓取的 goroutine 中
+	subMu sync.RWMutex
+
 	// 訂閱的頻道 ID 列表
 	channels map[uint]bool
 
+	// 訂閱的社群 ID 列表
+	guilds map[uint]bool
+
+	// 以 API token 連線時的身分，限制社群的 token 只能訂閱允許的社群與頻道
+	principal *auth.TokenPrincipal
+
+	// 訂閱前檢查頻道所屬社群與成員資格
+	channelGuild ChannelGuildResolver
+	isMember     GuildMemberChecker
+
 	// 緩衝通道，用於發送消息
 	send chan []byte
+
+	// 關閉時的重新連線通知，writePump 送出後以 1012 關閉連線
+	shutdown  chan []byte
+	drainOnce sync.Once
 }
 
 // Message 代表 WebSocket 消息
 type Message struct {
 	Type      string `json:"type"`
 	ChannelID uint   `json:"channel_id,omitempty"`
+	GuildID   uint   `json:"guild_id,omitempty"`
 	Data      any    `json:"data"`
 	Timestamp int64  `json:"timestamp"`
 }
 
+// ReconnectData reconnect 消息的內容，客戶端應等待 DelayMS 毫秒後再重新連線
+type ReconnectData struct {
+	DelayMS int64 `json:"delay_ms"`
+}
+
 // NewClient 創建新的客戶端
 func NewClient(conn *websocket.Conn, manager *Manager, userID uint, username string) *Client {
 	return &Client{
@@ -59,15 +104,18 @@ func NewClient(conn *websocket.Conn, manager *Manager, userID uint, username str
 		userID:   userID,
 		username: username,
 		channels: make(map[uint]bool),
+		guilds:   make(map[uint]bool),
 		send:     make(chan []byte, 256),
+		shutdown: make(chan []byte, 1),
 	}
 }
 
 // readPump 從 WebSocket 連接讀取消息並發送到管理器
 func (c *Client) readPump() {
 	defer func() {
-		c.manager.unregister <- c
+		c.manager.unregisterClient(c)
 		c.conn.Close()
+		c.manager.conns.Done()
 	}()
 
 	c.conn.SetReadDeadline(time.Now().Add(pongWait))
@@ -84,8 +132,9 @@ func (c *Client) readPump() {
 				err,
 				websocket.CloseGoingAway,
 				websocket.CloseAbnormalClosure,
+				websocket.CloseServiceRestart,
 			) {
-				log.Printf("websocket error: %v", err)
+				c.manager.logger.Warn("websocket error", zap.Error(err))
 			}
 			break
 		}
@@ -93,7 +142,9 @@ func (c *Client) readPump() {
 		// 解析消息
 		var msg Message
 		if err := json.Unmarshal(message, &msg); err != nil {
-			log.Printf("error unmarshaling message: %v", err)
+			c.manager.logger.Warn("error unmarshaling message", zap.Error(err))
+			c.sendError(errInvalidFrame)
+
 			continue
 		}
 
@@ -137,6 +188,32 @@ func (c *Client) writePump() {
 				return
 			}
 
+		case frame := <-c.shutdown:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+
+			// 先送出已排隊的消息，再通知重新連線並關閉
+			for n := len(c.send); n > 0; n-- {
+				message, ok := <-c.send
+				if !ok {
+					break
+				}
+
+				if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+					return
+				}
+			}
+
+			if err := c.conn.WriteMessage(websocket.TextMessage, frame); err != nil {
+				return
+			}
+
+			c.conn.WriteMessage(
+				websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseServiceRestart, "server restarting"),
+			)
+
+			return
+
 		case <-ticker.C:
 			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
@@ -146,23 +223,96 @@ func (c *Client) writePump() {
 	}
 }
 
+// drain 要求 writePump 送出重新連線通知後關閉連線，重複呼叫只有第一次有效
+func (c *Client) drain(frame []byte) {
+	c.drainOnce.Do(func() {
+		c.shutdown <- frame
+	})
+}
+
+// closeWith 以指定的關閉代碼關閉尚未啟動讀寫 goroutine 的連線
+func (c *Client) closeWith(code int, reason string) {
+	_ = c.conn.WriteControl(
+		websocket.CloseMessage,
+		websocket.FormatCloseMessage(code, reason),
+		time.Now().Add(writeWait),
+	)
+	_ = c.conn.Close()
+}
+
 // handleMessage 處理從客戶端接收的消息
 func (c *Client) handleMessage(msg *Message) {
 	switch msg.Type {
 	case "subscribe":
 		// 訂閱頻道
-		if msg.ChannelID > 0 {
-			c.channels[msg.ChannelID] = true
-			log.Printf("User %s subscribed to channel %d", c.username, msg.ChannelID)
+		if msg.ChannelID == 0 {
+			c.sendError(requiredField("channel_id"))
+			return
 		}
 
+		if err := c.authorizeChannel(msg.ChannelID); err != nil {
+			c.sendError(err)
+			return
+		}
+
+		c.subMu.Lock()
+		c.channels[msg.ChannelID] = true
+		c.subMu.Unlock()
+
+		c.manager.logger.Debug("User subscribed to channel",
+			zap.String("username", c.username),
+			zap.Uint("channelID", msg.ChannelID))
+
 	case "unsubscribe":
 		// 取消訂閱頻道
-		if msg.ChannelID > 0 {
-			delete(c.channels, msg.ChannelID)
-			log.Printf("User %s unsubscribed from channel %d", c.username, msg.ChannelID)
+		if msg.ChannelID == 0 {
+			c.sendError(requiredField("channel_id"))
+			return
 		}
 
+		c.subMu.Lock()
+		delete(c.channels, msg.ChannelID)
+		c.subMu.Unlock()
+
+		c.manager.logger.Debug("User unsubscribed from channel",
+			zap.String("username", c.username),
+			zap.Uint("channelID", msg.ChannelID))
+
+	case "subscribe_guild":
+		// 訂閱社群層級的事件
+		if msg.GuildID == 0 {
+			c.sendError(requiredField("guild_id"))
+			return
+		}
+
+		if err := c.authorizeGuild(msg.GuildID); err != nil {
+			c.sendError(err)
+			return
+		}
+
+		c.subMu.Lock()
+		c.guilds[msg.GuildID] = true
+		c.subMu.Unlock()
+
+		c.manager.logger.Debug("User subscribed to guild",
+			zap.String("username", c.username),
+			zap.Uint("guildID", msg.GuildID))
+
+	case "unsubscribe_guild":
+		// 取消訂閱社群層級的事件
+		if msg.GuildID == 0 {
+			c.sendError(requiredField("guild_id"))
+			return
+		}
+
+		c.subMu.Lock()
+		delete(c.guilds, msg.GuildID)
+		c.subMu.Unlock()
+
+		c.manager.logger.Debug("User unsubscribed from guild",
+			zap.String("username", c.username),
+			zap.Uint("guildID", msg.GuildID))
+
 	case "ping":
 		// 回應 pong
 		response := Message{
@@ -174,10 +324,89 @@ func (c *Client) handleMessage(msg *Message) {
 		}
 
 	default:
-		log.Printf("Unknown message type: %s", msg.Type)
+		c.manager.logger.Debug("Unknown message type", zap.String("type", msg.Type))
+		c.sendError(errUnknownMessageType.WithMessage("unknown message type: " + msg.Type))
+	}
+}
+
+// authorizeChannel 檢查使用者能否訂閱頻道：頻道所屬社群必須通過 authorizeGuild
+func (c *Client) authorizeChannel(channelID uint) *apperror.Error {
+	if c.channelGuild == nil {
+		return errChannelNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), writeWait)
+	defer cancel()
+
+	guildID, err := c.channelGuild(ctx, channelID)
+	if err != nil {
+		c.manager.logger.Debug("Failed to resolve channel guild",
+			zap.Uint("channelID", channelID),
+			zap.Error(err))
+
+		return errChannelNotFound
+	}
+
+	return c.authorizeGuild(guildID)
+}
+
+// authorizeGuild 檢查使用者能否訂閱社群：必須是社群成員，限制社群的 token 也必須允許該社群
+func (c *Client) authorizeGuild(guildID uint) *apperror.Error {
+	if c.principal != nil && !c.principal.AllowsGuild(guildID) {
+		return errGuildNotAllowed
+	}
+
+	if c.isMember == nil {
+		return errNotGuildMember
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), writeWait)
+	defer cancel()
+
+	member, err := c.isMember(ctx, guildID, c.userID)
+	if err != nil {
+		c.manager.logger.Error("Failed to check guild membership",
+			zap.Uint("guildID", guildID),
+			zap.Uint("userID", c.userID),
+			zap.Error(err))
+
+		return apperror.Internal(err)
+	}
+
+	if !member {
+		return errNotGuildMember
+	}
+
+	return nil
+}
+
+// sendError 以 error 類型的消息回報錯誤，Data 的格式與 REST API 的 ErrorResponse 相同
+func (c *Client) sendError(err *apperror.Error) {
+	response := Message{
+		Type:      "error",
+		Data:      err.Response(),
+		Timestamp: time.Now().Unix(),
+	}
+	data, marshalErr := json.Marshal(response)
+	if marshalErr != nil {
+		return
+	}
+
+	// 緩衝區已滿時捨棄錯誤消息，不因此中斷連線
+	select {
+	case c.send <- data:
+	default:
 	}
 }
 
+// requiredField 缺少必要欄位的驗證錯誤
+func requiredField(field string) *apperror.Error {
+	e := apperror.InvalidArgument(apperror.CodeValidationFailed, "request validation failed")
+	e.Fields = []apperror.FieldError{{Field: field, Rule: "required", Message: "is required"}}
+
+	return e
+}
+
 // SendMessage 發送消息給客戶端
 func (c *Client) SendMessage(data []byte) {
 	select {
@@ -185,11 +414,35 @@ func (c *Client) SendMessage(data []byte) {
 	default:
 		// 如果發送緩衝區已滿，關閉客戶端
 		close(c.send)
-		c.manager.unregister <- c
+		c.manager.unregisterClient(c)
 	}
 }
 
 // IsSubscribed 檢查客戶端是否訂閱了指定頻道
 func (c *Client) IsSubscribed(channelID uint) bool {
+	c.subMu.RLock()
+	defer c.subMu.RUnlock()
+
 	return c.channels[channelID]
 }
+
+// IsSubscribedToGuild 檢查客戶端是否訂閱了指定社群
+func (c *Client) IsSubscribedToGuild(guildID uint) bool {
+	c.subMu.RLock()
+	defer c.subMu.RUnlock()
+
+	return c.guilds[guildID]
+}
+
+// subscribedChannels 回傳目前訂閱的頻道 ID
+func (c *Client) subscribedChannels() []uint {
+	c.subMu.RLock()
+	defer c.subMu.RUnlock()
+
+	ids := make([]uint, 0, len(c.channels))
+	for id := range c.channels {
+		ids = append(ids, id)
+	}
+
+	return ids
+}
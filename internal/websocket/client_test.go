@@ -0,0 +1,169 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/walnut-almonds/talkrealm/internal/apperror"
+)
+
+const (
+	testMemberGuild   = 10
+	testMemberChannel = 100
+	testOtherGuild    = 20
+	testOtherChannel  = 200
+)
+
+// dialSubscriber 以使用者 1（只是 testMemberGuild 的成員）連線
+func dialSubscriber(t *testing.T) (*Manager, *websocket.Conn) {
+	t.Helper()
+
+	channelGuild := func(_ context.Context, channelID uint) (uint, error) {
+		switch channelID {
+		case testMemberChannel:
+			return testMemberGuild, nil
+		case testOtherChannel:
+			return testOtherGuild, nil
+		default:
+			return 0, errChannelNotFound
+		}
+	}
+	isMember := func(_ context.Context, guildID, userID uint) (bool, error) {
+		return guildID == testMemberGuild && userID == 1, nil
+	}
+
+	manager, url, _ := startTestServerWith(t, channelGuild, isMember)
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	waitForClients(t, manager, 1)
+
+	return manager, conn
+}
+
+// roundTrip 送出消息後讀取下一個回應
+func roundTrip(t *testing.T, conn *websocket.Conn, msg Message) Message {
+	t.Helper()
+
+	if err := conn.WriteJSON(msg); err != nil {
+		t.Fatalf("write %s: %v", msg.Type, err)
+	}
+
+	return readMessage(t, conn)
+}
+
+func readMessage(t *testing.T, conn *websocket.Conn) Message {
+	t.Helper()
+
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	var msg Message
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+
+	return msg
+}
+
+// errorCode 取出 error 消息的錯誤代碼
+func errorCode(t *testing.T, msg Message) string {
+	t.Helper()
+
+	if msg.Type != "error" {
+		t.Fatalf("message type = %q, want error", msg.Type)
+	}
+
+	data, _ := json.Marshal(msg.Data)
+
+	var resp apperror.Response
+	if err := json.Unmarshal(data, &resp); err != nil {
+		t.Fatalf("unmarshal error frame %s: %v", data, err)
+	}
+
+	return resp.Code
+}
+
+func TestSubscribeRequiresGuildMembership(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  Message
+		code string
+	}{
+		{
+			name: "guild of non-member",
+			msg:  Message{Type: "subscribe_guild", GuildID: testOtherGuild},
+			code: "not_guild_member",
+		},
+		{
+			name: "channel in guild of non-member",
+			msg:  Message{Type: "subscribe", ChannelID: testOtherChannel},
+			code: "not_guild_member",
+		},
+		{
+			name: "unknown channel",
+			msg:  Message{Type: "subscribe", ChannelID: 999},
+			code: "channel_not_found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager, conn := dialSubscriber(t)
+
+			if code := errorCode(t, roundTrip(t, conn, tt.msg)); code != tt.code {
+				t.Fatalf("error code = %q, want %q", code, tt.code)
+			}
+
+			// 被拒絕的訂閱不會收到該社群的事件
+			manager.BroadcastToGuild(context.Background(), testOtherGuild, "guild_update", nil)
+			manager.BroadcastToChannel(
+				context.Background(),
+				testOtherChannel,
+				"message_create",
+				nil,
+			)
+
+			// 直接發給使用者的消息排在前面的廣播之後，收到它即表示前面的廣播沒有送達
+			manager.BroadcastToUser(context.Background(), 1, "barrier", nil)
+
+			if msg := readMessage(t, conn); msg.Type != "barrier" {
+				t.Fatalf("message type = %q, want barrier", msg.Type)
+			}
+		})
+	}
+}
+
+func TestSubscribeAllowsGuildMember(t *testing.T) {
+	manager, conn := dialSubscriber(t)
+
+	for _, msg := range []Message{
+		{Type: "subscribe_guild", GuildID: testMemberGuild},
+		{Type: "subscribe", ChannelID: testMemberChannel},
+	} {
+		if err := conn.WriteJSON(msg); err != nil {
+			t.Fatalf("write %s: %v", msg.Type, err)
+		}
+	}
+
+	// 以 ping 確認前面的訂閱都已處理且沒有錯誤
+	if msg := roundTrip(t, conn, Message{Type: "ping"}); msg.Type != "pong" {
+		t.Fatalf("message type = %q, want pong", msg.Type)
+	}
+
+	manager.BroadcastToGuild(context.Background(), testMemberGuild, "guild_update", nil)
+	if msg := readMessage(t, conn); msg.Type != "guild_update" {
+		t.Fatalf("message type = %q, want guild_update", msg.Type)
+	}
+
+	manager.BroadcastToChannel(context.Background(), testMemberChannel, "message_create", nil)
+	if msg := readMessage(t, conn); msg.Type != "message_create" {
+		t.Fatalf("message type = %q, want message_create", msg.Type)
+	}
+}
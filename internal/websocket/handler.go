@@ -1,14 +1,19 @@
 package websocket
 
 import (
-	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+	"github.com/walnut-almonds/talkrealm/internal/apperror"
+	"github.com/walnut-almonds/talkrealm/internal/middleware"
 	"github.com/walnut-almonds/talkrealm/pkg/auth"
+	"go.uber.org/zap"
 )
 
+// errShuttingDown 伺服器關閉中拒絕新的 WebSocket 連線
+var errShuttingDown = apperror.ServiceUnavailable("shutting_down", "server is shutting down")
+
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
@@ -19,13 +24,19 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
-// HandleWebSocket 處理 WebSocket 連接請求
-func HandleWebSocket(manager *Manager) gin.HandlerFunc {
+// HandleWebSocket 處理 WebSocket 連接請求，channelGuild 與 isMember 用於檢查使用者能否訂閱頻道與社群
+func HandleWebSocket(
+	manager *Manager,
+	channelGuild ChannelGuildResolver,
+	isMember GuildMemberChecker,
+) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 從上下文中獲取使用者資訊（由認證中介軟體設置）
 		userID, exists := c.Get("user_id")
 		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "未授權"})
+			_ = c.Error(apperror.Unauthorized(apperror.CodeUnauthorized, "unauthorized"))
+			c.Abort()
+
 			return
 		}
 
@@ -34,20 +45,35 @@ func HandleWebSocket(manager *Manager) gin.HandlerFunc {
 			username = "unknown"
 		}
 
+		// 關閉中的實例不再升級連線，客戶端應改連其他實例
+		if manager.isClosing() {
+			_ = c.Error(errShuttingDown)
+			c.Abort()
+
+			return
+		}
+
 		// 升級 HTTP 連接到 WebSocket
 		conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 		if err != nil {
-			log.Printf("Failed to upgrade connection: %v", err)
+			manager.logger.Warn("Failed to upgrade connection", zap.Error(err))
 			return
 		}
 
 		// 創建新客戶端
 		client := NewClient(conn, manager, userID.(uint), username.(string))
+		client.channelGuild = channelGuild
+		client.isMember = isMember
+		if principal, ok := middleware.TokenPrincipal(c); ok {
+			client.principal = principal
+		}
 
 		// 註冊客戶端
 		manager.RegisterClient(client)
 
-		log.Printf("WebSocket connection established for user %s (ID: %d)", username, userID)
+		manager.logger.Info("WebSocket connection established",
+			zap.Any("username", username),
+			zap.Any("userID", userID))
 	}
 }
 
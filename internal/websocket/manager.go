@@ -1,9 +1,19 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"math/rand/v2"
 	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/walnut-almonds/talkrealm/pkg/metrics"
+	"github.com/walnut-almonds/talkrealm/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
 )
 
 // Manager 管理所有 WebSocket 連接
@@ -22,142 +32,371 @@ type Manager struct {
 
 	// 互斥鎖保護客戶端映射
 	mu sync.RWMutex
+
+	// 在伺服器實例之間轉送廣播
+	backplane       Backplane
+	unsubscribe     func()
+	unsubscribeOnce sync.Once
+
+	// 關閉時客戶端重新連線前等待的隨機時間上限，避免所有客戶端同時重連
+	reconnectJitter time.Duration
+
+	// closing 在 Shutdown 後為 true，不再接受新連線；與 conns.Add 一起在 mu 下讀寫
+	closing bool
+
+	// 已註冊且讀取 goroutine 尚未結束的連線
+	conns sync.WaitGroup
+
+	// done 關閉時 Run 結束，stopped 在 Run 結束後關閉
+	done     chan struct{}
+	stopped  chan struct{}
+	stopOnce sync.Once
+
+	metrics *metrics.Metrics
+	tracer  trace.Tracer
+	logger  *zap.Logger
 }
 
-// NewManager 創建新的 WebSocket 管理器
-func NewManager() *Manager {
-	return &Manager{
-		clients:    make(map[*Client]bool),
-		broadcast:  make(chan []byte),
-		register:   make(chan *Client),
-		unregister: make(chan *Client),
+// NewManager 創建新的 WebSocket 管理器，並訂閱 backplane 上的廣播
+//
+// reconnectJitter 為關閉時通知客戶端重新連線的延遲上限
+func NewManager(
+	backplane Backplane,
+	reconnectJitter time.Duration,
+	metrics *metrics.Metrics,
+	tracer trace.Tracer,
+	logger *zap.Logger,
+) *Manager {
+	m := &Manager{
+		clients:         make(map[*Client]bool),
+		broadcast:       make(chan []byte),
+		register:        make(chan *Client),
+		unregister:      make(chan *Client),
+		backplane:       backplane,
+		reconnectJitter: reconnectJitter,
+		done:            make(chan struct{}),
+		stopped:         make(chan struct{}),
+		metrics:         metrics,
+		tracer:          tracer,
+		logger:          logger,
 	}
+
+	m.unsubscribe = backplane.Subscribe(m.deliver)
+
+	return m
 }
 
 // Run 運行管理器的主循環
 func (m *Manager) Run() {
-	log.Println("WebSocket Manager started")
+	m.logger.Info("WebSocket Manager started")
+	defer close(m.stopped)
+
 	for {
 		select {
+		case <-m.done:
+			m.logger.Info("WebSocket Manager stopped")
+			return
+
 		case client := <-m.register:
 			m.mu.Lock()
 			m.clients[client] = true
+			// 在 Shutdown 取得客戶端清單之後才完成註冊的連線，同樣通知重新連線
+			if m.closing {
+				client.drain(m.reconnectFrame())
+			}
 			m.mu.Unlock()
-			log.Printf("Client registered: User %s (ID: %d). Total clients: %d",
-				client.username, client.userID, len(m.clients))
+			m.logger.Info("Client registered",
+				zap.String("username", client.username),
+				zap.Uint("userID", client.userID),
+				zap.Int("clients", len(m.clients)))
 
 		case client := <-m.unregister:
 			m.mu.Lock()
 			if _, ok := m.clients[client]; ok {
 				delete(m.clients, client)
 				close(client.send)
-				log.Printf("Client unregistered: User %s (ID: %d). Total clients: %d",
-					client.username, client.userID, len(m.clients))
+				m.logger.Info("Client unregistered",
+					zap.String("username", client.username),
+					zap.Uint("userID", client.userID),
+					zap.Int("clients", len(m.clients)))
 			}
 			m.mu.Unlock()
 
 		case message := <-m.broadcast:
-			m.mu.RLock()
+			start := time.Now()
+			count := 0
+
+			// 發送緩衝區已滿的客戶端會被移除，需要寫鎖
+			m.mu.Lock()
 			for client := range m.clients {
 				select {
 				case client.send <- message:
+					count++
 				default:
+					m.metrics.WebSocketSendDropped("all")
 					close(client.send)
 					delete(m.clients, client)
 				}
 			}
-			m.mu.RUnlock()
+			m.mu.Unlock()
+
+			m.metrics.ObserveBroadcast(count, time.Since(start))
 		}
 	}
 }
 
-// RegisterClient 註冊新客戶端
+// Close 停止接收 backplane 上的廣播
+func (m *Manager) Close() {
+	m.unsubscribeOnce.Do(m.unsubscribe)
+}
+
+// Shutdown 排空本實例的所有連線並停止 Run
+//
+// 停止接受新連線後，通知每個客戶端在隨機延遲後重新連線，再以 1012（Service Restart）關閉連線。
+// ctx 逾時時強制關閉剩餘的連線並回傳 ctx 的錯誤
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	m.closing = true
+	clients := make([]*Client, 0, len(m.clients))
+	for client := range m.clients {
+		clients = append(clients, client)
+	}
+	m.mu.Unlock()
+
+	// 其他實例的廣播不再推送給即將關閉的連線
+	m.Close()
+
+	m.logger.Info("Draining WebSocket connections", zap.Int("clients", len(clients)))
+
+	for _, client := range clients {
+		client.drain(m.reconnectFrame())
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		m.conns.Wait()
+		close(drained)
+	}()
+
+	var err error
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		err = ctx.Err()
+
+		m.mu.RLock()
+		m.logger.Warn("WebSocket drain timed out, closing remaining connections",
+			zap.Int("clients", len(m.clients)))
+		for client := range m.clients {
+			_ = client.conn.Close()
+		}
+		m.mu.RUnlock()
+	}
+
+	m.stopOnce.Do(func() { close(m.done) })
+
+	select {
+	case <-m.stopped:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	return err
+}
+
+// reconnectFrame 建立通知客戶端重新連線的消息，delay_ms 為 [0, reconnectJitter) 的隨機值
+func (m *Manager) reconnectFrame() []byte {
+	var delay time.Duration
+	if m.reconnectJitter > 0 {
+		delay = rand.N(m.reconnectJitter)
+	}
+
+	data, _ := json.Marshal(Message{
+		Type:      "reconnect",
+		Data:      ReconnectData{DelayMS: delay.Milliseconds()},
+		Timestamp: time.Now().Unix(),
+	})
+
+	return data
+}
+
+// accept 在關閉前登記新連線，關閉後回傳 false
+func (m *Manager) accept() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closing {
+		return false
+	}
+
+	m.conns.Add(1)
+
+	return true
+}
+
+// isClosing 檢查是否已開始關閉
+func (m *Manager) isClosing() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.closing
+}
+
+// RegisterClient 註冊新客戶端，管理器已開始關閉時直接以 1012 關閉連線
 func (m *Manager) RegisterClient(client *Client) {
-	m.register <- client
+	if !m.accept() {
+		client.closeWith(websocket.CloseServiceRestart, "server shutting down")
+		return
+	}
+
+	// 排空逾時後 Run 可能已停止
+	select {
+	case m.register <- client:
+	case <-m.done:
+		client.closeWith(websocket.CloseServiceRestart, "server shutting down")
+		m.conns.Done()
+
+		return
+	}
+
 	// 啟動客戶端的讀寫 goroutines
 	go client.writePump()
 	go client.readPump()
 }
 
+// unregisterClient 取消註冊客戶端，Run 已停止時直接返回
+func (m *Manager) unregisterClient(client *Client) {
+	select {
+	case m.unregister <- client:
+	case <-m.done:
+	}
+}
+
 // BroadcastToChannel 向訂閱了指定頻道的所有客戶端廣播消息
-func (m *Manager) BroadcastToChannel(channelID uint, msgType string, data interface{}) {
-	message := Message{
+func (m *Manager) BroadcastToChannel(
+	ctx context.Context,
+	channelID uint,
+	msgType string,
+	data any,
+) {
+	m.publish(ctx, &Envelope{ChannelID: channelID}, Message{
 		Type:      msgType,
 		ChannelID: channelID,
 		Data:      data,
 		Timestamp: 0, // 將由前端設置或使用當前時間
-	}
-
-	messageBytes, err := json.Marshal(message)
-	if err != nil {
-		log.Printf("Error marshaling message: %v", err)
-		return
-	}
-
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-
-	count := 0
-	for client := range m.clients {
-		// 只發送給訂閱了該頻道的客戶端
-		if client.IsSubscribed(channelID) {
-			select {
-			case client.send <- messageBytes:
-				count++
-			default:
-				// 客戶端的發送通道已滿，跳過
-				log.Printf("Failed to send message to client %s (buffer full)", client.username)
-			}
-		}
-	}
+	})
+}
 
-	log.Printf("Broadcasted %s message to channel %d: %d clients", msgType, channelID, count)
+// BroadcastToGuild 向訂閱了指定社群的所有客戶端廣播消息
+func (m *Manager) BroadcastToGuild(ctx context.Context, guildID uint, msgType string, data any) {
+	m.publish(ctx, &Envelope{GuildID: guildID}, Message{
+		Type:      msgType,
+		GuildID:   guildID,
+		Data:      data,
+		Timestamp: 0,
+	})
 }
 
 // BroadcastToAll 向所有連接的客戶端廣播消息
-func (m *Manager) BroadcastToAll(msgType string, data interface{}) {
-	message := Message{
+func (m *Manager) BroadcastToAll(ctx context.Context, msgType string, data any) {
+	m.publish(ctx, &Envelope{}, Message{
 		Type:      msgType,
 		Data:      data,
 		Timestamp: 0,
-	}
-
-	messageBytes, err := json.Marshal(message)
-	if err != nil {
-		log.Printf("Error marshaling message: %v", err)
-		return
-	}
-
-	m.broadcast <- messageBytes
+	})
 }
 
 // BroadcastToUser 向指定使用者發送消息
-func (m *Manager) BroadcastToUser(userID uint, msgType string, data interface{}) {
-	message := Message{
+func (m *Manager) BroadcastToUser(ctx context.Context, userID uint, msgType string, data any) {
+	m.publish(ctx, &Envelope{UserID: userID}, Message{
 		Type:      msgType,
 		Data:      data,
 		Timestamp: 0,
-	}
+	})
+}
+
+// publish 序列化消息後送到 backplane，由所有實例推送給各自的客戶端
+func (m *Manager) publish(ctx context.Context, env *Envelope, message Message) {
+	ctx, span := m.tracer.Start(ctx, "WebSocket.publish", trace.WithAttributes(
+		attribute.String("websocket.target", env.target()),
+		attribute.String("websocket.message_type", message.Type),
+	))
+	defer span.End()
 
 	messageBytes, err := json.Marshal(message)
 	if err != nil {
-		log.Printf("Error marshaling message: %v", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "marshal failed")
+		m.logger.Error("Error marshaling message", zap.Error(err))
+
 		return
 	}
 
+	env.Payload = messageBytes
+	env.TraceParent = tracing.TraceParent(ctx)
+
+	if err := m.backplane.Publish(env); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "publish failed")
+		m.logger.Error("Failed to publish message",
+			zap.String("type", message.Type),
+			zap.Error(err))
+	}
+}
+
+// deliver 將 backplane 轉送來的廣播推送給本實例中符合目標的客戶端
+func (m *Manager) deliver(env *Envelope) {
+	_, span := m.tracer.Start(
+		tracing.WithTraceParent(context.Background(), env.TraceParent),
+		"WebSocket.deliver",
+		trace.WithAttributes(attribute.String("websocket.target", env.target())),
+	)
+	defer span.End()
+
+	if env.ChannelID == 0 && env.GuildID == 0 && env.UserID == 0 {
+		select {
+		case m.broadcast <- env.Payload:
+		case <-m.done:
+		}
+
+		return
+	}
+
+	start := time.Now()
+
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
+	count := 0
 	for client := range m.clients {
-		if client.userID == userID {
-			select {
-			case client.send <- messageBytes:
-				log.Printf("Sent %s message to user %d", msgType, userID)
-			default:
-				log.Printf("Failed to send message to user %d (buffer full)", userID)
-			}
+		switch {
+		case env.ChannelID != 0 && !client.IsSubscribed(env.ChannelID):
+			continue
+		case env.GuildID != 0 && !client.IsSubscribedToGuild(env.GuildID):
+			continue
+		case env.UserID != 0 && client.userID != env.UserID:
+			continue
+		}
+
+		select {
+		case client.send <- env.Payload:
+			count++
+		default:
+			// 客戶端的發送通道已滿，跳過
+			m.metrics.WebSocketSendDropped(env.target())
+			m.logger.Warn("Failed to send message to client (buffer full)",
+				zap.String("username", client.username))
 		}
 	}
+
+	m.metrics.ObserveBroadcast(count, time.Since(start))
+	span.SetAttributes(attribute.Int("websocket.recipients", count))
+
+	m.logger.Debug("Delivered broadcast",
+		zap.Uint("channelID", env.ChannelID),
+		zap.Uint("guildID", env.GuildID),
+		zap.Uint("userID", env.UserID),
+		zap.Int("clients", count))
 }
 
 // GetConnectedClients 獲取當前連接的客戶端數量
@@ -180,3 +419,18 @@ func (m *Manager) GetChannelSubscribers(channelID uint) int {
 	}
 	return count
 }
+
+// ChannelSubscriptions 統計每個頻道在本實例上的訂閱客戶端數
+func (m *Manager) ChannelSubscriptions() map[uint]int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	counts := make(map[uint]int)
+	for client := range m.clients {
+		for _, channelID := range client.subscribedChannels() {
+			counts[channelID]++
+		}
+	}
+
+	return counts
+}
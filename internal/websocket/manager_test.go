@@ -0,0 +1,172 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/walnut-almonds/talkrealm/internal/middleware"
+	"github.com/walnut-almonds/talkrealm/pkg/metrics"
+	"go.opentelemetry.io/otel/trace/noop"
+	"go.uber.org/zap"
+)
+
+const testReconnectJitter = 200 * time.Millisecond
+
+// startTestServer 啟動執行中的管理器與接受 WebSocket 連線的 httptest 伺服器
+//
+// 回傳的 channel 在 Run 結束後關閉
+func startTestServer(t *testing.T) (*Manager, string, <-chan struct{}) {
+	t.Helper()
+
+	return startTestServerWith(t, nil, nil)
+}
+
+// startTestServerWith 與 startTestServer 相同，並以指定的頻道解析與成員檢查處理訂閱
+func startTestServerWith(
+	t *testing.T,
+	channelGuild ChannelGuildResolver,
+	isMember GuildMemberChecker,
+) (*Manager, string, <-chan struct{}) {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+
+	manager := NewManager(
+		NewMemoryBackplane(),
+		testReconnectJitter,
+		metrics.New(),
+		noop.NewTracerProvider().Tracer("test"),
+		zap.NewNop(),
+	)
+
+	runDone := make(chan struct{})
+	go func() {
+		manager.Run()
+		close(runDone)
+	}()
+
+	router := gin.New()
+	router.Use(middleware.ErrorHandler(zap.NewNop()))
+	router.GET("/ws", func(c *gin.Context) {
+		c.Set("user_id", uint(1))
+		c.Set("username", "alice")
+	}, HandleWebSocket(manager, channelGuild, isMember))
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	return manager, "ws" + strings.TrimPrefix(server.URL, "http") + "/ws", runDone
+}
+
+// waitForClients 等待管理器註冊 n 個客戶端
+func waitForClients(t *testing.T, manager *Manager, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for manager.GetConnectedClients() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("connected clients = %d, want %d", manager.GetConnectedClients(), n)
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestShutdownSendsReconnectAndCloses(t *testing.T) {
+	manager, url, runDone := startTestServer(t)
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	waitForClients(t, manager, 1)
+
+	shutdownErr := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		shutdownErr <- manager.Shutdown(ctx)
+	}()
+
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("read reconnect frame: %v", err)
+	}
+
+	var msg struct {
+		Type string        `json:"type"`
+		Data ReconnectData `json:"data"`
+	}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		t.Fatalf("unmarshal reconnect frame %q: %v", data, err)
+	}
+
+	if msg.Type != "reconnect" {
+		t.Errorf("message type = %q, want reconnect", msg.Type)
+	}
+
+	if msg.Data.DelayMS < 0 || msg.Data.DelayMS >= testReconnectJitter.Milliseconds() {
+		t.Errorf(
+			"delay_ms = %d, want [0, %d)",
+			msg.Data.DelayMS,
+			testReconnectJitter.Milliseconds(),
+		)
+	}
+
+	_, _, err = conn.ReadMessage()
+
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != websocket.CloseServiceRestart {
+		t.Fatalf("read after reconnect = %v, want close %d", err, websocket.CloseServiceRestart)
+	}
+
+	if err := <-shutdownErr; err != nil {
+		t.Fatalf("Shutdown() = %v, want nil", err)
+	}
+
+	select {
+	case <-runDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after Shutdown")
+	}
+
+	if n := manager.GetConnectedClients(); n != 0 {
+		t.Errorf("connected clients after Shutdown = %d, want 0", n)
+	}
+}
+
+func TestShutdownRejectsNewConnections(t *testing.T) {
+	manager, url, runDone := startTestServer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := manager.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() = %v, want nil", err)
+	}
+
+	<-runDone
+
+	conn, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	if err == nil {
+		conn.Close()
+		t.Fatal("dial after Shutdown succeeded, want rejection")
+	}
+
+	if resp == nil || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("dial after Shutdown = %v, want status %d", err, http.StatusServiceUnavailable)
+	}
+}
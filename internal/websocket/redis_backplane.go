@@ -0,0 +1,93 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/walnut-almonds/talkrealm/pkg/config"
+	"go.uber.org/zap"
+)
+
+const (
+	// redisBackplaneChannel 轉送廣播的 Redis pub/sub 頻道
+	redisBackplaneChannel = "talkrealm:websocket:broadcast"
+	// redisPublishTimeout 單次發布廣播的逾時
+	redisPublishTimeout = 5 * time.Second
+)
+
+// RedisBackplane 以 Redis pub/sub 在多個伺服器實例之間轉送廣播
+//
+// 多個副本部署時使用：任一實例轉送 outbox 事件時發布的廣播，會送到所有實例再推送給各自的客戶端。
+// pub/sub 不保留訊息，實例與 Redis 斷線期間的廣播會遺失，客戶端重新連線後需重新載入狀態
+type RedisBackplane struct {
+	client *redis.Client
+	logger *zap.Logger
+}
+
+// NewRedisBackplane 建立 Redis backplane
+func NewRedisBackplane(cfg *config.RedisConfig, logger *zap.Logger) *RedisBackplane {
+	return &RedisBackplane{
+		client: redis.NewClient(&redis.Options{
+			Addr:     fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
+			Password: cfg.Password,
+			DB:       cfg.DB,
+		}),
+		logger: logger,
+	}
+}
+
+// Publish 將廣播發布到 Redis，由所有訂閱的實例（包含本實例）接收
+func (b *RedisBackplane) Publish(env *Envelope) error {
+	data, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisPublishTimeout)
+	defer cancel()
+
+	return b.client.Publish(ctx, redisBackplaneChannel, data).Err()
+}
+
+// Subscribe 訂閱 Redis 上的廣播，回傳取消訂閱的函式；斷線時 go-redis 會自動重新訂閱
+func (b *RedisBackplane) Subscribe(deliver func(env *Envelope)) func() {
+	pubsub := b.client.Subscribe(context.Background(), redisBackplaneChannel)
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		for msg := range pubsub.Channel() {
+			var env Envelope
+			if err := json.Unmarshal([]byte(msg.Payload), &env); err != nil {
+				b.logger.Error("Failed to decode backplane message", zap.Error(err))
+				continue
+			}
+
+			deliver(&env)
+		}
+	}()
+
+	return func() {
+		_ = pubsub.Close()
+
+		wg.Wait()
+	}
+}
+
+// Ping 檢查 Redis 連線
+func (b *RedisBackplane) Ping(ctx context.Context) error {
+	return b.client.Ping(ctx).Err()
+}
+
+// Close 關閉 Redis 連線
+func (b *RedisBackplane) Close() error {
+	return b.client.Close()
+}
@@ -0,0 +1,88 @@
+//go:build integration
+
+package websocket
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/walnut-almonds/talkrealm/pkg/config"
+	"go.uber.org/zap"
+)
+
+// newTestRedisBackplane 連線到 TEST_REDIS_HOST 指定的 Redis，未設定時略過測試
+func newTestRedisBackplane(t *testing.T) *RedisBackplane {
+	t.Helper()
+
+	host := os.Getenv("TEST_REDIS_HOST")
+	if host == "" {
+		t.Skip("TEST_REDIS_HOST is not set")
+	}
+
+	port := 6379
+	if value := os.Getenv("TEST_REDIS_PORT"); value != "" {
+		var err error
+		if port, err = strconv.Atoi(value); err != nil {
+			t.Fatalf("parse TEST_REDIS_PORT: %v", err)
+		}
+	}
+
+	backplane := NewRedisBackplane(&config.RedisConfig{
+		Host:     host,
+		Port:     port,
+		Password: os.Getenv("TEST_REDIS_PASSWORD"),
+	}, zap.NewNop())
+	t.Cleanup(func() { _ = backplane.Close() })
+
+	if err := backplane.Ping(context.Background()); err != nil {
+		t.Fatalf("ping redis: %v", err)
+	}
+
+	return backplane
+}
+
+func TestRedisBackplaneDeliversToEveryReplica(t *testing.T) {
+	// 兩個連到同一個 Redis 的 backplane 模擬兩個副本
+	replicas := []*RedisBackplane{newTestRedisBackplane(t), newTestRedisBackplane(t)}
+
+	received := make([]chan *Envelope, len(replicas))
+	for i, replica := range replicas {
+		received[i] = make(chan *Envelope, 1)
+
+		unsubscribe := replica.Subscribe(func(env *Envelope) {
+			select {
+			case received[i] <- env:
+			default: // 重試發布時可能收到多次
+			}
+		})
+		t.Cleanup(unsubscribe)
+	}
+
+	// 訂閱在背景完成，重試發布直到所有副本都收到
+	want := &Envelope{ChannelID: 42, Payload: []byte(`{"type":"new_message"}`)}
+	deadline := time.After(5 * time.Second)
+
+	for i := range replicas {
+		for {
+			if err := replicas[0].Publish(want); err != nil {
+				t.Fatalf("Publish() = %v", err)
+			}
+
+			select {
+			case got := <-received[i]:
+				if got.ChannelID != want.ChannelID || string(got.Payload) != string(want.Payload) {
+					t.Fatalf("replica %d received %+v, want %+v", i, got, want)
+				}
+			case <-time.After(100 * time.Millisecond):
+				continue
+			case <-deadline:
+				t.Fatalf("replica %d did not receive the broadcast", i)
+			}
+
+			break
+		}
+	}
+}
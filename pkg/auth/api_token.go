@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strings"
+)
+
+// APITokenPrefix 所有 API token 的固定前綴，方便秘密掃描工具辨識外洩的 token
+const APITokenPrefix = "trk_"
+
+const (
+	apiTokenLookupLength = 8
+	apiTokenSecretLength = 32
+)
+
+var ErrInvalidAPIToken = errors.New("invalid api token")
+
+// API token 權限範圍
+const (
+	ScopeUsersRead     = "users.read"
+	ScopeUsersWrite    = "users.write"
+	ScopeGuildsRead    = "guilds.read"
+	ScopeGuildsWrite   = "guilds.write"
+	ScopeChannelsRead  = "channels.read"
+	ScopeChannelsWrite = "channels.write"
+	ScopeMessagesRead  = "messages.read"
+	ScopeMessagesWrite = "messages.write"
+)
+
+// AllScopes 所有可指派給 API token 的權限範圍
+var AllScopes = []string{
+	ScopeUsersRead,
+	ScopeUsersWrite,
+	ScopeGuildsRead,
+	ScopeGuildsWrite,
+	ScopeChannelsRead,
+	ScopeChannelsWrite,
+	ScopeMessagesRead,
+	ScopeMessagesWrite,
+}
+
+// TokenPrincipal 透過 API token 認證後的身分與權限
+type TokenPrincipal struct {
+	UserID   uint
+	Username string
+	Email    string
+	IsBot    bool
+	Scopes   []string
+	// 限制 token 只能存取的社群，空值表示不限制
+	GuildIDs []uint
+}
+
+// HasScope 檢查是否擁有指定權限範圍
+func (p *TokenPrincipal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+// AllowsGuild 檢查是否允許存取指定社群
+func (p *TokenPrincipal) AllowsGuild(guildID uint) bool {
+	return len(p.GuildIDs) == 0 || slices.Contains(p.GuildIDs, guildID)
+}
+
+// IsValidScope 檢查權限範圍是否有效
+func IsValidScope(scope string) bool {
+	return slices.Contains(AllScopes, scope)
+}
+
+// GenerateAPIToken 產生新的 API token
+//
+// 回傳完整 token（只會顯示一次）、用於查詢的前綴，以及要儲存的雜湊值
+func GenerateAPIToken() (token, prefix, hash string, err error) {
+	lookup := make([]byte, apiTokenLookupLength/2)
+	if _, err := rand.Read(lookup); err != nil {
+		return "", "", "", err
+	}
+
+	secret := make([]byte, apiTokenSecretLength)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", "", err
+	}
+
+	prefix = APITokenPrefix + hex.EncodeToString(lookup)
+	token = prefix + "_" + hex.EncodeToString(secret)
+
+	return token, prefix, HashAPIToken(token), nil
+}
+
+// ParseAPITokenPrefix 從完整 token 取出查詢用前綴
+//
+// token 格式：trk_<lookup>_<secret>
+func ParseAPITokenPrefix(token string) (string, error) {
+	rest, ok := strings.CutPrefix(token, APITokenPrefix)
+	if !ok {
+		return "", ErrInvalidAPIToken
+	}
+
+	lookup, secret, ok := strings.Cut(rest, "_")
+	if !ok || len(lookup) != apiTokenLookupLength || len(secret) != apiTokenSecretLength*2 {
+		return "", ErrInvalidAPIToken
+	}
+
+	return APITokenPrefix + lookup, nil
+}
+
+// HashAPIToken 計算 token 的雜湊值
+func HashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// CompareAPITokenHash 以固定時間比較 token 與雜湊值
+func CompareAPITokenHash(token, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(HashAPIToken(token)), []byte(hash)) == 1
+}
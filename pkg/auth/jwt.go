@@ -7,6 +7,9 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// accessTokenAudience 存取 token 的 aud，與其他以同一把金鑰簽章的 token（例如 OIDC 登入狀態）區隔
+const accessTokenAudience = "talkrealm:access"
+
 var (
 	ErrInvalidToken = errors.New("invalid token")
 	ErrExpiredToken = errors.New("token has expired")
@@ -42,6 +45,7 @@ func (m *JWTManager) GenerateToken(userID uint, username, email string) (string,
 		Username: username,
 		Email:    email,
 		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{accessTokenAudience},
 			ExpiresAt: jwt.NewNumericDate(nowTime.Add(m.tokenDuration)),
 			IssuedAt:  jwt.NewNumericDate(nowTime),
 			NotBefore: jwt.NewNumericDate(nowTime),
@@ -66,6 +70,7 @@ func (m *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
 
 			return []byte(m.secretKey), nil
 		},
+		jwt.WithAudience(accessTokenAudience),
 	)
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
@@ -76,7 +81,7 @@ func (m *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
 	}
 
 	claims, ok := token.Claims.(*Claims)
-	if !ok || !token.Valid {
+	if !ok || !token.Valid || claims.UserID == 0 {
 		return nil, ErrInvalidToken
 	}
 
@@ -0,0 +1,326 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/walnut-almonds/talkrealm/pkg/config"
+	"golang.org/x/oauth2"
+)
+
+// oidcStateAudience 登入狀態的 aud，避免與存取 token 互相冒用
+const oidcStateAudience = "talkrealm:oidc-state"
+
+var (
+	ErrOIDCProviderNotFound = errors.New("oidc provider not found")
+	ErrOIDCInvalidState     = errors.New("invalid oidc state")
+	ErrOIDCInvalidIDToken   = errors.New("invalid oidc id token")
+)
+
+// OIDCIdentity 從 ID token 解析出的使用者身分
+type OIDCIdentity struct {
+	Provider          string
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	PreferredUsername string
+	Name              string
+	Picture           string
+}
+
+// OIDCState 登入流程中需要在瀏覽器端暫存的資料
+type OIDCState struct {
+	Provider string `json:"provider"`
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	// 已登入的使用者明確連結身分時為該使用者 ID，一般登入為 0
+	LinkUserID uint `json:"link_user_id,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// OIDCProvider 單一 OIDC 身分提供者
+type OIDCProvider struct {
+	cfg config.OIDCProviderConfig
+
+	mu       sync.Mutex
+	oauth2   *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+// OIDCManager 管理所有 OIDC 身分提供者與登入狀態
+type OIDCManager struct {
+	secretKey string
+	stateTTL  time.Duration
+	providers map[string]*OIDCProvider
+	order     []string
+}
+
+// NewOIDCManager 建立 OIDC 管理器
+func NewOIDCManager(secretKey string, cfg *config.OIDCConfig) *OIDCManager {
+	m := &OIDCManager{
+		secretKey: secretKey,
+		stateTTL:  cfg.StateTTL,
+		providers: make(map[string]*OIDCProvider),
+	}
+
+	if m.stateTTL <= 0 {
+		m.stateTTL = 10 * time.Minute
+	}
+
+	for _, p := range cfg.Providers {
+		if p.Name == "" {
+			continue
+		}
+
+		m.providers[p.Name] = &OIDCProvider{cfg: p}
+		m.order = append(m.order, p.Name)
+	}
+
+	return m
+}
+
+// Providers 回傳所有已設定的身分提供者設定（依設定順序）
+func (m *OIDCManager) Providers() []config.OIDCProviderConfig {
+	providers := make([]config.OIDCProviderConfig, 0, len(m.order))
+	for _, name := range m.order {
+		providers = append(providers, m.providers[name].cfg)
+	}
+
+	return providers
+}
+
+// Provider 取得指定名稱的身分提供者
+func (m *OIDCManager) Provider(name string) (*OIDCProvider, error) {
+	p, ok := m.providers[name]
+	if !ok {
+		return nil, ErrOIDCProviderNotFound
+	}
+
+	return p, nil
+}
+
+// StateTTL 回傳登入狀態的有效時間
+func (m *OIDCManager) StateTTL() time.Duration {
+	return m.stateTTL
+}
+
+// NewState 產生新的登入狀態（state、nonce 與 PKCE verifier）
+func (m *OIDCManager) NewState(provider string) (*OIDCState, error) {
+	state, err := randomString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := randomString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	nowTime := time.Now()
+
+	return &OIDCState{
+		Provider: provider,
+		State:    state,
+		Nonce:    nonce,
+		Verifier: oauth2.GenerateVerifier(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{oidcStateAudience},
+			ExpiresAt: jwt.NewNumericDate(nowTime.Add(m.stateTTL)),
+			IssuedAt:  jwt.NewNumericDate(nowTime),
+		},
+	}, nil
+}
+
+// SignState 將登入狀態簽章成字串，供存放於 cookie
+func (m *OIDCManager) SignState(state *OIDCState) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, state)
+
+	return token.SignedString([]byte(m.secretKey))
+}
+
+// ParseState 驗證並解析 cookie 中的登入狀態
+func (m *OIDCManager) ParseState(tokenString string) (*OIDCState, error) {
+	token, err := jwt.ParseWithClaims(
+		tokenString,
+		&OIDCState{},
+		func(token *jwt.Token) (any, error) {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, ErrOIDCInvalidState
+			}
+
+			return []byte(m.secretKey), nil
+		},
+		jwt.WithAudience(oidcStateAudience),
+	)
+	if err != nil {
+		return nil, ErrOIDCInvalidState
+	}
+
+	state, ok := token.Claims.(*OIDCState)
+	if !ok || !token.Valid {
+		return nil, ErrOIDCInvalidState
+	}
+
+	return state, nil
+}
+
+// Config 回傳身分提供者的設定
+func (p *OIDCProvider) Config() config.OIDCProviderConfig {
+	return p.cfg
+}
+
+// discover 透過 discovery 文件初始化提供者（僅在第一次使用時執行）
+func (p *OIDCProvider) discover(
+	ctx context.Context,
+) (*oauth2.Config, *oidc.IDTokenVerifier, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.oauth2 != nil {
+		return p.oauth2, p.verifier, nil
+	}
+
+	provider, err := oidc.NewProvider(ctx, p.cfg.IssuerURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to discover oidc provider %s: %w", p.cfg.Name, err)
+	}
+
+	scopes := p.cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"profile", "email"}
+	}
+
+	if !slices.Contains(scopes, oidc.ScopeOpenID) {
+		scopes = append([]string{oidc.ScopeOpenID}, scopes...)
+	}
+
+	p.oauth2 = &oauth2.Config{
+		ClientID:     p.cfg.ClientID,
+		ClientSecret: p.cfg.ClientSecret,
+		RedirectURL:  p.cfg.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       scopes,
+	}
+	p.verifier = provider.Verifier(&oidc.Config{ClientID: p.cfg.ClientID})
+
+	return p.oauth2, p.verifier, nil
+}
+
+// AuthCodeURL 產生導向身分提供者的授權網址（authorization code + PKCE）
+func (p *OIDCProvider) AuthCodeURL(ctx context.Context, state *OIDCState) (string, error) {
+	oauth2Config, _, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return oauth2Config.AuthCodeURL(
+		state.State,
+		oidc.Nonce(state.Nonce),
+		oauth2.S256ChallengeOption(state.Verifier),
+	), nil
+}
+
+// Exchange 以授權碼換取 token，並驗證 ID token
+func (p *OIDCProvider) Exchange(
+	ctx context.Context,
+	code string,
+	state *OIDCState,
+) (*OIDCIdentity, error) {
+	oauth2Config, verifier, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := oauth2Config.Exchange(ctx, code, oauth2.VerifierOption(state.Verifier))
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange oidc code: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, ErrOIDCInvalidIDToken
+	}
+
+	idToken, err := verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrOIDCInvalidIDToken, err)
+	}
+
+	if idToken.Nonce != state.Nonce {
+		return nil, ErrOIDCInvalidIDToken
+	}
+
+	var claims struct {
+		Email             string `json:"email"`
+		EmailVerified     any    `json:"email_verified"`
+		PreferredUsername string `json:"preferred_username"`
+		Name              string `json:"name"`
+		Picture           string `json:"picture"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrOIDCInvalidIDToken, err)
+	}
+
+	return &OIDCIdentity{
+		Provider:          p.cfg.Name,
+		Subject:           idToken.Subject,
+		Email:             strings.ToLower(claims.Email),
+		EmailVerified:     parseEmailVerified(claims.EmailVerified),
+		PreferredUsername: claims.PreferredUsername,
+		Name:              claims.Name,
+		Picture:           claims.Picture,
+	}, nil
+}
+
+// EmailAllowed 檢查 email 網域是否在允許清單中
+func (p *OIDCProvider) EmailAllowed(email string) bool {
+	if len(p.cfg.AllowedDomains) == 0 {
+		return true
+	}
+
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+
+	domain := email[at+1:]
+	for _, allowed := range p.cfg.AllowedDomains {
+		if strings.EqualFold(domain, allowed) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseEmailVerified 部分身分提供者會以字串回傳 email_verified
+func parseEmailVerified(v any) bool {
+	switch value := v.(type) {
+	case bool:
+		return value
+	case string:
+		return strings.EqualFold(value, "true")
+	default:
+		return false
+	}
+}
+
+// randomString 產生 URL-safe 的隨機字串
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
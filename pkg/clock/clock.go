@@ -0,0 +1,28 @@
+package clock
+
+import "time"
+
+// Clock 提供目前時間，測試時可替換為固定或可手動推進的時間
+type Clock interface {
+	Now() time.Time
+}
+
+type realClock struct{}
+
+// Real 使用系統時間的 Clock
+func Real() Clock {
+	return realClock{}
+}
+
+// Now 取得系統目前時間
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// Func 將函式轉換為 Clock
+type Func func() time.Time
+
+// Now 呼叫函式取得目前時間
+func (f Func) Now() time.Time {
+	return f()
+}
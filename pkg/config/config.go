@@ -10,53 +10,180 @@ import (
 
 // Config 應用程式配置結構
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	Redis    RedisConfig    `mapstructure:"redis"`
-	JWT      JWTConfig      `mapstructure:"jwt"`
-	Log      LogConfig      `mapstructure:"log"`
+	Server        ServerConfig       `mapstructure:"server"`
+	Database      DatabaseConfig     `mapstructure:"database"`
+	Redis         RedisConfig        `mapstructure:"redis"`
+	WebSocket     WebSocketConfig    `mapstructure:"websocket"`
+	JWT           JWTConfig          `mapstructure:"jwt"`
+	OIDC          OIDCConfig         `mapstructure:"oidc"`
+	Account       AccountConfig      `mapstructure:"account"`
+	Moderation    ModerationConfig   `mapstructure:"moderation"`
+	AuditLog      AuditLogConfig     `mapstructure:"audit_log"`
+	RateLimit     RateLimitConfig    `mapstructure:"rate_limit"`
+	EventWebhooks EventWebhookConfig `mapstructure:"event_webhooks"`
+	Outbox        OutboxConfig       `mapstructure:"outbox"`
+	Storage       StorageConfig      `mapstructure:"storage"`
+	Metrics       MetricsConfig      `mapstructure:"metrics"`
+	Tracing       TracingConfig      `mapstructure:"tracing"`
+	Log           LogConfig          `mapstructure:"log"`
 }
 
 // ServerConfig 伺服器配置
 type ServerConfig struct {
-	Port         int           `mapstructure:"port"`
-	Mode         string        `mapstructure:"mode"`
-	ReadTimeout  time.Duration `mapstructure:"read_timeout"`
-	WriteTimeout time.Duration `mapstructure:"write_timeout"`
-	IdleTimeout  time.Duration `mapstructure:"idle_timeout"`
+	Port            int           `mapstructure:"port"`
+	Mode            string        `mapstructure:"mode"`
+	ReadTimeout     time.Duration `mapstructure:"read_timeout"`
+	WriteTimeout    time.Duration `mapstructure:"write_timeout"`
+	IdleTimeout     time.Duration `mapstructure:"idle_timeout"`
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"` // 關閉時等待進行中請求的時間，逾時後取消剩餘請求
+	DrainDelay      time.Duration `mapstructure:"drain_delay"`      // 收到關閉訊號後先讓就緒檢查失敗，等待負載平衡器移除本實例的時間
+	ReadyTimeout    time.Duration `mapstructure:"ready_timeout"`    // 就緒檢查中每個相依元件的檢查時間上限
+	ReconnectJitter time.Duration `mapstructure:"reconnect_jitter"` // 關閉時通知 WebSocket 客戶端重新連線的隨機延遲上限，避免同時重連
 }
 
 // DatabaseConfig 資料庫配置
 type DatabaseConfig struct {
-	Host            string `mapstructure:"host"`
-	Port            int    `mapstructure:"port"`
-	User            string `mapstructure:"user"`
-	Password        string `mapstructure:"password"`
-	DBName          string `mapstructure:"dbname"`
-	SSLMode         string `mapstructure:"sslmode"`
-	MaxIdleConns    int    `mapstructure:"max_idle_conns"`
-	MaxOpenConns    int    `mapstructure:"max_open_conns"`
-	ConnMaxLifetime int    `mapstructure:"conn_max_lifetime"` // 分鐘
-	LogMode         bool   `mapstructure:"log_mode"`
+	Driver          string        `mapstructure:"driver"`       // postgres 或 sqlite
+	Path            string        `mapstructure:"path"`         // SQLite 資料庫檔案路徑，:memory: 表示記憶體資料庫
+	AutoMigrate     bool          `mapstructure:"auto_migrate"` // 啟動時自動套用遷移，適合單節點部署
+	Host            string        `mapstructure:"host"`
+	Port            int           `mapstructure:"port"`
+	User            string        `mapstructure:"user"`
+	Password        string        `mapstructure:"password"          secret:"true"`
+	DBName          string        `mapstructure:"dbname"`
+	SSLMode         string        `mapstructure:"sslmode"`
+	TimeZone        string        `mapstructure:"timezone"` // PostgreSQL 連線的 session 時區
+	MaxIdleConns    int           `mapstructure:"max_idle_conns"`
+	MaxOpenConns    int           `mapstructure:"max_open_conns"`
+	ConnMaxLifetime int           `mapstructure:"conn_max_lifetime"` // 分鐘
+	LogMode         bool          `mapstructure:"log_mode"`
+	QueryTimeout    time.Duration `mapstructure:"query_timeout"` // 單一 SQL 語句的逾時，0 表示不限制
 }
 
 // RedisConfig Redis 配置
 type RedisConfig struct {
 	Host     string `mapstructure:"host"`
 	Port     int    `mapstructure:"port"`
-	Password string `mapstructure:"password"`
+	Password string `mapstructure:"password" secret:"true"`
 	DB       int    `mapstructure:"db"`
 }
 
+// WebSocketConfig WebSocket 配置
+type WebSocketConfig struct {
+	Backplane string `mapstructure:"backplane"` // memory, redis（多個副本需要使用 redis 轉送廣播）
+}
+
 // JWTConfig JWT 配置
 type JWTConfig struct {
-	Secret          string `mapstructure:"secret"`
+	Secret          string `mapstructure:"secret"           secret:"true"`
 	ExpirationHours int    `mapstructure:"expiration_hours"`
 }
 
+// OIDCConfig OIDC 單一登入配置
+type OIDCConfig struct {
+	// 登入流程暫存狀態的有效時間
+	StateTTL  time.Duration        `mapstructure:"state_ttl"`
+	Providers []OIDCProviderConfig `mapstructure:"providers"`
+}
+
+// OIDCProviderConfig 單一 OIDC 身分提供者配置
+type OIDCProviderConfig struct {
+	Name         string   `mapstructure:"name"`
+	DisplayName  string   `mapstructure:"display_name"`
+	IssuerURL    string   `mapstructure:"issuer_url"`
+	ClientID     string   `mapstructure:"client_id"`
+	ClientSecret string   `mapstructure:"client_secret" secret:"true"`
+	RedirectURL  string   `mapstructure:"redirect_url"`
+	Scopes       []string `mapstructure:"scopes"`
+	// 允許登入的 email 網域，空值表示不限制
+	AllowedDomains []string `mapstructure:"allowed_domains"`
+	// 找不到對應帳號時是否自動建立使用者
+	AutoCreate bool `mapstructure:"auto_create"`
+}
+
+// AccountConfig 帳號刪除與資料匯出配置
+type AccountConfig struct {
+	DeletionGracePeriod time.Duration `mapstructure:"deletion_grace_period"`
+	PurgeInterval       time.Duration `mapstructure:"purge_interval"`
+	ExportTTL           time.Duration `mapstructure:"export_ttl"`
+	ExportPollInterval  time.Duration `mapstructure:"export_poll_interval"`
+	ReauthWindow        time.Duration `mapstructure:"reauth_window"` // SSO 帳號執行敏感操作前必須在此時間內登入
+}
+
+// ModerationConfig 社群管理配置
+type ModerationConfig struct {
+	ExpiryInterval time.Duration `mapstructure:"expiry_interval"` // 檢查到期封鎖與禁言的間隔
+}
+
+// AuditLogConfig 稽核紀錄配置
+type AuditLogConfig struct {
+	Retention     time.Duration `mapstructure:"retention"`      // 稽核紀錄保留時間，0 表示永久保留
+	PurgeInterval time.Duration `mapstructure:"purge_interval"` // 清除過期紀錄的間隔
+	Output        string        `mapstructure:"output"`         // 另外輸出稽核紀錄的位置：stdout、stderr 或檔案路徑，空白表示只寫入資料庫
+}
+
+// RateLimitConfig 訊息速率限制配置
+type RateLimitConfig struct {
+	Store                    string        `mapstructure:"store"`               // memory, redis（多個副本需要使用 redis）
+	MessagesPerWindow        int           `mapstructure:"messages_per_window"` // 每位使用者在時間窗內可發送的訊息數，0 表示不限制
+	Window                   time.Duration `mapstructure:"window"`
+	WebhookMessagesPerWindow int           `mapstructure:"webhook_messages_per_window"` // 每個 webhook 在時間窗內可發送的訊息數，0 表示不限制
+	WebhookWindow            time.Duration `mapstructure:"webhook_window"`
+}
+
+// EventWebhookConfig 傳出事件 webhook 配置
+type EventWebhookConfig struct {
+	PollInterval      time.Duration `mapstructure:"poll_interval"`       // 檢查待投遞事件的間隔
+	Timeout           time.Duration `mapstructure:"timeout"`             // 單次投遞請求的逾時
+	MaxAttempts       int           `mapstructure:"max_attempts"`        // 單一事件最多嘗試次數
+	RetryBackoff      time.Duration `mapstructure:"retry_backoff"`       // 第一次重試前的等待時間，之後每次加倍
+	MaxRetryBackoff   time.Duration `mapstructure:"max_retry_backoff"`   // 重試等待時間上限
+	DisableAfter      int           `mapstructure:"disable_after"`       // 連續失敗幾次後自動停用，0 表示不停用
+	DeliveryRetention time.Duration `mapstructure:"delivery_retention"`  // 投遞紀錄保留時間，0 表示永久保留
+	AllowInsecureURLs bool          `mapstructure:"allow_insecure_urls"` // 允許 http 網址（僅供開發環境使用）
+	AllowPrivateIPs   bool          `mapstructure:"allow_private_ips"`   // 允許投遞到迴路與私有網路位址（僅供開發環境使用）
+}
+
+// OutboxConfig 領域事件 outbox 配置
+type OutboxConfig struct {
+	PollInterval time.Duration `mapstructure:"poll_interval"` // 檢查未轉送事件的間隔
+	Retention    time.Duration `mapstructure:"retention"`     // 已轉送事件的保留時間，0 表示永久保留
+}
+
+// StorageConfig 檔案儲存配置
+type StorageConfig struct {
+	Driver    string `mapstructure:"driver"` // local
+	LocalPath string `mapstructure:"local_path"`
+}
+
+// MetricsConfig Prometheus 指標配置
+type MetricsConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Path    string `mapstructure:"path"`
+	Port    int    `mapstructure:"port"` // 大於零時改由獨立的連接埠提供指標，不經過對外的 API 連接埠
+}
+
+// TracingConfig OpenTelemetry 追蹤配置
+type TracingConfig struct {
+	Exporter    string  `mapstructure:"exporter"`     // none、otlp 或 stdout
+	Endpoint    string  `mapstructure:"endpoint"`     // OTLP/HTTP 收集器位址，例如 localhost:4318；空白時使用 OTEL_EXPORTER_OTLP_ENDPOINT
+	Insecure    bool    `mapstructure:"insecure"`     // 連線收集器時不使用 TLS
+	SampleRatio float64 `mapstructure:"sample_ratio"` // 沒有上游取樣決定時的取樣比例（0 到 1）
+	ServiceName string  `mapstructure:"service_name"`
+}
+
 // LogConfig 日誌配置
 type LogConfig struct {
-	Level string `mapstructure:"level"`
+	Level        string            `mapstructure:"level"`
+	RedactFields []string          `mapstructure:"redact_fields"` // 日誌中以這些名稱記錄的欄位一律遮蔽（不分大小寫）
+	RedactParams []string          `mapstructure:"redact_params"` // 請求日誌中遮蔽的查詢參數與路徑參數
+	Sampling     []LogSamplingRule `mapstructure:"sampling"`      // 高流量路由的請求日誌取樣
+}
+
+// LogSamplingRule 單一路由的請求日誌取樣規則，4xx 與 5xx 的請求一律記錄
+type LogSamplingRule struct {
+	Route string `mapstructure:"route"` // 路由樣板，例如 /api/v1/channels/:id/messages
+	Every int    `mapstructure:"every"` // 每 N 個請求記錄一筆
 }
 
 // Load 載入配置檔案
@@ -97,18 +224,27 @@ func setDefaults() {
 	viper.SetDefault("server.read_timeout", 10*time.Second)
 	viper.SetDefault("server.write_timeout", 10*time.Second)
 	viper.SetDefault("server.idle_timeout", 60*time.Second)
+	viper.SetDefault("server.shutdown_timeout", 10*time.Second)
+	viper.SetDefault("server.drain_delay", 0)
+	viper.SetDefault("server.ready_timeout", 2*time.Second)
+	viper.SetDefault("server.reconnect_jitter", 5*time.Second)
 
 	// Database 預設值
+	viper.SetDefault("database.driver", "postgres")
+	viper.SetDefault("database.path", "data/talkrealm.db")
+	viper.SetDefault("database.auto_migrate", false)
 	viper.SetDefault("database.host", "localhost")
 	viper.SetDefault("database.port", 5432)
 	viper.SetDefault("database.user", "postgres")
 	viper.SetDefault("database.password", "")
 	viper.SetDefault("database.dbname", "talkrealm")
 	viper.SetDefault("database.sslmode", "disable")
+	viper.SetDefault("database.timezone", "Asia/Taipei")
 	viper.SetDefault("database.max_idle_conns", 10)
 	viper.SetDefault("database.max_open_conns", 100)
 	viper.SetDefault("database.conn_max_lifetime", 60) // 60 分鐘
 	viper.SetDefault("database.log_mode", false)
+	viper.SetDefault("database.query_timeout", 10*time.Second)
 
 	// Redis 預設值
 	viper.SetDefault("redis.host", "localhost")
@@ -116,10 +252,88 @@ func setDefaults() {
 	viper.SetDefault("redis.password", "")
 	viper.SetDefault("redis.db", 0)
 
+	// WebSocket 預設值
+	viper.SetDefault("websocket.backplane", "memory")
+
 	// JWT 預設值
 	viper.SetDefault("jwt.secret", "your-secret-key-change-this-in-production")
 	viper.SetDefault("jwt.expiration_hours", 24*time.Hour)
 
+	// OIDC 預設值
+	viper.SetDefault("oidc.state_ttl", 10*time.Minute)
+
+	// Account 預設值
+	viper.SetDefault("account.deletion_grace_period", 30*24*time.Hour)
+	viper.SetDefault("account.purge_interval", time.Hour)
+	viper.SetDefault("account.export_ttl", 7*24*time.Hour)
+	viper.SetDefault("account.export_poll_interval", 10*time.Second)
+	viper.SetDefault("account.reauth_window", 10*time.Minute)
+
+	// Moderation 預設值
+	viper.SetDefault("moderation.expiry_interval", time.Minute)
+
+	// AuditLog 預設值
+	viper.SetDefault("audit_log.retention", 90*24*time.Hour)
+	viper.SetDefault("audit_log.purge_interval", 24*time.Hour)
+	viper.SetDefault("audit_log.output", "")
+
+	// RateLimit 預設值
+	viper.SetDefault("rate_limit.store", "memory")
+	viper.SetDefault("rate_limit.messages_per_window", 10)
+	viper.SetDefault("rate_limit.window", 10*time.Second)
+	viper.SetDefault("rate_limit.webhook_messages_per_window", 30)
+	viper.SetDefault("rate_limit.webhook_window", time.Minute)
+
+	// EventWebhooks 預設值
+	viper.SetDefault("event_webhooks.poll_interval", 5*time.Second)
+	viper.SetDefault("event_webhooks.timeout", 10*time.Second)
+	viper.SetDefault("event_webhooks.max_attempts", 8)
+	viper.SetDefault("event_webhooks.retry_backoff", 30*time.Second)
+	viper.SetDefault("event_webhooks.max_retry_backoff", time.Hour)
+	viper.SetDefault("event_webhooks.disable_after", 20)
+	viper.SetDefault("event_webhooks.delivery_retention", 7*24*time.Hour)
+	viper.SetDefault("event_webhooks.allow_insecure_urls", false)
+	viper.SetDefault("event_webhooks.allow_private_ips", false)
+
+	// Outbox 預設值
+	viper.SetDefault("outbox.poll_interval", time.Second)
+	viper.SetDefault("outbox.retention", 24*time.Hour)
+
+	// Storage 預設值
+	viper.SetDefault("storage.driver", "local")
+	viper.SetDefault("storage.local_path", "./data")
+
+	// Metrics 預設值
+	viper.SetDefault("metrics.enabled", true)
+	viper.SetDefault("metrics.path", "/metrics")
+	viper.SetDefault("metrics.port", 0)
+
+	// Tracing 預設值
+	viper.SetDefault("tracing.exporter", "none")
+	viper.SetDefault("tracing.endpoint", "")
+	viper.SetDefault("tracing.insecure", false)
+	viper.SetDefault("tracing.sample_ratio", 1.0)
+	viper.SetDefault("tracing.service_name", "talkrealm")
+
 	// Log 預設值
 	viper.SetDefault("log.level", "info")
+	viper.SetDefault("log.redact_fields", []string{
+		"password",
+		"secret",
+		"token",
+		"authorization",
+		"cookie",
+		"client_secret",
+	})
+	viper.SetDefault("log.redact_params", []string{
+		"token",
+		"access_token",
+		"id_token",
+		"code",
+		"state",
+	})
+	viper.SetDefault("log.sampling", []map[string]any{
+		{"route": "/livez", "every": 100},
+		{"route": "/readyz", "every": 100},
+	})
 }
@@ -0,0 +1,66 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// maskedValue 取代機密設定值的字串
+const maskedValue = "******"
+
+// Masked 回傳可寫入日誌的設定內容，標記為 secret 的欄位以 ****** 取代
+//
+// 鍵名使用設定檔中的名稱，未設定的機密欄位保持空白，方便確認是否已設定
+func (c *Config) Masked() map[string]any {
+	masked, _ := maskValue(reflect.ValueOf(*c)).(map[string]any)
+	return masked
+}
+
+func maskValue(v reflect.Value) any {
+	if d, ok := v.Interface().(time.Duration); ok {
+		return d.String()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		out := make(map[string]any, v.NumField())
+
+		for i := range v.NumField() {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+
+			name, _, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+			if name == "" {
+				name = field.Name
+			}
+
+			value := v.Field(i)
+			if field.Tag.Get("secret") == "true" && !value.IsZero() {
+				out[name] = maskedValue
+				continue
+			}
+
+			out[name] = maskValue(value)
+		}
+
+		return out
+	case reflect.Slice, reflect.Array:
+		out := make([]any, v.Len())
+		for i := range v.Len() {
+			out[i] = maskValue(v.Index(i))
+		}
+
+		return out
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+
+		return maskValue(v.Elem())
+	default:
+		return v.Interface()
+	}
+}
@@ -1,30 +1,49 @@
 package database
 
 import (
+	"context"
 	"fmt"
 	"time"
 
-	"github.com/walnut-almonds/talkrealm/internal/model"
 	"github.com/walnut-almonds/talkrealm/pkg/config"
-	"github.com/walnut-almonds/talkrealm/pkg/logger"
+	"go.uber.org/zap"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	gormlogger "gorm.io/gorm/logger"
 )
 
-var db *gorm.DB
-
-// Init 初始化資料庫連線
-func Init(cfg *config.DatabaseConfig) error {
-	dsn := fmt.Sprintf(
-		"host=%s user=%s password=%s dbname=%s port=%d sslmode=%s TimeZone=Asia/Taipei",
-		cfg.Host,
-		cfg.User,
-		cfg.Password,
-		cfg.DBName,
-		cfg.Port,
-		cfg.SSLMode,
-	)
+// 支援的資料庫驅動程式
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// Open 依設定的驅動程式開啟資料庫連線
+func Open(cfg *config.DatabaseConfig, logger *zap.Logger) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+
+	switch cfg.Driver {
+	case DriverPostgres, "":
+		dialector = postgres.Open(fmt.Sprintf(
+			"host=%s user=%s password=%s dbname=%s port=%d sslmode=%s TimeZone=%s",
+			cfg.Host,
+			cfg.User,
+			cfg.Password,
+			cfg.DBName,
+			cfg.Port,
+			cfg.SSLMode,
+			cfg.TimeZone,
+		))
+	case DriverSQLite:
+		var err error
+
+		dialector, err = openSQLite(cfg.Path)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %s", cfg.Driver)
+	}
 
 	// 設定 GORM logger
 	gormLogLevel := gormlogger.Silent
@@ -32,44 +51,81 @@ func Init(cfg *config.DatabaseConfig) error {
 		gormLogLevel = gormlogger.Info
 	}
 
-	var err error
-
-	db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{
+	conn, err := gorm.Open(dialector, &gorm.Config{
 		Logger: gormlogger.Default.LogMode(gormLogLevel),
+		// 將唯一約束違反等驅動程式錯誤轉換為 gorm 的通用錯誤
+		TranslateError: true,
 		NowFunc: func() time.Time {
 			return time.Now().UTC()
 		},
 	})
 	if err != nil {
-		return fmt.Errorf("failed to connect to database: %w", err)
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	if err := registerQueryTimeout(conn, cfg.QueryTimeout); err != nil {
+		return nil, fmt.Errorf("failed to register query timeout: %w", err)
 	}
 
 	// 取得底層的 SQL DB 進行連線池設定
-	sqlDB, err := db.DB()
+	sqlDB, err := conn.DB()
 	if err != nil {
-		return fmt.Errorf("failed to get database instance: %w", err)
+		return nil, fmt.Errorf("failed to get database instance: %w", err)
 	}
 
 	// 設定連線池
-	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
-	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
-	sqlDB.SetConnMaxLifetime(time.Duration(cfg.ConnMaxLifetime) * time.Minute)
+	if cfg.Driver == DriverSQLite {
+		// SQLite 同一時間只允許一個寫入者，共用單一連線避免鎖定錯誤；
+		// 記憶體資料庫也只存在於建立它的連線中，連線不能被關閉
+		sqlDB.SetMaxOpenConns(1)
+		sqlDB.SetMaxIdleConns(1)
+		sqlDB.SetConnMaxLifetime(0)
+		sqlDB.SetConnMaxIdleTime(0)
+
+		logger.Info("Database connected successfully",
+			zap.String("driver", cfg.Driver),
+			zap.String("path", cfg.Path),
+		)
+	} else {
+		sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+		sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+		sqlDB.SetConnMaxLifetime(time.Duration(cfg.ConnMaxLifetime) * time.Minute)
+
+		logger.Info("Database connected successfully",
+			zap.String("host", cfg.Host),
+			zap.String("database", cfg.DBName),
+		)
+	}
+
+	return conn, nil
+}
 
-	logger.Info("Database connected successfully",
-		"host", cfg.Host,
-		"database", cfg.DBName,
+// OpenInMemory 開啟已套用所有遷移的 SQLite 記憶體資料庫
+//
+// 每次呼叫都會得到獨立的空資料庫，適合不依賴外部服務的測試
+func OpenInMemory() (*gorm.DB, error) {
+	conn, err := Open(
+		&config.DatabaseConfig{Driver: DriverSQLite, Path: SQLiteMemory},
+		zap.NewNop(),
 	)
+	if err != nil {
+		return nil, err
+	}
 
-	return nil
-}
+	migrator, err := NewMigrator(conn, zap.NewNop())
+	if err != nil {
+		return nil, err
+	}
 
-// GetDB 取得資料庫實例
-func GetDB() *gorm.DB {
-	return db
+	if err := migrator.Up(context.Background()); err != nil {
+		return nil, err
+	}
+
+	return conn, nil
 }
 
 // Close 關閉資料庫連線
-func Close() error {
+func Close(db *gorm.DB) error {
 	sqlDB, err := db.DB()
 	if err != nil {
 		return err
@@ -78,32 +134,12 @@ func Close() error {
 	return sqlDB.Close()
 }
 
-// AutoMigrate 自動遷移資料庫結構
-func AutoMigrate() error {
-	logger.Info("Running database migrations...")
-
-	err := db.AutoMigrate(
-		&model.User{},
-		&model.Guild{},
-		&model.Channel{},
-		&model.Message{},
-		&model.GuildMember{},
-	)
-	if err != nil {
-		return fmt.Errorf("failed to migrate database: %w", err)
-	}
-
-	logger.Info("Database migrations completed successfully")
-
-	return nil
-}
-
 // HealthCheck 檢查資料庫連線狀態
-func HealthCheck() error {
+func HealthCheck(ctx context.Context, db *gorm.DB) error {
 	sqlDB, err := db.DB()
 	if err != nil {
 		return err
 	}
 
-	return sqlDB.Ping()
+	return sqlDB.PingContext(ctx)
 }
@@ -0,0 +1,447 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+//go:embed migrations/postgres/*.sql migrations/sqlite/*.sql
+var migrationFiles embed.FS
+
+// migrationLockKey 遷移使用的 advisory lock 鍵值，同一時間只有一個實例能執行遷移
+const migrationLockKey int64 = 0x74616c6b7265616c // "talkreal"
+
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// ErrUnknownMigration 資料庫已套用的版本不存在於目前的執行檔中
+var ErrUnknownMigration = errors.New("database has migrations unknown to this binary")
+
+// Migration 單一版本的遷移腳本
+type Migration struct {
+	Version uint
+	Name    string
+	up      string
+	down    string
+}
+
+// MigrationStatus 遷移版本的套用狀態
+type MigrationStatus struct {
+	Version   uint
+	Name      string
+	AppliedAt *time.Time
+	Unknown   bool // 資料庫已套用但執行檔中沒有對應的腳本
+}
+
+// Migrator 依版本執行嵌入執行檔的 SQL 遷移
+type Migrator struct {
+	db         *sql.DB
+	dialect    string
+	migrations []Migration
+	logger     *zap.Logger
+}
+
+// NewMigrator 建立遷移執行器
+func NewMigrator(db *gorm.DB, logger *zap.Logger) (*Migrator, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database instance: %w", err)
+	}
+
+	// 各資料庫的 DDL 語法不同，遷移腳本依驅動程式分目錄存放，版本號必須一致
+	dialect := db.Dialector.Name()
+	if dialect != DriverPostgres && dialect != DriverSQLite {
+		return nil, fmt.Errorf("unsupported database driver for migrations: %s", dialect)
+	}
+
+	migrations, err := loadMigrations(migrationFiles, path.Join("migrations", dialect))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{db: sqlDB, dialect: dialect, migrations: migrations, logger: logger}, nil
+}
+
+// loadMigrations 讀取 dir 目錄中的 <版本>_<名稱>.up.sql 與 .down.sql
+func loadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[uint]*Migration)
+
+	for _, entry := range entries {
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name: %s", entry.Name())
+		}
+
+		version, err := strconv.ParseUint(match[1], 10, 32)
+		if err != nil || version == 0 {
+			return nil, fmt.Errorf("invalid migration version: %s", entry.Name())
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		migration, ok := byVersion[uint(version)]
+		if !ok {
+			migration = &Migration{Version: uint(version), Name: match[2]}
+			byVersion[uint(version)] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("conflicting names for migration version %d", version)
+		}
+
+		if match[3] == "up" {
+			migration.up = string(content)
+		} else {
+			migration.down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.up == "" || migration.down == "" {
+			return nil, fmt.Errorf(
+				"migration %d is missing its up or down script",
+				migration.Version,
+			)
+		}
+
+		migrations = append(migrations, *migration)
+	}
+
+	slices.SortFunc(migrations, func(a, b Migration) int {
+		return int(a.Version) - int(b.Version)
+	})
+
+	return migrations, nil
+}
+
+// Latest 取得執行檔中最新的遷移版本
+func (m *Migrator) Latest() uint {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up 套用所有尚未執行的遷移
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.To(ctx, m.Latest())
+}
+
+// Down 依序回滾最近套用的 steps 個遷移
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	if steps <= 0 {
+		return errors.New("steps must be positive")
+	}
+
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		versions := make([]uint, 0, len(applied))
+		for version := range applied {
+			versions = append(versions, version)
+		}
+
+		slices.Sort(versions)
+
+		target := uint(0)
+		if steps < len(versions) {
+			target = versions[len(versions)-steps-1]
+		}
+
+		return m.migrateTo(ctx, conn, applied, target)
+	})
+}
+
+// To 將資料庫遷移到指定版本，版本較新時往上套用、較舊時往下回滾
+func (m *Migrator) To(ctx context.Context, version uint) error {
+	if version != 0 && !slices.ContainsFunc(m.migrations, func(migration Migration) bool {
+		return migration.Version == version
+	}) {
+		return fmt.Errorf("migration version %d does not exist", version)
+	}
+
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		return m.migrateTo(ctx, conn, applied, version)
+	})
+}
+
+// Status 取得所有遷移版本的套用狀態
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var statuses []MigrationStatus
+
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+			if row, ok := applied[migration.Version]; ok {
+				status.AppliedAt = &row.appliedAt
+				delete(applied, migration.Version)
+			}
+
+			statuses = append(statuses, status)
+		}
+
+		for version, row := range applied {
+			statuses = append(statuses, MigrationStatus{
+				Version:   version,
+				Name:      row.name,
+				AppliedAt: &row.appliedAt,
+				Unknown:   true,
+			})
+		}
+
+		return nil
+	})
+
+	slices.SortFunc(statuses, func(a, b MigrationStatus) int {
+		return int(a.Version) - int(b.Version)
+	})
+
+	return statuses, err
+}
+
+// Wait 等待資料庫套用執行檔中的所有遷移，每隔 interval 檢查一次，直到完成或 ctx 結束
+//
+// 供新版本啟動前等待遷移 Job 完成；只讀取版本紀錄、不取得 advisory lock，遷移執行中也能輪詢
+func (m *Migrator) Wait(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		pending, err := m.pending(ctx)
+		if err == nil && len(pending) == 0 {
+			return nil
+		}
+
+		// 遷移 Job 尚未建立版本資料表時查詢會失敗，視為尚未完成繼續等待
+		m.logger.Info("Waiting for database migrations",
+			zap.Uints("pending", pending),
+			zap.Error(err),
+		)
+
+		select {
+		case <-ctx.Done():
+			if err != nil {
+				return fmt.Errorf("database schema is not ready: %w", err)
+			}
+
+			return fmt.Errorf("database schema is not ready: pending migrations %v", pending)
+		case <-ticker.C:
+		}
+	}
+}
+
+// pending 取得執行檔中尚未套用的遷移版本
+func (m *Migrator) pending(ctx context.Context) ([]uint, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	applied, err := appliedMigrations(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []uint
+
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration.Version)
+		}
+	}
+
+	return pending, nil
+}
+
+// migrateTo 套用版本不大於 target 的遷移，並回滾大於 target 的遷移
+func (m *Migrator) migrateTo(
+	ctx context.Context,
+	conn *sql.Conn,
+	applied map[uint]appliedMigration,
+	target uint,
+) error {
+	for version := range applied {
+		if version > target && !slices.ContainsFunc(m.migrations, func(migration Migration) bool {
+			return migration.Version == version
+		}) {
+			return fmt.Errorf("%w: version %d", ErrUnknownMigration, version)
+		}
+	}
+
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok || migration.Version > target {
+			continue
+		}
+
+		m.logger.Info("Applying migration",
+			zap.Uint("version", migration.Version),
+			zap.String("name", migration.Name),
+		)
+
+		if err := runMigration(ctx, conn, migration.up,
+			`INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)`,
+			migration.Version, migration.Name, time.Now().UTC(),
+		); err != nil {
+			return fmt.Errorf("migration %d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+	}
+
+	for _, migration := range slices.Backward(m.migrations) {
+		if _, ok := applied[migration.Version]; !ok || migration.Version <= target {
+			continue
+		}
+
+		m.logger.Info("Reverting migration",
+			zap.Uint("version", migration.Version),
+			zap.String("name", migration.Name),
+		)
+
+		if err := runMigration(ctx, conn, migration.down,
+			`DELETE FROM schema_migrations WHERE version = $1`,
+			migration.Version,
+		); err != nil {
+			return fmt.Errorf(
+				"revert of migration %d_%s failed: %w",
+				migration.Version,
+				migration.Name,
+				err,
+			)
+		}
+	}
+
+	return nil
+}
+
+// runMigration 在同一個交易中執行遷移腳本並更新版本紀錄
+func runMigration(ctx context.Context, conn *sql.Conn, script, record string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+type appliedMigration struct {
+	name      string
+	appliedAt time.Time
+}
+
+// appliedMigrations 取得資料庫中已套用的遷移版本
+func appliedMigrations(ctx context.Context, conn *sql.Conn) (map[uint]appliedMigration, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, name, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[uint]appliedMigration)
+
+	for rows.Next() {
+		var (
+			version uint
+			row     appliedMigration
+		)
+
+		if err := rows.Scan(&version, &row.name, &row.appliedAt); err != nil {
+			return nil, err
+		}
+
+		applied[version] = row
+	}
+
+	return applied, rows.Err()
+}
+
+// withLock 取得專用連線與 advisory lock 後執行 fn，避免多個實例同時遷移
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	// SQLite 只在單一節點使用且連線池只有一條連線，不需要 advisory lock
+	if m.dialect == DriverSQLite {
+		if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+			version integer PRIMARY KEY,
+			name text NOT NULL,
+			applied_at datetime NOT NULL
+		)`); err != nil {
+			return fmt.Errorf("failed to create schema version table: %w", err)
+		}
+
+		return fn(conn)
+	}
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockKey); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+
+	defer func() {
+		// 即使 ctx 已取消也要釋放鎖，否則這條連線回到連線池後仍會持有鎖
+		unlockCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+
+		if _, err := conn.ExecContext(unlockCtx, `SELECT pg_advisory_unlock($1)`, migrationLockKey); err != nil {
+			m.logger.Error("Failed to release migration lock", zap.Error(err))
+
+			// 丟棄這條連線，關閉 session 時鎖會一併釋放
+			_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version bigint PRIMARY KEY,
+		name text NOT NULL,
+		applied_at timestamptz NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("failed to create schema version table: %w", err)
+	}
+
+	return fn(conn)
+}
@@ -0,0 +1,161 @@
+//go:build integration
+
+package database
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/walnut-almonds/talkrealm/pkg/config"
+)
+
+// newTestPostgresConfig 在 TEST_POSTGRES_HOST 指定的 PostgreSQL 上建立一個空的測試資料庫，
+// 未設定時略過測試；測試結束後刪除該資料庫
+func newTestPostgresConfig(t *testing.T) *config.DatabaseConfig {
+	t.Helper()
+
+	host := os.Getenv("TEST_POSTGRES_HOST")
+	if host == "" {
+		t.Skip("TEST_POSTGRES_HOST is not set")
+	}
+
+	port := 5432
+	if value := os.Getenv("TEST_POSTGRES_PORT"); value != "" {
+		var err error
+		if port, err = strconv.Atoi(value); err != nil {
+			t.Fatalf("parse TEST_POSTGRES_PORT: %v", err)
+		}
+	}
+
+	cfg := &config.DatabaseConfig{
+		Driver:       DriverPostgres,
+		Host:         host,
+		Port:         port,
+		User:         os.Getenv("TEST_POSTGRES_USER"),
+		Password:     os.Getenv("TEST_POSTGRES_PASSWORD"),
+		DBName:       "postgres",
+		SSLMode:      "disable",
+		TimeZone:     "UTC",
+		MaxIdleConns: 2,
+		MaxOpenConns: 10,
+	}
+
+	admin, err := Open(cfg, zap.NewNop())
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { _ = Close(admin) })
+
+	name := fmt.Sprintf("talkrealm_test_%d", time.Now().UnixNano())
+	if err := admin.Exec(`CREATE DATABASE "` + name + `"`).Error; err != nil {
+		t.Fatalf("create database: %v", err)
+	}
+
+	// Cleanup 以後進先出執行，刪除資料庫時 admin 連線仍然開啟
+	t.Cleanup(func() {
+		if err := admin.Exec(`DROP DATABASE IF EXISTS "` + name + `" WITH (FORCE)`).Error; err != nil {
+			t.Errorf("drop database: %v", err)
+		}
+	})
+
+	test := *cfg
+	test.DBName = name
+
+	return &test
+}
+
+func TestConcurrentMigratorsApplyEachMigrationOnce(t *testing.T) {
+	cfg := newTestPostgresConfig(t)
+
+	// 每個 migrator 使用自己的連線池，模擬同時啟動的多個副本
+	const replicas = 4
+
+	migrators := make([]*Migrator, replicas)
+
+	for i := range migrators {
+		db, err := Open(cfg, zap.NewNop())
+		if err != nil {
+			t.Fatalf("open database: %v", err)
+		}
+		t.Cleanup(func() { _ = Close(db) })
+
+		if migrators[i], err = NewMigrator(db, zap.NewNop()); err != nil {
+			t.Fatalf("new migrator: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	var (
+		wg   sync.WaitGroup
+		errs = make([]error, replicas)
+	)
+
+	start := make(chan struct{})
+
+	for i, migrator := range migrators {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			<-start
+			errs[i] = migrator.Up(ctx)
+		}()
+	}
+
+	close(start)
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("replica %d: migrate up: %v", i, err)
+		}
+	}
+
+	rows, err := migrators[0].db.QueryContext(
+		ctx,
+		`SELECT version FROM schema_migrations ORDER BY version`,
+	)
+	if err != nil {
+		t.Fatalf("list applied migrations: %v", err)
+	}
+	defer rows.Close()
+
+	var applied []uint
+
+	for rows.Next() {
+		var version uint
+		if err := rows.Scan(&version); err != nil {
+			t.Fatalf("scan applied migration: %v", err)
+		}
+
+		applied = append(applied, version)
+	}
+
+	if err := rows.Err(); err != nil {
+		t.Fatalf("list applied migrations: %v", err)
+	}
+
+	if len(applied) != len(migrators[0].migrations) {
+		t.Fatalf(
+			"applied versions = %v, want each of %d migrations once",
+			applied,
+			len(migrators[0].migrations),
+		)
+	}
+
+	for i, migration := range migrators[0].migrations {
+		if applied[i] != migration.Version {
+			t.Errorf("applied[%d] = %d, want %d", i, applied[i], migration.Version)
+		}
+	}
+}
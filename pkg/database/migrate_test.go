@@ -0,0 +1,113 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/walnut-almonds/talkrealm/pkg/config"
+)
+
+func TestWaitReturnsOnceMigrationsAreApplied(t *testing.T) {
+	db, err := Open(&config.DatabaseConfig{Driver: DriverSQLite, Path: SQLiteMemory}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { _ = Close(db) })
+
+	migrator, err := NewMigrator(db, zap.NewNop())
+	if err != nil {
+		t.Fatalf("new migrator: %v", err)
+	}
+
+	// 版本資料表尚未建立時持續等待直到逾時
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := migrator.Wait(ctx, 10*time.Millisecond); err == nil {
+		t.Fatal("Wait() on an unmigrated database = nil, want error")
+	}
+
+	if err := migrator.To(context.Background(), migrator.migrations[0].Version); err != nil {
+		t.Fatalf("migrate to first version: %v", err)
+	}
+
+	if len(migrator.migrations) > 1 {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		if err := migrator.Wait(ctx, 10*time.Millisecond); err == nil {
+			t.Fatal("Wait() with pending migrations = nil, want error")
+		}
+	}
+
+	done := make(chan error, 1)
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		done <- migrator.Wait(ctx, 10*time.Millisecond)
+	}()
+
+	if err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatalf("Wait() after migrate up = %v, want nil", err)
+	}
+}
+
+func TestLocalPasswordBackfillMarksLinkedAccountsUnknown(t *testing.T) {
+	db, err := Open(&config.DatabaseConfig{Driver: DriverSQLite, Path: SQLiteMemory}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { _ = Close(db) })
+
+	migrator, err := NewMigrator(db, zap.NewNop())
+	if err != nil {
+		t.Fatalf("new migrator: %v", err)
+	}
+
+	ctx := context.Background()
+
+	if err := migrator.To(ctx, 3); err != nil {
+		t.Fatalf("migrate to version 3: %v", err)
+	}
+
+	// 已連結身分的帳號可能是 SSO 帳號，也可能是後來連結 SSO 的密碼帳號
+	if err := db.Exec(`INSERT INTO users (id, username, email, password, created_at) VALUES
+		(1, 'local', 'local@example.com', 'hash', '2024-01-01 00:00:00'),
+		(2, 'linked', 'linked@example.com', 'hash', '2024-01-01 00:00:00')`).Error; err != nil {
+		t.Fatalf("insert users: %v", err)
+	}
+
+	if err := db.Exec(`INSERT INTO user_identities (user_id, provider, subject, created_at)
+		VALUES (2, 'sso', 'subject-2', '2024-06-01 00:00:00')`).Error; err != nil {
+		t.Fatalf("insert identity: %v", err)
+	}
+
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+
+	var users []struct {
+		ID              uint
+		NoLocalPassword *bool
+	}
+	if err := db.Raw("SELECT id, no_local_password FROM users ORDER BY id").Scan(&users).Error; err != nil {
+		t.Fatalf("list users: %v", err)
+	}
+
+	if len(users) != 2 || users[0].NoLocalPassword == nil || *users[0].NoLocalPassword ||
+		users[1].NoLocalPassword != nil {
+		t.Fatalf(
+			"no_local_password = %+v, want false for the password account and NULL for the linked one",
+			users,
+		)
+	}
+}
@@ -0,0 +1,246 @@
+-- 初始資料表結構，與原本 GORM AutoMigrate 建立的結構相同，既有資料庫可直接套用
+
+CREATE TABLE IF NOT EXISTS "users" (
+    "id" bigserial,
+    "username" text NOT NULL,
+    "email" text NOT NULL,
+    "password" text NOT NULL,
+    "nickname" text,
+    "avatar" text,
+    "status" text DEFAULT 'offline',
+    "is_bot" boolean DEFAULT false,
+    "bot_owner_id" bigint,
+    "deletion_scheduled_at" timestamptz,
+    "anonymized_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_users_username" ON "users" ("username");
+CREATE INDEX IF NOT EXISTS "idx_users_deletion_scheduled_at" ON "users" ("deletion_scheduled_at");
+CREATE INDEX IF NOT EXISTS "idx_users_bot_owner_id" ON "users" ("bot_owner_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_users_email" ON "users" ("email");
+
+CREATE TABLE IF NOT EXISTS "user_identities" (
+    "id" bigserial,
+    "user_id" bigint NOT NULL,
+    "provider" text NOT NULL,
+    "subject" text NOT NULL,
+    "email" text,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_user_identities_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_identity_provider_subject" ON "user_identities" ("provider","subject");
+CREATE INDEX IF NOT EXISTS "idx_user_identities_user_id" ON "user_identities" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "api_tokens" (
+    "id" bigserial,
+    "user_id" bigint NOT NULL,
+    "name" text NOT NULL,
+    "prefix" text NOT NULL,
+    "token_hash" text NOT NULL,
+    "scopes" text NOT NULL,
+    "guild_ids" text,
+    "expires_at" timestamptz,
+    "last_used_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_api_tokens_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_api_tokens_user_id" ON "api_tokens" ("user_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_api_tokens_prefix" ON "api_tokens" ("prefix");
+
+CREATE TABLE IF NOT EXISTS "guilds" (
+    "id" bigserial,
+    "name" text NOT NULL,
+    "description" text,
+    "icon" text,
+    "owner_id" bigint NOT NULL,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_guilds_owner" FOREIGN KEY ("owner_id") REFERENCES "users"("id")
+);
+
+CREATE TABLE IF NOT EXISTS "channels" (
+    "id" bigserial,
+    "guild_id" bigint NOT NULL,
+    "name" text NOT NULL,
+    "type" text NOT NULL,
+    "parent_id" bigint,
+    "topic" text,
+    "position" bigint DEFAULT 0,
+    "slow_mode_seconds" bigint DEFAULT 0,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_channels_guild" FOREIGN KEY ("guild_id") REFERENCES "guilds"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_channels_parent_id" ON "channels" ("parent_id");
+
+CREATE TABLE IF NOT EXISTS "messages" (
+    "id" bigserial,
+    "channel_id" bigint NOT NULL,
+    "user_id" bigint NOT NULL,
+    "content" text NOT NULL,
+    "type" text DEFAULT 'text',
+    "published_at" timestamptz,
+    "source_message_id" bigint,
+    "source_channel_id" bigint,
+    "webhook_id" bigint,
+    "author_name" text,
+    "author_avatar" text,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_messages_channel" FOREIGN KEY ("channel_id") REFERENCES "channels"("id"),
+    CONSTRAINT "fk_messages_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_messages_webhook_id" ON "messages" ("webhook_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_message_crosspost" ON "messages" ("channel_id","source_message_id");
+
+CREATE TABLE IF NOT EXISTS "guild_members" (
+    "id" bigserial,
+    "guild_id" bigint NOT NULL,
+    "user_id" bigint NOT NULL,
+    "nickname" text,
+    "role" text DEFAULT 'member',
+    "joined_at" timestamptz,
+    "timeout_until" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_guild_members_guild" FOREIGN KEY ("guild_id") REFERENCES "guilds"("id"),
+    CONSTRAINT "fk_guild_members_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_guild_members_timeout_until" ON "guild_members" ("timeout_until");
+
+CREATE TABLE IF NOT EXISTS "data_exports" (
+    "id" bigserial,
+    "user_id" bigint NOT NULL,
+    "status" text NOT NULL DEFAULT 'pending',
+    "blob_key" text,
+    "size" bigint,
+    "error" text,
+    "completed_at" timestamptz,
+    "expires_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_data_exports_expires_at" ON "data_exports" ("expires_at");
+CREATE INDEX IF NOT EXISTS "idx_data_exports_user_id" ON "data_exports" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "audit_log_entries" (
+    "id" bigserial,
+    "guild_id" bigint NOT NULL,
+    "actor_id" bigint NOT NULL,
+    "action" text NOT NULL,
+    "target_type" text,
+    "target_id" bigint,
+    "changes" text,
+    "reason" text,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_audit_guild" ON "audit_log_entries" ("guild_id","created_at");
+CREATE INDEX IF NOT EXISTS "idx_audit_log_entries_action" ON "audit_log_entries" ("action");
+CREATE INDEX IF NOT EXISTS "idx_audit_log_entries_actor_id" ON "audit_log_entries" ("actor_id");
+
+CREATE TABLE IF NOT EXISTS "guild_bans" (
+    "id" bigserial,
+    "guild_id" bigint NOT NULL,
+    "user_id" bigint NOT NULL,
+    "moderator_id" bigint NOT NULL,
+    "reason" text,
+    "expires_at" timestamptz,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_guild_bans_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_guild_bans_expires_at" ON "guild_bans" ("expires_at");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_guild_ban_user" ON "guild_bans" ("guild_id","user_id");
+
+CREATE TABLE IF NOT EXISTS "channel_follows" (
+    "id" bigserial,
+    "source_channel_id" bigint NOT NULL,
+    "target_channel_id" bigint NOT NULL,
+    "guild_id" bigint NOT NULL,
+    "created_by_id" bigint NOT NULL,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_channel_follows_source_channel" FOREIGN KEY ("source_channel_id") REFERENCES "channels"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_channel_follows_guild_id" ON "channel_follows" ("guild_id");
+CREATE INDEX IF NOT EXISTS "idx_channel_follows_target_channel_id" ON "channel_follows" ("target_channel_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_channel_follow" ON "channel_follows" ("source_channel_id","target_channel_id");
+
+CREATE TABLE IF NOT EXISTS "webhooks" (
+    "id" bigserial,
+    "guild_id" bigint NOT NULL,
+    "channel_id" bigint NOT NULL,
+    "user_id" bigint NOT NULL,
+    "name" text NOT NULL,
+    "avatar" text,
+    "token_hash" text NOT NULL,
+    "created_by_id" bigint NOT NULL,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_webhooks_channel_id" ON "webhooks" ("channel_id");
+CREATE INDEX IF NOT EXISTS "idx_webhooks_guild_id" ON "webhooks" ("guild_id");
+
+CREATE TABLE IF NOT EXISTS "event_webhooks" (
+    "id" bigserial,
+    "guild_id" bigint NOT NULL,
+    "url" text NOT NULL,
+    "secret" text NOT NULL,
+    "events" text NOT NULL,
+    "enabled" boolean NOT NULL,
+    "failure_count" bigint NOT NULL,
+    "disabled_at" timestamptz,
+    "created_by_id" bigint NOT NULL,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_event_webhooks_guild_id" ON "event_webhooks" ("guild_id");
+
+CREATE TABLE IF NOT EXISTS "event_deliveries" (
+    "id" bigserial,
+    "webhook_id" bigint NOT NULL,
+    "event_type" text NOT NULL,
+    "payload" text NOT NULL,
+    "status" text NOT NULL,
+    "attempts" bigint NOT NULL,
+    "next_attempt_at" timestamptz,
+    "response_status" bigint,
+    "last_error" text,
+    "delivered_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_event_deliveries_created_at" ON "event_deliveries" ("created_at");
+CREATE INDEX IF NOT EXISTS "idx_event_delivery_due" ON "event_deliveries" ("status","next_attempt_at");
+CREATE INDEX IF NOT EXISTS "idx_event_deliveries_webhook_id" ON "event_deliveries" ("webhook_id");
+
+CREATE TABLE IF NOT EXISTS "outbox_events" (
+    "id" bigserial,
+    "type" text NOT NULL,
+    "guild_id" bigint NOT NULL,
+    "channel_id" bigint,
+    "payload" text NOT NULL,
+    "origin" text NOT NULL,
+    "attempts" bigint NOT NULL,
+    "last_error" text,
+    "created_at" timestamptz,
+    "dispatched_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_outbox_pending" ON "outbox_events" ("dispatched_at");
+CREATE INDEX IF NOT EXISTS "idx_outbox_events_created_at" ON "outbox_events" ("created_at");
@@ -0,0 +1,76 @@
+DROP INDEX IF EXISTS "idx_outbox_undispatched";
+
+ALTER TABLE "event_deliveries"
+    DROP CONSTRAINT IF EXISTS "chk_event_deliveries_status",
+    DROP CONSTRAINT IF EXISTS "fk_event_deliveries_webhook";
+
+ALTER TABLE "event_webhooks"
+    DROP CONSTRAINT IF EXISTS "fk_event_webhooks_guild";
+
+ALTER TABLE "webhooks"
+    DROP CONSTRAINT IF EXISTS "fk_webhooks_user",
+    DROP CONSTRAINT IF EXISTS "fk_webhooks_channel",
+    DROP CONSTRAINT IF EXISTS "fk_webhooks_guild";
+
+ALTER TABLE "channel_follows"
+    DROP CONSTRAINT IF EXISTS "fk_channel_follows_guild",
+    DROP CONSTRAINT IF EXISTS "fk_channel_follows_target_channel",
+    DROP CONSTRAINT IF EXISTS "fk_channel_follows_source_channel",
+    ADD CONSTRAINT "fk_channel_follows_source_channel" FOREIGN KEY ("source_channel_id") REFERENCES "channels"("id");
+
+ALTER TABLE "guild_bans"
+    DROP CONSTRAINT IF EXISTS "fk_guild_bans_user",
+    ADD CONSTRAINT "fk_guild_bans_user" FOREIGN KEY ("user_id") REFERENCES "users"("id"),
+    DROP CONSTRAINT IF EXISTS "fk_guild_bans_guild";
+
+DROP INDEX IF EXISTS "idx_guild_members_user_id";
+DROP INDEX IF EXISTS "idx_guild_member";
+
+ALTER TABLE "guild_members"
+    DROP CONSTRAINT IF EXISTS "chk_guild_members_role",
+    DROP CONSTRAINT IF EXISTS "fk_guild_members_user",
+    ADD CONSTRAINT "fk_guild_members_user" FOREIGN KEY ("user_id") REFERENCES "users"("id"),
+    DROP CONSTRAINT IF EXISTS "fk_guild_members_guild",
+    ADD CONSTRAINT "fk_guild_members_guild" FOREIGN KEY ("guild_id") REFERENCES "guilds"("id");
+
+DROP INDEX IF EXISTS "idx_messages_user_created";
+DROP INDEX IF EXISTS "idx_messages_channel_created";
+
+ALTER TABLE "messages"
+    DROP CONSTRAINT IF EXISTS "chk_messages_type",
+    DROP CONSTRAINT IF EXISTS "fk_messages_user",
+    ADD CONSTRAINT "fk_messages_user" FOREIGN KEY ("user_id") REFERENCES "users"("id"),
+    DROP CONSTRAINT IF EXISTS "fk_messages_channel",
+    ADD CONSTRAINT "fk_messages_channel" FOREIGN KEY ("channel_id") REFERENCES "channels"("id");
+
+DROP INDEX IF EXISTS "idx_channels_guild_position";
+
+ALTER TABLE "channels"
+    DROP CONSTRAINT IF EXISTS "chk_channels_type",
+    DROP CONSTRAINT IF EXISTS "fk_channels_parent",
+    DROP CONSTRAINT IF EXISTS "fk_channels_guild",
+    ADD CONSTRAINT "fk_channels_guild" FOREIGN KEY ("guild_id") REFERENCES "guilds"("id");
+
+DROP INDEX IF EXISTS "idx_guilds_owner_id";
+
+ALTER TABLE "guilds"
+    DROP CONSTRAINT IF EXISTS "fk_guilds_owner",
+    ADD CONSTRAINT "fk_guilds_owner" FOREIGN KEY ("owner_id") REFERENCES "users"("id");
+
+DROP INDEX IF EXISTS "idx_data_exports_active";
+
+ALTER TABLE "data_exports"
+    DROP CONSTRAINT IF EXISTS "chk_data_exports_status",
+    DROP CONSTRAINT IF EXISTS "fk_data_exports_user";
+
+ALTER TABLE "api_tokens"
+    DROP CONSTRAINT IF EXISTS "fk_api_tokens_user",
+    ADD CONSTRAINT "fk_api_tokens_user" FOREIGN KEY ("user_id") REFERENCES "users"("id");
+
+ALTER TABLE "user_identities"
+    DROP CONSTRAINT IF EXISTS "fk_user_identities_user",
+    ADD CONSTRAINT "fk_user_identities_user" FOREIGN KEY ("user_id") REFERENCES "users"("id");
+
+ALTER TABLE "users"
+    DROP CONSTRAINT IF EXISTS "chk_users_status",
+    DROP CONSTRAINT IF EXISTS "fk_users_bot_owner";
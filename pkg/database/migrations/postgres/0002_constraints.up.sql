@@ -0,0 +1,110 @@
+-- 外鍵刪除規則、查詢所需索引與列舉欄位檢查約束
+--
+-- 使用者帳號不會被實際刪除（刪除帳號時會匿名化），因此指向 users 的外鍵只影響機器人與關聯資料；
+-- 稽核紀錄、outbox 事件與訊息的來源欄位（webhook_id、source_*）是歷史紀錄，不建立外鍵
+
+-- 移除重複的社群成員，保留最早加入的一筆
+DELETE FROM "guild_members" a
+    USING "guild_members" b
+    WHERE a."guild_id" = b."guild_id" AND a."user_id" = b."user_id" AND a."id" > b."id";
+
+-- 清除已不存在的關聯資料，避免新增外鍵失敗
+DELETE FROM "guild_bans" WHERE "guild_id" NOT IN (SELECT "id" FROM "guilds");
+DELETE FROM "channel_follows" WHERE "target_channel_id" NOT IN (SELECT "id" FROM "channels");
+DELETE FROM "channel_follows" WHERE "guild_id" NOT IN (SELECT "id" FROM "guilds");
+DELETE FROM "webhooks" WHERE "channel_id" NOT IN (SELECT "id" FROM "channels");
+DELETE FROM "event_deliveries" WHERE "webhook_id" NOT IN (SELECT "id" FROM "event_webhooks");
+DELETE FROM "event_webhooks" WHERE "guild_id" NOT IN (SELECT "id" FROM "guilds");
+DELETE FROM "data_exports" WHERE "user_id" NOT IN (SELECT "id" FROM "users");
+UPDATE "channels" SET "parent_id" = NULL WHERE "parent_id" NOT IN (SELECT "id" FROM "channels");
+UPDATE "users" SET "bot_owner_id" = NULL WHERE "bot_owner_id" NOT IN (SELECT "id" FROM "users");
+
+-- users
+ALTER TABLE "users"
+    ADD CONSTRAINT "fk_users_bot_owner" FOREIGN KEY ("bot_owner_id") REFERENCES "users"("id") ON DELETE SET NULL,
+    ADD CONSTRAINT "chk_users_status" CHECK ("status" IN ('online', 'offline', 'busy', 'away'));
+
+-- user_identities / api_tokens / data_exports
+ALTER TABLE "user_identities"
+    DROP CONSTRAINT IF EXISTS "fk_user_identities_user",
+    ADD CONSTRAINT "fk_user_identities_user" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE;
+
+ALTER TABLE "api_tokens"
+    DROP CONSTRAINT IF EXISTS "fk_api_tokens_user",
+    ADD CONSTRAINT "fk_api_tokens_user" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE;
+
+ALTER TABLE "data_exports"
+    ADD CONSTRAINT "fk_data_exports_user" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE,
+    ADD CONSTRAINT "chk_data_exports_status" CHECK ("status" IN ('pending', 'processing', 'ready', 'failed'));
+
+-- 每位使用者同時只能有一個進行中的匯出
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_data_exports_active" ON "data_exports" ("user_id")
+    WHERE "status" IN ('pending', 'processing');
+
+-- guilds
+ALTER TABLE "guilds"
+    DROP CONSTRAINT IF EXISTS "fk_guilds_owner",
+    ADD CONSTRAINT "fk_guilds_owner" FOREIGN KEY ("owner_id") REFERENCES "users"("id") ON DELETE RESTRICT;
+
+CREATE INDEX IF NOT EXISTS "idx_guilds_owner_id" ON "guilds" ("owner_id");
+
+-- channels
+ALTER TABLE "channels"
+    DROP CONSTRAINT IF EXISTS "fk_channels_guild",
+    ADD CONSTRAINT "fk_channels_guild" FOREIGN KEY ("guild_id") REFERENCES "guilds"("id") ON DELETE CASCADE,
+    ADD CONSTRAINT "fk_channels_parent" FOREIGN KEY ("parent_id") REFERENCES "channels"("id") ON DELETE SET NULL,
+    ADD CONSTRAINT "chk_channels_type" CHECK ("type" IN ('text', 'voice', 'announcement', 'category'));
+
+CREATE INDEX IF NOT EXISTS "idx_channels_guild_position" ON "channels" ("guild_id", "position");
+
+-- messages
+ALTER TABLE "messages"
+    DROP CONSTRAINT IF EXISTS "fk_messages_channel",
+    ADD CONSTRAINT "fk_messages_channel" FOREIGN KEY ("channel_id") REFERENCES "channels"("id") ON DELETE CASCADE,
+    DROP CONSTRAINT IF EXISTS "fk_messages_user",
+    ADD CONSTRAINT "fk_messages_user" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE RESTRICT,
+    ADD CONSTRAINT "chk_messages_type" CHECK ("type" IN ('text', 'image', 'file'));
+
+CREATE INDEX IF NOT EXISTS "idx_messages_channel_created" ON "messages" ("channel_id", "created_at" DESC);
+CREATE INDEX IF NOT EXISTS "idx_messages_user_created" ON "messages" ("user_id", "created_at" DESC);
+
+-- guild_members
+ALTER TABLE "guild_members"
+    DROP CONSTRAINT IF EXISTS "fk_guild_members_guild",
+    ADD CONSTRAINT "fk_guild_members_guild" FOREIGN KEY ("guild_id") REFERENCES "guilds"("id") ON DELETE CASCADE,
+    DROP CONSTRAINT IF EXISTS "fk_guild_members_user",
+    ADD CONSTRAINT "fk_guild_members_user" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE,
+    ADD CONSTRAINT "chk_guild_members_role" CHECK ("role" IN ('owner', 'admin', 'moderator', 'member'));
+
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_guild_member" ON "guild_members" ("guild_id", "user_id");
+CREATE INDEX IF NOT EXISTS "idx_guild_members_user_id" ON "guild_members" ("user_id");
+
+-- guild_bans
+ALTER TABLE "guild_bans"
+    ADD CONSTRAINT "fk_guild_bans_guild" FOREIGN KEY ("guild_id") REFERENCES "guilds"("id") ON DELETE CASCADE,
+    DROP CONSTRAINT IF EXISTS "fk_guild_bans_user",
+    ADD CONSTRAINT "fk_guild_bans_user" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE;
+
+-- channel_follows
+ALTER TABLE "channel_follows"
+    DROP CONSTRAINT IF EXISTS "fk_channel_follows_source_channel",
+    ADD CONSTRAINT "fk_channel_follows_source_channel" FOREIGN KEY ("source_channel_id") REFERENCES "channels"("id") ON DELETE CASCADE,
+    ADD CONSTRAINT "fk_channel_follows_target_channel" FOREIGN KEY ("target_channel_id") REFERENCES "channels"("id") ON DELETE CASCADE,
+    ADD CONSTRAINT "fk_channel_follows_guild" FOREIGN KEY ("guild_id") REFERENCES "guilds"("id") ON DELETE CASCADE;
+
+-- webhooks
+ALTER TABLE "webhooks"
+    ADD CONSTRAINT "fk_webhooks_guild" FOREIGN KEY ("guild_id") REFERENCES "guilds"("id") ON DELETE CASCADE,
+    ADD CONSTRAINT "fk_webhooks_channel" FOREIGN KEY ("channel_id") REFERENCES "channels"("id") ON DELETE CASCADE,
+    ADD CONSTRAINT "fk_webhooks_user" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE RESTRICT;
+
+-- event_webhooks / event_deliveries
+ALTER TABLE "event_webhooks"
+    ADD CONSTRAINT "fk_event_webhooks_guild" FOREIGN KEY ("guild_id") REFERENCES "guilds"("id") ON DELETE CASCADE;
+
+ALTER TABLE "event_deliveries"
+    ADD CONSTRAINT "fk_event_deliveries_webhook" FOREIGN KEY ("webhook_id") REFERENCES "event_webhooks"("id") ON DELETE CASCADE,
+    ADD CONSTRAINT "chk_event_deliveries_status" CHECK ("status" IN ('pending', 'succeeded', 'failed'));
+
+-- outbox_events：只有尚未轉送的事件需要被掃描
+CREATE INDEX IF NOT EXISTS "idx_outbox_undispatched" ON "outbox_events" ("id") WHERE "dispatched_at" IS NULL;
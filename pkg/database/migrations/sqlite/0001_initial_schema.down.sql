@@ -0,0 +1,15 @@
+DROP TABLE IF EXISTS "outbox_events";
+DROP TABLE IF EXISTS "event_deliveries";
+DROP TABLE IF EXISTS "event_webhooks";
+DROP TABLE IF EXISTS "webhooks";
+DROP TABLE IF EXISTS "channel_follows";
+DROP TABLE IF EXISTS "guild_bans";
+DROP TABLE IF EXISTS "audit_log_entries";
+DROP TABLE IF EXISTS "data_exports";
+DROP TABLE IF EXISTS "guild_members";
+DROP TABLE IF EXISTS "messages";
+DROP TABLE IF EXISTS "channels";
+DROP TABLE IF EXISTS "guilds";
+DROP TABLE IF EXISTS "api_tokens";
+DROP TABLE IF EXISTS "user_identities";
+DROP TABLE IF EXISTS "users";
@@ -0,0 +1,231 @@
+-- SQLite 無法以 ALTER TABLE 新增約束，外鍵、檢查約束與索引直接建立在初始結構中，
+-- 結果與 PostgreSQL 套用 0001、0002 後相同
+
+CREATE TABLE IF NOT EXISTS "users" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "username" text NOT NULL,
+    "email" text NOT NULL,
+    "password" text NOT NULL,
+    "nickname" text,
+    "avatar" text,
+    "status" text DEFAULT 'offline' CONSTRAINT "chk_users_status" CHECK ("status" IN ('online', 'offline', 'busy', 'away')),
+    "is_bot" boolean DEFAULT false,
+    "bot_owner_id" integer CONSTRAINT "fk_users_bot_owner" REFERENCES "users"("id") ON DELETE SET NULL,
+    "deletion_scheduled_at" datetime,
+    "anonymized_at" datetime,
+    "created_at" datetime,
+    "updated_at" datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_users_username" ON "users" ("username");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_users_email" ON "users" ("email");
+CREATE INDEX IF NOT EXISTS "idx_users_deletion_scheduled_at" ON "users" ("deletion_scheduled_at");
+CREATE INDEX IF NOT EXISTS "idx_users_bot_owner_id" ON "users" ("bot_owner_id");
+
+CREATE TABLE IF NOT EXISTS "user_identities" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "user_id" integer NOT NULL CONSTRAINT "fk_user_identities_user" REFERENCES "users"("id") ON DELETE CASCADE,
+    "provider" text NOT NULL,
+    "subject" text NOT NULL,
+    "email" text,
+    "created_at" datetime,
+    "updated_at" datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_identity_provider_subject" ON "user_identities" ("provider", "subject");
+CREATE INDEX IF NOT EXISTS "idx_user_identities_user_id" ON "user_identities" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "api_tokens" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "user_id" integer NOT NULL CONSTRAINT "fk_api_tokens_user" REFERENCES "users"("id") ON DELETE CASCADE,
+    "name" text NOT NULL,
+    "prefix" text NOT NULL,
+    "token_hash" text NOT NULL,
+    "scopes" text NOT NULL,
+    "guild_ids" text,
+    "expires_at" datetime,
+    "last_used_at" datetime,
+    "created_at" datetime,
+    "updated_at" datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_api_tokens_prefix" ON "api_tokens" ("prefix");
+CREATE INDEX IF NOT EXISTS "idx_api_tokens_user_id" ON "api_tokens" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "guilds" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "name" text NOT NULL,
+    "description" text,
+    "icon" text,
+    "owner_id" integer NOT NULL CONSTRAINT "fk_guilds_owner" REFERENCES "users"("id") ON DELETE RESTRICT,
+    "created_at" datetime,
+    "updated_at" datetime
+);
+CREATE INDEX IF NOT EXISTS "idx_guilds_owner_id" ON "guilds" ("owner_id");
+
+CREATE TABLE IF NOT EXISTS "channels" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "guild_id" integer NOT NULL CONSTRAINT "fk_channels_guild" REFERENCES "guilds"("id") ON DELETE CASCADE,
+    "name" text NOT NULL,
+    "type" text NOT NULL CONSTRAINT "chk_channels_type" CHECK ("type" IN ('text', 'voice', 'announcement', 'category')),
+    "parent_id" integer CONSTRAINT "fk_channels_parent" REFERENCES "channels"("id") ON DELETE SET NULL,
+    "topic" text,
+    "position" integer DEFAULT 0,
+    "slow_mode_seconds" integer DEFAULT 0,
+    "created_at" datetime,
+    "updated_at" datetime
+);
+CREATE INDEX IF NOT EXISTS "idx_channels_parent_id" ON "channels" ("parent_id");
+CREATE INDEX IF NOT EXISTS "idx_channels_guild_position" ON "channels" ("guild_id", "position");
+
+CREATE TABLE IF NOT EXISTS "messages" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "channel_id" integer NOT NULL CONSTRAINT "fk_messages_channel" REFERENCES "channels"("id") ON DELETE CASCADE,
+    "user_id" integer NOT NULL CONSTRAINT "fk_messages_user" REFERENCES "users"("id") ON DELETE RESTRICT,
+    "content" text NOT NULL,
+    "type" text DEFAULT 'text' CONSTRAINT "chk_messages_type" CHECK ("type" IN ('text', 'image', 'file')),
+    "published_at" datetime,
+    "source_message_id" integer,
+    "source_channel_id" integer,
+    "webhook_id" integer,
+    "author_name" text,
+    "author_avatar" text,
+    "created_at" datetime,
+    "updated_at" datetime
+);
+CREATE INDEX IF NOT EXISTS "idx_messages_webhook_id" ON "messages" ("webhook_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_message_crosspost" ON "messages" ("channel_id", "source_message_id");
+CREATE INDEX IF NOT EXISTS "idx_messages_channel_created" ON "messages" ("channel_id", "created_at" DESC);
+CREATE INDEX IF NOT EXISTS "idx_messages_user_created" ON "messages" ("user_id", "created_at" DESC);
+
+CREATE TABLE IF NOT EXISTS "guild_members" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "guild_id" integer NOT NULL CONSTRAINT "fk_guild_members_guild" REFERENCES "guilds"("id") ON DELETE CASCADE,
+    "user_id" integer NOT NULL CONSTRAINT "fk_guild_members_user" REFERENCES "users"("id") ON DELETE CASCADE,
+    "nickname" text,
+    "role" text DEFAULT 'member' CONSTRAINT "chk_guild_members_role" CHECK ("role" IN ('owner', 'admin', 'moderator', 'member')),
+    "joined_at" datetime,
+    "timeout_until" datetime,
+    "created_at" datetime,
+    "updated_at" datetime
+);
+CREATE INDEX IF NOT EXISTS "idx_guild_members_timeout_until" ON "guild_members" ("timeout_until");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_guild_member" ON "guild_members" ("guild_id", "user_id");
+CREATE INDEX IF NOT EXISTS "idx_guild_members_user_id" ON "guild_members" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "data_exports" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "user_id" integer NOT NULL CONSTRAINT "fk_data_exports_user" REFERENCES "users"("id") ON DELETE CASCADE,
+    "status" text NOT NULL DEFAULT 'pending' CONSTRAINT "chk_data_exports_status" CHECK ("status" IN ('pending', 'processing', 'ready', 'failed')),
+    "blob_key" text,
+    "size" integer,
+    "error" text,
+    "completed_at" datetime,
+    "expires_at" datetime,
+    "created_at" datetime,
+    "updated_at" datetime
+);
+CREATE INDEX IF NOT EXISTS "idx_data_exports_expires_at" ON "data_exports" ("expires_at");
+CREATE INDEX IF NOT EXISTS "idx_data_exports_user_id" ON "data_exports" ("user_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_data_exports_active" ON "data_exports" ("user_id")
+    WHERE "status" IN ('pending', 'processing');
+
+CREATE TABLE IF NOT EXISTS "audit_log_entries" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "guild_id" integer NOT NULL,
+    "actor_id" integer NOT NULL,
+    "action" text NOT NULL,
+    "target_type" text,
+    "target_id" integer,
+    "changes" text,
+    "reason" text,
+    "created_at" datetime
+);
+CREATE INDEX IF NOT EXISTS "idx_audit_guild" ON "audit_log_entries" ("guild_id", "created_at");
+CREATE INDEX IF NOT EXISTS "idx_audit_log_entries_action" ON "audit_log_entries" ("action");
+CREATE INDEX IF NOT EXISTS "idx_audit_log_entries_actor_id" ON "audit_log_entries" ("actor_id");
+
+CREATE TABLE IF NOT EXISTS "guild_bans" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "guild_id" integer NOT NULL CONSTRAINT "fk_guild_bans_guild" REFERENCES "guilds"("id") ON DELETE CASCADE,
+    "user_id" integer NOT NULL CONSTRAINT "fk_guild_bans_user" REFERENCES "users"("id") ON DELETE CASCADE,
+    "moderator_id" integer NOT NULL,
+    "reason" text,
+    "expires_at" datetime,
+    "created_at" datetime
+);
+CREATE INDEX IF NOT EXISTS "idx_guild_bans_expires_at" ON "guild_bans" ("expires_at");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_guild_ban_user" ON "guild_bans" ("guild_id", "user_id");
+
+CREATE TABLE IF NOT EXISTS "channel_follows" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "source_channel_id" integer NOT NULL CONSTRAINT "fk_channel_follows_source_channel" REFERENCES "channels"("id") ON DELETE CASCADE,
+    "target_channel_id" integer NOT NULL CONSTRAINT "fk_channel_follows_target_channel" REFERENCES "channels"("id") ON DELETE CASCADE,
+    "guild_id" integer NOT NULL CONSTRAINT "fk_channel_follows_guild" REFERENCES "guilds"("id") ON DELETE CASCADE,
+    "created_by_id" integer NOT NULL,
+    "created_at" datetime
+);
+CREATE INDEX IF NOT EXISTS "idx_channel_follows_guild_id" ON "channel_follows" ("guild_id");
+CREATE INDEX IF NOT EXISTS "idx_channel_follows_target_channel_id" ON "channel_follows" ("target_channel_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_channel_follow" ON "channel_follows" ("source_channel_id", "target_channel_id");
+
+CREATE TABLE IF NOT EXISTS "webhooks" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "guild_id" integer NOT NULL CONSTRAINT "fk_webhooks_guild" REFERENCES "guilds"("id") ON DELETE CASCADE,
+    "channel_id" integer NOT NULL CONSTRAINT "fk_webhooks_channel" REFERENCES "channels"("id") ON DELETE CASCADE,
+    "user_id" integer NOT NULL CONSTRAINT "fk_webhooks_user" REFERENCES "users"("id") ON DELETE RESTRICT,
+    "name" text NOT NULL,
+    "avatar" text,
+    "token_hash" text NOT NULL,
+    "created_by_id" integer NOT NULL,
+    "created_at" datetime,
+    "updated_at" datetime
+);
+CREATE INDEX IF NOT EXISTS "idx_webhooks_guild_id" ON "webhooks" ("guild_id");
+CREATE INDEX IF NOT EXISTS "idx_webhooks_channel_id" ON "webhooks" ("channel_id");
+
+CREATE TABLE IF NOT EXISTS "event_webhooks" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "guild_id" integer NOT NULL CONSTRAINT "fk_event_webhooks_guild" REFERENCES "guilds"("id") ON DELETE CASCADE,
+    "url" text NOT NULL,
+    "secret" text NOT NULL,
+    "events" text NOT NULL,
+    "enabled" boolean NOT NULL,
+    "failure_count" integer NOT NULL,
+    "disabled_at" datetime,
+    "created_by_id" integer NOT NULL,
+    "created_at" datetime,
+    "updated_at" datetime
+);
+CREATE INDEX IF NOT EXISTS "idx_event_webhooks_guild_id" ON "event_webhooks" ("guild_id");
+
+CREATE TABLE IF NOT EXISTS "event_deliveries" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "webhook_id" integer NOT NULL CONSTRAINT "fk_event_deliveries_webhook" REFERENCES "event_webhooks"("id") ON DELETE CASCADE,
+    "event_type" text NOT NULL,
+    "payload" text NOT NULL,
+    "status" text NOT NULL CONSTRAINT "chk_event_deliveries_status" CHECK ("status" IN ('pending', 'succeeded', 'failed')),
+    "attempts" integer NOT NULL,
+    "next_attempt_at" datetime,
+    "response_status" integer,
+    "last_error" text,
+    "delivered_at" datetime,
+    "created_at" datetime,
+    "updated_at" datetime
+);
+CREATE INDEX IF NOT EXISTS "idx_event_deliveries_created_at" ON "event_deliveries" ("created_at");
+CREATE INDEX IF NOT EXISTS "idx_event_delivery_due" ON "event_deliveries" ("status", "next_attempt_at");
+CREATE INDEX IF NOT EXISTS "idx_event_deliveries_webhook_id" ON "event_deliveries" ("webhook_id");
+
+CREATE TABLE IF NOT EXISTS "outbox_events" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "type" text NOT NULL,
+    "guild_id" integer NOT NULL,
+    "channel_id" integer,
+    "payload" text NOT NULL,
+    "origin" text NOT NULL,
+    "attempts" integer NOT NULL,
+    "last_error" text,
+    "created_at" datetime,
+    "dispatched_at" datetime
+);
+CREATE INDEX IF NOT EXISTS "idx_outbox_pending" ON "outbox_events" ("dispatched_at");
+CREATE INDEX IF NOT EXISTS "idx_outbox_events_created_at" ON "outbox_events" ("created_at");
+CREATE INDEX IF NOT EXISTS "idx_outbox_undispatched" ON "outbox_events" ("id") WHERE "dispatched_at" IS NULL;
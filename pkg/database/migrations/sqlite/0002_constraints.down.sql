@@ -0,0 +1,2 @@
+-- 約束與索引已包含在 0001_initial_schema，保留此版本讓兩種資料庫的版本號一致
+SELECT 1;
@@ -0,0 +1,2 @@
+ALTER TABLE "event_deliveries" DROP COLUMN "trace_parent";
+ALTER TABLE "outbox_events" DROP COLUMN "trace_parent";
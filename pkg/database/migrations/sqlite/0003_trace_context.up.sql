@@ -0,0 +1,3 @@
+-- 非同步處理的事件記錄產生它的請求的 W3C traceparent，轉送與 webhook 投遞時接續同一個追蹤
+ALTER TABLE "outbox_events" ADD COLUMN "trace_parent" text;
+ALTER TABLE "event_deliveries" ADD COLUMN "trace_parent" text;
@@ -0,0 +1,9 @@
+-- 以 SSO 建立的帳號沒有可用的本地密碼（password 欄位為隨機雜湊），
+-- 只有這類帳號能以身分提供者驗證過的 email 自動連結，敏感操作改以最近一次登入確認
+ALTER TABLE "users" ADD COLUMN "no_local_password" boolean DEFAULT false;
+
+-- 既有帳號無法從資料判斷是否有本地密碼：SSO 帳號的隨機雜湊與一般密碼雜湊無從區分，
+-- 已連結身分的密碼帳號也同樣有身分連結。有身分連結的帳號標記為未知（NULL），
+-- 下次以密碼登入成功時改為 false；沒有身分連結的帳號一定是以密碼註冊，維持 false
+UPDATE "users" SET "no_local_password" = NULL
+WHERE EXISTS (SELECT 1 FROM "user_identities" i WHERE i."user_id" = "users"."id");
@@ -0,0 +1,103 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	gosqlite "github.com/glebarez/go-sqlite"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// SQLiteMemory 以此路徑開啟的 SQLite 資料庫只存在記憶體中
+const SQLiteMemory = ":memory:"
+
+// sqlitePragmas 每條連線開啟時套用的設定：
+// 啟用外鍵、WAL 日誌、等待鎖定最多 5 秒，並在交易開始時就取得寫入鎖
+const sqlitePragmas = "_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_txlock=immediate"
+
+// openSQLite 建立 SQLite 的 GORM dialector，使用純 Go 實作的驅動程式，不需要 cgo
+func openSQLite(path string) (gorm.Dialector, error) {
+	if path == "" {
+		return nil, fmt.Errorf("sqlite database path is required")
+	}
+
+	if path != SQLiteMemory {
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			return nil, fmt.Errorf("failed to create database directory: %w", err)
+		}
+	}
+
+	conn := sql.OpenDB(&utcConnector{dsn: path + "?" + sqlitePragmas})
+
+	return &sqlite.Dialector{DriverName: sqlite.DriverName, Conn: conn}, nil
+}
+
+// sqliteTimeFormat 寫入 SQLite 的時間格式
+//
+// SQLite 以文字儲存時間，時間的比較與排序都是字串比較，
+// 因此一律轉為 UTC 並固定小數位數，讓字典順序與時間順序一致
+const sqliteTimeFormat = "2006-01-02 15:04:05.000000000-07:00"
+
+// utcConnector 開啟的連線會將時間參數轉為固定格式的 UTC 字串
+type utcConnector struct {
+	dsn string
+}
+
+// sqliteConn SQLite 驅動程式連線實作的介面
+type sqliteConn interface {
+	driver.Conn
+	driver.ConnBeginTx
+	driver.ConnPrepareContext
+	driver.ExecerContext
+	driver.QueryerContext
+}
+
+type utcConn struct {
+	sqliteConn
+}
+
+// Connect 開啟一條新的 SQLite 連線
+func (c *utcConnector) Connect(context.Context) (driver.Conn, error) {
+	conn, err := c.Driver().Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	sc, ok := conn.(sqliteConn)
+	if !ok {
+		_ = conn.Close()
+		return nil, fmt.Errorf("unexpected sqlite connection type %T", conn)
+	}
+
+	return &utcConn{sqliteConn: sc}, nil
+}
+
+// Driver 回傳底層的 SQLite 驅動程式
+func (c *utcConnector) Driver() driver.Driver {
+	return &gosqlite.Driver{}
+}
+
+// CheckNamedValue 將時間參數轉換為固定格式的 UTC 字串，其他型別交給預設的轉換規則
+func (c *utcConn) CheckNamedValue(value *driver.NamedValue) error {
+	switch v := value.Value.(type) {
+	case time.Time:
+		value.Value = v.UTC().Format(sqliteTimeFormat)
+		return nil
+	case *time.Time:
+		if v == nil {
+			value.Value = nil
+		} else {
+			value.Value = v.UTC().Format(sqliteTimeFormat)
+		}
+
+		return nil
+	default:
+		return driver.ErrSkip
+	}
+}
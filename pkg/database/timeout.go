@@ -0,0 +1,69 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const queryCancelKey = "talkrealm:query_cancel"
+
+// registerQueryTimeout 為每個 create/query/update/delete 語句加上逾時限制
+//
+// 逾時會套用在呼叫端傳入的 context 之上，因此請求被取消時查詢也會一併中止；
+// Row/Raw 回傳的游標由呼叫端自行讀取，不在此限制範圍內
+func registerQueryTimeout(db *gorm.DB, timeout time.Duration) error {
+	if timeout <= 0 {
+		return nil
+	}
+
+	before := func(tx *gorm.DB) {
+		ctx := tx.Statement.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		tx.Statement.Context = ctx
+		tx.InstanceSet(queryCancelKey, cancel)
+	}
+
+	after := func(tx *gorm.DB) {
+		if cancel, ok := tx.InstanceGet(queryCancelKey); ok {
+			cancel.(context.CancelFunc)()
+		}
+	}
+
+	cb := db.Callback()
+
+	if err := cb.Create().Before("gorm:create").Register("talkrealm:timeout_before", before); err != nil {
+		return err
+	}
+
+	if err := cb.Create().After("gorm:create").Register("talkrealm:timeout_after", after); err != nil {
+		return err
+	}
+
+	if err := cb.Query().Before("gorm:query").Register("talkrealm:timeout_before", before); err != nil {
+		return err
+	}
+
+	if err := cb.Query().After("gorm:after_query").Register("talkrealm:timeout_after", after); err != nil {
+		return err
+	}
+
+	if err := cb.Update().Before("gorm:update").Register("talkrealm:timeout_before", before); err != nil {
+		return err
+	}
+
+	if err := cb.Update().After("gorm:update").Register("talkrealm:timeout_after", after); err != nil {
+		return err
+	}
+
+	if err := cb.Delete().Before("gorm:delete").Register("talkrealm:timeout_before", before); err != nil {
+		return err
+	}
+
+	return cb.Delete().After("gorm:delete").Register("talkrealm:timeout_after", after)
+}
@@ -0,0 +1,18 @@
+package database
+
+import (
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+	gormtracing "gorm.io/plugin/opentelemetry/tracing"
+)
+
+// Trace 為每個查詢建立 span，作為請求 span 的子 span
+//
+// 查詢參數可能包含訊息內容與密碼雜湊，只記錄 SQL 本身
+func Trace(db *gorm.DB, provider trace.TracerProvider) error {
+	return db.Use(gormtracing.NewPlugin(
+		gormtracing.WithTracerProvider(provider),
+		gormtracing.WithoutQueryVariables(),
+		gormtracing.WithoutMetrics(),
+	))
+}
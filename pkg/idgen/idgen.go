@@ -0,0 +1,28 @@
+package idgen
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// Generator 產生不重複的識別碼（伺服器實例 ID、系統帳號名稱等）
+//
+// 只用於識別，不可用來產生 token 或密碼等秘密資訊
+type Generator interface {
+	NewID() string
+}
+
+type randomGenerator struct{}
+
+// Random 以 128 位元隨機數產生十六進位識別碼的 Generator
+func Random() Generator {
+	return randomGenerator{}
+}
+
+// NewID 產生新的識別碼
+func (randomGenerator) NewID() string {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+
+	return hex.EncodeToString(id)
+}
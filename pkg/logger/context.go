@@ -0,0 +1,38 @@
+package logger
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+type (
+	requestIDKey struct{}
+	loggerKey    struct{}
+)
+
+// WithRequestID 將請求 ID 放入 context
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID 取得 context 中的請求 ID，不在請求中時回傳空字串
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// NewContext 將附帶請求 ID 等欄位的 logger 放入 context
+func NewContext(ctx context.Context, logger *zap.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext 取得 context 中的 logger，讓請求期間的每一行日誌都帶有請求 ID；
+// 不在請求中（例如背景工作）時回傳 fallback
+func FromContext(ctx context.Context, fallback *zap.Logger) *zap.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*zap.Logger); ok {
+		return logger
+	}
+
+	return fallback
+}
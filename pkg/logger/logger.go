@@ -1,17 +1,18 @@
 package logger
 
 import (
+	"github.com/walnut-almonds/talkrealm/pkg/config"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
-var log *zap.Logger
-
-// Init 初始化日誌系統
-func Init(level string) error {
+// New 依日誌設定建立 JSON 格式的 logger，設定中的敏感欄位會被遮蔽
+//
+// 不設定全域 logger，需要記錄日誌的元件由建構函式注入
+func New(cfg *config.LogConfig) (*zap.Logger, error) {
 	var zapLevel zapcore.Level
 
-	switch level {
+	switch cfg.Level {
 	case "debug":
 		zapLevel = zapcore.DebugLevel
 	case "info":
@@ -24,53 +25,31 @@ func Init(level string) error {
 		zapLevel = zapcore.InfoLevel
 	}
 
-	config := zap.Config{
-		Level:            zap.NewAtomicLevelAt(zapLevel),
-		Development:      false,
-		Encoding:         "json",
-		EncoderConfig:    zap.NewProductionEncoderConfig(),
-		OutputPaths:      []string{"stdout"},
-		ErrorOutputPaths: []string{"stderr"},
-	}
-
-	var err error
-
-	log, err = config.Build()
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return build(zapLevel, []string{"stdout"}, cfg.RedactFields)
 }
 
-// Sync 同步日誌
-func Sync() {
-	if log != nil {
-		_ = log.Sync()
+// NewAudit 建立輸出稽核紀錄的 logger，output 為 stdout、stderr 或檔案路徑
+//
+// 稽核紀錄與一般日誌分開輸出，方便另外保存或送往 SIEM；output 為空白時回傳不輸出的 logger
+func NewAudit(output string, redactFields []string) (*zap.Logger, error) {
+	if output == "" {
+		return zap.NewNop(), nil
 	}
-}
 
-// Debug 除錯級別日誌
-func Debug(msg string, fields ...any) {
-	log.Sugar().Debugw(msg, fields...)
+	return build(zapcore.InfoLevel, []string{output}, redactFields)
 }
 
-// Info 資訊級別日誌
-func Info(msg string, fields ...any) {
-	log.Sugar().Infow(msg, fields...)
-}
-
-// Warn 警告級別日誌
-func Warn(msg string, fields ...any) {
-	log.Sugar().Warnw(msg, fields...)
-}
-
-// Error 錯誤級別日誌
-func Error(msg string, fields ...any) {
-	log.Sugar().Errorw(msg, fields...)
-}
+func build(level zapcore.Level, outputs, redactFields []string) (*zap.Logger, error) {
+	config := zap.Config{
+		Level:            zap.NewAtomicLevelAt(level),
+		Development:      false,
+		Encoding:         "json",
+		EncoderConfig:    zap.NewProductionEncoderConfig(),
+		OutputPaths:      outputs,
+		ErrorOutputPaths: []string{"stderr"},
+	}
 
-// Fatal 致命錯誤級別日誌
-func Fatal(msg string, fields ...any) {
-	log.Sugar().Fatalw(msg, fields...)
+	return config.Build(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return NewRedactingCore(core, redactFields)
+	}))
 }
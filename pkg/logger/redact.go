@@ -0,0 +1,144 @@
+package logger
+
+import (
+	"net/url"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Redacted 取代敏感內容的字串
+const Redacted = "[REDACTED]"
+
+// redactingCore 將指定名稱的欄位值取代為 [REDACTED]，避免密碼或 token 被寫入日誌
+type redactingCore struct {
+	zapcore.Core
+	keys map[string]bool
+}
+
+// NewRedactingCore 包裝 core，名稱符合 keys（不分大小寫）的欄位一律遮蔽
+func NewRedactingCore(core zapcore.Core, keys []string) zapcore.Core {
+	if len(keys) == 0 {
+		return core
+	}
+
+	return &redactingCore{Core: core, keys: lowerSet(keys)}
+}
+
+func (c *redactingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactingCore{Core: c.Core.With(c.redact(fields)), keys: c.keys}
+}
+
+func (c *redactingCore) Check(
+	entry zapcore.Entry,
+	checked *zapcore.CheckedEntry,
+) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+
+	return checked
+}
+
+func (c *redactingCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(entry, c.redact(fields))
+}
+
+// redact 回傳遮蔽後的欄位；沒有需要遮蔽的欄位時直接回傳原本的 slice
+func (c *redactingCore) redact(fields []zapcore.Field) []zapcore.Field {
+	var out []zapcore.Field
+
+	for i, field := range fields {
+		if !c.keys[strings.ToLower(field.Key)] {
+			continue
+		}
+
+		if out == nil {
+			out = append([]zapcore.Field(nil), fields...)
+		}
+
+		out[i] = zap.String(field.Key, Redacted)
+	}
+
+	if out == nil {
+		return fields
+	}
+
+	return out
+}
+
+// RedactQuery 遮蔽查詢字串中名稱符合 params（不分大小寫）的參數值
+//
+// 無法解析的查詢字串整個遮蔽，避免記錄到格式錯誤但仍包含 token 的內容
+func RedactQuery(rawQuery string, params []string) string {
+	if rawQuery == "" || len(params) == 0 {
+		return rawQuery
+	}
+
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return Redacted
+	}
+
+	set := lowerSet(params)
+	redacted := false
+
+	for key, vals := range values {
+		if !set[strings.ToLower(key)] {
+			continue
+		}
+
+		for i := range vals {
+			vals[i] = Redacted
+		}
+
+		redacted = true
+	}
+
+	if !redacted {
+		return rawQuery
+	}
+
+	return values.Encode()
+}
+
+// RedactPath 依路由樣板遮蔽路徑中名稱符合 params 的路徑參數，例如 /webhooks/:id/:token 的 token
+func RedactPath(path, route string, params []string) string {
+	if route == "" || len(params) == 0 || strings.Contains(route, "*") {
+		return path
+	}
+
+	pathSegments := strings.Split(path, "/")
+	routeSegments := strings.Split(route, "/")
+
+	if len(pathSegments) != len(routeSegments) {
+		return path
+	}
+
+	set := lowerSet(params)
+	redacted := false
+
+	for i, segment := range routeSegments {
+		name, ok := strings.CutPrefix(segment, ":")
+		if ok && set[strings.ToLower(name)] {
+			pathSegments[i] = Redacted
+			redacted = true
+		}
+	}
+
+	if !redacted {
+		return path
+	}
+
+	return strings.Join(pathSegments, "/")
+}
+
+func lowerSet(keys []string) map[string]bool {
+	set := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		set[strings.ToLower(key)] = true
+	}
+
+	return set
+}
@@ -0,0 +1,168 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "talkrealm"
+
+// WebSocketStats 提供即時的 WebSocket 連線狀態，於每次抓取指標時讀取
+type WebSocketStats interface {
+	GetConnectedClients() int
+	ChannelSubscriptions() map[uint]int
+}
+
+// Metrics 應用程式的 Prometheus 指標
+//
+// 每個實例使用獨立的 registry，同一個行程內的多個伺服器不會互相干擾
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests        *prometheus.CounterVec
+	httpDuration        *prometheus.HistogramVec
+	wsSendDrops         *prometheus.CounterVec
+	wsBroadcastFanout   prometheus.Histogram
+	wsBroadcastDuration prometheus.Histogram
+	messagesCreated     *prometheus.CounterVec
+}
+
+// New 建立指標並註冊 Go runtime 與行程的指標
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by method, route template and status code.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method, route template and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		wsSendDrops: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "websocket_send_buffer_drops_total",
+			Help:      "WebSocket messages dropped because a client's send buffer was full.",
+		}, []string{"target"}),
+		wsBroadcastFanout: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "websocket_broadcast_recipients",
+			Help:      "Number of local clients a WebSocket broadcast was delivered to.",
+			Buckets:   []float64{0, 1, 2, 5, 10, 25, 50, 100, 250, 500, 1000, 5000},
+		}),
+		wsBroadcastDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "websocket_broadcast_duration_seconds",
+			Help:      "Time spent delivering a WebSocket broadcast to local clients.",
+			Buckets:   prometheus.ExponentialBuckets(0.00001, 4, 10),
+		}),
+		messagesCreated: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "messages_created_total",
+			Help:      "Messages created by source (user, webhook or crosspost).",
+		}, []string{"source"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.wsSendDrops,
+		m.wsBroadcastFanout,
+		m.wsBroadcastDuration,
+		m.messagesCreated,
+	)
+
+	return m
+}
+
+// Handler 回傳 Prometheus 抓取指標用的 HTTP 處理器
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// RegisterDB 註冊資料庫連線池的統計（sql.DB.Stats）
+func (m *Metrics) RegisterDB(db *sql.DB, name string) error {
+	return m.registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// RegisterWebSocket 註冊目前的連線數與各頻道的訂閱數
+func (m *Metrics) RegisterWebSocket(stats WebSocketStats) error {
+	connections := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "websocket_connections",
+		Help:      "Currently connected WebSocket clients.",
+	}, func() float64 {
+		return float64(stats.GetConnectedClients())
+	})
+
+	if err := m.registry.Register(connections); err != nil {
+		return err
+	}
+
+	return m.registry.Register(&subscriptionCollector{
+		stats: stats,
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "websocket", "channel_subscriptions"),
+			"WebSocket clients subscribed to each channel.",
+			[]string{"channel_id"},
+			nil,
+		),
+	})
+}
+
+// ObserveHTTPRequest 記錄一次 HTTP 請求，route 為路由樣板（例如 /api/v1/guilds/:id）
+func (m *Metrics) ObserveHTTPRequest(method, route string, status int, elapsed time.Duration) {
+	code := strconv.Itoa(status)
+	m.httpRequests.WithLabelValues(method, route, code).Inc()
+	m.httpDuration.WithLabelValues(method, route, code).Observe(elapsed.Seconds())
+}
+
+// WebSocketSendDropped 記錄一則因客戶端發送緩衝區已滿而捨棄的消息
+//
+// target 為廣播的對象種類：channel、guild、user 或 all
+func (m *Metrics) WebSocketSendDropped(target string) {
+	m.wsSendDrops.WithLabelValues(target).Inc()
+}
+
+// ObserveBroadcast 記錄一次廣播送達的本機客戶端數與花費時間
+func (m *Metrics) ObserveBroadcast(recipients int, elapsed time.Duration) {
+	m.wsBroadcastFanout.Observe(float64(recipients))
+	m.wsBroadcastDuration.Observe(elapsed.Seconds())
+}
+
+// MessageCreated 記錄一則新訊息
+func (m *Metrics) MessageCreated(source string) {
+	m.messagesCreated.WithLabelValues(source).Inc()
+}
+
+// subscriptionCollector 在抓取時計算各頻道的訂閱數，取消訂閱的頻道不會留下過時的序列
+type subscriptionCollector struct {
+	stats WebSocketStats
+	desc  *prometheus.Desc
+}
+
+func (c *subscriptionCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *subscriptionCollector) Collect(ch chan<- prometheus.Metric) {
+	for channelID, count := range c.stats.ChannelSubscriptions() {
+		ch <- prometheus.MustNewConstMetric(
+			c.desc,
+			prometheus.GaugeValue,
+			float64(count),
+			strconv.FormatUint(uint64(channelID), 10),
+		)
+	}
+}
@@ -0,0 +1,95 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// memorySweepInterval 清除過期計數的間隔
+const memorySweepInterval = time.Minute
+
+// MemoryLimiter 單一程序內的限流器，適合開發環境或單一副本部署
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	windows   map[string]*memoryWindow
+	lastSweep time.Time
+}
+
+type memoryWindow struct {
+	count   int
+	resetAt time.Time
+}
+
+// NewMemoryLimiter 建立記憶體限流器
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		windows:   make(map[string]*memoryWindow),
+		lastSweep: time.Now(),
+	}
+}
+
+// Allow 在 window 時間窗內替 key 計數一次
+func (l *MemoryLimiter) Allow(
+	_ context.Context,
+	key string,
+	limit int,
+	window time.Duration,
+) (bool, time.Duration, error) {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	w, ok := l.windows[key]
+	if !ok || !now.Before(w.resetAt) {
+		w = &memoryWindow{resetAt: now.Add(window)}
+		l.windows[key] = w
+	}
+
+	w.count++
+	if w.count > limit {
+		return false, w.resetAt.Sub(now), nil
+	}
+
+	return true, 0, nil
+}
+
+// Release 撤回一次計數
+func (l *MemoryLimiter) Release(_ context.Context, key string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if w, ok := l.windows[key]; ok && w.count > 0 && time.Now().Before(w.resetAt) {
+		w.count--
+	}
+
+	return nil
+}
+
+// Ping 記憶體限流器永遠可用
+func (l *MemoryLimiter) Ping(context.Context) error {
+	return nil
+}
+
+// Close 釋放資源
+func (l *MemoryLimiter) Close() error {
+	return nil
+}
+
+// sweep 定期移除已過期的計數，避免記憶體無限成長
+func (l *MemoryLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < memorySweepInterval {
+		return
+	}
+
+	for key, w := range l.windows {
+		if !now.Before(w.resetAt) {
+			delete(l.windows, key)
+		}
+	}
+
+	l.lastSweep = now
+}
@@ -0,0 +1,48 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/walnut-almonds/talkrealm/pkg/config"
+)
+
+// Limiter 固定時間窗的計數限流器
+type Limiter interface {
+	// Allow 在 window 時間窗內替 key 計數一次，超過 limit 時回傳 false 與需要等待的時間
+	Allow(
+		ctx context.Context,
+		key string,
+		limit int,
+		window time.Duration,
+	) (bool, time.Duration, error)
+	// Release 撤回一次 Allow 的計數，用於取得額度後操作卻沒有完成的情況；時間窗已過期時不做任何事
+	Release(ctx context.Context, key string) error
+	// Ping 檢查計數儲存是否可用，供就緒檢查使用
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+// Rule 限流規則，Limit 為 0 表示不限制
+type Rule struct {
+	Limit  int
+	Window time.Duration
+}
+
+// Enabled 檢查規則是否啟用
+func (r Rule) Enabled() bool {
+	return r.Limit > 0 && r.Window > 0
+}
+
+// New 依設定建立限流器；多個伺服器副本需要使用 redis 共用計數
+func New(cfg *config.RateLimitConfig, redisCfg *config.RedisConfig) (Limiter, error) {
+	switch cfg.Store {
+	case "", "memory":
+		return NewMemoryLimiter(), nil
+	case "redis":
+		return NewRedisLimiter(redisCfg), nil
+	default:
+		return nil, fmt.Errorf("unsupported rate limit store: %s", cfg.Store)
+	}
+}
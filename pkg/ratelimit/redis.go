@@ -0,0 +1,92 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/walnut-almonds/talkrealm/pkg/config"
+)
+
+// redisKeyPrefix 限流計數在 Redis 中的 key 前綴
+const redisKeyPrefix = "talkrealm:ratelimit:"
+
+// allowScript 原子地遞增計數，第一次計數時設定過期時間，並回傳目前計數與剩餘毫秒
+var allowScript = redis.NewScript(`
+local count = redis.call("INCR", KEYS[1])
+if count == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+local ttl = redis.call("PTTL", KEYS[1])
+if ttl < 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+	ttl = tonumber(ARGV[1])
+end
+return {count, ttl}
+`)
+
+// releaseScript 計數仍存在且大於 0 時減一，不會延長或清除過期時間
+var releaseScript = redis.NewScript(`
+local count = tonumber(redis.call("GET", KEYS[1]) or "0")
+if count > 0 then
+	redis.call("DECR", KEYS[1])
+end
+return 0
+`)
+
+// RedisLimiter 以 Redis 共用計數的限流器，多個伺服器副本會共用同一組限制
+type RedisLimiter struct {
+	client *redis.Client
+}
+
+// NewRedisLimiter 建立 Redis 限流器
+func NewRedisLimiter(cfg *config.RedisConfig) *RedisLimiter {
+	return &RedisLimiter{
+		client: redis.NewClient(&redis.Options{
+			Addr:     fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
+			Password: cfg.Password,
+			DB:       cfg.DB,
+		}),
+	}
+}
+
+// Allow 在 window 時間窗內替 key 計數一次
+func (l *RedisLimiter) Allow(
+	ctx context.Context,
+	key string,
+	limit int,
+	window time.Duration,
+) (bool, time.Duration, error) {
+	result, err := allowScript.Run(
+		ctx,
+		l.client,
+		[]string{redisKeyPrefix + key},
+		window.Milliseconds(),
+	).Int64Slice()
+	if err != nil {
+		return false, 0, err
+	}
+
+	count, ttl := result[0], result[1]
+	if count > int64(limit) {
+		return false, time.Duration(ttl) * time.Millisecond, nil
+	}
+
+	return true, 0, nil
+}
+
+// Release 撤回一次計數
+func (l *RedisLimiter) Release(ctx context.Context, key string) error {
+	return releaseScript.Run(ctx, l.client, []string{redisKeyPrefix + key}).Err()
+}
+
+// Ping 檢查 Redis 連線
+func (l *RedisLimiter) Ping(ctx context.Context) error {
+	return l.client.Ping(ctx).Err()
+}
+
+// Close 關閉 Redis 連線
+func (l *RedisLimiter) Close() error {
+	return l.client.Close()
+}
@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/walnut-almonds/talkrealm/pkg/config"
+)
+
+var (
+	ErrObjectNotFound = errors.New("object not found")
+	ErrInvalidKey     = errors.New("invalid object key")
+)
+
+// BlobStore 檔案物件儲存介面
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader) (int64, error)
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	// Ping 檢查儲存是否可用，供就緒檢查使用
+	Ping(ctx context.Context) error
+}
+
+// New 依設定建立檔案儲存
+func New(cfg *config.StorageConfig) (BlobStore, error) {
+	switch cfg.Driver {
+	case "", "local":
+		return NewLocalStore(cfg.LocalPath)
+	default:
+		return nil, fmt.Errorf("unsupported storage driver: %s", cfg.Driver)
+	}
+}
+
+// LocalStore 以本機檔案系統實作的檔案儲存
+type LocalStore struct {
+	root string
+}
+
+// NewLocalStore 建立本機檔案儲存
+func NewLocalStore(root string) (*LocalStore, error) {
+	if root == "" {
+		root = "./data"
+	}
+
+	if err := os.MkdirAll(root, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	return &LocalStore{root: root}, nil
+}
+
+// Put 寫入物件，先寫入暫存檔再改名，避免讀到不完整的檔案
+func (s *LocalStore) Put(_ context.Context, key string, r io.Reader) (int64, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return 0, err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return 0, err
+	}
+
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	size, err := io.Copy(tmp, r)
+	if err != nil {
+		_ = tmp.Close()
+		return 0, err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return 0, err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return 0, err
+	}
+
+	return size, nil
+}
+
+// Open 開啟物件
+func (s *LocalStore) Open(_ context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path) //nolint:gosec // 路徑已經過 path 檢查
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrObjectNotFound
+		}
+
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// Delete 刪除物件，物件不存在時不視為錯誤
+func (s *LocalStore) Delete(_ context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// Ping 檢查根目錄是否存在
+func (s *LocalStore) Ping(_ context.Context) error {
+	info, err := os.Stat(s.root)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("storage root is not a directory: %s", s.root)
+	}
+
+	return nil
+}
+
+// path 將物件 key 轉為檔案路徑，並防止跳出根目錄
+func (s *LocalStore) path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if key == "" || strings.Contains(key, "..") || clean == "/" {
+		return "", ErrInvalidKey
+	}
+
+	return filepath.Join(s.root, filepath.FromSlash(clean)), nil
+}
@@ -0,0 +1,179 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/walnut-almonds/talkrealm/buildinfo"
+	"github.com/walnut-almonds/talkrealm/pkg/config"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"go.uber.org/zap"
+)
+
+// 支援的匯出方式
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+// ScopeName 應用程式建立 span 時使用的 instrumentation scope
+const ScopeName = "github.com/walnut-almonds/talkrealm"
+
+// propagator 以 W3C Trace Context 與 Baggage 在請求、outbox 事件與 webhook 之間傳遞追蹤
+var propagator = propagation.NewCompositeTextMapPropagator(
+	propagation.TraceContext{},
+	propagation.Baggage{},
+)
+
+// Provider 追蹤提供者
+type Provider struct {
+	trace.TracerProvider
+	shutdown func(ctx context.Context) error
+}
+
+// New 依設定建立追蹤提供者，exporter 為 none 時不產生任何 span
+func New(ctx context.Context, cfg *config.TracingConfig) (*Provider, error) {
+	var (
+		exporter sdktrace.SpanExporter
+		err      error
+	)
+
+	switch cfg.Exporter {
+	case "", ExporterNone:
+		return Noop(), nil
+	case ExporterOTLP:
+		opts := []otlptracehttp.Option{}
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+		}
+
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	default:
+		return nil, fmt.Errorf("unsupported tracing exporter: %s", cfg.Exporter)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s trace exporter: %w", cfg.Exporter, err)
+	}
+
+	res := resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),
+		semconv.ServiceVersion(buildinfo.Version),
+	)
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(routeTargetProcessor{}),
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(
+			sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio)),
+		),
+	)
+
+	return &Provider{TracerProvider: provider, shutdown: provider.Shutdown}, nil
+}
+
+// httpTargetKey HTTP 伺服器 span 記錄請求路徑的屬性（舊版語意慣例，otelgin 仍會設定）
+const httpTargetKey = attribute.Key("http.target")
+
+// routeTargetProcessor 將伺服器 span 的 http.target 改為路由樣板
+//
+// 請求路徑可能帶有機密，例如 /api/v1/webhooks/:id/:token 的 webhook token，不能寫入追蹤。
+// 沒有對應路由的請求（404）無法得知哪些片段是機密，直接清空
+type routeTargetProcessor struct{}
+
+// OnStart 在 span 開始時以 http.route 覆寫 http.target
+func (routeTargetProcessor) OnStart(_ context.Context, s sdktrace.ReadWriteSpan) {
+	var (
+		hasTarget bool
+		route     string
+	)
+
+	for _, attr := range s.Attributes() {
+		switch attr.Key {
+		case httpTargetKey:
+			hasTarget = true
+		case semconv.HTTPRouteKey:
+			route = attr.Value.AsString()
+		}
+	}
+
+	if hasTarget {
+		s.SetAttributes(httpTargetKey.String(route))
+	}
+}
+
+// OnEnd 不需要處理
+func (routeTargetProcessor) OnEnd(sdktrace.ReadOnlySpan) {}
+
+// Shutdown 不需要處理
+func (routeTargetProcessor) Shutdown(context.Context) error { return nil }
+
+// ForceFlush 不需要處理
+func (routeTargetProcessor) ForceFlush(context.Context) error { return nil }
+
+// Noop 不產生任何 span 的追蹤提供者
+func Noop() *Provider {
+	return &Provider{
+		TracerProvider: noop.NewTracerProvider(),
+		shutdown:       func(context.Context) error { return nil },
+	}
+}
+
+// Shutdown 送出尚未匯出的 span 並停止匯出
+func (p *Provider) Shutdown(ctx context.Context) error {
+	return p.shutdown(ctx)
+}
+
+// Propagator 回傳請求標頭使用的傳遞格式
+func Propagator() propagation.TextMapPropagator {
+	return propagator
+}
+
+// TraceParent 將 context 中的追蹤序列化為 traceparent，沒有追蹤時回傳空字串
+//
+// 用於寫入 outbox 事件等非同步處理的紀錄，稍後再以 WithTraceParent 接續同一個追蹤
+func TraceParent(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+
+	return carrier.Get("traceparent")
+}
+
+// WithTraceParent 回傳以 traceparent 為上層 span 的 context，traceparent 為空時原樣回傳
+func WithTraceParent(ctx context.Context, traceParent string) context.Context {
+	if traceParent == "" {
+		return ctx
+	}
+
+	return propagator.Extract(ctx, propagation.MapCarrier{"traceparent": traceParent})
+}
+
+// LogFields 回傳 context 中追蹤的 trace_id 與 span_id，讓日誌能對應到追蹤
+func LogFields(ctx context.Context) []zap.Field {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return nil
+	}
+
+	return []zap.Field{
+		zap.String("trace_id", sc.TraceID().String()),
+		zap.String("span_id", sc.SpanID().String()),
+	}
+}
@@ -0,0 +1,83 @@
+package tracing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestRouteTargetProcessorHidesRequestPath(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(routeTargetProcessor{}),
+		sdktrace.WithSpanProcessor(recorder),
+	)
+
+	router := gin.New()
+	router.Use(otelgin.Middleware("test", otelgin.WithTracerProvider(provider)))
+	router.POST("/api/v1/webhooks/:id/:token", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	const secret = "s3cr3t-webhook-token"
+
+	tests := []struct {
+		name       string
+		path       string
+		wantTarget string
+	}{
+		{
+			name:       "matched route",
+			path:       "/api/v1/webhooks/42/" + secret,
+			wantTarget: "/api/v1/webhooks/:id/:token",
+		},
+		{
+			name:       "unmatched route",
+			path:       "/api/v1/webhook/42/" + secret,
+			wantTarget: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router.ServeHTTP(
+				httptest.NewRecorder(),
+				httptest.NewRequest(http.MethodPost, tt.path+"?wait=true", nil),
+			)
+
+			spans := recorder.Ended()
+			if len(spans) == 0 {
+				t.Fatal("no span recorded")
+			}
+
+			span := spans[len(spans)-1]
+
+			var target string
+			for _, attr := range span.Attributes() {
+				if strings.Contains(attr.Value.Emit(), secret) {
+					t.Errorf("attribute %s leaks the token: %q", attr.Key, attr.Value.Emit())
+				}
+
+				if attr.Key == httpTargetKey {
+					target = attr.Value.AsString()
+				}
+			}
+
+			if strings.Contains(span.Name(), secret) {
+				t.Errorf("span name leaks the token: %q", span.Name())
+			}
+
+			if target != tt.wantTarget {
+				t.Errorf("http.target = %q, want %q", target, tt.wantTarget)
+			}
+		})
+	}
+}
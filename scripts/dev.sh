@@ -67,7 +67,7 @@ docker_reset() {
     docker-compose down -v
     docker-compose up -d
     sleep 3
-    go run scripts/migrate.go
+    go run cmd/server/main.go migrate up
     echo -e "${GREEN}✅ 資料庫已重置${NC}"
   else
     echo "已取消"
@@ -77,7 +77,7 @@ docker_reset() {
 # 資料庫遷移
 migrate() {
   echo -e "${CYAN}📦 執行資料庫遷移...${NC}"
-  go run scripts/migrate.go
+  go run cmd/server/main.go migrate up
   echo -e "${GREEN}✅ 遷移完成${NC}"
 }
 
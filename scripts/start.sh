@@ -24,11 +24,11 @@ go mod download
 
 # 執行資料庫遷移
 echo "🗄️  執行資料庫遷移..."
-go run scripts/migrate.go
+go run cmd/server/main.go migrate up
 
 # 建置伺服器
 echo "🔨 建置伺服器..."
-go build -o bin/server cmd/server/main.go
+go build -o bin/server ./cmd/server
 
 # 啟動伺服器
 echo ""